@@ -694,6 +694,19 @@ type Workflow struct {
 	Concurrency *Concurrency
 	// Jobs is mappings from job ID to the job object. Keys are in lower case since they are case-insensitive.
 	Jobs map[string]*Job
+	// CompositeAction is set when this tree was built from a composite action metadata file
+	// (action.yml) instead of a workflow. Its steps are put in a single job so that step-level
+	// rules can visit them.
+	CompositeAction *CompositeAction
 	// BaseNode is a base node of the YAML file.
 	BaseNode *yaml.Node
 }
+
+// CompositeAction is metadata of a composite action whose "runs.steps" are linted.
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions#runs-for-composite-actions
+type CompositeAction struct {
+	// Inputs is mapping from input names to their keys. Keys are in lower case since they are case-insensitive.
+	Inputs map[string]*String
+	// Pos is a position of "runs" section in source.
+	Pos *Position
+}
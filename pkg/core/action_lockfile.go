@@ -0,0 +1,328 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+	"gopkg.in/yaml.v3"
+)
+
+// ActionLockfilePath is the path of the action lockfile relative to the
+// repository root.
+const ActionLockfilePath = ".github/sisakulint.lock"
+
+// ActionLockfileVersion is the version of the lockfile format.
+const ActionLockfileVersion = 1
+
+const actionLockfileHeader = `# Generated by "sisakulint pin". Do not edit by hand.
+# Run "sisakulint pin -update" to bump actions to the newest tag of the same major version.
+`
+
+// ActionLockfile maps each "owner/repo@ref" used by the workflows of a
+// repository to the commit it is pinned to. ref is the tag or branch written
+// in "uses:", or the version comment of an action already pinned to a SHA.
+type ActionLockfile struct {
+	Version int                    `yaml:"version"`
+	Actions map[string]*ActionLock `yaml:"actions"`
+
+	// byRepo indexes Actions by lowercased "owner/repo", built when the
+	// lockfile is read.
+	byRepo map[string][]*ActionLock
+}
+
+// ActionLock is the resolved commit of one action ref. Version is the most
+// specific tag of the commit, such as "v4.2.1" for "v4".
+type ActionLock struct {
+	Version string `yaml:"version"`
+	SHA     string `yaml:"sha"`
+}
+
+// ReadActionLockfile reads the lockfile at path.
+func ReadActionLockfile(path string) (*ActionLockfile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read action lockfile %q: %w", path, err)
+	}
+	var lock ActionLockfile
+	if err := yaml.Unmarshal(b, &lock); err != nil {
+		return nil, fmt.Errorf("could not parse action lockfile %q: %w", path, err)
+	}
+	if lock.Version > ActionLockfileVersion {
+		return nil, fmt.Errorf("action lockfile %q has version %d but this sisakulint supports up to %d", path, lock.Version, ActionLockfileVersion)
+	}
+	if lock.Actions == nil {
+		lock.Actions = map[string]*ActionLock{}
+	}
+	for key, a := range lock.Actions {
+		if a == nil || !isFullSha(a.SHA) {
+			return nil, fmt.Errorf("action lockfile %q: %q must have a full length commit SHA", path, key)
+		}
+	}
+	lock.byRepo = lock.indexByRepo()
+	return &lock, nil
+}
+
+// loadActionLockfile reads the lockfile of the repository at root, or returns
+// nil when there is none.
+func loadActionLockfile(root string) (*ActionLockfile, error) {
+	path := filepath.Join(root, filepath.FromSlash(ActionLockfilePath))
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	return ReadActionLockfile(path)
+}
+
+// WriteFile writes the lockfile with keys in sorted order.
+func (l *ActionLockfile) WriteFile(path string) error {
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	b = append([]byte(actionLockfileHeader), b...)
+	if err := os.WriteFile(path, b, 0o644); err != nil { //nolint:gosec // the lockfile is meant to be committed
+		return fmt.Errorf("could not write action lockfile %q: %w", path, err)
+	}
+	return nil
+}
+
+// lookup returns the lock of owner/repo@ref, or nil.
+func (l *ActionLockfile) lookup(owner, repo, ref string) *ActionLock {
+	if l == nil {
+		return nil
+	}
+	return l.Actions[actionLockKeyOf(owner, repo, ref)]
+}
+
+// repoLocks returns the locks of every ref of owner/repo, sorted by version.
+func (l *ActionLockfile) repoLocks(owner, repo string) []*ActionLock {
+	if l == nil {
+		return nil
+	}
+	byRepo := l.byRepo
+	if byRepo == nil {
+		byRepo = l.indexByRepo()
+	}
+	return byRepo[strings.ToLower(owner+"/"+repo)]
+}
+
+func (l *ActionLockfile) indexByRepo() map[string][]*ActionLock {
+	byRepo := map[string][]*ActionLock{}
+	for key, a := range l.Actions {
+		if i := strings.LastIndex(key, "@"); i > 0 {
+			repo := strings.ToLower(key[:i])
+			byRepo[repo] = append(byRepo[repo], a)
+		}
+	}
+	for _, locks := range byRepo {
+		sort.Slice(locks, func(i, j int) bool { return locks[i].Version < locks[j].Version })
+	}
+	return byRepo
+}
+
+func actionLockKeyOf(owner, repo, ref string) string {
+	return strings.ToLower(owner+"/"+repo) + "@" + ref
+}
+
+// actionLockKey returns the lockfile key of a "uses:" value and its line
+// comment. Local actions, Docker images, and SHA pinned actions without a
+// version comment have no key.
+func actionLockKey(uses, comment string) (key, owner, repo, ref string, ok bool) {
+	if isLocalAction(uses) || isDockerAction(uses) || strings.Contains(uses, "${{") {
+		return "", "", "", "", false
+	}
+	owner, repo, ref, ok = parseActionRef(uses)
+	if !ok || ref == "" {
+		return "", "", "", "", false
+	}
+	if isFullSha(ref) {
+		ref = parseVersionComment(comment)
+		if ref == "" {
+			return "", "", "", "", false
+		}
+	}
+	return actionLockKeyOf(owner, repo, ref), owner, repo, ref, true
+}
+
+// resolveActionLock resolves the commit of ref and its most specific tag.
+func resolveActionLock(ctx context.Context, gh *github.Client, owner, repo, ref string) (*ActionLock, error) {
+	sha, _, err := gh.Repositories.GetCommitSHA1(ctx, owner, repo, ref, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit SHA1: %w", err)
+	}
+	version := ref
+	if shortTagPattern.MatchString(ref) || minorTagPattern.MatchString(ref) {
+		long, err := getLongVersion(gh, owner, repo, sha, ref)
+		if err != nil {
+			return nil, err
+		}
+		if long != "" {
+			version = long
+		}
+	}
+	return &ActionLock{Version: version, SHA: sha}, nil
+}
+
+// versionMajor returns the major version of a tag like "v4.2.1", or "".
+func versionMajor(tag string) string {
+	if !versionCommentPattern.MatchString(tag) {
+		return ""
+	}
+	major, _, _ := strings.Cut(strings.TrimPrefix(tag, "v"), ".")
+	return major
+}
+
+// newestTagInMajor returns the newest release tag with the same major version
+// as current, ignoring pre-releases. ok is false when no tag is newer.
+func newestTagInMajor(tags map[string]string, current string) (tag string, ok bool) {
+	major := versionMajor(current)
+	if major == "" {
+		return "", false
+	}
+	best := strings.TrimPrefix(current, "v")
+	for name := range tags {
+		if !semverPattern.MatchString(name) || strings.Contains(name, "-") || versionMajor(name) != major {
+			continue
+		}
+		if compareVersions(strings.TrimPrefix(name, "v"), best) > 0 {
+			best = strings.TrimPrefix(name, "v")
+			tag = name
+		}
+	}
+	return tag, tag != ""
+}
+
+// runPin implements "sisakulint pin", which writes the action lockfile of the
+// repository.
+func (cmd *Command) runPin(args []string) int {
+	var update bool
+	var githubTokenFlag string
+	var githubAPIURLFlag string
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(cmd.Stderr)
+	flags.BoolVar(&update, "update", false, "Bump locked actions to the newest tag within the same major version")
+	flags.StringVar(&githubTokenFlag, "github-token", "", "GitHub API token. Falls back to SISAKULINT_GITHUB_TOKEN, GITHUB_TOKEN, then GH_TOKEN")
+	flags.StringVar(&githubAPIURLFlag, "github-api-url", "", "REST API URL of a GitHub Enterprise Server. Falls back to GITHUB_API_URL")
+	flags.Usage = func() {
+		fmt.Fprintf(cmd.Stderr, "Usage: sisakulint pin [FLAGS] [DIR]\n\nResolve every action used by the workflows in DIR (default: current directory) to a commit SHA and write %s.\n\nFlags:\n", ActionLockfilePath)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitStatusSuccessNoProblem
+		}
+		return ExitStatusInvalidCommandOption
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintf(cmd.Stderr, "sisakulint pin takes at most one directory: %s\n", strings.Join(flags.Args(), " "))
+		return ExitStatusInvalidCommandOption
+	}
+	root := "."
+	if flags.NArg() == 1 {
+		root = flags.Arg(0)
+	}
+	apiURL, err := ResolveGitHubAPIURL(githubAPIURLFlag, nil)
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "Invalid value for -github-api-url: %v\n", err)
+		return ExitStatusInvalidCommandOption
+	}
+
+	generator, err := collectWorkflowActions(root, cmd.Stderr)
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+	lockPath := filepath.Join(root, filepath.FromSlash(ActionLockfilePath))
+	old := &ActionLockfile{Actions: map[string]*ActionLock{}}
+	if _, err := os.Stat(lockPath); err == nil {
+		if old, err = ReadActionLockfile(lockPath); err != nil {
+			fmt.Fprintln(cmd.Stderr, err.Error())
+			return ExitStatusFailure
+		}
+	}
+
+	ctx := context.Background()
+	token, _ := ResolveGitHubToken(githubTokenFlag, nil)
+	gh, err := NewGitHubClientWithAPIURL(ctx, token, apiURL)
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusInvalidCommandOption
+	}
+
+	lock := &ActionLockfile{Version: ActionLockfileVersion, Actions: map[string]*ActionLock{}}
+	added, updated, failed := 0, 0, 0
+	tagsOf := map[string]map[string]string{}
+	for _, ref := range generator.GetSortedActionRefs() {
+		prev := old.Actions[ref.Key]
+		switch {
+		case prev != nil && !update:
+			lock.Actions[ref.Key] = prev
+			continue
+		case prev != nil:
+			repoKey := strings.ToLower(ref.Owner + "/" + ref.Repo)
+			tags, ok := tagsOf[repoKey]
+			if !ok {
+				tags, err = listActionTags(ctx, gh, ref.Owner, ref.Repo)
+				if err != nil {
+					fmt.Fprintf(cmd.Stderr, "Could not list tags of %s/%s: %v\n", ref.Owner, ref.Repo, err)
+					failed++
+					lock.Actions[ref.Key] = prev
+					continue
+				}
+				tagsOf[repoKey] = tags
+			}
+			lock.Actions[ref.Key] = prev
+			if tag, ok := newestTagInMajor(tags, prev.Version); ok && tags[tag] != prev.SHA {
+				lock.Actions[ref.Key] = &ActionLock{Version: tag, SHA: tags[tag]}
+				fmt.Fprintf(cmd.Stdout, "%s: %s -> %s\n", ref.Key, prev.Version, tag)
+				updated++
+			}
+		default:
+			a, err := resolveActionLock(ctx, gh, ref.Owner, ref.Repo, ref.Ref)
+			if err != nil {
+				fmt.Fprintf(cmd.Stderr, "Could not resolve %s: %v\n", ref.Key, err)
+				failed++
+				continue
+			}
+			lock.Actions[ref.Key] = a
+			added++
+		}
+	}
+	removed := 0
+	for key := range old.Actions {
+		if _, ok := lock.Actions[key]; !ok {
+			removed++
+		}
+	}
+
+	if err := lock.WriteFile(lockPath); err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+	fmt.Fprintf(cmd.Stdout, "Wrote %s: %d %s (%d added, %d updated, %d removed)\n",
+		lockPath, len(lock.Actions), pluralize(len(lock.Actions), "action", "actions"), added, updated, removed)
+	if failed > 0 {
+		if token == "" {
+			fmt.Fprintln(cmd.Stderr, "Set -github-token or GITHUB_TOKEN to raise the GitHub API rate limit")
+		}
+		return ExitStatusFailure
+	}
+	return ExitStatusSuccessNoProblem
+}
+
+// lockedActionRef is an action ref collected for the lockfile.
+type lockedActionRef struct {
+	Key, Owner, Repo, Ref string
+}
+
+// sortLockedActionRefs sorts refs by key.
+func sortLockedActionRefs(refs []*lockedActionRef) {
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Key < refs[j].Key })
+}
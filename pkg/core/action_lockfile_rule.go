@@ -0,0 +1,117 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// ActionLockfileRule reports actions pinned to a SHA which disagrees with the
+// action lockfile (.github/sisakulint.lock) written by "sisakulint pin", so
+// that every workflow of a repository runs the same reviewed commit of an
+// action.
+type ActionLockfileRule struct {
+	BaseRule
+	lockfile *ActionLockfile
+}
+
+// NewActionLockfileRule creates the rule. It reports nothing when lockfile is
+// nil, i.e. the repository has no lockfile.
+func NewActionLockfileRule(lockfile *ActionLockfile) *ActionLockfileRule {
+	return &ActionLockfileRule{
+		BaseRule: BaseRule{
+			RuleName: "action-lockfile",
+			RuleDesc: "Checks that actions pinned to a commit SHA agree with .github/sisakulint.lock",
+			severity: SeverityHigh,
+		},
+		lockfile: lockfile,
+	}
+}
+
+// VisitStep compares the SHA of each pinned action with its lock. The lock is
+// found by the version comment of the line. An action of a locked repository
+// whose comment is missing or names no lock must be pinned to one of the
+// locked commits of the repository, so that dropping or editing the comment
+// cannot hide a different commit.
+func (rule *ActionLockfileRule) VisitStep(step *ast.Step) error {
+	if rule.lockfile == nil {
+		return nil
+	}
+	action, ok := step.Exec.(*ast.ExecAction)
+	if !ok || action.Uses == nil || action.Uses.BaseNode == nil {
+		return nil
+	}
+	uses := action.Uses.Value
+	if isLocalAction(uses) || isDockerAction(uses) || strings.Contains(uses, "${{") {
+		return nil
+	}
+	owner, repo, sha, ok := parseActionRef(uses)
+	if !ok || !isFullSha(sha) {
+		return nil
+	}
+	if key, lock := rule.commentLock(action); lock != nil {
+		if strings.EqualFold(lock.SHA, sha) {
+			return nil
+		}
+		rule.Errorf(step.Pos,
+			"%q is pinned to %s, but %s locks %s to %s (%s). "+
+				"Run \"sisakulint -fix on\" to apply the lockfile, or \"sisakulint pin\" if the lockfile is outdated",
+			uses, shortSHA(sha), ActionLockfilePath, key, shortSHA(lock.SHA), lock.Version)
+		rule.AddAutoFixer(NewStepFixer(step, rule))
+		return nil
+	}
+
+	locks := rule.lockfile.repoLocks(owner, repo)
+	if len(locks) == 0 {
+		return nil
+	}
+	locked := make([]string, 0, len(locks))
+	for _, l := range locks {
+		if strings.EqualFold(l.SHA, sha) {
+			return nil
+		}
+		locked = append(locked, fmt.Sprintf("%s (%s)", shortSHA(l.SHA), l.Version))
+	}
+	rule.Errorf(step.Pos,
+		"%q is pinned to %s, which is none of the commits %s locks %s/%s to: %s. "+
+			"Pin it to a locked commit with its version comment, or run \"sisakulint pin\" if the lockfile is outdated",
+		uses, shortSHA(sha), ActionLockfilePath, owner, repo, strings.Join(locked, ", "))
+	if len(locks) == 1 {
+		rule.AddAutoFixer(NewStepFixer(step, rule))
+	}
+	return nil
+}
+
+// commentLock returns the lock named by the version comment of the action,
+// or nil when there is no comment or the lockfile has no such lock.
+func (rule *ActionLockfileRule) commentLock(action *ast.ExecAction) (string, *ActionLock) {
+	key, owner, repo, ref, ok := actionLockKey(action.Uses.Value, action.Uses.BaseNode.LineComment)
+	if !ok {
+		return "", nil
+	}
+	return key, rule.lockfile.lookup(owner, repo, ref)
+}
+
+// FixStep pins the action to the locked SHA and version: the lock named by
+// the version comment, or the only lock of the repository.
+func (rule *ActionLockfileRule) FixStep(step *ast.Step) error {
+	action := step.Exec.(*ast.ExecAction)
+	_, lock := rule.commentLock(action)
+	if lock == nil {
+		owner, repo, _, ok := parseActionRef(action.Uses.Value)
+		if !ok {
+			return nil
+		}
+		locks := rule.lockfile.repoLocks(owner, repo)
+		if len(locks) != 1 {
+			return nil
+		}
+		lock = locks[0]
+	}
+	path := action.Uses.Value[:strings.LastIndex(action.Uses.Value, "@")]
+	action.Uses.Value = path + "@" + lock.SHA
+	action.Uses.BaseNode.Value = action.Uses.Value
+	action.Uses.BaseNode.LineComment = lock.Version
+	return nil
+}
@@ -0,0 +1,339 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+const (
+	lockSHA1 = "1111111111111111111111111111111111111111"
+	lockSHA2 = "2222222222222222222222222222222222222222"
+	lockSHA3 = "3333333333333333333333333333333333333333"
+)
+
+func TestReadActionLockfile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sisakulint.lock")
+	lock := &ActionLockfile{Version: ActionLockfileVersion, Actions: map[string]*ActionLock{
+		"actions/checkout@v4": {Version: "v4.2.1", SHA: lockSHA1},
+	}}
+	if err := lock.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadActionLockfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := got.lookup("Actions", "Checkout", "v4"); a == nil || a.SHA != lockSHA1 || a.Version != "v4.2.1" {
+		t.Errorf("lookup = %+v", a)
+	}
+
+	writeTestFile(t, path, "version: 1\nactions:\n  actions/checkout@v4:\n    version: v4\n    sha: main\n")
+	if _, err := ReadActionLockfile(path); err == nil {
+		t.Error("a lock without a full length SHA should be rejected")
+	}
+	writeTestFile(t, path, "version: 9\nactions: {}\n")
+	if _, err := ReadActionLockfile(path); err == nil || !strings.Contains(err.Error(), "version 9") {
+		t.Errorf("newer format version should be rejected, got %v", err)
+	}
+}
+
+func TestActionLockKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		uses, comment, want string
+	}{
+		{uses: "actions/checkout@v4", want: "actions/checkout@v4"},
+		{uses: "github/codeql-action/upload-sarif@v3", want: "github/codeql-action@v3"},
+		{uses: "Owner/Repo@" + lockSHA1, comment: "# v1.2.3", want: "owner/repo@v1.2.3"},
+		{uses: "owner/repo@" + lockSHA1},
+		{uses: "./.github/actions/local"},
+		{uses: "docker://alpine:3"},
+	}
+	for _, tc := range tests {
+		key, _, _, _, ok := actionLockKey(tc.uses, tc.comment)
+		if key != tc.want || ok != (tc.want != "") {
+			t.Errorf("actionLockKey(%q, %q) = %q, %v; want %q", tc.uses, tc.comment, key, ok, tc.want)
+		}
+	}
+}
+
+func TestNewestTagInMajor(t *testing.T) {
+	t.Parallel()
+
+	tags := map[string]string{
+		"v4": "a", "v4.1.1": "b", "v4.2.2": "c", "v4.10.0": "d", "v4.11.0-rc.1": "e", "v5.0.0": "f",
+	}
+	if tag, ok := newestTagInMajor(tags, "v4.1.1"); !ok || tag != "v4.10.0" {
+		t.Errorf("newestTagInMajor(v4.1.1) = %q, %v", tag, ok)
+	}
+	if _, ok := newestTagInMajor(tags, "v4.10.0"); ok {
+		t.Error("the newest tag should not be bumped")
+	}
+	if _, ok := newestTagInMajor(tags, "main"); ok {
+		t.Error("branches have no major version")
+	}
+}
+
+func TestActionLockfileRule(t *testing.T) {
+	t.Parallel()
+
+	root := makeTestProject(t)
+	writeTestFile(t, filepath.Join(root, ".github", "sisakulint.lock"), `version: 1
+actions:
+  actions/checkout@v4.2.1:
+    version: v4.2.1
+    sha: `+lockSHA1+`
+  actions/setup-go@v5:
+    version: v5.0.2
+    sha: `+lockSHA2+`
+`)
+	project, err := NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@` + lockSHA1 + ` # v4.2.1
+      - uses: actions/checkout@` + lockSHA3 + ` # v4.2.1
+      - uses: actions/setup-node@` + lockSHA3 + ` # v4.0.0
+`
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, ".github", "workflows", "ci.yml")
+	result, err := linter.Lint(path, []byte(src), project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []*LintingError
+	for _, e := range result.Errors {
+		if e.Type == "action-lockfile" {
+			found = append(found, e)
+		}
+	}
+	if len(found) != 1 || found[0].LineNumber != 7 || !strings.Contains(found[0].Description, "111111111111 (v4.2.1)") {
+		t.Fatalf("want one finding at line 7, got %v", found)
+	}
+
+	for _, f := range result.AutoFixers {
+		if f.RuleName() == "action-lockfile" {
+			if err := f.Fix(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	fixed, err := encodeFixedWorkflow(result.ParsedWorkflow.BaseNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(fixed), "actions/checkout@"+lockSHA3) {
+		t.Errorf("the mismatched pin should be replaced:\n%s", fixed)
+	}
+
+	// commit-sha pins unpinned refs to the locked commit without the API.
+	commitSha := CommitShaRule("")
+	commitSha.lockfile = project.ActionLockfile()
+	unpinned := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/setup-go@v5
+`
+	wf, errs := Parse([]byte(unpinned))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	step := wf.Jobs["build"].Steps[0]
+	if err := commitSha.FixStep(step); err != nil {
+		t.Fatal(err)
+	}
+	if uses := step.Exec.(*ast.ExecAction).Uses.BaseNode; uses.Value != "actions/setup-go@"+lockSHA2 || uses.LineComment != "v5.0.2" {
+		t.Errorf("commit-sha fix should use the lockfile, got %q %q", uses.Value, uses.LineComment)
+	}
+}
+
+func TestActionLockfileRule_PinsWithoutKnownComment(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sisakulint.lock")
+	writeTestFile(t, path, `version: 1
+actions:
+  actions/checkout@v4.2.1:
+    version: v4.2.1
+    sha: `+lockSHA1+`
+  actions/setup-go@v5:
+    version: v5.0.2
+    sha: `+lockSHA2+`
+  actions/setup-go@v4:
+    version: v4.1.0
+    sha: `+lockSHA3+`
+`)
+	lockfile, err := ReadActionLockfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf, errs := Parse([]byte(`on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@` + lockSHA1 + `
+      - uses: actions/setup-go@` + lockSHA2 + ` # v5.0.2
+      - uses: actions/checkout@` + lockSHA3 + `
+      - uses: actions/checkout@` + lockSHA3 + ` # v9.9.9
+      - uses: actions/setup-go@` + lockSHA1 + `
+      - uses: actions/setup-node@` + lockSHA3 + `
+`))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	rule := NewActionLockfileRule(lockfile)
+	visitor := NewSyntaxTreeVisitor()
+	visitor.AddVisitor(rule)
+	if err := visitor.VisitTree(wf); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []int
+	for _, e := range rule.Errors() {
+		lines = append(lines, e.LineNumber)
+		if !strings.Contains(e.Description, "which is none of the commits") {
+			t.Errorf("unexpected message: %s", e.Description)
+		}
+	}
+	if !reflect.DeepEqual(lines, []int{8, 9, 10}) {
+		t.Fatalf("want findings at lines 8, 9 and 10, got %v", rule.Errors())
+	}
+	// Only actions/checkout has a single lock to pin to.
+	if n := len(rule.AutoFixers()); n != 2 {
+		t.Fatalf("want 2 auto-fixers, got %d", n)
+	}
+	for _, f := range rule.AutoFixers() {
+		if err := f.Fix(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, i := range []int{2, 3} {
+		uses := wf.Jobs["build"].Steps[i].Exec.(*ast.ExecAction).Uses.BaseNode
+		if uses.Value != "actions/checkout@"+lockSHA1 || uses.LineComment != "v4.2.1" {
+			t.Errorf("step %d should be pinned to the locked commit, got %q %q", i, uses.Value, uses.LineComment)
+		}
+	}
+}
+
+// pinTestServer serves commits and tags of actions/checkout from a fake
+// GitHub Enterprise Server API.
+type pinTestServer struct {
+	mu   sync.Mutex
+	tags map[string]string
+}
+
+func (s *pinTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/api/v3")
+	switch {
+	case path == "/repos/actions/checkout/tags":
+		var b strings.Builder
+		b.WriteString("[")
+		first := true
+		for name, sha := range s.tags {
+			if !first {
+				b.WriteString(",")
+			}
+			first = false
+			b.WriteString(`{"name":"` + name + `","commit":{"sha":"` + sha + `"}}`)
+		}
+		b.WriteString("]")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(b.String()))
+	case strings.HasPrefix(path, "/repos/actions/checkout/commits/"):
+		if sha, ok := s.tags[strings.TrimPrefix(path, "/repos/actions/checkout/commits/")]; ok {
+			_, _ = w.Write([]byte(sha))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCommand_Pin(t *testing.T) {
+	t.Parallel()
+
+	fake := &pinTestServer{tags: map[string]string{"v4": lockSHA1, "v4.1.0": lockSHA1}}
+	srv := httptest.NewServer(http.HandlerFunc(fake.handler))
+	t.Cleanup(srv.Close)
+
+	root := makeTestProject(t)
+	writeTestFile(t, filepath.Join(root, ".github", "workflows", "ci.yml"), `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: ./.github/actions/local
+`)
+	pin := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		cmd := &Command{Stdout: &stdout, Stderr: &stderr}
+		status := cmd.Main(append([]string{"sisakulint", "pin", "-github-api-url", srv.URL + "/api/v3"}, append(args, root)...))
+		return status, stdout.String() + stderr.String()
+	}
+
+	if status, out := pin(); status != ExitStatusSuccessNoProblem {
+		t.Fatalf("pin failed with %d: %s", status, out)
+	}
+	lockPath := filepath.Join(root, ".github", "sisakulint.lock")
+	lock, err := ReadActionLockfile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := lock.Actions["actions/checkout@v4"]; a == nil || a.SHA != lockSHA1 || a.Version != "v4.1.0" || len(lock.Actions) != 1 {
+		t.Fatalf("unexpected lockfile: %+v", lock.Actions)
+	}
+
+	// A new release: plain pin keeps the lock, -update bumps within v4.
+	fake.mu.Lock()
+	fake.tags["v4.2.0"] = lockSHA2
+	fake.tags["v5.0.0"] = lockSHA3
+	fake.mu.Unlock()
+	if status, out := pin(); status != ExitStatusSuccessNoProblem || !strings.Contains(out, "0 added, 0 updated") {
+		t.Fatalf("pin should keep existing locks, got %d: %s", status, out)
+	}
+	status, out := pin("-update")
+	if status != ExitStatusSuccessNoProblem || !strings.Contains(out, "actions/checkout@v4: v4.1.0 -> v4.2.0") {
+		t.Fatalf("pin -update failed with %d: %s", status, out)
+	}
+	if lock, err = ReadActionLockfile(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if a := lock.Actions["actions/checkout@v4"]; a.SHA != lockSHA2 || a.Version != "v4.2.0" {
+		t.Errorf("lock was not bumped within v4: %+v", a)
+	}
+	b, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "# Generated by \"sisakulint pin\"") {
+		t.Errorf("lockfile should start with the header:\n%s", b)
+	}
+}
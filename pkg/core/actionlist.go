@@ -19,6 +19,7 @@ func NewActionListRule() *ActionList {
 		BaseRule: BaseRule{
 			RuleName: "action-list",
 			RuleDesc: "Check if action references are in whitelist or not in blacklist",
+			severity: SeverityLow,
 		},
 	}
 }
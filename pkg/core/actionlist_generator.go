@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,13 +13,15 @@ import (
 
 // ActionListGenerator は既存のワークフローファイルからアクションリストを生成する
 type ActionListGenerator struct {
-	actions map[string]bool // 重複を避けるためのセット
+	actions map[string]bool             // 重複を避けるためのセット
+	refs    map[string]*lockedActionRef // ロックファイルのキーから owner/repo@ref へのマップ
 }
 
 // NewActionListGenerator は新しいActionListGeneratorを作成
 func NewActionListGenerator() *ActionListGenerator {
 	return &ActionListGenerator{
 		actions: make(map[string]bool),
+		refs:    make(map[string]*lockedActionRef),
 	}
 }
 
@@ -32,6 +35,15 @@ func (g *ActionListGenerator) CollectActionsFromWorkflow(workflow *ast.Workflow)
 					// バージョン部分をワイルドカードに変換してパターン化
 					pattern := g.normalizeActionPattern(usesValue)
 					g.actions[pattern] = true
+
+					// SHA でピン留めされたアクションはバージョンコメントのタグで記録する
+					comment := ""
+					if action.Uses.BaseNode != nil {
+						comment = action.Uses.BaseNode.LineComment
+					}
+					if key, owner, repo, ref, ok := actionLockKey(usesValue, comment); ok {
+						g.refs[key] = &lockedActionRef{Key: key, Owner: owner, Repo: repo, Ref: ref}
+					}
 				}
 			}
 		}
@@ -74,22 +86,33 @@ func (g *ActionListGenerator) GetSortedActions() []string {
 	return actions
 }
 
-// GenerateActionListConfig は既存のワークフローファイルからaction-list設定を生成
-func GenerateActionListConfig(root string) error {
+// GetSortedActionRefs は収集した owner/repo@ref をロックファイルのキー順に返す
+func (g *ActionListGenerator) GetSortedActionRefs() []*lockedActionRef {
+	refs := make([]*lockedActionRef, 0, len(g.refs))
+	for _, ref := range g.refs {
+		refs = append(refs, ref)
+	}
+	sortLockedActionRefs(refs)
+	return refs
+}
+
+// collectWorkflowActions は root 配下のワークフローファイルからアクション参照を収集する
+// 読み込めないファイルは警告を warn に出力してスキップする
+func collectWorkflowActions(root string, warn io.Writer) (*ActionListGenerator, error) {
 	// .github/workflows ディレクトリを探す
 	workflowsDir := filepath.Join(root, ".github", "workflows")
 	if _, err := os.Stat(workflowsDir); os.IsNotExist(err) {
-		return fmt.Errorf(".github/workflows directory not found at %s", workflowsDir)
+		return nil, fmt.Errorf(".github/workflows directory not found at %s", workflowsDir)
 	}
 
 	// ワークフローファイルを探す
 	files, err := filepath.Glob(filepath.Join(workflowsDir, "*.y*ml"))
 	if err != nil {
-		return fmt.Errorf("failed to find workflow files: %w", err)
+		return nil, fmt.Errorf("failed to find workflow files: %w", err)
 	}
 
 	if len(files) == 0 {
-		return fmt.Errorf("no workflow files found in %s", workflowsDir)
+		return nil, fmt.Errorf("no workflow files found in %s", workflowsDir)
 	}
 
 	// アクションを収集
@@ -97,23 +120,32 @@ func GenerateActionListConfig(root string) error {
 	for _, file := range files {
 		content, err := os.ReadFile(file)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to read %s: %v\n", file, err)
+			fmt.Fprintf(warn, "Warning: failed to read %s: %v\n", file, err)
 			continue
 		}
 
 		// ワークフローをパース
 		workflow, errs := Parse(content)
 		if len(errs) > 0 {
-			fmt.Fprintf(os.Stderr, "Warning: failed to parse workflow from %s: %v\n", file, errs[0])
+			fmt.Fprintf(warn, "Warning: failed to parse workflow from %s: %v\n", file, errs[0])
 			continue
 		}
 		if workflow == nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to parse workflow from %s\n", file)
+			fmt.Fprintf(warn, "Warning: failed to parse workflow from %s\n", file)
 			continue
 		}
 
 		generator.CollectActionsFromWorkflow(workflow)
 	}
+	return generator, nil
+}
+
+// GenerateActionListConfig は既存のワークフローファイルからaction-list設定を生成
+func GenerateActionListConfig(root string) error {
+	generator, err := collectWorkflowActions(root, os.Stderr)
+	if err != nil {
+		return err
+	}
 
 	actions := generator.GetSortedActions()
 	if len(actions) == 0 {
@@ -0,0 +1,257 @@
+package core
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+)
+
+// actionStepOutputs is the action run by a step with an ID and the outputs
+// its metadata declares.
+type actionStepOutputs struct {
+	spec string
+	meta *ActionMetadata
+}
+
+// RuleAction is a rule to check the inputs given to actions in "with" and the
+// references to their outputs against the action metadata (action.yml).
+type RuleAction struct {
+	BaseRule
+	metadataResolver ActionMetadataResolver
+	// steps maps the IDs of the steps of the current job to the actions they
+	// run. Steps whose action metadata could not be resolved are not included.
+	steps map[string]*actionStepOutputs
+}
+
+// ActionRule creates a new RuleAction instance. Action metadata is resolved
+// with resolver, which is shared with the other rules reading action.yml.
+func ActionRule(resolver ActionMetadataResolver) *RuleAction {
+	return &RuleAction{
+		BaseRule: BaseRule{
+			RuleName: "action",
+			RuleDesc: "Checks for unknown, missing required and deprecated inputs of actions and references to undefined outputs of actions",
+			severity: SeverityMedium,
+		},
+		metadataResolver: resolver,
+		steps:            map[string]*actionStepOutputs{},
+	}
+}
+
+// VisitJobPre is callback when visiting Job node before visiting its children.
+func (rule *RuleAction) VisitJobPre(n *ast.Job) error {
+	rule.steps = map[string]*actionStepOutputs{}
+	return nil
+}
+
+// VisitJobPost is callback when visiting Job node after visiting its children.
+// Job outputs are evaluated after all steps ran, so they can refer to the
+// outputs of any step.
+func (rule *RuleAction) VisitJobPost(n *ast.Job) error {
+	for _, name := range slices.Sorted(maps.Keys(n.Outputs)) {
+		rule.checkOutputRefs(n.Outputs[name].Value, false)
+	}
+	return nil
+}
+
+// VisitStep is callback when visiting Step node.
+func (rule *RuleAction) VisitStep(n *ast.Step) error {
+	rule.checkOutputRefs(n.Name, false)
+	rule.checkOutputRefs(n.If, true)
+	if n.Env != nil {
+		rule.checkOutputRefs(n.Env.Expression, false)
+		for _, name := range slices.Sorted(maps.Keys(n.Env.Vars)) {
+			rule.checkOutputRefs(n.Env.Vars[name].Value, false)
+		}
+	}
+
+	switch e := n.Exec.(type) {
+	case *ast.ExecRun:
+		rule.checkOutputRefs(e.Run, false)
+		rule.checkOutputRefs(e.Shell, false)
+		rule.checkOutputRefs(e.WorkingDirectory, false)
+	case *ast.ExecAction:
+		for _, name := range slices.Sorted(maps.Keys(e.Inputs)) {
+			rule.checkOutputRefs(e.Inputs[name].Value, false)
+		}
+		rule.checkOutputRefs(e.Entrypoint, false)
+		rule.checkOutputRefs(e.Args, false)
+		rule.checkAction(n, e)
+	}
+	return nil
+}
+
+// checkAction checks the inputs of the action run by the step and remembers
+// the outputs the action declares for the following steps.
+func (rule *RuleAction) checkAction(step *ast.Step, exec *ast.ExecAction) {
+	if exec.Uses == nil || rule.metadataResolver == nil {
+		return
+	}
+	spec := exec.Uses.Value
+	if spec == "" || strings.HasPrefix(spec, "docker://") || strings.Contains(spec, "${{") {
+		return
+	}
+	meta, err := rule.metadataResolver.FindMetadata(spec)
+	if err != nil {
+		rule.Debug("could not resolve metadata for %q: %v", spec, err)
+		return
+	}
+	if meta == nil {
+		return
+	}
+
+	if !meta.SkipInputs {
+		rule.checkInputs(spec, exec, meta)
+	}
+	if step.ID != nil && step.ID.Value != "" && !strings.Contains(step.ID.Value, "${{") {
+		rule.steps[strings.ToLower(step.ID.Value)] = &actionStepOutputs{spec: spec, meta: meta}
+	}
+}
+
+// checkInputs reports inputs in "with" which the action does not define or
+// deprecates, and required inputs without default values which are missing.
+// * https://docs.github.com/en/actions/creating-actions/metadata-syntax-for-github-actions#inputs
+func (rule *RuleAction) checkInputs(spec string, exec *ast.ExecAction, meta *ActionMetadata) {
+	for _, id := range slices.Sorted(maps.Keys(exec.Inputs)) {
+		input := exec.Inputs[id]
+		declared, ok := meta.Inputs[id]
+		if !ok {
+			rule.Errorf(input.Name.Pos, "input %q is not defined in action %q. %s", input.Name.Value, spec, availableNames("inputs", meta.Inputs))
+			continue
+		}
+		if declared.DeprecationMessage != "" {
+			rule.ErrorfWithSeverity(input.Name.Pos, SeverityLow, "input %q of action %q is deprecated: %s", input.Name.Value, spec, strings.TrimSpace(declared.DeprecationMessage))
+		}
+	}
+
+	var missing []string
+	for id, declared := range meta.Inputs {
+		if _, ok := exec.Inputs[id]; !ok && declared.Required {
+			missing = append(missing, declared.Name)
+		}
+	}
+	if len(missing) > 0 {
+		rule.Errorf(exec.Uses.Pos, "missing required inputs %s of action %q. They have no default value and must be set in \"with\"", expressions.SortedQuotes(missing), spec)
+	}
+}
+
+// checkOutputRefs reports steps.<id>.outputs.<name> references in str to
+// outputs which the action of step <id> does not declare. cond is true when
+// str is an "if:" condition, which may be written without ${{ }}.
+func (rule *RuleAction) checkOutputRefs(str *ast.String, cond bool) {
+	if str == nil || len(rule.steps) == 0 {
+		return
+	}
+	var exprs []parsedExpression
+	if cond && !strings.Contains(str.Value, "${{") {
+		if expr, err := rule.parseExpression(str.Value); err == nil {
+			exprs = append(exprs, parsedExpression{raw: str.Value, node: expr, pos: str.Pos})
+		}
+	} else {
+		exprs = rule.extractAndParseExpressions(str)
+	}
+
+	for _, e := range exprs {
+		expressions.VisitExprNode(e.node, func(node, _ expressions.ExprNode, entering bool) {
+			if !entering {
+				return
+			}
+			id, name, ok := stepOutputReference(node)
+			if !ok {
+				return
+			}
+			s, ok := rule.steps[id]
+			if !ok || !checksActionOutputs(s) {
+				return
+			}
+			if _, ok := s.meta.Outputs[name]; !ok {
+				rule.Errorf(e.pos, "output %q of step %q is not defined in action %q. %s", name, id, s.spec, availableNames("outputs", s.meta.Outputs))
+			}
+		})
+	}
+}
+
+// checksActionOutputs returns whether references to the outputs of the action
+// are checked. Outputs of local actions are already typed by the expression
+// rule, and actions/github-script sets arbitrary outputs with core.setOutput.
+func checksActionOutputs(s *actionStepOutputs) bool {
+	if s.meta.SkipOutputs || strings.HasPrefix(s.spec, "./") {
+		return false
+	}
+	return !strings.HasPrefix(s.spec, "actions/github-script@")
+}
+
+// stepOutputReference returns the step ID and the output name of a
+// steps.<id>.outputs.<name> property access, lowercased since both are case
+// insensitive.
+func stepOutputReference(node expressions.ExprNode) (string, string, bool) {
+	out, ok := node.(*expressions.ObjectDerefNode)
+	if !ok {
+		return "", "", false
+	}
+	outputs, ok := out.Receiver.(*expressions.ObjectDerefNode)
+	if !ok || !strings.EqualFold(outputs.Property, "outputs") {
+		return "", "", false
+	}
+	step, ok := outputs.Receiver.(*expressions.ObjectDerefNode)
+	if !ok {
+		return "", "", false
+	}
+	steps, ok := step.Receiver.(*expressions.VariableNode)
+	if !ok || !strings.EqualFold(steps.Name, ContextSteps) {
+		return "", "", false
+	}
+	return strings.ToLower(step.Property), strings.ToLower(out.Property), true
+}
+
+// extractAndParseExpressions extracts all expressions from string and parses them
+func (rule *RuleAction) extractAndParseExpressions(str *ast.String) []parsedExpression {
+	value := str.Value
+	var result []parsedExpression
+	offset := 0
+
+	for {
+		idx := strings.Index(value[offset:], "${{")
+		if idx == -1 {
+			break
+		}
+		start := offset + idx
+		endIdx := strings.Index(value[start:], "}}")
+		if endIdx == -1 {
+			break
+		}
+
+		exprContent := strings.TrimSpace(value[start+3 : start+endIdx])
+		if expr, err := rule.parseExpression(exprContent); err == nil && expr != nil {
+			result = append(result, parsedExpression{
+				raw:  exprContent,
+				node: expr,
+				pos:  stringOffsetPosition(str, start),
+			})
+		}
+		offset = start + endIdx + 2
+	}
+	return result
+}
+
+// parseExpression parses a single expression string into an AST node
+func (rule *RuleAction) parseExpression(exprStr string) (expressions.ExprNode, *expressions.ExprError) {
+	l := expressions.NewTokenizer(exprStr + "}}")
+	p := expressions.NewMiniParser()
+	return p.Parse(l)
+}
+
+// availableNames formats the names of the inputs or outputs of an action for
+// error messages.
+func availableNames[T any](kind string, m map[string]T) string {
+	if len(m) == 0 {
+		return "the action defines no " + kind
+	}
+	names := make([]string, 0, len(m))
+	for n := range m {
+		names = append(names, n)
+	}
+	return "available " + kind + " are " + expressions.SortedQuotes(names)
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const testActionMetadata = `name: Deploy
+inputs:
+  token:
+    required: true
+  environment:
+    required: true
+    default: staging
+  dry-run:
+    deprecationMessage: Use "mode" instead.
+  mode:
+    default: apply
+outputs:
+  url:
+    description: URL of the deployment
+runs:
+  using: node24
+  main: index.js
+`
+
+func runActionRule(t *testing.T, steps string) []*LintingError {
+	t.Helper()
+
+	var meta ActionMetadata
+	if err := yaml.Unmarshal([]byte(testActionMetadata), &meta); err != nil {
+		t.Fatal(err)
+	}
+	rule := ActionRule(fakeActionMetadataResolver{
+		"example/deploy@v1":        &meta,
+		"./.github/actions/deploy": &meta,
+		"actions/github-script@v7": {Outputs: ActionOutputsMetadata{"result": {Name: "result"}}},
+	})
+	visitWorkflowSource(t, rule, `on: push
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    outputs:
+      url: ${{ steps.deploy.outputs.url }}
+      id: ${{ steps.deploy.outputs.id }}
+    steps:
+`+steps)
+	return rule.Errors()
+}
+
+func TestActionRule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		steps string
+		want  []string
+	}{
+		{
+			name: "valid",
+			steps: `      - uses: example/deploy@v1
+        id: deploy
+        with:
+          token: ${{ secrets.TOKEN }}
+          Mode: plan
+      - run: echo ${{ steps.deploy.outputs.URL }}
+      - uses: actions/checkout@v5
+        with:
+          unknown-to-resolver: true
+`,
+			want: []string{`7:11: output "id" of step "deploy" is not defined in action "example/deploy@v1". available outputs are "url"`},
+		},
+		{
+			name: "unknown and deprecated inputs",
+			steps: `      - uses: example/deploy@v1
+        with:
+          token: x
+          tokn: x
+          dry-run: true
+`,
+			want: []string{
+				`13:11: input "dry-run" of action "example/deploy@v1" is deprecated: Use "mode" instead.`,
+				`12:11: input "tokn" is not defined in action "example/deploy@v1". available inputs are "dry-run", "environment", "mode", "token"`,
+			},
+		},
+		{
+			name: "missing required input",
+			steps: `      - uses: example/deploy@v1
+        with:
+          mode: plan
+`,
+			want: []string{`9:15: missing required inputs "token" of action "example/deploy@v1". They have no default value and must be set in "with"`},
+		},
+		{
+			name: "undefined outputs",
+			steps: `      - id: deploy
+        uses: example/deploy@v1
+        with:
+          token: x
+      - if: steps.deploy.outputs.status == 'ok'
+        run: |
+          echo ok
+          echo ${{ steps.deploy.outputs.link }}
+        env:
+          URL: ${{ steps.deploy.outputs.url }}
+`,
+			want: []string{
+				`13:13: output "status" of step "deploy" is not defined in action "example/deploy@v1"`,
+				`16:6: output "link" of step "deploy"`,
+				`7:11: output "id" of step "deploy"`,
+			},
+		},
+		{
+			name: "outputs of local actions and github-script are not checked",
+			steps: `      - id: deploy
+        uses: ./.github/actions/deploy
+        with:
+          token: x
+      - id: script
+        uses: actions/github-script@v7
+      - run: echo ${{ steps.script.outputs.anything }} ${{ steps.deploy.outputs.link }}
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			errs := runActionRule(t, tc.steps)
+			if len(errs) != len(tc.want) {
+				t.Fatalf("want %d errors, got %v", len(tc.want), errs)
+			}
+			for i, want := range tc.want {
+				got := errs[i].Error()
+				if !strings.Contains(got, want) {
+					t.Errorf("error %d %q does not contain %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestActionRule_DeprecatedInputSeverity(t *testing.T) {
+	t.Parallel()
+
+	errs := runActionRule(t, `      - uses: example/deploy@v1
+        with:
+          token: x
+          dry-run: true
+`)
+	if len(errs) != 1 || errs[0].Severity != SeverityLow {
+		t.Fatalf("want one low severity error, got %v", errs)
+	}
+}
+
+func TestActionInputsMetadata_Required(t *testing.T) {
+	t.Parallel()
+
+	var meta ActionMetadata
+	if err := yaml.Unmarshal([]byte(testActionMetadata), &meta); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"token": true, "environment": false, "dry-run": false, "mode": false} {
+		if got := meta.Inputs[name].Required; got != want {
+			t.Errorf("Required of input %q = %v, want %v", name, got, want)
+		}
+	}
+	if meta.Inputs["dry-run"].DeprecationMessage != `Use "mode" instead.` {
+		t.Errorf("unexpected deprecation message %q", meta.Inputs["dry-run"].DeprecationMessage)
+	}
+}
@@ -0,0 +1,545 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// AdvisoryDBVersion is the version of the advisory database file format.
+const AdvisoryDBVersion = 1
+
+// osvEcosystemGitHubActions is the OSV ecosystem name of GitHub Actions.
+const osvEcosystemGitHubActions = "GitHub Actions"
+
+// AdvisoryDB is a local copy of the security advisories of the GitHub Actions
+// ecosystem. With -advisory-db, KnownVulnerableActionsRule matches against it
+// instead of querying the GitHub API, so it works without network access.
+type AdvisoryDB struct {
+	Version     int                 `json:"version"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Advisories  []*AdvisoryDBRecord `json:"advisories"`
+	// Tags maps "owner/repo" to its tags and the commit SHAs they point to,
+	// so that refs pinned to a SHA can be matched to a version offline.
+	Tags map[string]map[string]string `json:"tags,omitempty"`
+
+	indexOnce sync.Once
+	byPackage map[string][]*VulnerabilityInfo
+}
+
+// AdvisoryDBRecord is one vulnerable version range of an action.
+type AdvisoryDBRecord struct {
+	Package             string `json:"package"`
+	GHSAID              string `json:"ghsa_id"`
+	Severity            string `json:"severity"`
+	Summary             string `json:"summary"`
+	VulnerableRange     string `json:"vulnerable_range"`
+	FirstPatchedVersion string `json:"first_patched_version,omitempty"`
+	HTMLURL             string `json:"html_url,omitempty"`
+}
+
+func (r *AdvisoryDBRecord) vulnerabilityInfo() *VulnerabilityInfo {
+	url := r.HTMLURL
+	if url == "" && r.GHSAID != "" {
+		url = "https://github.com/advisories/" + r.GHSAID
+	}
+	return &VulnerabilityInfo{
+		GHSAID:              r.GHSAID,
+		Severity:            r.Severity,
+		Summary:             r.Summary,
+		FirstPatchedVersion: r.FirstPatchedVersion,
+		VulnerableRange:     r.VulnerableRange,
+		HTMLURL:             url,
+	}
+}
+
+// osvRecord is the subset of the OSV schema (https://ossf.github.io/osv-schema/)
+// used by the GitHub advisory database.
+type osvRecord struct {
+	ID       string `json:"id"`
+	Summary  string `json:"summary"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string              `json:"type"`
+			Events []map[string]string `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+	Withdrawn string `json:"withdrawn"`
+}
+
+// records converts the GitHub Actions ranges of an OSV record. Each
+// introduced/fixed pair becomes one record with a range such as
+// ">= 1.0.0, < 1.2.3".
+func (o *osvRecord) records() []*AdvisoryDBRecord {
+	if o.Withdrawn != "" {
+		return nil
+	}
+	var recs []*AdvisoryDBRecord
+	for _, a := range o.Affected {
+		if a.Package.Ecosystem != osvEcosystemGitHubActions || a.Package.Name == "" {
+			continue
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "ECOSYSTEM" && r.Type != "SEMVER" {
+				continue
+			}
+			introduced := ""
+			for _, e := range r.Events {
+				if v, ok := e["introduced"]; ok {
+					introduced = v
+					continue
+				}
+				var upper, patched string
+				if v, ok := e["fixed"]; ok {
+					upper, patched = "< "+v, v
+				} else if v, ok := e["last_affected"]; ok {
+					upper = "<= " + v
+				} else {
+					continue
+				}
+				vulnRange := upper
+				if introduced != "" && introduced != "0" {
+					vulnRange = ">= " + introduced + ", " + upper
+				}
+				recs = append(recs, &AdvisoryDBRecord{
+					Package:             a.Package.Name,
+					GHSAID:              o.ID,
+					Severity:            strings.ToLower(o.DatabaseSpecific.Severity),
+					Summary:             o.Summary,
+					VulnerableRange:     vulnRange,
+					FirstPatchedVersion: patched,
+				})
+				introduced = ""
+			}
+			if introduced != "" {
+				// Affected from the introduced version with no fix yet.
+				vulnRange := ">= " + introduced
+				if introduced == "0" {
+					vulnRange = ">= 0"
+				}
+				recs = append(recs, &AdvisoryDBRecord{
+					Package:         a.Package.Name,
+					GHSAID:          o.ID,
+					Severity:        strings.ToLower(o.DatabaseSpecific.Severity),
+					Summary:         o.Summary,
+					VulnerableRange: vulnRange,
+				})
+			}
+		}
+	}
+	return recs
+}
+
+// ReadAdvisoryDB loads an advisory database. path is either a file written by
+// "sisakulint advisory-db", an OSV JSON file (a single record or an array of
+// records), or a directory containing OSV JSON files such as a checkout of
+// github/advisory-database.
+func ReadAdvisoryDB(path string) (*AdvisoryDB, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read advisory database %q: %w", path, err)
+	}
+	db := &AdvisoryDB{Version: AdvisoryDBVersion}
+	if info.IsDir() {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(p, ".json") {
+				return nil
+			}
+			b, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return db.addJSON(b, p)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not read advisory database %q: %w", path, err)
+		}
+	} else {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read advisory database %q: %w", path, err)
+		}
+		if err := db.addJSON(b, path); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func (db *AdvisoryDB) addJSON(b []byte, path string) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		var osv []*osvRecord
+		if err := json.Unmarshal(b, &osv); err != nil {
+			return fmt.Errorf("could not parse OSV records in %q: %w", path, err)
+		}
+		for _, o := range osv {
+			db.Advisories = append(db.Advisories, o.records()...)
+		}
+		return nil
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return fmt.Errorf("could not parse advisory database %q: %w", path, err)
+	}
+	if _, ok := probe["advisories"]; ok {
+		var native AdvisoryDB
+		if err := json.Unmarshal(b, &native); err != nil {
+			return fmt.Errorf("could not parse advisory database %q: %w", path, err)
+		}
+		if native.Version > AdvisoryDBVersion {
+			return fmt.Errorf("advisory database %q has version %d but this sisakulint supports up to version %d", path, native.Version, AdvisoryDBVersion)
+		}
+		db.GeneratedAt = native.GeneratedAt
+		db.Advisories = append(db.Advisories, native.Advisories...)
+		for pkg, tags := range native.Tags {
+			if db.Tags == nil {
+				db.Tags = map[string]map[string]string{}
+			}
+			db.Tags[strings.ToLower(pkg)] = tags
+		}
+		return nil
+	}
+	if _, ok := probe["affected"]; ok {
+		var o osvRecord
+		if err := json.Unmarshal(b, &o); err != nil {
+			return fmt.Errorf("could not parse OSV record %q: %w", path, err)
+		}
+		db.Advisories = append(db.Advisories, o.records()...)
+		return nil
+	}
+	// Other JSON files in an advisory directory are not advisories.
+	return nil
+}
+
+func (db *AdvisoryDB) index() {
+	db.byPackage = make(map[string][]*VulnerabilityInfo, len(db.Advisories))
+	for _, r := range db.Advisories {
+		pkg := strings.ToLower(r.Package)
+		db.byPackage[pkg] = append(db.byPackage[pkg], r.vulnerabilityInfo())
+	}
+}
+
+// advisoriesFor returns every advisory of the "owner/repo" action.
+func (db *AdvisoryDB) advisoriesFor(pkg string) []*VulnerabilityInfo {
+	db.indexOnce.Do(db.index)
+	return db.byPackage[strings.ToLower(pkg)]
+}
+
+// commentVersionPattern finds a version in the comment of a SHA pinned
+// action such as "# v4.1.7".
+var commentVersionPattern = regexp.MustCompile(`\bv?(\d+(?:\.\d+)*(?:-[0-9A-Za-z.]+)?)\b`)
+
+// longestTagFor returns the longest tag pointing at sha, preferring e.g.
+// v1.2.3 over v1 like the online resolution.
+func longestTagFor(tags map[string]string, sha string) string {
+	best := ""
+	for tag, s := range tags {
+		if s == sha && (len(tag) > len(best) || len(tag) == len(best) && tag < best) {
+			best = tag
+		}
+	}
+	return best
+}
+
+// resolveVersion maps ref of the "owner/repo" action to the version matched
+// against vulnerable ranges, without network access. A SHA is looked up in
+// the recorded tags, then in the version comment of the uses: line. ok is
+// false when a SHA cannot be mapped to any version.
+func (db *AdvisoryDB) resolveVersion(pkg, ref, comment string) (version string, ok bool) {
+	tags := db.Tags[strings.ToLower(pkg)]
+	if isFullLengthCommitSHA(ref) {
+		if tag := longestTagFor(tags, ref); tag != "" {
+			return tag, true
+		}
+		if m := commentVersionPattern.FindString(comment); m != "" {
+			return m, true
+		}
+		return "", false
+	}
+	if sha, found := tags[ref]; found {
+		if tag := longestTagFor(tags, sha); tag != "" {
+			return tag, true
+		}
+	}
+	return ref, true
+}
+
+// tagSHA returns the commit SHA of a tag of the "owner/repo" action.
+func (db *AdvisoryDB) tagSHA(pkg, tag string) (string, bool) {
+	sha, ok := db.Tags[strings.ToLower(pkg)][tag]
+	return sha, ok
+}
+
+// WriteFile writes db as indented JSON.
+func (db *AdvisoryDB) WriteFile(path string) error {
+	b, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if err := os.WriteFile(path, b, 0o644); err != nil { //nolint:gosec // the database is meant to be shared and committed
+		return fmt.Errorf("could not write advisory database %q: %w", path, err)
+	}
+	return nil
+}
+
+// advisoryVulnerabilities converts the vulnerabilities of an advisory. When
+// pkg is not empty only the vulnerabilities of that package are returned.
+func advisoryVulnerabilities(advisory *github.GlobalSecurityAdvisory, pkg string) []*AdvisoryDBRecord {
+	var recs []*AdvisoryDBRecord
+	for _, vuln := range advisory.Vulnerabilities {
+		if vuln.Package == nil || vuln.Package.Name == nil {
+			continue
+		}
+		if pkg != "" && *vuln.Package.Name != pkg {
+			continue
+		}
+		recs = append(recs, &AdvisoryDBRecord{
+			Package:             vuln.Package.GetName(),
+			GHSAID:              advisory.GetGHSAID(),
+			Severity:            advisory.GetSeverity(),
+			Summary:             advisory.GetSummary(),
+			VulnerableRange:     vuln.GetVulnerableVersionRange(),
+			FirstPatchedVersion: vuln.GetFirstPatchedVersion(),
+			HTMLURL:             fmt.Sprintf("https://github.com/advisories/%s", advisory.GetGHSAID()),
+		})
+	}
+	return recs
+}
+
+// listActionTags returns the tags of owner/repo and their commit SHAs. At most
+// 10 pages are read, the same bound as the online tag resolution.
+func listActionTags(ctx context.Context, gh *github.Client, owner, repo string) (map[string]string, error) {
+	tags := map[string]string{}
+	opts := &github.ListOptions{PerPage: 100}
+	for i := 0; i < 10; i++ {
+		page, resp, err := gh.Repositories.ListTags(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags: %w", err)
+		}
+		for _, tag := range page {
+			tags[tag.GetName()] = tag.GetCommit().GetSHA()
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return tags, nil
+}
+
+// FetchAdvisoryDB downloads every reviewed advisory of the actions ecosystem
+// from the GitHub API. With withTags, the tags of each affected action are
+// recorded too.
+func FetchAdvisoryDB(ctx context.Context, gh *github.Client, withTags bool) (*AdvisoryDB, error) {
+	db := &AdvisoryDB{Version: AdvisoryDBVersion, GeneratedAt: time.Now().UTC().Truncate(time.Second)}
+	ecosystem := "actions"
+	withdrawn := false
+	opts := &github.ListGlobalSecurityAdvisoriesOptions{
+		ListCursorOptions: github.ListCursorOptions{PerPage: 100},
+		Ecosystem:         &ecosystem,
+		IsWithdrawn:       &withdrawn,
+	}
+	for {
+		advisories, resp, err := gh.SecurityAdvisories.ListGlobalSecurityAdvisories(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch security advisories: %w", err)
+		}
+		for _, a := range advisories {
+			db.Advisories = append(db.Advisories, advisoryVulnerabilities(a, "")...)
+		}
+		if resp == nil || resp.After == "" {
+			break
+		}
+		opts.After = resp.After
+	}
+	sort.SliceStable(db.Advisories, func(i, j int) bool {
+		a, b := db.Advisories[i], db.Advisories[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.GHSAID < b.GHSAID
+	})
+
+	if withTags {
+		db.Tags = map[string]map[string]string{}
+		for _, pkg := range db.packages() {
+			owner, repo, ok := strings.Cut(pkg, "/")
+			if !ok {
+				continue
+			}
+			// Actions in a subdirectory ("owner/repo/path") share the tags of
+			// the repository.
+			repo, _, _ = strings.Cut(repo, "/")
+			pkg = owner + "/" + repo
+			if _, done := db.Tags[pkg]; done {
+				continue
+			}
+			tags, err := listActionTags(ctx, gh, owner, repo)
+			if err != nil {
+				if IsGitHubRateLimitError(err) {
+					return nil, err
+				}
+				var ghErr *github.ErrorResponse
+				if errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == 404 {
+					continue // the repository was deleted or made private
+				}
+				return nil, fmt.Errorf("%s: %w", pkg, err)
+			}
+			db.Tags[pkg] = tags
+		}
+	}
+	return db, nil
+}
+
+// packages returns the sorted, lower-cased package names of db.
+func (db *AdvisoryDB) packages() []string {
+	seen := map[string]struct{}{}
+	var pkgs []string
+	for _, r := range db.Advisories {
+		pkg := strings.ToLower(r.Package)
+		if _, ok := seen[pkg]; ok {
+			continue
+		}
+		seen[pkg] = struct{}{}
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// runAdvisoryDB implements "sisakulint advisory-db", which downloads the
+// advisory database for -advisory-db. When the output file already exists it
+// is refreshed and the difference is reported.
+func (cmd *Command) runAdvisoryDB(args []string) int {
+	var output string
+	var noTags bool
+	var githubTokenFlag string
+	var githubAPIURLFlag string
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(cmd.Stderr)
+	flags.StringVar(&output, "o", "", "Write the database to this file instead of stdout. An existing file is refreshed")
+	flags.BoolVar(&noTags, "no-tags", false, "Do not record the tags of affected actions. SHA pinned actions are then matched by their version comment only")
+	flags.StringVar(&githubTokenFlag, "github-token", "", "GitHub API token. Falls back to SISAKULINT_GITHUB_TOKEN, GITHUB_TOKEN, then GH_TOKEN")
+	flags.StringVar(&githubAPIURLFlag, "github-api-url", "", "REST API URL of a GitHub Enterprise Server. Falls back to GITHUB_API_URL")
+	flags.Usage = func() {
+		fmt.Fprintf(cmd.Stderr, "Usage: sisakulint advisory-db [FLAGS]\n\nDownload the GitHub Actions security advisories for offline use with -advisory-db.\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitStatusSuccessNoProblem
+		}
+		return ExitStatusInvalidCommandOption
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(cmd.Stderr, "sisakulint advisory-db takes no arguments: %s\n", strings.Join(flags.Args(), " "))
+		return ExitStatusInvalidCommandOption
+	}
+
+	var old *AdvisoryDB
+	if output != "" {
+		if _, err := os.Stat(output); err == nil {
+			db, err := ReadAdvisoryDB(output)
+			if err != nil {
+				fmt.Fprintln(cmd.Stderr, err.Error())
+				return ExitStatusFailure
+			}
+			old = db
+		}
+	}
+
+	apiURL, err := ResolveGitHubAPIURL(githubAPIURLFlag, nil)
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "Invalid value for -github-api-url: %v\n", err)
+		return ExitStatusInvalidCommandOption
+	}
+
+	ctx := context.Background()
+	token, _ := ResolveGitHubToken(githubTokenFlag, nil)
+	gh, err := NewGitHubClientWithAPIURL(ctx, token, apiURL)
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusInvalidCommandOption
+	}
+	db, err := FetchAdvisoryDB(ctx, gh, !noTags)
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		if IsGitHubRateLimitError(err) && token == "" {
+			fmt.Fprintln(cmd.Stderr, "Set -github-token or GITHUB_TOKEN to raise the GitHub API rate limit")
+		}
+		return ExitStatusFailure
+	}
+
+	if output == "" {
+		b, err := json.MarshalIndent(db, "", "  ")
+		if err != nil {
+			fmt.Fprintln(cmd.Stderr, err.Error())
+			return ExitStatusFailure
+		}
+		fmt.Fprintf(cmd.Stdout, "%s\n", b)
+		return ExitStatusSuccessNoProblem
+	}
+	if err := db.WriteFile(output); err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+	if old == nil {
+		fmt.Fprintf(cmd.Stderr, "Wrote %d %s to %s\n", len(db.Advisories), pluralize(len(db.Advisories), "advisory record", "advisory records"), output)
+	} else {
+		added, removed := diffAdvisoryDB(old, db)
+		fmt.Fprintf(cmd.Stderr, "Refreshed %s: %d added, %d removed, %d %s in total\n", output, added, removed, len(db.Advisories), pluralize(len(db.Advisories), "advisory record", "advisory records"))
+	}
+	return ExitStatusSuccessNoProblem
+}
+
+// diffAdvisoryDB counts the records of db which are not in old and vice versa.
+func diffAdvisoryDB(old, db *AdvisoryDB) (added, removed int) {
+	key := func(r *AdvisoryDBRecord) string {
+		return strings.ToLower(r.Package) + "\x00" + r.GHSAID + "\x00" + r.VulnerableRange
+	}
+	before := map[string]struct{}{}
+	for _, r := range old.Advisories {
+		before[key(r)] = struct{}{}
+	}
+	after := map[string]struct{}{}
+	for _, r := range db.Advisories {
+		k := key(r)
+		after[k] = struct{}{}
+		if _, ok := before[k]; !ok {
+			added++
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			removed++
+		}
+	}
+	return added, removed
+}
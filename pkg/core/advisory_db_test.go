@@ -0,0 +1,165 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadAdvisoryDB_OSV(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "advisories"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "advisories", "GHSA-aaaa-bbbb-cccc.json"), `{
+  "id": "GHSA-aaaa-bbbb-cccc",
+  "summary": "Command injection",
+  "affected": [
+    {
+      "package": {"ecosystem": "GitHub Actions", "name": "Owner/Action"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.2.3"}, {"introduced": "2.0.0"}, {"last_affected": "2.1.0"}]}]
+    },
+    {
+      "package": {"ecosystem": "npm", "name": "left-pad"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.0.0"}]}]
+    }
+  ],
+  "database_specific": {"severity": "MODERATE"}
+}`)
+	writeTestFile(t, filepath.Join(dir, "advisories", "GHSA-withdrawn.json"), `{
+  "id": "GHSA-dddd-eeee-ffff",
+  "withdrawn": "2024-01-01T00:00:00Z",
+  "affected": [{"package": {"ecosystem": "GitHub Actions", "name": "owner/action"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]}]
+}`)
+	writeTestFile(t, filepath.Join(dir, "schema.json"), `{"type": "object"}`)
+
+	db, err := ReadAdvisoryDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vulns := db.advisoriesFor("owner/action")
+	if len(vulns) != 2 {
+		t.Fatalf("want 2 ranges, got %+v", vulns)
+	}
+	if vulns[0].VulnerableRange != "< 1.2.3" || vulns[0].FirstPatchedVersion != "1.2.3" || vulns[0].Severity != "moderate" {
+		t.Errorf("unexpected first range: %+v", vulns[0])
+	}
+	if vulns[1].VulnerableRange != ">= 2.0.0, <= 2.1.0" || vulns[1].FirstPatchedVersion != "" {
+		t.Errorf("unexpected second range: %+v", vulns[1])
+	}
+	if vulns[0].HTMLURL != "https://github.com/advisories/GHSA-aaaa-bbbb-cccc" {
+		t.Errorf("HTMLURL = %q", vulns[0].HTMLURL)
+	}
+}
+
+func TestReadAdvisoryDB_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "advisories.json")
+	db := &AdvisoryDB{
+		Version: AdvisoryDBVersion,
+		Advisories: []*AdvisoryDBRecord{
+			{Package: "owner/action", GHSAID: "GHSA-1", Severity: "high", Summary: "s", VulnerableRange: "< 2.0.0", FirstPatchedVersion: "2.0.0"},
+		},
+		Tags: map[string]map[string]string{"owner/action": {"v1": "sha1", "v1.0.0": "sha1"}},
+	}
+	if err := db.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadAdvisoryDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.advisoriesFor("Owner/Action")) != 1 {
+		t.Errorf("advisories were not read back: %+v", got.Advisories)
+	}
+	if sha, ok := got.tagSHA("owner/action", "v1.0.0"); !ok || sha != "sha1" {
+		t.Errorf("tagSHA = %q, %v", sha, ok)
+	}
+
+	writeTestFile(t, path, `{"version": 99, "advisories": []}`)
+	if _, err := ReadAdvisoryDB(path); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("newer format version should be rejected, got %v", err)
+	}
+}
+
+func TestAdvisoryDB_ResolveVersion(t *testing.T) {
+	t.Parallel()
+
+	const sha = "1111111111111111111111111111111111111111"
+	db := &AdvisoryDB{Tags: map[string]map[string]string{
+		"owner/action": {"v1": sha, "v1.2.0": sha},
+	}}
+	tests := []struct {
+		ref, comment, want string
+		ok                 bool
+	}{
+		{ref: sha, want: "v1.2.0", ok: true},
+		{ref: "v1", want: "v1.2.0", ok: true},
+		{ref: "v3", want: "v3", ok: true},
+		{ref: "2222222222222222222222222222222222222222", comment: "# v4.1.7", want: "v4.1.7", ok: true},
+		{ref: "2222222222222222222222222222222222222222", ok: false},
+	}
+	for _, tc := range tests {
+		got, ok := db.resolveVersion("owner/action", tc.ref, tc.comment)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("resolveVersion(%q, %q) = %q, %v; want %q, %v", tc.ref, tc.comment, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestKnownVulnerableActionsRule_OfflineAdvisoryDB(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "advisories.json")
+	writeTestFile(t, dbPath, `{
+  "version": 1,
+  "advisories": [
+    {"package": "example/vulnerable", "ghsa_id": "GHSA-xxxx-yyyy-zzzz", "severity": "critical", "summary": "Secrets leak", "vulnerable_range": "< 1.4.0", "first_patched_version": "1.4.0"}
+  ],
+  "tags": {"example/vulnerable": {"v1.3.0": "3333333333333333333333333333333333333333"}}
+}`)
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard, AdvisoryDBPath: dbPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: example/vulnerable@v1.3.0
+      - uses: example/vulnerable@3333333333333333333333333333333333333333
+      - uses: example/vulnerable@v1.4.0
+`
+	result, err := linter.Lint("test.yaml", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []int
+	for _, e := range result.Errors {
+		if e.Type == "known-vulnerable-actions" {
+			lines = append(lines, e.LineNumber)
+			if !strings.Contains(e.Description, "GHSA-xxxx-yyyy-zzzz") || e.Severity != SeverityCritical {
+				t.Errorf("unexpected finding: %+v", e)
+			}
+		}
+	}
+	if len(lines) != 2 || lines[0] != 6 || lines[1] != 7 {
+		t.Errorf("want findings at lines 6 and 7, got %v", lines)
+	}
+}
+
+func TestNewLinter_InvalidAdvisoryDB(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "broken.json")
+	writeTestFile(t, path, `{`)
+	if _, err := NewLinter(io.Discard, &LinterOptions{AdvisoryDBPath: path}); err == nil {
+		t.Error("invalid advisory database should be an error")
+	}
+}
@@ -63,6 +63,7 @@ func NewAIActionExcessiveToolsRule() *AIActionExcessiveToolsRule {
 		BaseRule: BaseRule{
 			RuleName: "ai-action-excessive-tools",
 			RuleDesc: "AI action grants dangerous tools (Bash/Write/Edit) in workflow triggered by untrusted users",
+			severity: SeverityHigh,
 		},
 	}
 }
@@ -36,6 +36,7 @@ func NewAIActionExecutionOrderRule() *AIActionExecutionOrderRule {
 		BaseRule: BaseRule{
 			RuleName: "ai-action-execution-order",
 			RuleDesc: "AI action is not the last step in the job",
+			severity: SeverityMedium,
 		},
 	}
 }
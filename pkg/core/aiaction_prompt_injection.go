@@ -52,6 +52,7 @@ func NewAIActionPromptInjectionRule() *AIActionPromptInjectionRule {
 		BaseRule: BaseRule{
 			RuleName: "ai-action-prompt-injection",
 			RuleDesc: "Untrusted user input is directly interpolated into AI agent prompt",
+			severity: SeverityHigh,
 		},
 	}
 }
@@ -43,6 +43,7 @@ func NewAIActionUnrestrictedTriggerRule() *AIActionUnrestrictedTriggerRule {
 		BaseRule: BaseRule{
 			RuleName: "ai-action-unrestricted-trigger",
 			RuleDesc: "AI action allows any GitHub user to trigger agent execution",
+			severity: SeverityHigh,
 		},
 	}
 }
@@ -53,6 +53,7 @@ func NewAIActionUnsafeSandboxRule() *AIActionUnsafeSandboxRule {
 		BaseRule: BaseRule{
 			RuleName: "ai-action-unsafe-sandbox",
 			RuleDesc: "AI action has unsafe sandbox or safety-strategy configuration",
+			severity: SeverityHigh,
 		},
 	}
 }
@@ -16,6 +16,7 @@ func NewArchivedUsesRule() *ArchivedUsesRule {
 		BaseRule: BaseRule{
 			RuleName: "archived-uses",
 			RuleDesc: "Detects usage of actions/reusable workflows from archived repositories that are no longer maintained",
+			severity: SeverityMedium,
 		},
 	}
 	rule.initArchivedReposList()
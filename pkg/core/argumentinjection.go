@@ -124,6 +124,7 @@ func newArgumentInjectionRule(severityLevel string, checkPrivileged bool, wfTain
 		BaseRule: BaseRule{
 			RuleName: "argument-injection-" + severityLevel,
 			RuleDesc: desc,
+			severity: Severity(severityLevel),
 		},
 		severityLevel:      severityLevel,
 		checkPrivileged:    checkPrivileged,
@@ -737,7 +738,7 @@ func (rule *ArgumentInjectionRule) parseExpression(exprStr string) (expressions.
 }
 
 func (rule *ArgumentInjectionRule) checkUntrustedInput(expr parsedExpression) []string {
-	checker := expressions.NewExprSemanticsChecker(true, nil)
+	checker := newUntrustedInputChecker(rule.workflow)
 	_, errs := checker.Check(expr.node)
 
 	var paths []string
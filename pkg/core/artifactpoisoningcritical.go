@@ -26,6 +26,7 @@ func ArtifactPoisoningRule() *ArtifactPoisoning {
 		BaseRule: BaseRule{
 			RuleName: "artifact-poisoning-critical",
 			RuleDesc: "Detects unsafe artifact downloads that may allow artifact poisoning attacks. Artifacts should be extracted to a temporary folder to prevent overwriting existing files and should be treated as untrusted content.",
+			severity: SeverityCritical,
 		},
 	}
 }
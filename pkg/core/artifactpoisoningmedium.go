@@ -30,6 +30,7 @@ func NewArtifactPoisoningMediumRule() *ArtifactPoisoningMedium {
 		BaseRule: BaseRule{
 			RuleName: "artifact-poisoning-medium",
 			RuleDesc: "Detects third-party artifact download actions in workflows with untrusted triggers. These actions may download and extract artifacts unsafely, allowing file overwrites.",
+			severity: SeverityMedium,
 		},
 	}
 }
@@ -58,6 +58,7 @@ func NewArtipackedRule() *ArtipackedRule {
 		BaseRule: BaseRule{
 			RuleName: "artipacked",
 			RuleDesc: "Detects credential leakage risk when actions/checkout credentials are persisted and workspace is uploaded via actions/upload-artifact",
+			severity: SeverityCritical,
 		},
 	}
 }
@@ -88,8 +89,9 @@ func (rule *ArtipackedRule) VisitJobPost(node *ast.Job) error {
 		if info.version >= 6 {
 			severity = "Low"
 		}
-		rule.Errorf(
+		rule.ErrorfWithSeverity(
 			info.step.Pos,
+			Severity(strings.ToLower(severity)),
 			"[%s] actions/checkout without 'persist-credentials: false' at step %q. Credentials are stored in %s. While no dangerous upload-artifact was found in this job, consider adding 'persist-credentials: false' to prevent credential exposure. "+
 				"See https://unit42.paloaltonetworks.com/github-repo-artifacts-leak-tokens/",
 			severity,
@@ -197,8 +199,9 @@ func (rule *ArtipackedRule) handleUploadArtifact(step *ast.Step, action *ast.Exe
 			severity = "Medium"
 		}
 
-		rule.Errorf(
+		rule.ErrorfWithSeverity(
 			step.Pos,
+			Severity(strings.ToLower(severity)),
 			"[%s] actions/upload-artifact uploads workspace with path %q, which may include credentials from actions/checkout at line %d. "+
 				"The checkout action stores GITHUB_TOKEN in %s. "+
 				"Add 'persist-credentials: false' to the checkout step or avoid uploading the entire workspace. "+
@@ -0,0 +1,610 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+)
+
+// WorkflowJSONVersion is the version of the JSON form of a workflow written
+// by MarshalWorkflowJSON. It is bumped when a field is renamed or removed.
+const WorkflowJSONVersion = 1
+
+// MarshalWorkflowJSON serializes the syntax tree of a workflow as JSON.
+//
+//	{"version": 1, "path": ".github/workflows/ci.yml", "workflow": {...}}
+//
+// Fields are written in snake_case, e.g. "timeout_minutes" for
+// Job.TimeoutMinutes, and omitted when not set. Values of several possible
+// types (events, step execs, raw YAML values) have a "kind" field such as
+// "webhook_event" or "exec_run". Positions are {"line": 1, "col": 1} objects
+// under "pos". Strings containing "${{ }}" and "if:" conditions have the
+// parsed expression trees under "expressions". This is the input of policies
+// and the output of "sisakulint dump-ast". Every node kind is written by its
+// own function below, so that renaming a field of the ast package does not
+// change this format.
+func MarshalWorkflowJSON(path string, w *ast.Workflow) ([]byte, error) {
+	return json.Marshal(workflowJSONDocument(path, w))
+}
+
+func workflowJSONDocument(path string, w *ast.Workflow) map[string]interface{} {
+	return map[string]interface{}{
+		"version":  WorkflowJSONVersion,
+		"path":     path,
+		"workflow": workflowJSON(w),
+	}
+}
+
+// jsonObject is a JSON object being built. Fields whose value is nil are not
+// set, so that unset nodes are omitted.
+type jsonObject map[string]interface{}
+
+func (o jsonObject) set(key string, v interface{}) jsonObject {
+	if v != nil {
+		o[key] = v
+	}
+	return o
+}
+
+// jsonList converts a slice with f. It returns nil for a nil slice.
+func jsonList[T any](xs []T, f func(T) interface{}) interface{} {
+	if xs == nil {
+		return nil
+	}
+	out := make([]interface{}, 0, len(xs))
+	for _, x := range xs {
+		out = append(out, f(x))
+	}
+	return out
+}
+
+// jsonMap converts the values of a map with f. It returns nil for a nil map.
+func jsonMap[T any](m map[string]T, f func(T) interface{}) interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = f(v)
+	}
+	return out
+}
+
+func positionJSON(p *ast.Position) interface{} {
+	if p == nil {
+		return nil
+	}
+	return map[string]interface{}{"line": p.Line, "col": p.Col}
+}
+
+func stringJSON(s *ast.String) interface{} {
+	if s == nil {
+		return nil
+	}
+	o := jsonObject{"value": s.Value, "quoted": s.Quoted, "literal": s.Literal}
+	o.set("pos", positionJSON(s.Pos))
+	if exprs := templateExpressionsJSON(s); len(exprs) > 0 {
+		o["expressions"] = exprs
+	}
+	return o
+}
+
+// conditionJSON converts an "if:" condition, which is an expression even
+// without "${{ }}".
+func conditionJSON(s *ast.String) interface{} {
+	if s == nil {
+		return nil
+	}
+	o := stringJSON(s).(jsonObject)
+	if o["expressions"] == nil && s.Pos != nil {
+		o["expressions"] = []interface{}{conditionExpressionJSON(s)}
+	}
+	return o
+}
+
+func stringListJSON(ss []*ast.String) interface{} {
+	return jsonList(ss, stringJSON)
+}
+
+func boolJSON(b *ast.Bool) interface{} {
+	if b == nil {
+		return nil
+	}
+	return jsonObject{"value": b.Value}.set("expression", stringJSON(b.Expression)).set("pos", positionJSON(b.Pos))
+}
+
+func intJSON(i *ast.Int) interface{} {
+	if i == nil {
+		return nil
+	}
+	return jsonObject{"value": i.Value}.set("expression", stringJSON(i.Expression)).set("pos", positionJSON(i.Pos))
+}
+
+func floatJSON(f *ast.Float) interface{} {
+	if f == nil {
+		return nil
+	}
+	return jsonObject{"value": f.Value}.set("expression", stringJSON(f.Expression)).set("pos", positionJSON(f.Pos))
+}
+
+func workflowJSON(w *ast.Workflow) interface{} {
+	if w == nil {
+		return nil
+	}
+	return jsonObject{}.
+		set("name", stringJSON(w.Name)).
+		set("description", stringJSON(w.Description)).
+		set("run_name", stringJSON(w.RunName)).
+		set("on", jsonList(w.On, eventJSON)).
+		set("permissions", permissionsJSON(w.Permissions)).
+		set("env", envJSON(w.Env)).
+		set("defaults", defaultsJSON(w.Defaults)).
+		set("concurrency", concurrencyJSON(w.Concurrency)).
+		set("jobs", jsonMap(w.Jobs, jobJSON)).
+		set("composite_action", compositeActionJSON(w.CompositeAction))
+}
+
+func compositeActionJSON(a *ast.CompositeAction) interface{} {
+	if a == nil {
+		return nil
+	}
+	return jsonObject{}.set("inputs", jsonMap(a.Inputs, stringJSON)).set("pos", positionJSON(a.Pos))
+}
+
+// eventJSON converts an event. Its "kind" tells the type of the event and
+// "event" its name.
+func eventJSON(e ast.Event) interface{} {
+	var o jsonObject
+	switch e := e.(type) {
+	case *ast.WebhookEvent:
+		o = jsonObject{"kind": "webhook_event"}.
+			set("hook", stringJSON(e.Hook)).
+			set("types", stringListJSON(e.Types)).
+			set("branches", webhookEventFilterJSON(e.Branches)).
+			set("branches_ignore", webhookEventFilterJSON(e.BranchesIgnore)).
+			set("tags", webhookEventFilterJSON(e.Tags)).
+			set("tags_ignore", webhookEventFilterJSON(e.TagsIgnore)).
+			set("paths", webhookEventFilterJSON(e.Paths)).
+			set("paths_ignore", webhookEventFilterJSON(e.PathsIgnore)).
+			set("workflows", stringListJSON(e.Workflows)).
+			set("pos", positionJSON(e.Pos))
+	case *ast.ScheduledEvent:
+		o = jsonObject{"kind": "scheduled_event"}.set("cron", stringListJSON(e.Cron)).set("pos", positionJSON(e.Pos))
+	case *ast.WorkflowDispatchEvent:
+		o = jsonObject{"kind": "workflow_dispatch_event"}.set("inputs", jsonMap(e.Inputs, dispatchInputJSON)).set("pos", positionJSON(e.Pos))
+	case *ast.RepositoryDispatchEvent:
+		o = jsonObject{"kind": "repository_dispatch_event"}.set("types", stringListJSON(e.Types)).set("pos", positionJSON(e.Pos))
+	case *ast.WorkflowCallEvent:
+		o = jsonObject{"kind": "workflow_call_event"}.
+			set("inputs", jsonList(e.Inputs, workflowCallEventInputJSON)).
+			set("secrets", jsonMap(e.Secrets, workflowCallEventSecretJSON)).
+			set("outputs", jsonMap(e.Outputs, workflowCallEventOutputJSON)).
+			set("pos", positionJSON(e.Pos))
+	default:
+		return nil
+	}
+	o["event"] = e.EventName()
+	return o
+}
+
+func webhookEventFilterJSON(f *ast.WebhookEventFilter) interface{} {
+	if f == nil {
+		return nil
+	}
+	return jsonObject{}.set("name", stringJSON(f.Name)).set("values", stringListJSON(f.Values))
+}
+
+func dispatchInputJSON(i *ast.DispatchInput) interface{} {
+	if i == nil {
+		return nil
+	}
+	return jsonObject{"type": i.Type}.
+		set("name", stringJSON(i.Name)).
+		set("description", stringJSON(i.Description)).
+		set("required", boolJSON(i.Required)).
+		set("default", stringJSON(i.Default)).
+		set("options", stringListJSON(i.Options))
+}
+
+func workflowCallEventInputJSON(i *ast.WorkflowCallEventInput) interface{} {
+	if i == nil {
+		return nil
+	}
+	return jsonObject{"type": i.Type, "id": i.ID}.
+		set("name", stringJSON(i.Name)).
+		set("description", stringJSON(i.Description)).
+		set("default", stringJSON(i.Default)).
+		set("required", boolJSON(i.Required))
+}
+
+func workflowCallEventSecretJSON(s *ast.WorkflowCallEventSecret) interface{} {
+	if s == nil {
+		return nil
+	}
+	return jsonObject{}.
+		set("name", stringJSON(s.Name)).
+		set("description", stringJSON(s.Description)).
+		set("required", boolJSON(s.Required))
+}
+
+func workflowCallEventOutputJSON(o *ast.WorkflowCallEventOutput) interface{} {
+	if o == nil {
+		return nil
+	}
+	return jsonObject{}.
+		set("name", stringJSON(o.Name)).
+		set("description", stringJSON(o.Description)).
+		set("value", stringJSON(o.Value))
+}
+
+func permissionsJSON(p *ast.Permissions) interface{} {
+	if p == nil {
+		return nil
+	}
+	scope := func(s *ast.PermissionScope) interface{} {
+		if s == nil {
+			return nil
+		}
+		return jsonObject{}.set("name", stringJSON(s.Name)).set("value", stringJSON(s.Value))
+	}
+	return jsonObject{}.set("all", stringJSON(p.All)).set("scopes", jsonMap(p.Scopes, scope)).set("pos", positionJSON(p.Pos))
+}
+
+func defaultsJSON(d *ast.Defaults) interface{} {
+	if d == nil {
+		return nil
+	}
+	var run interface{}
+	if d.Run != nil {
+		run = jsonObject{}.
+			set("shell", stringJSON(d.Run.Shell)).
+			set("working_directory", stringJSON(d.Run.WorkingDirectory)).
+			set("pos", positionJSON(d.Run.Pos))
+	}
+	return jsonObject{}.set("run", run).set("pos", positionJSON(d.Pos))
+}
+
+func concurrencyJSON(c *ast.Concurrency) interface{} {
+	if c == nil {
+		return nil
+	}
+	return jsonObject{}.
+		set("group", stringJSON(c.Group)).
+		set("cancel_in_progress", boolJSON(c.CancelInProgress)).
+		set("pos", positionJSON(c.Pos))
+}
+
+func envJSON(e *ast.Env) interface{} {
+	if e == nil {
+		return nil
+	}
+	v := func(v *ast.EnvVar) interface{} {
+		if v == nil {
+			return nil
+		}
+		return jsonObject{}.set("name", stringJSON(v.Name)).set("value", stringJSON(v.Value))
+	}
+	return jsonObject{}.set("vars", jsonMap(e.Vars, v)).set("expression", stringJSON(e.Expression))
+}
+
+func jobJSON(j *ast.Job) interface{} {
+	if j == nil {
+		return nil
+	}
+	return jsonObject{}.
+		set("id", stringJSON(j.ID)).
+		set("name", stringJSON(j.Name)).
+		set("needs", stringListJSON(j.Needs)).
+		set("runs_on", runnerJSON(j.RunsOn)).
+		set("permissions", permissionsJSON(j.Permissions)).
+		set("environment", environmentJSON(j.Environment)).
+		set("concurrency", concurrencyJSON(j.Concurrency)).
+		set("outputs", jsonMap(j.Outputs, outputJSON)).
+		set("env", envJSON(j.Env)).
+		set("defaults", defaultsJSON(j.Defaults)).
+		set("if", conditionJSON(j.If)).
+		set("steps", jsonList(j.Steps, stepJSON)).
+		set("timeout_minutes", floatJSON(j.TimeoutMinutes)).
+		set("strategy", strategyJSON(j.Strategy)).
+		set("continue_on_error", boolJSON(j.ContinueOnError)).
+		set("container", containerJSON(j.Container)).
+		set("services", jsonMap(j.Services, serviceJSON)).
+		set("workflow_call", workflowCallJSON(j.WorkflowCall)).
+		set("pos", positionJSON(j.Pos))
+}
+
+func runnerJSON(r *ast.Runner) interface{} {
+	if r == nil {
+		return nil
+	}
+	return jsonObject{}.
+		set("labels", stringListJSON(r.Labels)).
+		set("labels_expr", stringJSON(r.LabelsExpr)).
+		set("group", stringJSON(r.Group))
+}
+
+func environmentJSON(e *ast.Environment) interface{} {
+	if e == nil {
+		return nil
+	}
+	return jsonObject{}.set("name", stringJSON(e.Name)).set("url", stringJSON(e.URL)).set("pos", positionJSON(e.Pos))
+}
+
+func outputJSON(o *ast.Output) interface{} {
+	if o == nil {
+		return nil
+	}
+	return jsonObject{}.set("name", stringJSON(o.Name)).set("value", stringJSON(o.Value))
+}
+
+func strategyJSON(s *ast.Strategy) interface{} {
+	if s == nil {
+		return nil
+	}
+	return jsonObject{}.
+		set("matrix", matrixJSON(s.Matrix)).
+		set("fail_fast", boolJSON(s.FailFast)).
+		set("max_parallel", intJSON(s.MaxParallel)).
+		set("pos", positionJSON(s.Pos))
+}
+
+func matrixJSON(m *ast.Matrix) interface{} {
+	if m == nil {
+		return nil
+	}
+	row := func(r *ast.MatrixRow) interface{} {
+		if r == nil {
+			return nil
+		}
+		return jsonObject{}.
+			set("name", stringJSON(r.Name)).
+			set("values", jsonList(r.Values, rawYAMLJSON)).
+			set("expression", stringJSON(r.Expression))
+	}
+	return jsonObject{}.
+		set("rows", jsonMap(m.Rows, row)).
+		set("include", matrixCombinationsJSON(m.Include)).
+		set("exclude", matrixCombinationsJSON(m.Exclude)).
+		set("expression", stringJSON(m.Expression)).
+		set("pos", positionJSON(m.Pos))
+}
+
+func matrixCombinationsJSON(cs *ast.MatrixCombinations) interface{} {
+	if cs == nil {
+		return nil
+	}
+	assign := func(a *ast.MatrixAssign) interface{} {
+		if a == nil {
+			return nil
+		}
+		return jsonObject{}.set("key", stringJSON(a.Key)).set("value", rawYAMLJSON(a.Value))
+	}
+	combination := func(c *ast.MatrixCombination) interface{} {
+		if c == nil {
+			return nil
+		}
+		return jsonObject{}.set("assigns", jsonMap(c.Assigns, assign)).set("expression", stringJSON(c.Expression))
+	}
+	return jsonObject{}.set("combinations", jsonList(cs.Combinations, combination)).set("expression", stringJSON(cs.Expression))
+}
+
+// rawYAMLJSON converts a raw YAML value such as a matrix value. Its "kind"
+// tells whether it is an object, an array or a string.
+func rawYAMLJSON(v ast.RawYAMLValue) interface{} {
+	switch v := v.(type) {
+	case *ast.RawYAMLObject:
+		return jsonObject{"kind": "raw_yaml_object"}.set("props", jsonMap(v.Props, rawYAMLJSON)).set("pos", positionJSON(v.Posi))
+	case *ast.RawYAMLArray:
+		return jsonObject{"kind": "raw_yaml_array"}.set("elems", jsonList(v.Elems, rawYAMLJSON)).set("pos", positionJSON(v.Posi))
+	case *ast.RawYAMLString:
+		return jsonObject{"kind": "raw_yaml_string", "value": v.Value}.set("pos", positionJSON(v.Posi))
+	default:
+		return nil
+	}
+}
+
+func stepJSON(s *ast.Step) interface{} {
+	if s == nil {
+		return nil
+	}
+	return jsonObject{}.
+		set("id", stringJSON(s.ID)).
+		set("if", conditionJSON(s.If)).
+		set("name", stringJSON(s.Name)).
+		set("exec", execJSON(s.Exec)).
+		set("env", envJSON(s.Env)).
+		set("continue_on_error", boolJSON(s.ContinueOnError)).
+		set("timeout_minutes", floatJSON(s.TimeoutMinutes)).
+		set("pos", positionJSON(s.Pos))
+}
+
+// execJSON converts what a step executes. Its "kind" is "exec_run" or
+// "exec_action".
+func execJSON(e ast.Exec) interface{} {
+	switch e := e.(type) {
+	case *ast.ExecRun:
+		return jsonObject{"kind": "exec_run"}.
+			set("run", stringJSON(e.Run)).
+			set("shell", stringJSON(e.Shell)).
+			set("working_directory", stringJSON(e.WorkingDirectory)).
+			set("run_pos", positionJSON(e.RunPos))
+	case *ast.ExecAction:
+		input := func(i *ast.Input) interface{} {
+			if i == nil {
+				return nil
+			}
+			return jsonObject{}.set("name", stringJSON(i.Name)).set("value", stringJSON(i.Value))
+		}
+		return jsonObject{"kind": "exec_action"}.
+			set("uses", stringJSON(e.Uses)).
+			set("inputs", jsonMap(e.Inputs, input)).
+			set("entrypoint", stringJSON(e.Entrypoint)).
+			set("args", stringJSON(e.Args))
+	default:
+		return nil
+	}
+}
+
+func containerJSON(c *ast.Container) interface{} {
+	if c == nil {
+		return nil
+	}
+	var credentials interface{}
+	if cr := c.Credentials; cr != nil {
+		credentials = jsonObject{}.
+			set("username", stringJSON(cr.Username)).
+			set("password", stringJSON(cr.Password)).
+			set("pos", positionJSON(cr.Pos))
+	}
+	return jsonObject{}.
+		set("image", stringJSON(c.Image)).
+		set("credentials", credentials).
+		set("env", envJSON(c.Env)).
+		set("ports", stringListJSON(c.Ports)).
+		set("volumes", stringListJSON(c.Volumes)).
+		set("options", stringJSON(c.Options)).
+		set("pos", positionJSON(c.Pos))
+}
+
+func serviceJSON(s *ast.Service) interface{} {
+	if s == nil {
+		return nil
+	}
+	return jsonObject{}.set("name", stringJSON(s.Name)).set("container", containerJSON(s.Container))
+}
+
+func workflowCallJSON(c *ast.WorkflowCall) interface{} {
+	if c == nil {
+		return nil
+	}
+	input := func(i *ast.WorkflowCallInput) interface{} {
+		if i == nil {
+			return nil
+		}
+		return jsonObject{}.set("name", stringJSON(i.Name)).set("value", stringJSON(i.Value))
+	}
+	secret := func(s *ast.WorkflowCallSecret) interface{} {
+		if s == nil {
+			return nil
+		}
+		return jsonObject{}.set("name", stringJSON(s.Name)).set("value", stringJSON(s.Value))
+	}
+	return jsonObject{"inherit_secrets": c.InheritSecrets}.
+		set("uses", stringJSON(c.Uses)).
+		set("inputs", jsonMap(c.Inputs, input)).
+		set("secrets", jsonMap(c.Secrets, secret))
+}
+
+// templateExpressionsJSON returns the "${{ }}" expressions in s.
+func templateExpressionsJSON(s *ast.String) []interface{} {
+	if s.Pos == nil || !strings.Contains(s.Value, "${{") {
+		return nil
+	}
+	line, col := s.Pos.Line, s.Pos.Col
+	if s.Quoted {
+		col++
+	}
+	var out []interface{}
+	rest, offset := s.Value, 0
+	for {
+		idx := strings.Index(rest, "${{")
+		if idx < 0 {
+			return out
+		}
+		start := idx + 3
+		rest = rest[start:]
+		offset += start
+		l := expressions.NewTokenizer(rest)
+		node, err := expressions.NewMiniParser().Parse(l)
+		e := map[string]interface{}{"pos": map[string]interface{}{"line": line, "col": col + offset - 3}}
+		if err != nil {
+			e["source"] = strings.TrimSpace(rest)
+			e["error"] = err.Message
+			return append(out, e)
+		}
+		consumed := l.GetCurrentOffset()
+		e["source"] = strings.TrimSpace(strings.TrimSuffix(rest[:consumed], "}}"))
+		e["tree"] = exprNodeJSON(node, line, col+offset)
+		out = append(out, e)
+		if consumed == 0 {
+			return out
+		}
+		rest = rest[consumed:]
+		offset += consumed
+	}
+}
+
+// conditionExpressionJSON returns the expression of an "if:" condition
+// written without "${{ }}".
+func conditionExpressionJSON(s *ast.String) interface{} {
+	line, col := s.Pos.Line, s.Pos.Col
+	if s.Quoted {
+		col++
+	}
+	e := map[string]interface{}{"source": s.Value, "pos": map[string]interface{}{"line": line, "col": col}}
+	node, err := expressions.NewMiniParser().Parse(expressions.NewTokenizer(s.Value + "}}"))
+	if err != nil {
+		e["error"] = err.Message
+		return e
+	}
+	e["tree"] = exprNodeJSON(node, line, col)
+	return e
+}
+
+// exprNodeJSON converts an expression tree. Positions of nodes are made
+// absolute with the position of the expression, like the positions of
+// errors reported by the expression rule.
+func exprNodeJSON(n expressions.ExprNode, line, col int) map[string]interface{} {
+	sub := func(c expressions.ExprNode) map[string]interface{} { return exprNodeJSON(c, line, col) }
+	var m map[string]interface{}
+	switch n := n.(type) {
+	case *expressions.VariableNode:
+		m = map[string]interface{}{"kind": "variable", "name": n.Name}
+	case *expressions.NullNode:
+		m = map[string]interface{}{"kind": "null"}
+	case *expressions.BoolNode:
+		m = map[string]interface{}{"kind": "bool", "value": n.Value}
+	case *expressions.IntNode:
+		m = map[string]interface{}{"kind": "int", "value": n.Value}
+	case *expressions.FloatNode:
+		m = map[string]interface{}{"kind": "float", "value": n.Value}
+	case *expressions.StringNode:
+		m = map[string]interface{}{"kind": "string", "value": unquotePolicyString(n.Value)}
+	case *expressions.ObjectDerefNode:
+		m = map[string]interface{}{"kind": "object_deref", "receiver": sub(n.Receiver), "property": n.Property}
+	case *expressions.ArrayDerefNode:
+		m = map[string]interface{}{"kind": "array_deref", "receiver": sub(n.Receiver)}
+	case *expressions.IndexAccessNode:
+		m = map[string]interface{}{"kind": "index_access", "operand": sub(n.Operand), "index": sub(n.Index)}
+	case *expressions.NotOpNode:
+		m = map[string]interface{}{"kind": "not", "operand": sub(n.Operand)}
+	case *expressions.CompareOpNode:
+		m = map[string]interface{}{"kind": "compare", "operator": compareOperators[n.Kind], "left": sub(n.Left), "right": sub(n.Right)}
+	case *expressions.LogicalOpNode:
+		m = map[string]interface{}{"kind": "logical", "operator": n.Kind.String(), "left": sub(n.Left), "right": sub(n.Right)}
+	case *expressions.FuncCallNode:
+		args := make([]interface{}, 0, len(n.Args))
+		for _, a := range n.Args {
+			args = append(args, sub(a))
+		}
+		m = map[string]interface{}{"kind": "call", "callee": n.Callee, "args": args}
+	default:
+		return map[string]interface{}{"kind": "unknown"}
+	}
+	if t := n.Token(); t != nil {
+		pos := convertExprLineColToPos(t.Line, t.Column, line, col)
+		m["pos"] = map[string]interface{}{"line": pos.Line, "col": pos.Col}
+	}
+	return m
+}
+
+var compareOperators = map[expressions.CompareOpNodeKind]string{
+	expressions.CompareOpNodeKindLess:      "<",
+	expressions.CompareOpNodeKindLessEq:    "<=",
+	expressions.CompareOpNodeKindGreater:   ">",
+	expressions.CompareOpNodeKindGreaterEq: ">=",
+	expressions.CompareOpNodeKindEq:        "==",
+	expressions.CompareOpNodeKindNotEq:     "!=",
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateASTJSONGolden = flag.Bool("update-ast-json", false, "rewrite the golden files of TestMarshalWorkflowJSON_Golden")
+
+// TestMarshalWorkflowJSON_Golden fails when the JSON form of a workflow
+// changes, since policies and "sisakulint dump-ast" users depend on it. When
+// the change is intended, bump WorkflowJSONVersion if a field was renamed or
+// removed, and rewrite the golden files with:
+//
+//	go test ./pkg/core -run TestMarshalWorkflowJSON_Golden -update-ast-json
+func TestMarshalWorkflowJSON_Golden(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"workflow", "action"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			src, err := os.ReadFile(filepath.Join("testdata", "ast_json", name+".yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, errs := DumpAST(".github/workflows/"+name+".yaml", src)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			got, err := json.MarshalIndent(doc, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			golden := filepath.Join("testdata", "ast_json", name+".json")
+			if *updateASTJSONGolden {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("JSON of %s.yaml differs from %s. If the change is intended, run the test with -update-ast-json", name, golden)
+			}
+		})
+	}
+}
@@ -0,0 +1,480 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fixSnapshot records the YAML tree of a workflow before auto-fixers mutate it
+// so that the fixed file can be written as line-range edits against the
+// original source. Fixers mutate the tree in place, so nodes which survive a
+// fix keep their pointer identity and their original positions; nodes created
+// by fixers are absent from the snapshot.
+type fixSnapshot struct {
+	root  *yaml.Node
+	nodes map[*yaml.Node]yaml.Node
+}
+
+// snapshotForFix records the state of every node reachable from root.
+func snapshotForFix(root *yaml.Node) *fixSnapshot {
+	s := &fixSnapshot{root: root, nodes: map[*yaml.Node]yaml.Node{}}
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n == nil {
+			return
+		}
+		if _, ok := s.nodes[n]; ok {
+			return
+		}
+		c := *n
+		c.Content = append([]*yaml.Node(nil), n.Content...)
+		s.nodes[n] = c
+		for _, child := range n.Content {
+			walk(child)
+		}
+	}
+	walk(root)
+	return s
+}
+
+// errFixNotExpressible means the fix could not be expressed as line-range
+// edits and the whole tree has to be re-encoded.
+var errFixNotExpressible = errors.New("auto-fix cannot be expressed as line-range edits")
+
+// encodeFixedWorkflow re-encodes the whole tree. This is the fallback of
+// writeFixedSource and rewrites quoting, blank lines and comments across the
+// file.
+func encodeFixedWorkflow(root *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFixedSource returns the content of the fixed workflow. Only the
+// mapping entries and sequence items touched by fixers are re-rendered; every
+// other line of source is kept byte for byte. When the edits cannot be
+// computed, or the result does not decode to the same document as a full
+// re-encode, the full re-encode is returned instead.
+func writeFixedSource(source []byte, snapshot *fixSnapshot, root *yaml.Node) ([]byte, error) {
+	full, err := encodeFixedWorkflow(root)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil || snapshot.root != root {
+		return full, nil
+	}
+	patched, err := snapshot.patch(source, root)
+	if err != nil {
+		return full, nil
+	}
+	if !sameYAMLDocument(patched, full) {
+		return full, nil
+	}
+	return patched, nil
+}
+
+// sameYAMLDocument reports whether a and b decode to equal values.
+func sameYAMLDocument(a, b []byte) bool {
+	var va, vb interface{}
+	if err := yaml.Unmarshal(a, &va); err != nil {
+		return false
+	}
+	if err := yaml.Unmarshal(b, &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// lineEdit replaces the lines start..end (1-based, inclusive) with text.
+// end == start-1 inserts text before line start.
+type lineEdit struct {
+	start, end int
+	text       string
+}
+
+// fixPatcher computes line edits for one file.
+type fixPatcher struct {
+	snapshot *fixSnapshot
+	lines    []string
+	newline  string
+}
+
+func (s *fixSnapshot) patch(source []byte, root *yaml.Node) ([]byte, error) {
+	if root.Kind != yaml.DocumentNode || len(root.Content) != 1 {
+		return nil, errFixNotExpressible
+	}
+	p := &fixPatcher{snapshot: s, newline: "\n"}
+	if bytes.Contains(source, []byte("\r\n")) {
+		p.newline = "\r\n"
+	}
+	p.lines = strings.SplitAfter(string(source), "\n")
+	if p.lines[len(p.lines)-1] == "" {
+		p.lines = p.lines[:len(p.lines)-1]
+	}
+	if !p.sameOwnFields(root) {
+		return nil, errFixNotExpressible
+	}
+	edits, ok := p.diff(root.Content[0], len(p.lines))
+	if !ok {
+		return nil, errFixNotExpressible
+	}
+
+	var b strings.Builder
+	next := 1
+	for _, e := range edits {
+		if e.start < next {
+			return nil, errFixNotExpressible
+		}
+		for ; next < e.start; next++ {
+			b.WriteString(p.lines[next-1])
+		}
+		b.WriteString(e.text)
+		next = e.end + 1
+	}
+	for ; next <= len(p.lines); next++ {
+		b.WriteString(p.lines[next-1])
+	}
+	return []byte(b.String()), nil
+}
+
+// sameOwnFields reports whether n existed before fixing and its own fields
+// and children are the same. Children themselves may have changed.
+func (p *fixPatcher) sameOwnFields(n *yaml.Node) bool {
+	old, ok := p.snapshot.nodes[n]
+	if !ok {
+		return false
+	}
+	if n.Kind != old.Kind || n.Style != old.Style || n.Tag != old.Tag || n.Value != old.Value || n.Anchor != old.Anchor ||
+		n.HeadComment != old.HeadComment || n.LineComment != old.LineComment || n.FootComment != old.FootComment {
+		return false
+	}
+	if len(n.Content) != len(old.Content) {
+		return false
+	}
+	for i, c := range n.Content {
+		if c != old.Content[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// unchanged reports whether the whole subtree of n is unchanged.
+func (p *fixPatcher) unchanged(n *yaml.Node) bool {
+	if !p.sameOwnFields(n) {
+		return false
+	}
+	for _, c := range n.Content {
+		if !p.unchanged(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// diff returns the edits for the subtree of n, whose source ends at line
+// limit at the latest. It returns false when the change must be handled by
+// re-rendering the enclosing mapping entry or sequence item.
+func (p *fixPatcher) diff(n *yaml.Node, limit int) ([]lineEdit, bool) {
+	if n.Kind == yaml.AliasNode || n.Kind == yaml.ScalarNode || n.Style&yaml.FlowStyle != 0 {
+		return nil, p.unchanged(n)
+	}
+	old, ok := p.snapshot.nodes[n]
+	if !ok || n.Kind != old.Kind || n.Style != old.Style || n.Tag != old.Tag || n.Anchor != old.Anchor ||
+		n.HeadComment != old.HeadComment || n.LineComment != old.LineComment || n.FootComment != old.FootComment {
+		return nil, false
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		return p.diffMapping(n, old, limit)
+	case yaml.SequenceNode:
+		return p.diffSequence(n, old, limit)
+	}
+	return nil, false
+}
+
+// entry is a mapping entry or a sequence item. key is nil for sequence items.
+type entry struct {
+	key, val *yaml.Node
+}
+
+func mappingEntries(content []*yaml.Node) []entry {
+	ret := make([]entry, 0, len(content)/2)
+	for i := 0; i+1 < len(content); i += 2 {
+		ret = append(ret, entry{content[i], content[i+1]})
+	}
+	return ret
+}
+
+func sequenceEntries(content []*yaml.Node) []entry {
+	ret := make([]entry, 0, len(content))
+	for _, c := range content {
+		ret = append(ret, entry{nil, c})
+	}
+	return ret
+}
+
+// head returns the node identifying e: the key of a mapping entry or the
+// item of a sequence.
+func (e entry) head() *yaml.Node {
+	if e.key != nil {
+		return e.key
+	}
+	return e.val
+}
+
+func (p *fixPatcher) diffMapping(n *yaml.Node, old yaml.Node, limit int) ([]lineEdit, bool) {
+	return p.diffEntries(mappingEntries(old.Content), mappingEntries(n.Content), limit, n)
+}
+
+func (p *fixPatcher) diffSequence(n *yaml.Node, old yaml.Node, limit int) ([]lineEdit, bool) {
+	return p.diffEntries(sequenceEntries(old.Content), sequenceEntries(n.Content), limit, n)
+}
+
+// diffEntries matches the entries of a block mapping or sequence before and
+// after fixing by node identity. Removed entries are deleted, added entries
+// are inserted after the preceding surviving entry, and surviving entries
+// are diffed recursively or re-rendered when that fails.
+func (p *fixPatcher) diffEntries(before, after []entry, limit int, n *yaml.Node) ([]lineEdit, bool) {
+	if len(before) == 0 {
+		return nil, false
+	}
+	isSeq := n.Kind == yaml.SequenceNode
+
+	// Source range and indentation of each entry before fixing.
+	type span struct{ start, end, col int }
+	spans := make([]span, len(before))
+	index := make(map[*yaml.Node]int, len(before))
+	for i, e := range before {
+		start, col, ok := p.entryStart(e, isSeq)
+		if !ok {
+			return nil, false
+		}
+		spans[i] = span{start: start, col: col}
+		index[e.head()] = i
+	}
+	for i := range spans {
+		end := limit
+		if i+1 < len(spans) {
+			end = spans[i+1].start - 1
+		}
+		spans[i].end = p.trimEnd(spans[i].start, end, spans[i].col)
+	}
+	col := spans[0].col
+
+	var edits []lineEdit
+	kept := make([]bool, len(before))
+	prev := -1
+	var pending []entry
+	flush := func() bool {
+		if len(pending) == 0 {
+			return true
+		}
+		var at int
+		if prev >= 0 {
+			at = spans[prev].end + 1
+		} else {
+			// Inserting before the first entry is only possible when it
+			// starts its line, i.e. it is not the first key of a sequence item.
+			at = spans[0].start
+			if !p.startsLine(at, spans[0].col) && !isSeq {
+				return false
+			}
+		}
+		var b strings.Builder
+		for _, e := range pending {
+			text, ok := p.render(e, isSeq, strings.Repeat(" ", col-1), col)
+			if !ok {
+				return false
+			}
+			b.WriteString(text)
+		}
+		edits = append(edits, lineEdit{start: at, end: at - 1, text: b.String()})
+		pending = nil
+		return true
+	}
+
+	for _, e := range after {
+		i, ok := index[e.head()]
+		if !ok || (e.key != nil && before[i].val != e.val && !isSeq) {
+			if ok {
+				// The key survived but its value was replaced.
+				if !flush() {
+					return nil, false
+				}
+				if i <= prev {
+					return nil, false
+				}
+				prev = i
+				kept[i] = true
+				text, ok := p.render(e, isSeq, p.linePrefix(spans[i].start, spans[i].col), spans[i].col)
+				if !ok {
+					return nil, false
+				}
+				edits = append(edits, lineEdit{start: spans[i].start, end: spans[i].end, text: text})
+				continue
+			}
+			pending = append(pending, e)
+			continue
+		}
+		if i <= prev {
+			// Entries were reordered.
+			return nil, false
+		}
+		if !flush() {
+			return nil, false
+		}
+		prev = i
+		kept[i] = true
+
+		sub, ok := p.diffEntry(e, spans[i].end)
+		if ok {
+			edits = append(edits, sub...)
+			continue
+		}
+		text, ok := p.render(e, isSeq, p.linePrefix(spans[i].start, spans[i].col), spans[i].col)
+		if !ok {
+			return nil, false
+		}
+		edits = append(edits, lineEdit{start: spans[i].start, end: spans[i].end, text: text})
+	}
+	if !flush() {
+		return nil, false
+	}
+
+	for i, k := range kept {
+		if k {
+			continue
+		}
+		if !isSeq && !p.startsLine(spans[i].start, spans[i].col) {
+			return nil, false
+		}
+		edits = append(edits, lineEdit{start: spans[i].start, end: spans[i].end})
+	}
+	sortLineEdits(edits)
+	return edits, true
+}
+
+// diffEntry diffs a surviving entry whose source ends at line limit.
+func (p *fixPatcher) diffEntry(e entry, limit int) ([]lineEdit, bool) {
+	if e.key != nil && !p.unchanged(e.key) {
+		return nil, false
+	}
+	return p.diff(e.val, limit)
+}
+
+// entryStart returns the first line and the indentation column of an entry.
+// The column is the one of the key for mapping entries and the one of "-"
+// for sequence items.
+func (p *fixPatcher) entryStart(e entry, isSeq bool) (int, int, bool) {
+	n := e.head()
+	if n.Line < 1 || n.Line > len(p.lines) {
+		return 0, 0, false
+	}
+	if !isSeq {
+		return n.Line, n.Column, true
+	}
+	// The item node starts after "- ". The dash is usually on the same line
+	// but may be alone on a previous line.
+	for line := n.Line; line >= 1 && line >= n.Line-1; line-- {
+		text := p.lines[line-1]
+		limit := len(text)
+		if line == n.Line {
+			limit = n.Column - 1
+		}
+		if limit > len(text) {
+			return 0, 0, false
+		}
+		if i := strings.LastIndex(text[:limit], "-"); i >= 0 && strings.TrimSpace(text[i+1:limit]) == "" {
+			return line, i + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// startsLine reports whether only spaces precede column col on line.
+func (p *fixPatcher) startsLine(line, col int) bool {
+	text := p.lines[line-1]
+	return col-1 <= len(text) && strings.TrimLeft(text[:col-1], " ") == ""
+}
+
+// linePrefix returns the text preceding column col on line, e.g. "    - "
+// for the first key of a sequence item.
+func (p *fixPatcher) linePrefix(line, col int) string {
+	text := p.lines[line-1]
+	if col-1 > len(text) {
+		return strings.Repeat(" ", col-1)
+	}
+	return text[:col-1]
+}
+
+// trimEnd moves end back over trailing blank lines and over comment lines
+// indented no deeper than col, which belong to the next entry.
+func (p *fixPatcher) trimEnd(start, end, col int) int {
+	for end > start {
+		text := strings.TrimRight(p.lines[end-1], "\r\n")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			end--
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") && len(text)-len(trimmed) <= col-1 {
+			end--
+			continue
+		}
+		break
+	}
+	return end
+}
+
+// render encodes one entry indented to column col. The first line starts
+// with firstPrefix instead of the indentation.
+func (p *fixPatcher) render(e entry, isSeq bool, firstPrefix string, col int) (string, bool) {
+	var n *yaml.Node
+	if isSeq {
+		n = &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{e.val}}
+	} else {
+		// Comments around the key stay in the source outside of the edited
+		// lines, so they must not be emitted again.
+		key := *e.key
+		key.HeadComment = ""
+		key.FootComment = ""
+		n = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{&key, e.val}}
+	}
+	out, err := encodeFixedWorkflow(n)
+	if err != nil {
+		return "", false
+	}
+	indent := strings.Repeat(" ", col-1)
+	var b strings.Builder
+	for i, line := range strings.SplitAfter(strings.TrimSuffix(string(out), "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case i == 0:
+			b.WriteString(firstPrefix)
+		case line != "":
+			b.WriteString(indent)
+		}
+		b.WriteString(line)
+		b.WriteString(p.newline)
+	}
+	return b.String(), true
+}
+
+func sortLineEdits(edits []lineEdit) {
+	// Insertion sort keeps insertions before replacements starting at the
+	// same line, in the order they were added.
+	for i := 1; i < len(edits); i++ {
+		for j := i; j > 0 && edits[j].start < edits[j-1].start; j-- {
+			edits[j], edits[j-1] = edits[j-1], edits[j]
+		}
+	}
+}
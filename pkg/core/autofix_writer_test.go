@@ -0,0 +1,200 @@
+package core
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const autofixWriterSource = `# top comment
+on: push   # trigger
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      # checkout
+      - uses: 'actions/checkout@v4'
+        with:
+          fetch-depth: 0
+
+      - run: |
+          echo "${{ github.event.head_commit.message }}"
+        shell: bash
+`
+
+// fixWithSnapshot parses src, applies mutate to the tree like an auto-fixer
+// would, and returns the written source.
+func fixWithSnapshot(t *testing.T, src string, mutate func(root *yaml.Node)) string {
+	t.Helper()
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &root); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	snapshot := snapshotForFix(&root)
+	mutate(&root)
+	out, err := writeFixedSource([]byte(src), snapshot, &root)
+	if err != nil {
+		t.Fatalf("writeFixedSource: %v", err)
+	}
+	return string(out)
+}
+
+// lookup follows mapping keys and sequence indexes (as ints) from the root.
+func lookup(t *testing.T, root *yaml.Node, path ...interface{}) *yaml.Node {
+	t.Helper()
+	n := root.Content[0]
+	for _, p := range path {
+		switch p := p.(type) {
+		case string:
+			found := false
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == p {
+					n = n.Content[i+1]
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Fatalf("key %q not found", p)
+			}
+		case int:
+			n = n.Content[p]
+		}
+	}
+	return n
+}
+
+func TestWriteFixedSource_ScalarChangeKeepsRestOfFile(t *testing.T) {
+	t.Parallel()
+
+	got := fixWithSnapshot(t, autofixWriterSource, func(root *yaml.Node) {
+		step := lookup(t, root, "jobs", "build", "steps", 0)
+		step.Content[1].Value = "actions/checkout@8e8c483db84b4bee98b60c0593521ed34d9990e8"
+		step.Content[1].LineComment = "# v6.0.1"
+	})
+	want := `# top comment
+on: push   # trigger
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      # checkout
+      - uses: 'actions/checkout@8e8c483db84b4bee98b60c0593521ed34d9990e8' # v6.0.1
+        with:
+          fetch-depth: 0
+
+      - run: |
+          echo "${{ github.event.head_commit.message }}"
+        shell: bash
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteFixedSource_InsertsAndDeletesEntries(t *testing.T) {
+	t.Parallel()
+
+	got := fixWithSnapshot(t, autofixWriterSource, func(root *yaml.Node) {
+		step := lookup(t, root, "jobs", "build", "steps", 1)
+		// Remove "shell" and add "env", like the code-injection fixer.
+		step.Content = step.Content[:2]
+		step.Content = append(step.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "env"},
+			&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: "MSG"},
+				{Kind: yaml.ScalarNode, Value: "${{ github.event.head_commit.message }}"},
+			}},
+		)
+		job := lookup(t, root, "jobs", "build")
+		job.Content = append(job.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "timeout-minutes"},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "5"},
+		)
+	})
+	want := `# top comment
+on: push   # trigger
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      # checkout
+      - uses: 'actions/checkout@v4'
+        with:
+          fetch-depth: 0
+
+      - run: |
+          echo "${{ github.event.head_commit.message }}"
+        env:
+          MSG: ${{ github.event.head_commit.message }}
+    timeout-minutes: 5
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteFixedSource_InsertsSequenceItem(t *testing.T) {
+	t.Parallel()
+
+	got := fixWithSnapshot(t, autofixWriterSource, func(root *yaml.Node) {
+		steps := lookup(t, root, "jobs", "build", "steps")
+		item := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "uses"},
+			{Kind: yaml.ScalarNode, Value: "step-security/harden-runner@v2"},
+		}}
+		steps.Content = append([]*yaml.Node{item}, steps.Content...)
+	})
+	want := `# top comment
+on: push   # trigger
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      # checkout
+      - uses: step-security/harden-runner@v2
+      - uses: 'actions/checkout@v4'
+        with:
+          fetch-depth: 0
+
+      - run: |
+          echo "${{ github.event.head_commit.message }}"
+        shell: bash
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteFixedSource_FallsBackToReencode(t *testing.T) {
+	t.Parallel()
+
+	var reordered *yaml.Node
+	got := fixWithSnapshot(t, autofixWriterSource, func(root *yaml.Node) {
+		// Swapping top-level keys cannot be expressed as line-range edits.
+		m := root.Content[0]
+		m.Content[0], m.Content[2] = m.Content[2], m.Content[0]
+		m.Content[1], m.Content[3] = m.Content[3], m.Content[1]
+		reordered = root
+	})
+	want, err := encodeFixedWorkflow(reordered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("want full re-encode, got:\n%s", got)
+	}
+}
+
+func TestWriteFixedSource_NoChange(t *testing.T) {
+	t.Parallel()
+
+	got := fixWithSnapshot(t, autofixWriterSource, func(*yaml.Node) {})
+	if got != autofixWriterSource {
+		t.Errorf("unchanged tree should keep the source as is, got:\n%s", got)
+	}
+}
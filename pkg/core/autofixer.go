@@ -81,3 +81,20 @@ func NewFuncFixer(ruleName string, fixer func() error) AutoFixer {
 		fixer:         fixer,
 	}
 }
+
+// repositoryFileFixer is a function fixer which writes files other than the
+// workflow.
+type repositoryFileFixer struct {
+	funcFixer
+}
+
+// NewRepositoryFileFixer is NewFuncFixer for fixers which write files other
+// than the workflow, such as .github/dependabot.yaml. They are not run to
+// compute the suggested fixes of -format output, which must not change the
+// repository.
+func NewRepositoryFileFixer(ruleName string, fixer func() error) AutoFixer {
+	return &repositoryFileFixer{funcFixer{
+		BaseAutoFixer: BaseAutoFixer{ruleName: ruleName},
+		fixer:         fixer,
+	}}
+}
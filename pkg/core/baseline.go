@@ -0,0 +1,201 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BaselineFormatVersion is the version written to and accepted from baseline files.
+const BaselineFormatVersion = 1
+
+// BaselineEntry is one frozen finding in a baseline file. Only Fingerprint
+// is used for matching; the other fields make the file reviewable.
+type BaselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	Rule        string `json:"rule"`
+	FilePath    string `json:"filepath"`
+	Line        int    `json:"line"`
+	Message     string `json:"message"`
+}
+
+// Baseline is a set of known findings. With -baseline only findings that are
+// not in the baseline are reported; with -baseline-write the current
+// findings are frozen into a new baseline file.
+type Baseline struct {
+	Version  int              `json:"version"`
+	Findings []*BaselineEntry `json:"findings"`
+
+	mu sync.Mutex
+	// remaining counts the not yet matched entries per fingerprint. The same
+	// fingerprint may appear several times, e.g. for identical lines.
+	remaining map[string]int
+	// checkedFiles are the normalized paths of files filtered in this run.
+	// Stale entries are only reported for files that were actually linted.
+	checkedFiles map[string]struct{}
+}
+
+var (
+	baselinePositionPattern = regexp.MustCompile(`\b(line|lines|col|column)\s+\d+`)
+	baselineLineColPattern  = regexp.MustCompile(`:\d+:\d+`)
+)
+
+// normalizeBaselineText collapses whitespace and removes positional numbers so
+// that edits elsewhere in the file do not change a fingerprint.
+func normalizeBaselineText(s string) string {
+	s = baselinePositionPattern.ReplaceAllString(s, "$1 N")
+	s = baselineLineColPattern.ReplaceAllString(s, ":N:N")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Fingerprint returns a stable identifier of the finding built from the rule
+// type, the file path, the normalized message and the source line the
+// finding points to. The line number itself is not part of the fingerprint,
+// so inserting lines above a finding does not invalidate it.
+func (e *LintingError) Fingerprint(sourceContent []byte) string {
+	snippet := ""
+	if len(sourceContent) > 0 && e.LineNumber > 0 {
+		if lineContent, found := e.extractLineContent(sourceContent); found {
+			snippet = lineContent
+		}
+	}
+	h := sha256.New()
+	for _, part := range []string{
+		e.Type,
+		normalizeReportPath(e.FilePath),
+		normalizeBaselineText(e.Description),
+		normalizeBaselineText(snippet),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewBaseline creates a baseline freezing every finding in results.
+func NewBaseline(results []*ValidateResult) *Baseline {
+	b := &Baseline{Version: BaselineFormatVersion, Findings: []*BaselineEntry{}}
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		for _, err := range r.Errors {
+			b.Findings = append(b.Findings, &BaselineEntry{
+				Fingerprint: err.Fingerprint(r.Source),
+				Rule:        err.Type,
+				FilePath:    normalizeReportPath(err.FilePath),
+				Line:        err.LineNumber,
+				Message:     err.Description,
+			})
+		}
+	}
+	sort.SliceStable(b.Findings, func(i, j int) bool {
+		x, y := b.Findings[i], b.Findings[j]
+		if x.FilePath != y.FilePath {
+			return x.FilePath < y.FilePath
+		}
+		if x.Line != y.Line {
+			return x.Line < y.Line
+		}
+		return x.Rule < y.Rule
+	})
+	return b
+}
+
+// ReadBaselineFile reads a baseline file written by -baseline-write.
+func ReadBaselineFile(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline file %q: %w", path, err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline file %q: %w", path, err)
+	}
+	if b.Version != BaselineFormatVersion {
+		return nil, fmt.Errorf("unsupported baseline file version %d in %q (expected %d)", b.Version, path, BaselineFormatVersion)
+	}
+	return &b, nil
+}
+
+// WriteFile writes the baseline as indented JSON.
+func (b *Baseline) WriteFile(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(path, data, 0644); err != nil { //nolint:gosec // baseline file is committed to git and must be readable by CI
+		return fmt.Errorf("failed to write baseline file %q: %w", path, err)
+	}
+	return nil
+}
+
+// reset restores the counts of unmatched entries so that the next run matches
+// against the whole baseline again. It is called at the start of each run.
+func (b *Baseline) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resetLocked()
+}
+
+func (b *Baseline) resetLocked() {
+	b.remaining = make(map[string]int, len(b.Findings))
+	for _, f := range b.Findings {
+		b.remaining[f.Fingerprint]++
+	}
+	b.checkedFiles = map[string]struct{}{}
+}
+
+// filter removes the findings of result that are recorded in the baseline.
+// It is safe for concurrent use.
+func (b *Baseline) filter(result *ValidateResult) {
+	if result == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.remaining == nil {
+		b.resetLocked()
+	}
+	b.checkedFiles[normalizeReportPath(result.FilePath)] = struct{}{}
+
+	kept := make([]*LintingError, 0, len(result.Errors))
+	for _, err := range result.Errors {
+		fp := err.Fingerprint(result.Source)
+		if b.remaining[fp] > 0 {
+			b.remaining[fp]--
+			continue
+		}
+		kept = append(kept, err)
+	}
+	result.Errors = kept
+}
+
+// StaleEntries returns the entries of files checked in this run that did not
+// match any finding. They can be pruned by re-running with -baseline-write.
+func (b *Baseline) StaleEntries() []*BaselineEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	remaining := make(map[string]int, len(b.remaining))
+	for fp, n := range b.remaining {
+		remaining[fp] = n
+	}
+	var stale []*BaselineEntry
+	for _, f := range b.Findings {
+		if _, ok := b.checkedFiles[normalizeReportPath(f.FilePath)]; !ok {
+			continue
+		}
+		if remaining[f.Fingerprint] > 0 {
+			remaining[f.Fingerprint]--
+			stale = append(stale, f)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const baselineTestWorkflow = `on: pull_request_target
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.pull_request.title }}"
+`
+
+func lintWithBaseline(t *testing.T, baselinePath string, src string) (*Linter, *ValidateResult) {
+	t.Helper()
+	linter, err := NewLinter(io.Discard, &LinterOptions{
+		LogOutputDestination: io.Discard,
+		BaselineFilePath:     baselinePath,
+	})
+	if err != nil {
+		t.Fatalf("NewLinter: %v", err)
+	}
+	result, err := linter.Lint("test.yaml", []byte(src), nil)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	return linter, result
+}
+
+func writeTestBaseline(t *testing.T, src string) string {
+	t.Helper()
+	_, result := lintWithBaseline(t, "", src)
+	if len(result.Errors) == 0 {
+		t.Fatal("fixture workflow should have findings")
+	}
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := NewBaseline([]*ValidateResult{result}).WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestBaseline_SuppressesKnownFindings(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestBaseline(t, baselineTestWorkflow)
+	linter, result := lintWithBaseline(t, path, baselineTestWorkflow)
+	if len(result.Errors) != 0 {
+		t.Errorf("baselined findings should not be reported, got %v", result.Errors)
+	}
+	if stale := linter.StaleBaselineEntries(); len(stale) != 0 {
+		t.Errorf("no entry should be stale, got %d", len(stale))
+	}
+}
+
+func TestBaseline_FiltersEveryRunOfSameLinter(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestBaseline(t, baselineTestWorkflow)
+	linter, _ := lintWithBaseline(t, path, baselineTestWorkflow)
+	result, err := linter.Lint("test.yaml", []byte(baselineTestWorkflow), nil)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("baselined findings should not be reported by the second run, got %v", result.Errors)
+	}
+}
+
+func TestBaseline_SurvivesEditsAboveFinding(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestBaseline(t, baselineTestWorkflow)
+	edited := strings.Replace(baselineTestWorkflow, "    steps:\n", "    steps:\n      - run: echo setup\n\n", 1)
+	_, result := lintWithBaseline(t, path, edited)
+	if len(result.Errors) != 0 {
+		t.Errorf("moving a finding down should not invalidate its baseline entry, got %v", result.Errors)
+	}
+}
+
+func TestBaseline_ReportsNewFindingsAndStaleEntries(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestBaseline(t, baselineTestWorkflow)
+	changed := strings.Replace(baselineTestWorkflow, "pull_request.title", "pull_request.body", 1)
+	linter, result := lintWithBaseline(t, path, changed)
+	found := false
+	for _, e := range result.Errors {
+		if e.Type == "code-injection-critical" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("new finding should be reported, got %v", result.Errors)
+	}
+	stale := linter.StaleBaselineEntries()
+	if len(stale) == 0 {
+		t.Fatal("entries of the removed finding should be stale")
+	}
+	for _, e := range stale {
+		if e.FilePath != "test.yaml" {
+			t.Errorf("stale entry file = %q, want test.yaml", e.FilePath)
+		}
+	}
+}
+
+func TestBaseline_DuplicateFingerprintsAreCounted(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestBaseline(t, baselineTestWorkflow)
+	// The same line twice yields the same fingerprint; only one is baselined.
+	duplicated := baselineTestWorkflow + `      - run: echo "${{ github.event.pull_request.title }}"
+`
+	_, result := lintWithBaseline(t, path, duplicated)
+	n := 0
+	for _, e := range result.Errors {
+		if e.Type == "code-injection-critical" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("want the second identical finding reported once, got %d: %v", n, result.Errors)
+	}
+}
+
+func TestReadBaselineFile_RejectsUnknownVersion(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	writeTestFile(t, path, `{"version": 99, "findings": []}`)
+	if _, err := ReadBaselineFile(path); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Fatalf("want version error, got %v", err)
+	}
+}
+
+func TestFingerprint_IgnoresLineNumbers(t *testing.T) {
+	t.Parallel()
+
+	a := &LintingError{Description: "bad thing at line 3", FilePath: "a.yml", LineNumber: 1, Type: "r"}
+	b := &LintingError{Description: "bad thing at line 9", FilePath: "a.yml", LineNumber: 2, Type: "r"}
+	src := []byte("  x: y\nx:   y\n")
+	if a.Fingerprint(src) != b.Fingerprint(src) {
+		t.Error("fingerprints should not depend on line numbers or indentation")
+	}
+	c := &LintingError{Description: "bad thing at line 3", FilePath: "b.yml", LineNumber: 1, Type: "r"}
+	if a.Fingerprint(src) == c.Fingerprint(src) {
+		t.Error("fingerprints should depend on the file path")
+	}
+}
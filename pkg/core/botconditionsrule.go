@@ -117,6 +117,7 @@ func NewBotConditionsRule() *BotConditionsRule {
 		BaseRule: BaseRule{
 			RuleName: "bot-conditions",
 			RuleDesc: "Detects spoofable bot detection conditions using github.actor or similar contexts",
+			severity: SeverityHigh,
 		},
 	}
 }
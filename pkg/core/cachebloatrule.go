@@ -40,6 +40,7 @@ func NewCacheBloatRule() *CacheBloatRule {
 		BaseRule: BaseRule{
 			RuleName: "cache-bloat",
 			RuleDesc: "Detects cache bloat risk when using actions/cache/restore and actions/cache/save without proper conditions",
+			severity: SeverityLow,
 		},
 	}
 }
@@ -36,6 +36,7 @@ func NewCachePoisoningPoisonableStepRule() *CachePoisoningPoisonableStepRule {
 		BaseRule: BaseRule{
 			RuleName: "cache-poisoning-poisonable-step",
 			RuleDesc: "Detects potential cache poisoning via execution of untrusted code after unsafe checkout",
+			severity: SeverityHigh,
 		},
 	}
 }
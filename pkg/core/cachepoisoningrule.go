@@ -88,6 +88,7 @@ func NewCachePoisoningRule(actionMetadata ...ActionMetadataResolver) *CachePoiso
 		BaseRule: BaseRule{
 			RuleName: "cache-poisoning",
 			RuleDesc: "Detects potential cache poisoning vulnerabilities when using cache with untrusted triggers or untrusted inputs in cache configuration",
+			severity: SeverityHigh,
 		},
 		actionMetadata:      resolver,
 		directCacheFixSteps: make([]*directCacheFixInfo, 0),
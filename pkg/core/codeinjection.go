@@ -32,22 +32,28 @@ type CodeInjectionRule struct {
 
 // reportCodeInjectionError emits the appropriate Errorf for a code injection finding.
 // It centralises the message formatting that is shared between VisitJobPre (normal path)
-// and VisitWorkflowPost (deferred cross-job path).
-func (rule *CodeInjectionRule) reportCodeInjectionError(pos *ast.Position, taintPath string, isInRunScript bool) {
+// and VisitWorkflowPost (deferred cross-job path). flow is the path of the tainted value
+// up to the expression, or nil when the expression is the untrusted input itself.
+func (rule *CodeInjectionRule) reportCodeInjectionError(pos *ast.Position, taintPath string, isInRunScript bool, flow []*TaintFlowStep) {
 	scriptType := "github-script"
+	sink := "used in actions/github-script script:"
 	if isInRunScript {
 		scriptType = "inline scripts"
+		sink = "used in run: script"
 	}
+	flow = taintFlowWithSink(flow, newTaintFlowStep(pos, "%s", sink))
 
 	if rule.checkPrivileged {
-		rule.Errorf(
+		rule.ErrorfWithFlow(
 			pos,
+			flow,
 			"code injection (critical): \"%s\" is potentially untrusted and used in a workflow with privileged triggers. Avoid using it directly in %s. Instead, pass it through an environment variable. See https://sisaku-security.github.io/lint/docs/rules/codeinjectioncritical/",
 			taintPath, scriptType,
 		)
 	} else {
-		rule.Errorf(
+		rule.ErrorfWithFlow(
 			pos,
+			flow,
 			"code injection (medium): \"%s\" is potentially untrusted. Avoid using it directly in %s. Instead, pass it through an environment variable. See https://sisaku-security.github.io/lint/docs/rules/codeinjectionmedium/",
 			taintPath, scriptType,
 		)
@@ -104,6 +110,7 @@ func newCodeInjectionRule(severityLevel string, checkPrivileged bool, wfTaintMap
 		BaseRule: BaseRule{
 			RuleName: "code-injection-" + severityLevel,
 			RuleDesc: desc,
+			severity: Severity(severityLevel),
 		},
 		severityLevel:      severityLevel,
 		checkPrivileged:    checkPrivileged,
@@ -221,7 +228,7 @@ func (rule *CodeInjectionRule) VisitJobPre(node *ast.Job) error {
 						isInRunScript: true,
 					})
 
-					rule.reportCodeInjectionError(expr.pos, strings.Join(untrustedPaths, "\", \""), true)
+					rule.reportCodeInjectionError(expr.pos, strings.Join(untrustedPaths, "\", \""), true, rule.stepOutputFlow(expr))
 				}
 			}
 		}
@@ -250,7 +257,7 @@ func (rule *CodeInjectionRule) VisitJobPre(node *ast.Job) error {
 								scriptInput:   scriptInput,
 							})
 
-							rule.reportCodeInjectionError(expr.pos, strings.Join(untrustedPaths, "\", \""), false)
+							rule.reportCodeInjectionError(expr.pos, strings.Join(untrustedPaths, "\", \""), false, rule.stepOutputFlow(expr))
 						}
 					}
 				}
@@ -300,7 +307,7 @@ func (rule *CodeInjectionRule) VisitWorkflowPost(node *ast.Workflow) error {
 
 		taintPath := fmt.Sprintf("%s (tainted via %s)", pending.expr.raw, strings.Join(sources, ", "))
 
-		rule.reportCodeInjectionError(pending.expr.pos, taintPath, pending.isInRunScript)
+		rule.reportCodeInjectionError(pending.expr.pos, taintPath, pending.isInRunScript, rule.workflowTaintMap.FlowFromExprNode(pending.expr.node))
 
 		// Wire up auto-fix, mirroring the normal path.
 		if pending.step != nil {
@@ -612,9 +619,22 @@ func (rule *CodeInjectionRule) parseExpression(exprStr string) (expressions.Expr
 	return p.Parse(l)
 }
 
+// newUntrustedInputChecker returns a checker of untrusted inputs, including inputs.* in composite actions.
+func newUntrustedInputChecker(workflow *ast.Workflow) *expressions.ExprSemanticsChecker {
+	checker := expressions.NewExprSemanticsChecker(true, nil)
+	if workflow != nil && workflow.CompositeAction != nil {
+		names := make([]string, 0, len(workflow.CompositeAction.Inputs))
+		for name := range workflow.CompositeAction.Inputs {
+			names = append(names, name)
+		}
+		checker.UpdateUntrustedInputs(expressions.CreateUntrustedInputsForReusableWorkflow(names))
+	}
+	return checker
+}
+
 // checkUntrustedInput checks if the expression contains untrusted input
 func (rule *CodeInjectionRule) checkUntrustedInput(expr parsedExpression) []string {
-	checker := expressions.NewExprSemanticsChecker(true, nil)
+	checker := newUntrustedInputChecker(rule.workflow)
 	_, errs := checker.Check(expr.node)
 
 	var paths []string
@@ -661,8 +681,16 @@ func (rule *CodeInjectionRule) checkUntrustedInputWithTaint(expr parsedExpressio
 	return paths
 }
 
+// stepOutputFlow returns the path of the tainted value of a steps.X.outputs.Y expression,
+// or nil when expr is not a tainted step output.
+func (rule *CodeInjectionRule) stepOutputFlow(expr parsedExpression) []*TaintFlowStep {
+	if rule.taintTracker == nil {
+		return nil
+	}
+	return rule.taintTracker.FlowOf(exprNodeToString(expr.node))
+}
+
 // isNeedsOutputExpr returns true if the expression is a needs.X.outputs.Y reference.
-// This is a package-level function so it can be reused by multiple injection rules.
 func isNeedsOutputExpr(expr parsedExpression) bool {
 	exprStr := exprNodeToString(expr.node)
 	lower := strings.ToLower(exprStr)
@@ -8,14 +8,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/sisaku-security/sisakulint/pkg/remote"
-	"gopkg.in/yaml.v3"
 )
 
 // バージョンとインストール情報を保持する変数
@@ -61,8 +62,27 @@ $ sisakulint -remote owner/repo
 $ sisakulint -remote owner/repo -pr 123
 $ sisakulint -remote https://github.com/owner/repo/pull/123
 $ sisakulint -remote "org:kubernetes"
+$ sisakulint -remote "org:kubernetes" -remote-report sarif -o report.sarif
 $ sisakulint -remote owner/repo -r -D 5
 
+# Language server for editors (LSP over stdio)
+
+$ sisakulint lsp
+
+# Offline advisory database for known-vulnerable-actions
+
+$ sisakulint advisory-db -o advisories.json
+$ sisakulint -advisory-db advisories.json
+
+# Lock actions to commit SHAs in .github/sisakulint.lock
+
+$ sisakulint pin
+$ sisakulint pin -update
+
+# Syntax tree of a workflow as JSON for other tools
+
+$ sisakulint dump-ast .github/workflows/ci.yml
+
 # Documents
 - https://sisaku-security.github.io/lint/
 
@@ -109,8 +129,9 @@ type Command struct {
 }
 
 // todo: linterを実行して結果を返すメソッド
-func (cmd *Command) runLint(args []string, linterOpts *LinterOptions, initConfig bool, generateBoilerplate bool) ([]*ValidateResult, error) {
-	l, err := NewLinter(cmd.Stdout, linterOpts)
+// findingsは、エラーを出力するためのio.Writer
+func (cmd *Command) runLint(args []string, findings io.Writer, linterOpts *LinterOptions, initConfig bool, generateBoilerplate bool) ([]*ValidateResult, error) {
+	l, err := NewLinter(findings, linterOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -123,23 +144,60 @@ func (cmd *Command) runLint(args []string, linterOpts *LinterOptions, initConfig
 		return nil, l.GenerateBoilerplate(".")
 	}
 
+	var results []*ValidateResult
 	if len(args) == 0 {
-		return l.LintRepository(".")
+		results, err = l.LintRepository(".")
+	} else {
+		results, err = l.LintFiles(args, nil)
+	}
+	if err != nil {
+		return nil, err
 	}
+	cmd.reportStaleBaselineEntries(l.StaleBaselineEntries())
+	return results, nil
+}
 
-	return l.LintFiles(args, nil)
+// reportStaleBaselineEntries tells the user which baseline entries no longer
+// match a finding so that the baseline file can shrink over time.
+func (cmd *Command) reportStaleBaselineEntries(stale []*BaselineEntry) {
+	if len(stale) == 0 {
+		return
+	}
+	fmt.Fprintf(cmd.Stderr, "sisakulint: %d baseline %s no longer match any finding; re-run with -baseline-write to remove them:\n",
+		len(stale), pluralize(len(stale), "entry", "entries"))
+	for _, e := range stale {
+		fmt.Fprintf(cmd.Stderr, "  %s:%d: %s [%s]\n", e.FilePath, e.Line, e.Message, e.Rule)
+	}
 }
 
 // runAutofix returns true if any fixer hit the GitHub API rate limit, so
 // Main can surface a non-zero exit and skip the affected file's write
-// (issue #474).
-func (cmd *Command) runAutofix(results []*ValidateResult, isDryRun bool, baseDir string) (rateLimited bool) {
+// (issue #474). In dry-run and patch modes nothing is written; instead a
+// unified diff of each file is written to diffOut. Fixers of rules rejected
+// by filter are not run and are counted per rule.
+func (cmd *Command) runAutofix(results []*ValidateResult, mode string, baseDir string, diffOut io.Writer, filter *fixRuleFilter) (rateLimited bool) {
+	showDiff := mode == FileFixDryRun || mode == FileFixPatch
+	skipped := map[string]int{}
+	defer func() { cmd.reportSkippedFixes(skipped) }()
 	for _, res := range results {
-		if len(res.AutoFixers) == 0 {
+		fixers := make([]AutoFixer, 0, len(res.AutoFixers))
+		for _, fixer := range res.AutoFixers {
+			if !filter.allows(fixer.RuleName()) {
+				skipped[fixer.RuleName()]++
+				continue
+			}
+			fixers = append(fixers, fixer)
+		}
+		if len(fixers) == 0 {
 			continue
 		}
 		fileRateLimited := false
-		for _, fixer := range res.AutoFixers {
+		snapshot := snapshotForFix(res.ParsedWorkflow.BaseNode)
+		var attribution *fixAttribution
+		if showDiff {
+			attribution = newFixAttribution(res.Source)
+		}
+		for _, fixer := range fixers {
 			if err := fixer.Fix(); err != nil {
 				if IsGitHubRateLimitError(err) {
 					fileRateLimited = true
@@ -153,17 +211,28 @@ func (cmd *Command) runAutofix(results []*ValidateResult, isDryRun bool, baseDir
 					fmt.Fprintf(cmd.Stderr, "Error while fixing %s: %v\n", fixer.RuleName(), err)
 				}
 			}
+			if attribution != nil {
+				if data, err := writeFixedSource(res.Source, snapshot, res.ParsedWorkflow.BaseNode); err == nil {
+					attribution.record(fixer.RuleName(), data)
+				}
+			}
 		}
-		var buf bytes.Buffer
-		enc := yaml.NewEncoder(&buf)
-		enc.SetIndent(2)
-		err := enc.Encode(res.ParsedWorkflow.BaseNode)
+		// Only the lines touched by fixers are rewritten so that comments,
+		// quoting and blank lines elsewhere in the file are preserved.
+		data, err := writeFixedSource(res.Source, snapshot, res.ParsedWorkflow.BaseNode)
 		if err != nil {
 			fmt.Fprintf(cmd.Stderr, "Error while marshaling the fixed workflow: %v\n", err)
 		}
-		data := buf.Bytes()
-		if isDryRun {
-			fmt.Fprintf(cmd.Stdout, "Fixed workflow %s:\n%s\n", res.FilePath, string(data))
+		if showDiff {
+			if err != nil {
+				continue
+			}
+			if d := newFixDiff(fixDiffPath(res.FilePath), res.Source, data); d != nil {
+				attribution.annotate(d)
+				if _, err := d.WriteTo(diffOut); err != nil {
+					fmt.Fprintf(cmd.Stderr, "Error while writing the diff of %s: %v\n", res.FilePath, err)
+				}
+			}
 			continue
 		}
 		if fileRateLimited {
@@ -188,6 +257,121 @@ func (cmd *Command) runAutofix(results []*ValidateResult, isDryRun bool, baseDir
 	return rateLimited
 }
 
+// findingsOutput returns where findings are printed. -fix patch without -o
+// writes the patch to stdout, so findings go to stderr to keep it applicable.
+func (cmd *Command) findingsOutput(fixMode, patchPath string) io.Writer {
+	if fixMode == FileFixPatch && patchPath == "" {
+		return cmd.Stderr
+	}
+	return cmd.Stdout
+}
+
+// applyAutofix runs the fixers in the given -fix mode. With -fix patch the
+// diffs of all files are collected into one patch written to patchPath, or to
+// stdout when it is empty. It returns a non-zero exit status on failure.
+func (cmd *Command) applyAutofix(results []*ValidateResult, mode, patchPath, baseDir string, filter *fixRuleFilter) int {
+	var diffOut io.Writer = cmd.Stdout
+	var patch bytes.Buffer
+	if mode == FileFixPatch && patchPath != "" {
+		diffOut = &patch
+	}
+	if rateLimited := cmd.runAutofix(results, mode, baseDir, diffOut, filter); rateLimited {
+		fmt.Fprintln(cmd.Stderr,
+			"sisakulint: commit-sha autofix aborted because the GitHub API rate limit was exceeded. "+
+				"Re-run with GITHUB_TOKEN / GH_TOKEN / SISAKULINT_GITHUB_TOKEN set or with -github-token to complete the fix.")
+		return ExitStatusFailure
+	}
+	if diffOut == &patch {
+		if err := os.WriteFile(patchPath, patch.Bytes(), 0644); err != nil { //nolint:gosec // patch files are meant to be shared and applied
+			fmt.Fprintf(cmd.Stderr, "Error while writing the patch file: %v\n", err)
+			return ExitStatusFailure
+		}
+	}
+	return 0
+}
+
+// reportSkippedFixes prints how many fixes -fix-rule and -fix-exclude-rule
+// filtered out, per rule.
+func (cmd *Command) reportSkippedFixes(skipped map[string]int) {
+	if len(skipped) == 0 {
+		return
+	}
+	names := make([]string, 0, len(skipped))
+	total := 0
+	for name, n := range skipped {
+		names = append(names, name)
+		total += n
+	}
+	sort.Strings(names)
+	fmt.Fprintf(cmd.Stderr, "Skipped %d %s filtered by -fix-rule/-fix-exclude-rule:\n", total, pluralize(total, "fix", "fixes"))
+	for _, name := range names {
+		fmt.Fprintf(cmd.Stderr, "  %s: %d\n", name, skipped[name])
+	}
+}
+
+// fixRuleFilter selects the auto-fixers to run by rule name. Patterns are
+// matched with path.Match so that "code-injection-*" selects a rule family.
+type fixRuleFilter struct {
+	include []string
+	exclude []string
+}
+
+func newFixRuleFilter(include, exclude []string) (*fixRuleFilter, error) {
+	for _, p := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid rule name pattern %q: %w", p, err)
+		}
+	}
+	return &fixRuleFilter{include: include, exclude: exclude}, nil
+}
+
+// checkKnownRules returns an error for the first pattern that matches none of
+// names, so that a typo in -fix-rule does not silently select no fixer, in the
+// same way as applyOptInRules rejects unknown -enable-rule names.
+func (f *fixRuleFilter) checkKnownRules(names []string) error {
+	for _, flag := range []struct {
+		name     string
+		patterns []string
+	}{{"-fix-rule", f.include}, {"-fix-exclude-rule", f.exclude}} {
+		for _, p := range flag.patterns {
+			if !slices.ContainsFunc(names, func(n string) bool {
+				ok, _ := path.Match(p, n)
+				return ok
+			}) {
+				return fmt.Errorf("pattern %q passed to %s matches no rule", p, flag.name)
+			}
+		}
+	}
+	return nil
+}
+
+// builtinRuleNames returns the names of all built-in rules, including opt-in
+// ones.
+func builtinRuleNames() []string {
+	return ruleNamesOf(makeRules("", false, "", "", nil, nil, nil, nil, nil, false, false))
+}
+
+func matchRuleName(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether fixers of the rule should run. A nil filter allows
+// every rule.
+func (f *fixRuleFilter) allows(name string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.include) > 0 && !matchRuleName(f.include, name) {
+		return false
+	}
+	return !matchRuleName(f.exclude, name)
+}
+
 type ignorePatternFlags []string
 
 func (i *ignorePatternFlags) String() string {
@@ -208,6 +392,16 @@ func (e *enabledRuleFlags) Set(v string) error {
 	return nil
 }
 
+type fixRuleFlags []string
+
+func (f *fixRuleFlags) String() string {
+	return "option for selecting auto-fix rules"
+}
+func (f *fixRuleFlags) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
 type remoteTargetFlags []string
 
 func (t *remoteTargetFlags) String() string {
@@ -224,6 +418,19 @@ func (t *remoteTargetFlags) Set(v string) error {
 
 // todo: sisakulintのmain関数
 func (cmd *Command) Main(args []string) int {
+	if len(args) > 1 && args[1] == "lsp" {
+		return cmd.runLSP(args[1:])
+	}
+	if len(args) > 1 && args[1] == "advisory-db" {
+		return cmd.runAdvisoryDB(args[1:])
+	}
+	if len(args) > 1 && args[1] == "pin" {
+		return cmd.runPin(args[1:])
+	}
+	if len(args) > 1 && args[1] == "dump-ast" {
+		return cmd.runDumpAST(args[1:])
+	}
+
 	var showVersion bool
 	var linterOpts LinterOptions
 	var ignorePats ignorePatternFlags
@@ -232,34 +439,51 @@ func (cmd *Command) Main(args []string) int {
 	var generateBoilerplate bool
 	var generateActionList bool
 	var autoFixMode string
+	var fixOutputPath string
+	var fixRules fixRuleFlags
+	var fixExcludeRules fixRuleFlags
 	var remoteInput string
+	var remoteReportFormat string
 	var recursive bool
 	var maxDepth int
 	var parallelism int
 	var limit int
 	var githubTokenFlag string
+	var githubAPIURLFlag string
 	var pullRequest int
 	var expectedHeadSHA string
 	var remoteCheckoutDir string
 	var remoteTargets remoteTargetFlags
+	var baselineWritePath string
+	var minSeverity string
 
 	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	flags.SetOutput(cmd.Stderr)
 	flags.Var(&ignorePats, "ignore", "Regular expression matching to error messages you want to ignore. This flag is repeatable")
 	flags.Var(&enabledRules, "enable-rule",
 		"Enable an opt-in rule by name. Repeatable. "+
-			"Currently available opt-in rules: missing-timeout-minutes")
+			"Currently available opt-in rules: missing-timeout-minutes, shellcheck")
 	flags.BoolVar(&generateBoilerplate, "boilerplate", false, "Generate a costomized template file for GitHub Actions workflow")
 	flags.StringVar(&linterOpts.CustomErrorMessageFormat, "format", "", "Custom template to format error messages in Go template syntax.")
+	flags.BoolVar(&linterOpts.FormatFixes, "format-fixes", false, "Include the auto-fixes of findings in -format output which prints them, such as {{sarif .}}. Auto-fixers may call the GitHub API")
 	flags.StringVar(&linterOpts.ConfigurationFilePath, "config-file", "", "File path to config file")
+	flags.StringVar(&linterOpts.BaselineFilePath, "baseline", "", "Report only findings that are not recorded in this baseline file")
+	flags.StringVar(&minSeverity, "min-severity", "", "Report only findings at or above this severity. Available options: critical, high, medium, low, info")
+	flags.StringVar(&linterOpts.ShellcheckExecutable, "shellcheck", "", "Command name or file path of the shellcheck executable used by the shellcheck rule (enable it with -enable-rule shellcheck). Defaults to shellcheck in PATH")
+	flags.StringVar(&linterOpts.AdvisoryDBPath, "advisory-db", "", "Match known-vulnerable-actions against this local advisory database (written by 'sisakulint advisory-db', or OSV JSON) instead of the GitHub API")
+	flags.StringVar(&baselineWritePath, "baseline-write", "", "Write all current findings to this baseline file and exit successfully")
 	flags.BoolVar(&initConfig, "init", false, "Generate default config file at .github/sisakulint.yaml in current project")
 	flags.BoolVar(&generateActionList, "generate-action-list", false, "Generate action list configuration from existing workflow files")
 	flags.BoolVar(&linterOpts.IsVerboseOutputEnabled, "verbose", false, "Enable verbose output")
 	flags.BoolVar(&linterOpts.IsDebugOutputEnabled, "debug", false, "Enable debug output (for development)")
 	flags.BoolVar(&showVersion, "version", false, "Show version and how this binary was installed")
 	flags.StringVar(&linterOpts.StdinInputFileName, "stdin-filename", "", "File name when reading input from stdin")
-	flags.StringVar(&autoFixMode, "fix", "off", "Enable auto-fix mode. Available options: off, on, dry-run")
+	flags.StringVar(&autoFixMode, "fix", "off", "Enable auto-fix mode. Available options: off, on, dry-run, patch")
+	flags.StringVar(&fixOutputPath, "o", "", "Write the combined patch of -fix patch, or the -remote-report, to this file instead of stdout")
+	flags.Var(&fixRules, "fix-rule", "Run only the auto-fixers of this rule name or glob pattern. This flag is repeatable")
+	flags.Var(&fixExcludeRules, "fix-exclude-rule", "Do not run the auto-fixers of this rule name or glob pattern. This flag is repeatable")
 	flags.StringVar(&remoteInput, "remote", "", "Remote repository to scan (owner/repo, URL, or search query like 'org:kubernetes')")
+	flags.StringVar(&remoteReportFormat, "remote-report", "", "Write one consolidated report of all scanned repositories. Available options: json, sarif, markdown (-remote only)")
 	flags.IntVar(&pullRequest, "pr", 0, "Pull request number to scan at its immutable head (-remote only)")
 	flags.StringVar(&expectedHeadSHA, "expected-head-sha", "", "Fail if the pull request head no longer matches this SHA (-remote -pr only)")
 	flags.StringVar(&remoteCheckoutDir, "remote-checkout-dir", "", "Extract the pull request snapshot into this new directory instead of a temporary directory (-remote -pr only)")
@@ -272,6 +496,9 @@ func (cmd *Command) Main(args []string) int {
 		"GitHub API token used by rules that call the GitHub API, including commit-sha autofix and known-vulnerable-actions. "+
 			"Falls back to SISAKULINT_GITHUB_TOKEN, GITHUB_TOKEN, then GH_TOKEN. "+
 			"Without a token, unauthenticated requests are limited to 60 req/h and may interrupt GitHub API checks")
+	flags.StringVar(&githubAPIURLFlag, "github-api-url", "",
+		"REST API URL of a GitHub Enterprise Server (e.g. https://github.example.com/api/v3) used by -remote and rules that call the GitHub API. "+
+			"Falls back to GITHUB_API_URL. Defaults to github.com")
 
 	flags.Usage = func() {
 		printingUsageHeader(cmd.Stderr)
@@ -285,10 +512,58 @@ func (cmd *Command) Main(args []string) int {
 		return ExitStatusInvalidCommandOption
 	}
 
-	if autoFixMode != "off" && autoFixMode != "on" && autoFixMode != FileFixDryRun {
+	if autoFixMode != "off" && autoFixMode != "on" && autoFixMode != FileFixDryRun && autoFixMode != FileFixPatch {
 		fmt.Fprintf(cmd.Stderr, "Invalid value for -fix: %s\n", autoFixMode)
 		return ExitStatusInvalidCommandOption
 	}
+	switch remoteReportFormat {
+	case "", RemoteReportJSON, RemoteReportSARIF, RemoteReportMarkdown:
+	default:
+		fmt.Fprintf(cmd.Stderr, "Invalid value for -remote-report: %s\n", remoteReportFormat)
+		return ExitStatusInvalidCommandOption
+	}
+	if remoteReportFormat != "" && (remoteInput == "" || pullRequest != 0) {
+		fmt.Fprintln(cmd.Stderr, "-remote-report requires -remote and cannot be combined with -pr")
+		return ExitStatusInvalidCommandOption
+	}
+	if fixOutputPath != "" && autoFixMode != FileFixPatch && remoteReportFormat == "" {
+		fmt.Fprintln(cmd.Stderr, "-o can only be used with -fix patch or -remote-report")
+		return ExitStatusInvalidCommandOption
+	}
+	if (len(fixRules) > 0 || len(fixExcludeRules) > 0) && autoFixMode == "off" {
+		fmt.Fprintln(cmd.Stderr, "-fix-rule and -fix-exclude-rule require -fix")
+		return ExitStatusInvalidCommandOption
+	}
+	fixFilter, err := newFixRuleFilter(fixRules, fixExcludeRules)
+	if err == nil {
+		err = fixFilter.checkKnownRules(builtinRuleNames())
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "Invalid value for -fix-rule or -fix-exclude-rule: %v\n", err)
+		return ExitStatusInvalidCommandOption
+	}
+	if minSeverity != "" {
+		s, err := ParseSeverity(minSeverity)
+		if err != nil {
+			fmt.Fprintf(cmd.Stderr, "Invalid value for -min-severity: %v\n", err)
+			return ExitStatusInvalidCommandOption
+		}
+		linterOpts.MinSeverity = s
+	}
+	apiURL, err := ResolveGitHubAPIURL(githubAPIURLFlag, nil)
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "Invalid value for -github-api-url: %v\n", err)
+		return ExitStatusInvalidCommandOption
+	}
+	linterOpts.GitHubAPIURL = apiURL
+	if baselineWritePath != "" && linterOpts.BaselineFilePath != "" {
+		fmt.Fprintln(cmd.Stderr, "-baseline and -baseline-write cannot be used together")
+		return ExitStatusInvalidCommandOption
+	}
+	if baselineWritePath != "" && remoteInput != "" {
+		fmt.Fprintln(cmd.Stderr, "-baseline-write cannot be combined with -remote")
+		return ExitStatusInvalidCommandOption
+	}
 	if pullRequest < 0 {
 		fmt.Fprintln(cmd.Stderr, "Invalid value for -pr: must not be negative")
 		return ExitStatusInvalidCommandOption
@@ -298,14 +573,14 @@ func (cmd *Command) Main(args []string) int {
 		return ExitStatusInvalidCommandOption
 	}
 	if pullRequest == 0 && remoteInput != "" && (expectedHeadSHA != "" || remoteCheckoutDir != "" || len(remoteTargets) > 0) {
-		parsed, err := remote.ParseInput(remoteInput)
+		parsed, err := remote.ParseInputForAPIURL(remoteInput, apiURL)
 		if err != nil || parsed.PullNumber == 0 {
 			fmt.Fprintln(cmd.Stderr, "-expected-head-sha, -remote-checkout-dir, and -remote-target require a pull request URL or -pr")
 			return ExitStatusInvalidCommandOption
 		}
 	}
 	if remoteInput != "" && autoFixMode == "on" && remoteCheckoutDir == "" {
-		parsed, _ := remote.ParseInput(remoteInput)
+		parsed, _ := remote.ParseInputForAPIURL(remoteInput, apiURL)
 		if pullRequest > 0 || (parsed != nil && parsed.PullNumber > 0) {
 			fmt.Fprintln(cmd.Stderr, "-remote pull request scans require -remote-checkout-dir when -fix on is used")
 			return ExitStatusInvalidCommandOption
@@ -327,7 +602,7 @@ func (cmd *Command) Main(args []string) int {
 
 	token, source := ResolveGitHubToken(githubTokenFlag, nil)
 	linterOpts.GitHubToken = token
-	enableAutofix := autoFixMode == "on" || autoFixMode == FileFixDryRun
+	enableAutofix := autoFixMode != "off"
 
 	if generateActionList {
 		if err := GenerateActionListConfig("."); err != nil {
@@ -346,7 +621,7 @@ func (cmd *Command) Main(args []string) int {
 	}
 
 	if remoteInput != "" {
-		parsed, err := remote.ParseInput(remoteInput)
+		parsed, err := remote.ParseInputForAPIURL(remoteInput, apiURL)
 		if err != nil {
 			fmt.Fprintf(cmd.Stderr, "Invalid remote input: %v\n", err)
 			return ExitStatusInvalidCommandOption
@@ -366,25 +641,41 @@ func (cmd *Command) Main(args []string) int {
 				remoteCheckoutDir,
 				remoteTargets,
 				autoFixMode,
+				fixOutputPath,
+				fixFilter,
 				&linterOpts,
 			)
 		}
-		return cmd.runRemoteScan(remoteInput, &linterOpts, &remote.ScannerOptions{
-			Parallelism: parallelism,
-			Recursive:   recursive,
-			MaxDepth:    maxDepth,
-			Limit:       limit,
-			Verbose:     linterOpts.IsVerboseOutputEnabled,
-			Output:      cmd.Stderr,
-			GitHubToken: linterOpts.GitHubToken,
+		if remoteReportFormat != "" && parsed.PullNumber > 0 {
+			fmt.Fprintln(cmd.Stderr, "-remote-report cannot be used with a pull request URL")
+			return ExitStatusInvalidCommandOption
+		}
+		return cmd.runRemoteScan(remoteInput, remoteReportFormat, fixOutputPath, &linterOpts, &remote.ScannerOptions{
+			Parallelism:  parallelism,
+			Recursive:    recursive,
+			MaxDepth:     maxDepth,
+			Limit:        limit,
+			Verbose:      linterOpts.IsVerboseOutputEnabled,
+			Output:       cmd.Stderr,
+			GitHubToken:  linterOpts.GitHubToken,
+			GitHubAPIURL: linterOpts.GitHubAPIURL,
 		})
 	}
 
-	errs, err := cmd.runLint(flags.Args(), &linterOpts, initConfig, generateBoilerplate)
+	errs, err := cmd.runLint(flags.Args(), cmd.findingsOutput(autoFixMode, fixOutputPath), &linterOpts, initConfig, generateBoilerplate)
 	if err != nil {
 		fmt.Fprintln(cmd.Stderr, err.Error())
 		return ExitStatusFailure
 	}
+	if baselineWritePath != "" {
+		baseline := NewBaseline(errs)
+		if err := baseline.WriteFile(baselineWritePath); err != nil {
+			fmt.Fprintln(cmd.Stderr, err.Error())
+			return ExitStatusFailure
+		}
+		fmt.Fprintf(cmd.Stderr, "wrote %d %s to baseline file %q\n", len(baseline.Findings), pluralize(len(baseline.Findings), "finding", "findings"), baselineWritePath)
+		return ExitStatusSuccessNoProblem
+	}
 	hasErrors := false
 	for _, r := range errs {
 		if len(r.Errors) > 0 {
@@ -394,11 +685,8 @@ func (cmd *Command) Main(args []string) int {
 	}
 	if hasErrors {
 		if enableAutofix {
-			if rateLimited := cmd.runAutofix(errs, autoFixMode == FileFixDryRun, ""); rateLimited {
-				fmt.Fprintln(cmd.Stderr,
-					"sisakulint: commit-sha autofix aborted because the GitHub API rate limit was exceeded. "+
-						"Re-run with GITHUB_TOKEN / GH_TOKEN / SISAKULINT_GITHUB_TOKEN set or with -github-token to complete the fix.")
-				return ExitStatusFailure
+			if status := cmd.applyAutofix(errs, autoFixMode, fixOutputPath, "", fixFilter); status != 0 {
+				return status
 			}
 		}
 		return ExitStatusSuccessProblemFound
@@ -408,20 +696,42 @@ func (cmd *Command) Main(args []string) int {
 }
 
 // runRemoteScan はリモートリポジトリをスキャンする
-func (cmd *Command) runRemoteScan(input string, linterOpts *LinterOptions, scannerOpts *remote.ScannerOptions) int {
+// reportFormat が指定された場合は全リポジトリの結果を1つのレポートにまとめて reportPath (空なら stdout) に書き出す
+func (cmd *Command) runRemoteScan(input string, reportFormat string, reportPath string, linterOpts *LinterOptions, scannerOpts *remote.ScannerOptions) int {
 	linterOpts.IsRemote = true
-	linter, err := NewLinter(cmd.Stdout, linterOpts)
+	out := cmd.Stdout
+	if reportFormat != "" {
+		// Findings go to the report only.
+		out = io.Discard
+	}
+	linter, err := NewLinter(out, linterOpts)
 	if err != nil {
 		fmt.Fprintf(cmd.Stderr, "Error initializing linter: %v\n", err)
 		return ExitStatusFailure
 	}
 
-	scannerOpts.LintFunc = func(filepath string, content []byte) (bool, error) {
-		result, err := linter.Lint(filepath, content, nil)
-		if err != nil {
-			return false, err
+	sources := &remoteReportSources{}
+	if reportFormat != "" {
+		scannerOpts.ReportFunc = func(filepath string, content []byte) ([]interface{}, error) {
+			result, err := linter.Lint(filepath, content, nil)
+			if err != nil {
+				return nil, err
+			}
+			sources.store(filepath, content)
+			findings := make([]interface{}, 0, len(result.Errors))
+			for _, e := range result.Errors {
+				findings = append(findings, e)
+			}
+			return findings, nil
+		}
+	} else {
+		scannerOpts.LintFunc = func(filepath string, content []byte) (bool, error) {
+			result, err := linter.Lint(filepath, content, nil)
+			if err != nil {
+				return false, err
+			}
+			return len(result.Errors) > 0, nil
 		}
-		return len(result.Errors) > 0, nil
 	}
 
 	scanner, err := remote.NewScanner(scannerOpts)
@@ -448,6 +758,18 @@ func (cmd *Command) runRemoteScan(input string, linterOpts *LinterOptions, scann
 		}
 	}
 
+	if reportFormat != "" {
+		report := newRemoteReport(results, sources, remote.ServerURL(linterOpts.GitHubAPIURL))
+		if err := cmd.writeRemoteReport(report, reportFormat, reportPath); err != nil {
+			fmt.Fprintf(cmd.Stderr, "Error writing remote report: %v\n", err)
+			return ExitStatusFailure
+		}
+		if report.findings() > 0 {
+			return ExitStatusSuccessProblemFound
+		}
+		return ExitStatusSuccessNoProblem
+	}
+
 	if hasErrors {
 		return ExitStatusSuccessProblemFound
 	}
@@ -456,6 +778,22 @@ func (cmd *Command) runRemoteScan(input string, linterOpts *LinterOptions, scann
 	return ExitStatusSuccessNoProblem
 }
 
+// writeRemoteReport writes the report to path, or to stdout when path is empty.
+func (cmd *Command) writeRemoteReport(report *remoteReport, format, path string) error {
+	if path == "" {
+		return report.write(cmd.Stdout, format)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // runRemotePullRequestScan materializes the exact PR head as a local project,
 // analyses every workflow for repository and cross-file context, and reports
 // (or fixes) only workflows changed by the pull request.
@@ -466,6 +804,8 @@ func (cmd *Command) runRemotePullRequestScan(
 	checkoutDir string,
 	requestedTargets []string,
 	autoFixMode string,
+	fixOutputPath string,
+	fixFilter *fixRuleFilter,
 	linterOpts *LinterOptions,
 ) int {
 	if input.Type == remote.InputTypeSearchQuery || input.Owner == "" || input.Repo == "" {
@@ -482,9 +822,9 @@ func (cmd *Command) runRemotePullRequestScan(
 	if linterOpts.GitHubToken == "" {
 		// Keep direct CLI scans compatible with gh auth and git credential
 		// fallback when no flag/environment token was supplied.
-		fetcher, err = remote.NewFetcher(1)
+		fetcher, err = remote.NewFetcherWithAPIURL(1, linterOpts.GitHubAPIURL)
 	} else {
-		fetcher, err = remote.NewFetcherWithToken(1, linterOpts.GitHubToken)
+		fetcher, err = remote.NewFetcherWithTokenAndAPIURL(1, linterOpts.GitHubToken, linterOpts.GitHubAPIURL)
 	}
 	if err != nil {
 		fmt.Fprintf(cmd.Stderr, "Error initializing remote fetcher: %v\n", err)
@@ -530,7 +870,7 @@ func (cmd *Command) runRemotePullRequestScan(
 	linterOpts.CurrentWorkingDirectoryPath = snapshot.Root
 	linterOpts.ReportFilePaths = targets
 	linterOpts.DisableRepositoryFileAutoFixers = true
-	linter, err := NewLinter(cmd.Stdout, linterOpts)
+	linter, err := NewLinter(cmd.findingsOutput(autoFixMode, fixOutputPath), linterOpts)
 	if err != nil {
 		fmt.Fprintf(cmd.Stderr, "Error initializing linter: %v\n", err)
 		return ExitStatusFailure
@@ -558,12 +898,9 @@ func (cmd *Command) runRemotePullRequestScan(
 		return ExitStatusSuccessNoProblem
 	}
 
-	if autoFixMode == "on" || autoFixMode == FileFixDryRun {
-		if rateLimited := cmd.runAutofix(targetResults, autoFixMode == FileFixDryRun, snapshot.Root); rateLimited {
-			fmt.Fprintln(cmd.Stderr,
-				"sisakulint: commit-sha autofix aborted because the GitHub API rate limit was exceeded. "+
-					"Re-run with GITHUB_TOKEN / GH_TOKEN / SISAKULINT_GITHUB_TOKEN set or with -github-token to complete the fix.")
-			return ExitStatusFailure
+	if autoFixMode != "off" {
+		if status := cmd.applyAutofix(targetResults, autoFixMode, fixOutputPath, snapshot.Root, fixFilter); status != 0 {
+			return status
 		}
 	}
 	return ExitStatusSuccessProblemFound
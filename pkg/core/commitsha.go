@@ -2,10 +2,13 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/go-github/v68/github"
 	"github.com/sisaku-security/sisakulint/pkg/ast"
@@ -14,12 +17,50 @@ import (
 type CommitSha struct {
 	BaseRule
 	githubToken string
+	// gitHubAPIURL is the API of a GitHub Enterprise Server, or empty for
+	// GitHub.com.
+	gitHubAPIURL string
 	// clientMu guards lazy initialization of client. Using sync.Mutex + nil
 	// check (rather than sync.Once) keeps the test path simple: tests can
 	// assign a fake client directly and githubClient() will skip the lazy
 	// constructor instead of depending on sync.Once internals.
 	clientMu sync.Mutex
 	client   *github.Client
+	// clientErr is the error of creating client, such as an invalid
+	// gitHubAPIURL.
+	clientErr error
+	// advisoryDB, when set, resolves the tags of version comments offline
+	// before falling back to the GitHub API.
+	advisoryDB *AdvisoryDB
+	// lockfile, when set, pins actions to the commits recorded by
+	// "sisakulint pin" instead of resolving their refs with the API.
+	lockfile *ActionLockfile
+}
+
+// versionCommentTagSHAs caches the commit SHA of "owner/repo@tag" resolved
+// while verifying version comments, shared by the rule instances of a run
+// since the same pinned action is usually referenced from many workflows.
+// A missing tag is cached as "". versionCommentRepoTags caches all the tags
+// of "owner/repo", listed to check comments naming a moving major or minor
+// tag. commitShaRateLimitReported reports the rate limit only once per run.
+var (
+	versionCommentTagSHAs      sync.Map
+	versionCommentRepoTags     sync.Map
+	commitShaRateLimitReported atomic.Bool
+)
+
+// resetCommitShaRunState clears the package-global state of CommitSha at
+// the start of each public Lint entry, like resetKnownVulnerableActionsRunState.
+func resetCommitShaRunState() {
+	commitShaRateLimitReported.Store(false)
+	versionCommentTagSHAs.Range(func(key, _ any) bool {
+		versionCommentTagSHAs.Delete(key)
+		return true
+	})
+	versionCommentRepoTags.Range(func(key, _ any) bool {
+		versionCommentRepoTags.Delete(key)
+		return true
+	})
 }
 
 func CommitShaRule(token string) *CommitSha {
@@ -27,6 +68,7 @@ func CommitShaRule(token string) *CommitSha {
 		BaseRule: BaseRule{
 			RuleName: "commit-sha",
 			RuleDesc: "Warn if the action ref is not a full length commit SHA and not an official GitHub Action.",
+			severity: SeverityHigh,
 		},
 		githubToken: token,
 	}
@@ -53,32 +95,219 @@ func (rule *CommitSha) VisitStep(step *ast.Step) error {
 				"the action ref in 'uses' for step '%s' should be a full length commit SHA for immutability and security. See https://sisaku-security.github.io/lint/docs/rules/commitsharule/",
 				step.String())
 			rule.AddAutoFixer(NewStepFixer(step, rule)) // add autofix for this CommitSha rule
+		} else {
+			rule.checkVersionComment(step, action)
 		}
 	}
 	return nil
 }
 
+// versionCommentPattern matches the version in a comment like "# v4.2.1" or
+// "# 1.2" after a pinned SHA.
+var versionCommentPattern = regexp.MustCompile(`^v?\d+(\.\d+){0,2}([-+][0-9A-Za-z.-]+)?$`)
+
+// parseVersionComment returns the tag of a "# vX.Y.Z" comment, or "" when the
+// comment does not start with a version.
+func parseVersionComment(comment string) string {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(comment), "#"))
+	if len(fields) == 0 {
+		return ""
+	}
+	tag := strings.TrimPrefix(fields[0], "tag=")
+	if !versionCommentPattern.MatchString(tag) {
+		return ""
+	}
+	return tag
+}
+
+// checkVersionComment verifies that the version comment of an action pinned
+// to a SHA names a tag pointing at that very commit. A stale or forged comment
+// makes reviewers believe a different release runs.
+func (rule *CommitSha) checkVersionComment(step *ast.Step, action *ast.ExecAction) {
+	if action.Uses.BaseNode == nil || isDockerAction(action.Uses.Value) {
+		return
+	}
+	tag := parseVersionComment(action.Uses.BaseNode.LineComment)
+	if tag == "" {
+		return
+	}
+	owner, repo, sha, ok := parseActionRef(action.Uses.Value)
+	if !ok {
+		return
+	}
+	tagSHA, ok := rule.resolveTagSHA(owner, repo, tag)
+	if !ok {
+		return
+	}
+	if tagSHA == "" && !strings.HasPrefix(tag, "v") {
+		// "# 1.2.3" is commonly written for the tag "v1.2.3".
+		vSHA, ok := rule.resolveTagSHA(owner, repo, "v"+tag)
+		if !ok {
+			return
+		}
+		if vSHA != "" {
+			tag, tagSHA = "v"+tag, vSHA
+		}
+	}
+	if strings.EqualFold(tagSHA, sha) {
+		return
+	}
+	if isMovingVersionTag(tag) {
+		// Major and minor tags move to each new release, so "# v4" on a
+		// commit of an older v4 release is still accurate.
+		refined, ok := rule.versionRefinedAt(owner, repo, tag, sha)
+		if !ok || refined {
+			return
+		}
+	}
+	if tagSHA == "" {
+		rule.Errorf(step.Pos,
+			"the version comment '# %s' of step '%s' names a tag which does not exist in %s/%s. "+
+				"Update the comment to the release of commit %s. See https://sisaku-security.github.io/lint/docs/rules/commitsharule/",
+			tag, step.String(), owner, repo, shortSHA(sha))
+		return
+	}
+	rule.Errorf(step.Pos,
+		"the version comment '# %s' of step '%s' does not match the pinned commit: %s/%s@%s is commit %s, not %s. "+
+			"Stale or forged version comments hide which release actually runs. See https://sisaku-security.github.io/lint/docs/rules/commitsharule/",
+		tag, step.String(), owner, repo, tag, shortSHA(tagSHA), shortSHA(sha))
+}
+
+// resolveTagSHA returns the commit SHA the tag points at, "" when the tag does
+// not exist, or ok=false when it could not be resolved.
+func (rule *CommitSha) resolveTagSHA(owner, repo, tag string) (sha string, ok bool) {
+	if rule.advisoryDB != nil {
+		if sha, ok := rule.advisoryDB.tagSHA(owner+"/"+repo, tag); ok {
+			return sha, true
+		}
+	}
+	key := strings.ToLower(owner+"/"+repo) + "@" + tag
+	if v, ok := versionCommentTagSHAs.Load(key); ok {
+		return v.(string), true
+	}
+	if commitShaRateLimitReported.Load() {
+		return "", false
+	}
+	gh, err := rule.githubClient()
+	if err != nil {
+		rule.Debug("could not resolve tag %s of %s/%s: %v", tag, owner, repo, err)
+		return "", false
+	}
+	sha, _, err = gh.Repositories.GetCommitSHA1(context.Background(), owner, repo, "tags/"+tag, "")
+	if err != nil {
+		var errResp *github.ErrorResponse
+		switch {
+		case errors.As(err, &errResp) && errResp.Response != nil &&
+			(errResp.Response.StatusCode == http.StatusNotFound || errResp.Response.StatusCode == http.StatusUnprocessableEntity):
+			sha = ""
+		case IsGitHubRateLimitError(err):
+			if commitShaRateLimitReported.CompareAndSwap(false, true) {
+				rule.Debug("GitHub API rate limit exceeded; skipping version comment checks for the rest of this run")
+			}
+			return "", false
+		default:
+			rule.Debug("could not resolve tag %s of %s/%s: %v", tag, owner, repo, err)
+			return "", false
+		}
+	}
+	versionCommentTagSHAs.Store(key, sha)
+	return sha, true
+}
+
+// isMovingVersionTag reports whether tag is a major or minor version like
+// "v4" or "4.2", which is moved to each new release of that version.
+func isMovingVersionTag(tag string) bool {
+	tag = "v" + strings.TrimPrefix(tag, "v")
+	return shortTagPattern.MatchString(tag) || minorTagPattern.MatchString(tag)
+}
+
+// versionRefinedAt reports whether tag or any tag refining it, such as
+// "v4.2.1" for "v4", points at sha. ok is false when the tags could not be
+// listed.
+func (rule *CommitSha) versionRefinedAt(owner, repo, tag, sha string) (refined bool, ok bool) {
+	tags, ok := rule.repositoryTags(owner, repo)
+	if !ok {
+		return false, false
+	}
+	for name, tagSHA := range tags {
+		if (name == tag || strings.HasPrefix(name, tag+".")) && strings.EqualFold(tagSHA, sha) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// repositoryTags returns the tags of owner/repo and the commit SHAs they point
+// at, from the advisory database when it records them, or from the API.
+func (rule *CommitSha) repositoryTags(owner, repo string) (map[string]string, bool) {
+	pkg := strings.ToLower(owner + "/" + repo)
+	if rule.advisoryDB != nil {
+		if tags, ok := rule.advisoryDB.Tags[pkg]; ok {
+			return tags, true
+		}
+	}
+	if v, ok := versionCommentRepoTags.Load(pkg); ok {
+		return v.(map[string]string), true
+	}
+	if commitShaRateLimitReported.Load() {
+		return nil, false
+	}
+	gh, err := rule.githubClient()
+	if err != nil {
+		rule.Debug("could not list tags of %s/%s: %v", owner, repo, err)
+		return nil, false
+	}
+	tags, err := listActionTags(context.Background(), gh, owner, repo)
+	if err != nil {
+		if IsGitHubRateLimitError(err) {
+			if commitShaRateLimitReported.CompareAndSwap(false, true) {
+				rule.Debug("GitHub API rate limit exceeded; skipping version comment checks for the rest of this run")
+			}
+		} else {
+			rule.Debug("could not list tags of %s/%s: %v", owner, repo, err)
+		}
+		return nil, false
+	}
+	versionCommentRepoTags.Store(pkg, tags)
+	return tags, true
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
 // from https://github.com/suzuki-shunsuke/pinact/blob/532aa7ba57db6c11937831f993b51640bbda94ac/pkg/controller/run/parse_line.go#L18-L19
 var (
 	semverPattern   = regexp.MustCompile(`^v?\d+\.\d+\.\d+[^ ]*$`)
 	shortTagPattern = regexp.MustCompile(`^v\d+$`)
+	minorTagPattern = regexp.MustCompile(`^v\d+\.\d+$`)
 )
 
+// getLongVersion returns the most specific tag pointing at sha which
+// refines expectedTag, e.g. "v4.2.1" for "v4", or "" when there is none.
 func getLongVersion(cl *github.Client, owner, repo, sha string, expectedTag string) (string, error) {
 	opts := &github.ListOptions{
 		PerPage: 100,
 	}
+	best := ""
 	for i := 0; i < 10; i++ {
 		tags, resp, err := cl.Repositories.ListTags(context.Background(), owner, repo, opts)
 		if err != nil {
 			return "", fmt.Errorf("failed to list tags: %w", err)
 		}
 		for _, tag := range tags {
-			if tag.GetCommit().GetSHA() == sha {
-				tagName := tag.GetName()
-				if strings.HasPrefix(tagName, expectedTag) {
-					return tagName, nil
-				}
+			if tag.GetCommit().GetSHA() != sha {
+				continue
+			}
+			tagName := tag.GetName()
+			if tagName != expectedTag && !strings.HasPrefix(tagName, expectedTag+".") {
+				continue
+			}
+			if best == "" || moreSpecificTag(tagName, best) {
+				best = tagName
 			}
 		}
 		if resp.NextPage == 0 {
@@ -86,23 +315,38 @@ func getLongVersion(cl *github.Client, owner, repo, sha string, expectedTag stri
 		}
 		opts.Page = resp.NextPage
 	}
-	return "", nil
+	return best, nil
 }
 
-func (rule *CommitSha) githubClient() *github.Client {
+// moreSpecificTag reports whether tag a is more specific than b: a full
+// semver tag wins, then the tag with more version components.
+func moreSpecificTag(a, b string) bool {
+	if sa, sb := semverPattern.MatchString(a), semverPattern.MatchString(b); sa != sb {
+		return sa
+	}
+	if ca, cb := strings.Count(a, "."), strings.Count(b, "."); ca != cb {
+		return ca > cb
+	}
+	return a < b
+}
+
+func (rule *CommitSha) githubClient() (*github.Client, error) {
 	rule.clientMu.Lock()
 	defer rule.clientMu.Unlock()
-	if rule.client == nil {
-		rule.client = NewGitHubClient(context.Background(), rule.githubToken)
+	if rule.client == nil && rule.clientErr == nil {
+		rule.client, rule.clientErr = NewGitHubClientWithAPIURL(context.Background(), rule.githubToken, rule.gitHubAPIURL)
 	}
-	return rule.client
+	return rule.client, rule.clientErr
 }
 
 func (rule *CommitSha) FixStep(step *ast.Step) error {
 	// at here, we can assume that the action ref is not a full length commit SHA
 	action := step.Exec.(*ast.ExecAction)
 	usesValue := action.Uses.Value
-	gh := rule.githubClient()
+	gh, err := rule.githubClient()
+	if err != nil {
+		return FormattedError(step.Pos, rule.RuleName, "%s", err.Error())
+	}
 	splitTag := strings.Split(usesValue, "@")
 	if len(splitTag) != 2 {
 		// Create a LintingError with position information
@@ -116,19 +360,27 @@ func (rule *CommitSha) FixStep(step *ast.Step) error {
 		return lintErr
 	}
 	tag := splitTag[1]
+	if lock := rule.lockfile.lookup(ownerRepo[0], ownerRepo[1], tag); lock != nil {
+		action.Uses.BaseNode.Value = splitTag[0] + "@" + lock.SHA
+		action.Uses.BaseNode.LineComment = lock.Version
+		return nil
+	}
 	isSemver := semverPattern.MatchString(splitTag[1])
-	isShortTag := shortTagPattern.MatchString(splitTag[1])
-	//tagComment := action.Uses.BaseNode.LineComment
+	isPartialVersion := shortTagPattern.MatchString(splitTag[1]) || minorTagPattern.MatchString(splitTag[1])
 	sha, _, err := gh.Repositories.GetCommitSHA1(context.TODO(), ownerRepo[0], ownerRepo[1], tag, "")
 	if err != nil {
 		return rule.wrapAPIError(step, "failed to get commit SHA1", err)
 	}
-	if !isSemver && isShortTag {
+	if !isSemver && isPartialVersion {
+		// Keep the human-readable release next to the SHA: "@v4" becomes
+		// "@<sha> # v4.2.1".
 		longVersion, err := getLongVersion(gh, ownerRepo[0], ownerRepo[1], sha, splitTag[1])
 		if err != nil {
 			return rule.wrapAPIError(step, "failed to get long version", err)
 		}
-		tag = longVersion
+		if longVersion != "" {
+			tag = longVersion
+		}
 	}
 	action.Uses.BaseNode.Value = splitTag[0] + "@" + sha
 	action.Uses.BaseNode.LineComment = tag
@@ -696,3 +696,238 @@ func stringContains(s, substr string) bool {
 			return false
 		}())
 }
+
+// newCommitShaTestServer serves the commit SHA of tags/refs and the tag list
+// of a repository like the GitHub REST API.
+func newCommitShaTestServer(t *testing.T, refs map[string]string, tags string) *github.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tags") {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(tags))
+			return
+		}
+		if _, ref, ok := strings.Cut(r.URL.Path, "/commits/"); ok {
+			if sha, ok := refs[ref]; ok {
+				_, _ = w.Write([]byte(sha))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	t.Cleanup(srv.Close)
+	client := github.NewClient(nil)
+	baseURL, _ := url.Parse(srv.URL + "/")
+	client.BaseURL = baseURL
+	return client
+}
+
+func TestCommitSha_FixStep_VersionComment(t *testing.T) {
+	t.Parallel()
+
+	const sha = "1111111111111111111111111111111111111111"
+	tags := `[
+  {"name": "v4.10.0", "commit": {"sha": "2222222222222222222222222222222222222222"}},
+  {"name": "v4.2.1", "commit": {"sha": "` + sha + `"}},
+  {"name": "v4.2", "commit": {"sha": "` + sha + `"}},
+  {"name": "v4", "commit": {"sha": "` + sha + `"}},
+  {"name": "v40.0.0", "commit": {"sha": "` + sha + `"}}
+]`
+	tests := []struct {
+		ref, want string
+	}{
+		{ref: "v4", want: "v4.2.1"},
+		{ref: "v4.2", want: "v4.2.1"},
+		{ref: "v4.2.1", want: "v4.2.1"},
+		{ref: "main", want: "main"},
+		{ref: "v5", want: "v5"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			t.Parallel()
+			rule := CommitShaRule("")
+			rule.client = newCommitShaTestServer(t, map[string]string{tc.ref: sha}, tags)
+			uses := &yaml.Node{Kind: yaml.ScalarNode, Value: "actions/checkout@" + tc.ref}
+			step := &ast.Step{
+				Exec: &ast.ExecAction{Uses: &ast.String{Value: uses.Value, BaseNode: uses}},
+				Pos:  &ast.Position{Line: 1, Col: 1},
+			}
+			if err := rule.FixStep(step); err != nil {
+				t.Fatal(err)
+			}
+			out, err := yaml.Marshal(uses)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "actions/checkout@" + sha + " # " + tc.want + "\n"
+			if string(out) != want {
+				t.Errorf("fixed to %q, want %q", out, want)
+			}
+		})
+	}
+}
+
+func TestParseVersionComment(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"# v4.2.1":          "v4.2.1",
+		"#v4":               "v4",
+		"# 1.2.3 (latest)":  "1.2.3",
+		"# tag=v2.0.0-rc.1": "v2.0.0-rc.1",
+		"# main":            "",
+		"# pinned by bot":   "",
+		"":                  "",
+	}
+	for comment, want := range tests {
+		if got := parseVersionComment(comment); got != want {
+			t.Errorf("parseVersionComment(%q) = %q, want %q", comment, got, want)
+		}
+	}
+}
+
+func TestCommitSha_VersionCommentMismatch(t *testing.T) {
+	resetCommitShaRunState()
+	t.Cleanup(resetCommitShaRunState)
+
+	const (
+		pinned = "1111111111111111111111111111111111111111"
+		other  = "2222222222222222222222222222222222222222"
+	)
+	client := newCommitShaTestServer(t, map[string]string{
+		"tags/v4.2.1": pinned,
+		"tags/v4.2.0": other,
+	}, `[]`)
+
+	tests := []struct {
+		name    string
+		uses    string
+		comment string
+		want    string
+	}{
+		{name: "matching comment", uses: "vc-owner/action@" + pinned, comment: "# v4.2.1"},
+		{name: "stale comment", uses: "vc-owner/action@" + pinned, comment: "# v4.2.0", want: "does not match the pinned commit"},
+		{name: "forged comment", uses: "vc-owner/action@" + pinned, comment: "# v9.9.9", want: "does not exist"},
+		{name: "not a version", uses: "vc-owner/action@" + pinned, comment: "# pinned for reproducibility"},
+		{name: "no comment", uses: "vc-owner/action@" + pinned},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := CommitShaRule("")
+			rule.client = client
+			step := &ast.Step{
+				Exec: &ast.ExecAction{Uses: &ast.String{
+					Value:    tc.uses,
+					BaseNode: &yaml.Node{Kind: yaml.ScalarNode, Value: tc.uses, LineComment: tc.comment},
+				}},
+				Pos: &ast.Position{Line: 3, Col: 9},
+			}
+			if err := rule.VisitStep(step); err != nil {
+				t.Fatal(err)
+			}
+			errs := rule.Errors()
+			if tc.want == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Description, tc.want) {
+				t.Errorf("want one error containing %q, got %v", tc.want, errs)
+			}
+			if len(rule.AutoFixers()) != 0 {
+				t.Error("a pinned action should not get the pinning auto-fixer")
+			}
+		})
+	}
+}
+
+func TestCommitSha_VersionCommentMovingAndUnprefixedTags(t *testing.T) {
+	resetCommitShaRunState()
+	t.Cleanup(resetCommitShaRunState)
+
+	const (
+		pinned = "1111111111111111111111111111111111111111"
+		latest = "2222222222222222222222222222222222222222"
+	)
+	client := newCommitShaTestServer(t, map[string]string{
+		"tags/v4":     latest,
+		"tags/v4.2":   latest,
+		"tags/v3":     latest,
+		"tags/v1.2.3": pinned,
+		"tags/v1.2.0": latest,
+	}, `[
+  {"name": "v4.3.0", "commit": {"sha": "`+latest+`"}},
+  {"name": "v4.2.1", "commit": {"sha": "`+pinned+`"}},
+  {"name": "v4", "commit": {"sha": "`+latest+`"}},
+  {"name": "v3.10.1", "commit": {"sha": "`+latest+`"}},
+  {"name": "v3", "commit": {"sha": "`+latest+`"}}
+]`)
+
+	tests := []struct {
+		name    string
+		comment string
+		want    string
+	}{
+		{name: "major tag moved past the pinned release", comment: "# v4"},
+		{name: "minor tag moved past the pinned release", comment: "# v4.2"},
+		{name: "major tag never pointing at the commit", comment: "# v3", want: "does not match the pinned commit"},
+		{name: "version without v prefix", comment: "# 1.2.3"},
+		{name: "stale version without v prefix", comment: "# 1.2.0", want: "moving-owner/action@v1.2.0 is commit 222222222222"},
+		{name: "missing version without v prefix", comment: "# 1.2.4", want: "does not exist"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := CommitShaRule("")
+			rule.client = client
+			uses := "moving-owner/action@" + pinned
+			step := &ast.Step{
+				Exec: &ast.ExecAction{Uses: &ast.String{
+					Value:    uses,
+					BaseNode: &yaml.Node{Kind: yaml.ScalarNode, Value: uses, LineComment: tc.comment},
+				}},
+				Pos: &ast.Position{Line: 3, Col: 9},
+			}
+			if err := rule.VisitStep(step); err != nil {
+				t.Fatal(err)
+			}
+			errs := rule.Errors()
+			if tc.want == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Description, tc.want) {
+				t.Errorf("want one error containing %q, got %v", tc.want, errs)
+			}
+		})
+	}
+}
+
+func TestCommitSha_VersionCommentOfflineAdvisoryDB(t *testing.T) {
+	t.Parallel()
+
+	const pinned = "3333333333333333333333333333333333333333"
+	rule := CommitShaRule("")
+	// Every API call fails; the tag must be resolved from the database.
+	rule.client = newCommitShaTestServer(t, nil, `[]`)
+	rule.advisoryDB = &AdvisoryDB{Tags: map[string]map[string]string{
+		"offline-owner/action": {"v1.3.0": "4444444444444444444444444444444444444444"},
+	}}
+	uses := "offline-owner/action@" + pinned
+	step := &ast.Step{
+		Exec: &ast.ExecAction{Uses: &ast.String{
+			Value:    uses,
+			BaseNode: &yaml.Node{Kind: yaml.ScalarNode, Value: uses, LineComment: "# v1.3.0"},
+		}},
+		Pos: &ast.Position{Line: 3, Col: 9},
+	}
+	if err := rule.VisitStep(step); err != nil {
+		t.Fatal(err)
+	}
+	if errs := rule.Errors(); len(errs) != 1 || !strings.Contains(errs[0].Description, "is commit 444444444444") {
+		t.Errorf("want a mismatch resolved from the advisory database, got %v", errs)
+	}
+}
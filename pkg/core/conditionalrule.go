@@ -16,6 +16,7 @@ func NewConditionalRule() *ConditionalRule {
 		BaseRule: BaseRule{
 			RuleName: "cond",
 			RuleDesc: "chaecks if a condition is true/false",
+			severity: SeverityMedium,
 		},
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -27,6 +28,21 @@ type Config struct {
 	// AllowedHosts は誤検知を抑制するためのhost allowlist (exact match or "*." suffix wildcard, case-insensitive)
 	SecretExfiltration SecretExfiltrationConfig `yaml:"secret-exfiltration"`
 
+	// Matrix はmatrixルールのオプションを持つ
+	// MaxJobs はmatrixが展開されるjob数の上限 (0の場合はデフォルトの100)
+	Matrix MatrixConfig `yaml:"matrix"`
+
+	// TimeoutMinutes はmissing-timeout-minutesルールのオプションを持つ
+	// job/stepごとのデフォルト値と上限、runnerラベルごとのデフォルト値を指定する
+	TimeoutMinutes TimeoutMinutesConfig `yaml:"timeout-minutes"`
+
+	// Rules はルールごとの設定 (無効化・深刻度の上書き・対象パスの限定)
+	// キーはルール名で、未知のルール名はvalidate時にエラーとなる
+	Rules map[string]*RuleConfig `yaml:"rules"`
+
+	// CustomRules は組織固有のポリシーを宣言的に記述したルール
+	CustomRules []*CustomRuleConfig `yaml:"custom-rules"`
+
 	actionListRegex []*regexp.Regexp
 }
 
@@ -59,6 +75,27 @@ func (c *Config) String() string {
 		parts = append(parts, fmt.Sprintf("secret-exfiltration.allowed-hosts: %v", c.SecretExfiltration.AllowedHosts))
 	}
 
+	if c.Matrix.MaxJobs != 0 {
+		parts = append(parts, fmt.Sprintf("matrix.max-jobs: %d", c.Matrix.MaxJobs))
+	}
+
+	if t := c.TimeoutMinutes; t.isSet() {
+		parts = append(parts, fmt.Sprintf("timeout-minutes: {job: %+v, step: %+v, runners: %v, job-only: %v}", t.Job, t.Step, t.Runners, t.JobOnly))
+	}
+
+	if len(c.Rules) > 0 {
+		names := make([]string, 0, len(c.Rules))
+		for name := range c.Rules {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts = append(parts, fmt.Sprintf("rules: %v", names))
+	}
+
+	if len(c.CustomRules) > 0 {
+		parts = append(parts, fmt.Sprintf("custom-rules: %v", customRuleNames(c.CustomRules)))
+	}
+
 	if len(parts) == 0 {
 		return "Config{empty}"
 	}
@@ -81,6 +118,23 @@ func parseConfig(b []byte, path string) (*Config, error) {
 		}
 		c.actionListRegex = append(c.actionListRegex, re)
 	}
+	if c.Matrix.MaxJobs < 0 {
+		return nil, fmt.Errorf("invalid config file %q: matrix.max-jobs must be a positive number but it is %d", path, c.Matrix.MaxJobs)
+	}
+	if err := c.TimeoutMinutes.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+	}
+	for name, rc := range c.Rules {
+		if rc == nil {
+			continue
+		}
+		if err := rc.compile(name); err != nil {
+			return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+		}
+	}
+	if err := compileCustomRules(c.CustomRules); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+	}
 	return &c, nil
 }
 
@@ -156,6 +210,77 @@ action-list: []
 #     - "*.example.com"
 secret-exfiltration:
   allowed-hosts: []
+
+# matrix section configures the matrix rule.
+# max-jobs is the number of jobs a matrix may expand to, after "exclude" and
+# "include" are applied. Larger matrices are reported. Defaults to 100.
+# GitHub rejects a matrix which generates more than 256 jobs.
+# 🧠 Example:
+# matrix:
+#   max-jobs: 50
+matrix:
+  max-jobs: 100
+
+# timeout-minutes section configures the missing-timeout-minutes rule. Setting
+# any option enables the rule, which is otherwise opt-in (-enable-rule).
+#   job.default / step.default: value the auto-fixer inserts (defaults to 5)
+#   job.max / step.max:         larger values are reported. Expressions are
+#                               reported when they cannot be evaluated from
+#                               literals and matrix values. Without a max,
+#                               expressions are accepted as they are
+#   runners:                    default of jobs by runner label. When several
+#                               labels match, the largest default is used
+#   job-only:                   does not require timeout-minutes on steps
+# 🧠 Example:
+# timeout-minutes:
+#   job:
+#     default: 30
+#     max: 120
+#   step:
+#     max: 60
+#   runners:
+#     linux-large: 90
+#   job-only: true
+timeout-minutes:
+  job-only: false
+
+# rules section configures individual rules by name.
+#   disable:      turns the rule off
+#   severity:     overrides the severity of its findings (critical, high, medium, low, info)
+#   paths:        runs the rule only on files matching one of the globs
+#   ignore-paths: skips files matching one of the globs
+# Globs are relative to the repository root. "*" does not cross "/", "**" does.
+# Unknown rule names are reported as configuration errors.
+# 🧠 Example:
+# rules:
+#   missing-timeout-minutes:
+#     disable: true
+#   commit-sha:
+#     severity: medium
+#     ignore-paths:
+#       - .github/workflows/experimental-*.yml
+rules: {}
+
+# custom-rules section declares organization specific rules.
+#   name:        rule name used in output, suppression comments and the rules section
+#   target:      workflow, job (default) or step
+#   if:          selects the nodes the rule applies to (optional)
+#   assert:      reports a node when this expression is false
+#   deny:        reports a node when this expression is true (use either assert or deny)
+#   message:     message of findings; ${{ }} placeholders are evaluated
+#   severity:    critical, high, medium (default), low or info
+# Expressions use the GitHub Actions expression syntax over the workflow, job and step objects.
+# 🧠 Example:
+# custom-rules:
+#   - name: no-ubuntu-latest
+#     deny: contains(job.runs-on, 'ubuntu-latest')
+#     message: job "${{ job.id }}" must pin the runner image instead of ubuntu-latest
+#   - name: deploy-needs-prod-environment
+#     if: startsWith(job.id, 'deploy')
+#     assert: job.environment.name == 'prod' && job.concurrency != null
+#     message: deploy job "${{ job.id }}" must use environment "prod" and set concurrency
+#     severity: high
+custom-rules: []
 `)
 	if err := os.WriteFile(path, b, 0644); err != nil { //nolint:gosec // config file is committed to git and must be readable by CI
 		return fmt.Errorf("failed to write config file %q: %w", path, err)
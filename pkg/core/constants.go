@@ -42,6 +42,7 @@ const (
 
 	// File action constants
 	FileFixDryRun = "dry-run"
+	FileFixPatch  = "patch"
 
 	// Parse SBOM tag constants
 	SBOMNullTag  = "!!null"
@@ -18,6 +18,7 @@ func CredentialsRule() *CredentialRule {
 		BaseRule: BaseRule{
 			RuleName: "credentials",
 			RuleDesc: "This rule checks for credentials in the source code",
+			severity: SeverityHigh,
 		},
 	}
 }
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cronField is the range and the names of one field of a POSIX cron
+// expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 6, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// cronSchedule is a parsed cron expression of a "schedule" event. Each field
+// holds the sorted values it matches.
+type cronSchedule struct {
+	minutes []int
+	hours   []int
+}
+
+// parseCron parses the five fields cron syntax GitHub Actions accepts: lists,
+// ranges and steps of numbers, and names of months and days of week.
+// * https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows#schedule
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("expected 5 fields (minute, hour, day of month, month, day of week) but found %d", len(fields))
+	}
+	values := make([][]int, len(fields))
+	for i, f := range fields {
+		v, err := cronFields[i].parse(f)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return &cronSchedule{minutes: values[0], hours: values[1]}, nil
+}
+
+func (f *cronField) parse(s string) ([]int, error) {
+	set := map[int]struct{}{}
+	for _, item := range strings.Split(s, ",") {
+		rng, step, hasStep := strings.Cut(item, "/")
+		from, to := f.min, f.max
+		if rng != "*" {
+			lo, hi, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = f.value(lo); err != nil {
+				return nil, err
+			}
+			to = from
+			if isRange {
+				if to, err = f.value(hi); err != nil {
+					return nil, err
+				}
+				if to < from {
+					return nil, fmt.Errorf("range %q of %s field is reversed", rng, f.name)
+				}
+			} else if hasStep {
+				// "a/n" means every n-th value from a
+				to = f.max
+			}
+		}
+		n := 1
+		if hasStep {
+			var err error
+			n, err = strconv.Atoi(step)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("step %q of %s field must be a positive number", step, f.name)
+			}
+		}
+		for v := from; v <= to; v += n {
+			set[v] = struct{}{}
+		}
+	}
+	values := make([]int, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return values, nil
+}
+
+func (f *cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid value of %s field", s, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d of %s field is out of range %d-%d", v, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// minInterval returns the shortest number of minutes between two runs of the
+// schedule within a day. Schedules running at most once an hour return 60.
+func (c *cronSchedule) minInterval() int {
+	interval := 60
+	for i := 1; i < len(c.minutes); i++ {
+		interval = min(interval, c.minutes[i]-c.minutes[i-1])
+	}
+	if len(c.minutes) > 1 && c.hasConsecutiveHours() {
+		// The last run of an hour is followed by the first run of the next.
+		interval = min(interval, 60-c.minutes[len(c.minutes)-1]+c.minutes[0])
+	}
+	return interval
+}
+
+func (c *cronSchedule) hasConsecutiveHours() bool {
+	for i := 1; i < len(c.hours); i++ {
+		if c.hours[i] == c.hours[i-1]+1 {
+			return true
+		}
+	}
+	return len(c.hours) > 1 && c.hours[0] == 0 && c.hours[len(c.hours)-1] == 23
+}
@@ -366,11 +366,22 @@ func (c *LocalReusableWorkflowCache) emitChainWarning(ws []workspaceLike, caller
 		)
 	}
 	err := FormattedError(caller.Pos, "reusable-workflow-taint", "%s", msg)
+	err.Severity = Severity(severity)
+	err.TaintFlow = chainTaintFlow(caller, sink)
 	if w := findWorkspace(ws, caller.CallerWorkflowPath); w != nil {
 		w.AppendError(err)
 	}
 }
 
+// chainTaintFlow returns the path of an untrusted value from the `with:` of
+// the caller to the sink in the callee.
+func chainTaintFlow(caller *CallerTaint, sink *CalleeSink) []*TaintFlowStep {
+	passed := newTaintFlowStep(caller.Pos, "untrusted input %s passed to reusable workflow input %s", strings.Join(caller.UntrustedSources, ", "), caller.InputName)
+	used := newTaintFlowStep(sink.Pos, "%s used in %s sink", sink.InputPath, sink.SinkType)
+	used.FilePath = sink.CalleeWorkflowPath
+	return []*TaintFlowStep{passed, used}
+}
+
 func (c *LocalReusableWorkflowCache) emitCallerOnlyWarnings(ws []workspaceLike, calleeSpec string, callers []*CallerTaint) {
 	seen := make(map[string]struct{})
 	for _, caller := range callers {
@@ -395,6 +406,7 @@ func (c *LocalReusableWorkflowCache) emitCallerOnlyWarnings(ws []workspaceLike,
 			calleeSpec,
 		)
 		err := FormattedError(caller.Pos, "reusable-workflow-taint", "%s", msg)
+		err.Severity = Severity(severity)
 		if w := findWorkspace(ws, caller.CallerWorkflowPath); w != nil {
 			w.AppendError(err)
 		}
@@ -419,6 +431,7 @@ func (c *LocalReusableWorkflowCache) emitCalleeSoloWarnings(ws []workspaceLike,
 			sink.InputName, sink.SinkType,
 		)
 		err := FormattedError(sink.Pos, "reusable-workflow-taint", "%s", msg)
+		err.Severity = SeverityMedium
 		if w := findWorkspace(ws, sink.CalleeWorkflowPath); w != nil {
 			w.AppendError(err)
 		}
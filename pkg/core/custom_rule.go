@@ -0,0 +1,416 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// CustomRuleConfig is a rule declared in the "custom-rules:" section of
+// sisakulint.yaml.
+//
+//	custom-rules:
+//	  - name: deploy-needs-prod-environment
+//	    description: Deploy jobs must run in the prod environment with concurrency
+//	    target: job
+//	    if: startsWith(job.id, 'deploy')
+//	    assert: job.environment.name == 'prod' && job.concurrency != null
+//	    message: job "${{ job.id }}" must use environment "prod" and set concurrency
+//	    severity: high
+//
+// Expressions use the syntax of GitHub Actions expressions and are evaluated
+// against the "workflow", "job" and "step" objects described in
+// docs/customrules.md.
+type CustomRuleConfig struct {
+	// Name is the rule name used in output, suppression comments and the
+	// "rules:" section.
+	Name string `yaml:"name"`
+	// Description describes the rule.
+	Description string `yaml:"description"`
+	// Target is the node the rule is evaluated on: workflow, job (default) or
+	// step.
+	Target string `yaml:"target"`
+	// If selects the nodes the rule applies to. Empty means all nodes.
+	If string `yaml:"if"`
+	// Assert reports a node when it evaluates to false.
+	Assert string `yaml:"assert"`
+	// Deny reports a node when it evaluates to true.
+	Deny string `yaml:"deny"`
+	// Message is the message of findings. "${{ }}" placeholders are
+	// evaluated in the same way as the expressions.
+	Message string `yaml:"message"`
+	// Severity is the default severity of findings. Empty means medium.
+	Severity string `yaml:"severity"`
+
+	target    string
+	cond      *policyExpr
+	check     *policyExpr
+	deny      bool
+	message   *policyTemplate
+	severity  Severity
+	variables []string
+}
+
+var customRuleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// customRuleVariables holds the variables available to each target.
+var customRuleVariables = map[string][]string{
+	"workflow": {"workflow"},
+	"job":      {"workflow", "job"},
+	"step":     {"workflow", "job", "step"},
+}
+
+// compile validates the rule and compiles its expressions.
+func (c *CustomRuleConfig) compile() error {
+	if !customRuleNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("name %q must consist of lower case letters, digits and hyphens", c.Name)
+	}
+	c.target = strings.ToLower(c.Target)
+	if c.target == "" {
+		c.target = "job"
+	}
+	vars, ok := customRuleVariables[c.target]
+	if !ok {
+		return fmt.Errorf("target %q must be one of \"workflow\", \"job\" or \"step\"", c.Target)
+	}
+	c.variables = vars
+	if (c.Assert == "") == (c.Deny == "") {
+		return fmt.Errorf("exactly one of \"assert\" or \"deny\" must be set")
+	}
+	if c.Message == "" {
+		return fmt.Errorf("\"message\" must be set")
+	}
+	if c.Severity != "" {
+		sev, err := ParseSeverity(c.Severity)
+		if err != nil {
+			return fmt.Errorf("severity: %w", err)
+		}
+		c.severity = sev
+	}
+
+	var err error
+	if c.If != "" {
+		if c.cond, err = compilePolicyExpr(c.If, vars); err != nil {
+			return fmt.Errorf("if: %w", err)
+		}
+	}
+	src, key := c.Assert, "assert"
+	if c.Deny != "" {
+		src, key, c.deny = c.Deny, "deny", true
+	}
+	if c.check, err = compilePolicyExpr(src, vars); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if c.message, err = compilePolicyTemplate(c.Message, vars); err != nil {
+		return fmt.Errorf("message: %w", err)
+	}
+	return nil
+}
+
+// compileCustomRules compiles the "custom-rules:" section.
+func compileCustomRules(configs []*CustomRuleConfig) error {
+	seen := map[string]struct{}{}
+	for i, c := range configs {
+		if c == nil {
+			return fmt.Errorf("custom-rules[%d]: rule is empty", i)
+		}
+		if err := c.compile(); err != nil {
+			return fmt.Errorf("custom-rules[%d] (%s): %w", i, c.Name, err)
+		}
+		if _, ok := seen[c.Name]; ok {
+			return fmt.Errorf("custom-rules[%d]: rule %q is declared more than once", i, c.Name)
+		}
+		seen[c.Name] = struct{}{}
+	}
+	return nil
+}
+
+// makeCustomRules creates the rules declared in the configuration. It fails
+// when a custom rule has the name of a built-in rule.
+func makeCustomRules(configs []*CustomRuleConfig, builtin []string) ([]Rule, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	names := make(map[string]struct{}, len(builtin))
+	for _, n := range builtin {
+		names[n] = struct{}{}
+	}
+	rules := make([]Rule, 0, len(configs))
+	for _, c := range configs {
+		if _, ok := names[c.Name]; ok {
+			return nil, fmt.Errorf("custom rule %q has the same name as a built-in rule", c.Name)
+		}
+		rules = append(rules, newCustomRule(c))
+	}
+	return rules, nil
+}
+
+// CustomRule is a rule compiled from a CustomRuleConfig.
+type CustomRule struct {
+	BaseRule
+	config   *CustomRuleConfig
+	workflow map[string]interface{}
+	job      map[string]interface{}
+}
+
+func newCustomRule(c *CustomRuleConfig) *CustomRule {
+	desc := c.Description
+	if desc == "" {
+		desc = "Custom rule declared in sisakulint.yaml"
+	}
+	return &CustomRule{
+		BaseRule: BaseRule{
+			RuleName: c.Name,
+			RuleDesc: desc,
+			severity: c.severity,
+		},
+		config: c,
+	}
+}
+
+// VisitWorkflowPre evaluates workflow rules and builds the workflow object.
+func (rule *CustomRule) VisitWorkflowPre(n *ast.Workflow) error {
+	rule.workflow = policyWorkflowObject(n)
+	if rule.config.target == "workflow" {
+		rule.evaluate(&ast.Position{Line: 1, Col: 1}, map[string]interface{}{"workflow": rule.workflow})
+	}
+	return nil
+}
+
+// VisitJobPre evaluates job rules and builds the job object.
+func (rule *CustomRule) VisitJobPre(n *ast.Job) error {
+	rule.job = policyJobObject(n)
+	if rule.config.target == "job" {
+		rule.evaluate(n.Pos, map[string]interface{}{"workflow": rule.workflow, "job": rule.job})
+	}
+	return nil
+}
+
+// VisitStep evaluates step rules.
+func (rule *CustomRule) VisitStep(n *ast.Step) error {
+	if rule.config.target == "step" {
+		rule.evaluate(n.Pos, map[string]interface{}{"workflow": rule.workflow, "job": rule.job, "step": policyStepObject(n)})
+	}
+	return nil
+}
+
+func (rule *CustomRule) evaluate(pos *ast.Position, vars map[string]interface{}) {
+	c := rule.config
+	if c.cond != nil && !c.cond.test(vars) {
+		return
+	}
+	if c.check.test(vars) != c.deny {
+		return
+	}
+	rule.Error(pos, c.message.render(vars))
+}
+
+// policyWorkflowObject builds the "workflow" object of custom rules.
+func policyWorkflowObject(w *ast.Workflow) map[string]interface{} {
+	events := make([]interface{}, 0, len(w.On))
+	for _, e := range w.On {
+		events = append(events, e.EventName())
+	}
+	jobs := map[string]interface{}{}
+	for id, j := range w.Jobs {
+		jobs[id] = policyJobObject(j)
+	}
+	return map[string]interface{}{
+		"name":        policyStr(w.Name),
+		"on":          events,
+		"permissions": policyPermissions(w.Permissions),
+		"env":         policyEnv(w.Env),
+		"concurrency": policyConcurrency(w.Concurrency),
+		"jobs":        jobs,
+	}
+}
+
+// policyJobObject builds the "job" object of custom rules.
+func policyJobObject(j *ast.Job) map[string]interface{} {
+	var runsOn interface{}
+	if j.RunsOn != nil {
+		labels := []interface{}{}
+		if j.RunsOn.LabelsExpr != nil {
+			labels = append(labels, j.RunsOn.LabelsExpr.Value)
+		}
+		for _, l := range j.RunsOn.Labels {
+			labels = append(labels, l.Value)
+		}
+		runsOn = labels
+	}
+	needs := make([]interface{}, 0, len(j.Needs))
+	for _, n := range j.Needs {
+		needs = append(needs, n.Value)
+	}
+	var environment interface{}
+	if j.Environment != nil {
+		environment = map[string]interface{}{
+			"name": policyStr(j.Environment.Name),
+			"url":  policyStr(j.Environment.URL),
+		}
+	}
+	var container interface{}
+	if j.Container != nil {
+		container = map[string]interface{}{"image": policyStr(j.Container.Image)}
+	}
+	services := map[string]interface{}{}
+	for name, s := range j.Services {
+		var image interface{}
+		if s.Container != nil {
+			image = policyStr(s.Container.Image)
+		}
+		services[name] = map[string]interface{}{"image": image}
+	}
+	outputs := map[string]interface{}{}
+	for name, o := range j.Outputs {
+		outputs[name] = policyStr(o.Value)
+	}
+	var uses, secrets interface{}
+	if j.WorkflowCall != nil {
+		uses = policyStr(j.WorkflowCall.Uses)
+		if j.WorkflowCall.InheritSecrets {
+			secrets = "inherit"
+		} else {
+			m := map[string]interface{}{}
+			for name, s := range j.WorkflowCall.Secrets {
+				m[name] = policyStr(s.Value)
+			}
+			secrets = m
+		}
+	}
+	steps := make([]interface{}, 0, len(j.Steps))
+	for _, s := range j.Steps {
+		steps = append(steps, policyStepObject(s))
+	}
+	return map[string]interface{}{
+		"id":                policyStr(j.ID),
+		"name":              policyStr(j.Name),
+		"runs-on":           runsOn,
+		"needs":             needs,
+		"if":                policyStr(j.If),
+		"permissions":       policyPermissions(j.Permissions),
+		"environment":       environment,
+		"concurrency":       policyConcurrency(j.Concurrency),
+		"env":               policyEnv(j.Env),
+		"timeout-minutes":   policyFloat(j.TimeoutMinutes),
+		"continue-on-error": policyBool(j.ContinueOnError),
+		"container":         container,
+		"services":          services,
+		"outputs":           outputs,
+		"uses":              uses,
+		"secrets":           secrets,
+		"steps":             steps,
+	}
+}
+
+// policyStepObject builds the "step" object of custom rules.
+func policyStepObject(s *ast.Step) map[string]interface{} {
+	var uses, run, shell, workingDirectory interface{}
+	with := map[string]interface{}{}
+	switch e := s.Exec.(type) {
+	case *ast.ExecAction:
+		uses = policyStr(e.Uses)
+		for name, in := range e.Inputs {
+			with[name] = policyStr(in.Value)
+		}
+	case *ast.ExecRun:
+		run = policyStr(e.Run)
+		shell = policyStr(e.Shell)
+		workingDirectory = policyStr(e.WorkingDirectory)
+	}
+	return map[string]interface{}{
+		"id":                policyStr(s.ID),
+		"name":              policyStr(s.Name),
+		"if":                policyStr(s.If),
+		"uses":              uses,
+		"with":              with,
+		"run":               run,
+		"shell":             shell,
+		"working-directory": workingDirectory,
+		"env":               policyEnv(s.Env),
+		"timeout-minutes":   policyFloat(s.TimeoutMinutes),
+		"continue-on-error": policyBool(s.ContinueOnError),
+	}
+}
+
+func policyStr(s *ast.String) interface{} {
+	if s == nil {
+		return nil
+	}
+	return s.Value
+}
+
+func policyBool(b *ast.Bool) interface{} {
+	switch {
+	case b == nil:
+		return nil
+	case b.Expression != nil:
+		return b.Expression.Value
+	default:
+		return b.Value
+	}
+}
+
+func policyFloat(f *ast.Float) interface{} {
+	switch {
+	case f == nil:
+		return nil
+	case f.Expression != nil:
+		return f.Expression.Value
+	default:
+		return f.Value
+	}
+}
+
+func policyEnv(e *ast.Env) interface{} {
+	if e == nil {
+		return nil
+	}
+	if e.Expression != nil {
+		return e.Expression.Value
+	}
+	m := make(map[string]interface{}, len(e.Vars))
+	for _, v := range e.Vars {
+		m[v.Name.Value] = policyStr(v.Value)
+	}
+	return m
+}
+
+// policyPermissions returns "read-all", "write-all" or an expression as a
+// string, a map from scope to access level, or nil when not set.
+func policyPermissions(p *ast.Permissions) interface{} {
+	if p == nil {
+		return nil
+	}
+	if p.All != nil {
+		return p.All.Value
+	}
+	m := make(map[string]interface{}, len(p.Scopes))
+	for name, s := range p.Scopes {
+		m[name] = policyStr(s.Value)
+	}
+	return m
+}
+
+func policyConcurrency(c *ast.Concurrency) interface{} {
+	if c == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"group":              policyStr(c.Group),
+		"cancel-in-progress": policyBool(c.CancelInProgress),
+	}
+}
+
+// customRuleNames returns the names of the custom rules in sorted order.
+func customRuleNames(configs []*CustomRuleConfig) []string {
+	names := make([]string, 0, len(configs))
+	for _, c := range configs {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,411 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+)
+
+// policyExpr is a compiled match expression of a custom rule. It uses the
+// syntax of GitHub Actions expressions and is evaluated against plain values
+// (nil, bool, float64, string, []interface{} and map[string]interface{})
+// built from the workflow syntax tree.
+type policyExpr struct {
+	src  string
+	node expressions.ExprNode
+}
+
+// policyFuncArity holds the built-in functions available in custom rules with
+// their minimum and maximum number of arguments. Maximum -1 means variadic.
+var policyFuncArity = map[string][2]int{
+	"contains":   {2, 2},
+	"startswith": {2, 2},
+	"endswith":   {2, 2},
+	"format":     {1, -1},
+	"join":       {1, 2},
+	"tojson":     {1, 1},
+	"fromjson":   {1, 1},
+}
+
+// compilePolicyExpr parses src, which may be wrapped in "${{ }}", and checks
+// that it only refers to the variables in roots and to known functions.
+func compilePolicyExpr(src string, roots []string) (*policyExpr, error) {
+	s := strings.TrimSpace(src)
+	if strings.HasPrefix(s, "${{") && strings.HasSuffix(s, "}}") {
+		s = strings.TrimSpace(s[3 : len(s)-2])
+	}
+	if s == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+	node, err := expressions.NewMiniParser().Parse(expressions.NewTokenizer(s + "}}"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %s", s, err.Message)
+	}
+
+	var problem string
+	expressions.VisitExprNode(node, func(n, _ expressions.ExprNode, entering bool) {
+		if !entering || problem != "" {
+			return
+		}
+		switch n := n.(type) {
+		case *expressions.VariableNode:
+			for _, r := range roots {
+				if n.Name == r {
+					return
+				}
+			}
+			problem = fmt.Sprintf("unknown variable %q. available variables are %s", n.Name, strings.Join(quoteAll(roots), ", "))
+		case *expressions.FuncCallNode:
+			arity, ok := policyFuncArity[strings.ToLower(n.Callee)]
+			if !ok {
+				problem = fmt.Sprintf("unknown function %q", n.Callee)
+				return
+			}
+			if len(n.Args) < arity[0] || (arity[1] >= 0 && len(n.Args) > arity[1]) {
+				problem = fmt.Sprintf("wrong number of arguments for %s(): %d", n.Callee, len(n.Args))
+			}
+		}
+	})
+	if problem != "" {
+		return nil, fmt.Errorf("invalid expression %q: %s", s, problem)
+	}
+	return &policyExpr{src: s, node: node}, nil
+}
+
+func quoteAll(ss []string) []string {
+	out := make([]string, 0, len(ss))
+	for _, s := range ss {
+		out = append(out, strconv.Quote(s))
+	}
+	return out
+}
+
+// eval evaluates the expression with the given variables.
+func (e *policyExpr) eval(vars map[string]interface{}) interface{} {
+	return evalPolicyNode(e.node, vars)
+}
+
+// test evaluates the expression and converts the result to a boolean in the
+// same way as "if:" conditions.
+func (e *policyExpr) test(vars map[string]interface{}) bool {
+	return policyTruthy(e.eval(vars))
+}
+
+func evalPolicyNode(n expressions.ExprNode, vars map[string]interface{}) interface{} {
+	switch n := n.(type) {
+	case *expressions.VariableNode:
+		return vars[n.Name]
+	case *expressions.NullNode:
+		return nil
+	case *expressions.BoolNode:
+		return n.Value
+	case *expressions.IntNode:
+		return float64(n.Value)
+	case *expressions.FloatNode:
+		return n.Value
+	case *expressions.StringNode:
+		return unquotePolicyString(n.Value)
+	case *expressions.ObjectDerefNode:
+		return policyProperty(evalPolicyNode(n.Receiver, vars), n.Property)
+	case *expressions.ArrayDerefNode:
+		switch v := evalPolicyNode(n.Receiver, vars).(type) {
+		case []interface{}:
+			return v
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			out := make([]interface{}, 0, len(v))
+			for _, k := range keys {
+				out = append(out, v[k])
+			}
+			return out
+		default:
+			return nil
+		}
+	case *expressions.IndexAccessNode:
+		operand := evalPolicyNode(n.Operand, vars)
+		switch idx := evalPolicyNode(n.Index, vars).(type) {
+		case string:
+			return policyProperty(operand, idx)
+		case float64:
+			if arr, ok := operand.([]interface{}); ok && idx >= 0 && int(idx) < len(arr) {
+				return arr[int(idx)]
+			}
+		}
+		return nil
+	case *expressions.NotOpNode:
+		return !policyTruthy(evalPolicyNode(n.Operand, vars))
+	case *expressions.LogicalOpNode:
+		// && and || return one of their operands, like GitHub Actions.
+		l := evalPolicyNode(n.Left, vars)
+		if n.Kind == expressions.LogicalOpNodeKindAnd {
+			if !policyTruthy(l) {
+				return l
+			}
+			return evalPolicyNode(n.Right, vars)
+		}
+		if policyTruthy(l) {
+			return l
+		}
+		return evalPolicyNode(n.Right, vars)
+	case *expressions.CompareOpNode:
+		return policyCompare(n.Kind, evalPolicyNode(n.Left, vars), evalPolicyNode(n.Right, vars))
+	case *expressions.FuncCallNode:
+		args := make([]interface{}, 0, len(n.Args))
+		for _, a := range n.Args {
+			args = append(args, evalPolicyNode(a, vars))
+		}
+		return callPolicyFunc(strings.ToLower(n.Callee), args)
+	default:
+		return nil
+	}
+}
+
+// unquotePolicyString removes the quotes of a string literal, which the
+// expression parser keeps, and unescapes "”".
+func unquotePolicyString(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		s = s[1 : len(s)-1]
+	}
+	return strings.ReplaceAll(s, "''", "'")
+}
+
+// policyProperty returns the property of an object, ignoring case. On an
+// array, which is the result of a "*" filter, it returns the property of
+// every element which has it.
+func policyProperty(v interface{}, name string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if p, ok := v[name]; ok {
+			return p
+		}
+		for k, p := range v {
+			if strings.EqualFold(k, name) {
+				return p
+			}
+		}
+	case []interface{}:
+		out := []interface{}{}
+		for _, elem := range v {
+			if p := policyProperty(elem, name); p != nil {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func policyTruthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+// policyNumber converts a value to a number as GitHub Actions does when
+// comparing values of different types.
+func policyNumber(v interface{}) float64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case float64:
+		return v
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return 0
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+		if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return float64(i)
+		}
+	}
+	return math.NaN()
+}
+
+// policyCompare compares two values. Strings are compared ignoring case,
+// values of different types are compared as numbers, and objects and arrays
+// are not equal to any value.
+func policyCompare(kind expressions.CompareOpNodeKind, l, r interface{}) bool {
+	var c int
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	switch {
+	case lok && rok:
+		c = strings.Compare(strings.ToLower(ls), strings.ToLower(rs))
+	case isPolicyComposite(l) || isPolicyComposite(r):
+		return kind == expressions.CompareOpNodeKindNotEq
+	default:
+		ln, rn := policyNumber(l), policyNumber(r)
+		if math.IsNaN(ln) || math.IsNaN(rn) {
+			return kind == expressions.CompareOpNodeKindNotEq
+		}
+		switch {
+		case ln < rn:
+			c = -1
+		case ln > rn:
+			c = 1
+		}
+	}
+	switch kind {
+	case expressions.CompareOpNodeKindLess:
+		return c < 0
+	case expressions.CompareOpNodeKindLessEq:
+		return c <= 0
+	case expressions.CompareOpNodeKindGreater:
+		return c > 0
+	case expressions.CompareOpNodeKindGreaterEq:
+		return c >= 0
+	case expressions.CompareOpNodeKindEq:
+		return c == 0
+	case expressions.CompareOpNodeKindNotEq:
+		return c != 0
+	default:
+		return false
+	}
+}
+
+func isPolicyComposite(v interface{}) bool {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// policyString converts a value to a string for string functions and
+// message interpolation.
+func policyString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		return "Array"
+	default:
+		return "Object"
+	}
+}
+
+func callPolicyFunc(name string, args []interface{}) interface{} {
+	switch name {
+	case "contains":
+		if arr, ok := args[0].([]interface{}); ok {
+			for _, elem := range arr {
+				if !isPolicyComposite(elem) && policyCompare(expressions.CompareOpNodeKindEq, elem, args[1]) {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.Contains(strings.ToLower(policyString(args[0])), strings.ToLower(policyString(args[1])))
+	case "startswith":
+		return strings.HasPrefix(strings.ToLower(policyString(args[0])), strings.ToLower(policyString(args[1])))
+	case "endswith":
+		return strings.HasSuffix(strings.ToLower(policyString(args[0])), strings.ToLower(policyString(args[1])))
+	case "format":
+		s := policyString(args[0])
+		for i, a := range args[1:] {
+			s = strings.ReplaceAll(s, "{"+strconv.Itoa(i)+"}", policyString(a))
+		}
+		return s
+	case "join":
+		sep := ","
+		if len(args) > 1 {
+			sep = policyString(args[1])
+		}
+		arr, ok := args[0].([]interface{})
+		if !ok {
+			return policyString(args[0])
+		}
+		parts := make([]string, 0, len(arr))
+		for _, elem := range arr {
+			parts = append(parts, policyString(elem))
+		}
+		return strings.Join(parts, sep)
+	case "tojson":
+		b, err := json.MarshalIndent(args[0], "", "  ")
+		if err != nil {
+			return nil
+		}
+		return string(b)
+	case "fromjson":
+		var v interface{}
+		if err := json.Unmarshal([]byte(policyString(args[0])), &v); err != nil {
+			return nil
+		}
+		return v
+	default:
+		return nil
+	}
+}
+
+// policyTemplate is a message with "${{ }}" placeholders.
+type policyTemplate struct {
+	literals []string
+	exprs    []*policyExpr
+}
+
+// compilePolicyTemplate compiles the "${{ }}" placeholders in s.
+func compilePolicyTemplate(s string, roots []string) (*policyTemplate, error) {
+	t := &policyTemplate{}
+	for {
+		start := strings.Index(s, "${{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed \"${{\" in %q", s)
+		}
+		e, err := compilePolicyExpr(s[start+3:start+end], roots)
+		if err != nil {
+			return nil, err
+		}
+		t.literals = append(t.literals, s[:start])
+		t.exprs = append(t.exprs, e)
+		s = s[start+end+2:]
+	}
+	t.literals = append(t.literals, s)
+	return t, nil
+}
+
+func (t *policyTemplate) render(vars map[string]interface{}) string {
+	var b strings.Builder
+	for i, e := range t.exprs {
+		b.WriteString(t.literals[i])
+		b.WriteString(policyString(e.eval(vars)))
+	}
+	b.WriteString(t.literals[len(t.literals)-1])
+	return b.String()
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolicyExpr(t *testing.T) {
+	t.Parallel()
+
+	vars := map[string]interface{}{
+		"job": map[string]interface{}{
+			"id":              "deploy-prod",
+			"runs-on":         []interface{}{"ubuntu-latest"},
+			"environment":     nil,
+			"timeout-minutes": 30.0,
+			"steps": []interface{}{
+				map[string]interface{}{"uses": "actions/checkout@v4"},
+				map[string]interface{}{"run": "make"},
+			},
+		},
+	}
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{src: "startsWith(job.id, 'DEPLOY')", want: true},
+		{src: "contains(job.runs-on, 'ubuntu-latest')", want: true},
+		{src: "job.environment == null", want: true},
+		{src: "job.environment.name == 'prod'", want: false},
+		{src: "job.timeout-minutes > 20 && job.timeout-minutes <= '30'", want: true},
+		{src: "contains(job.steps.*.uses, 'actions/checkout@v4')", want: true},
+		{src: "job.steps[1].run == 'make'", want: true},
+		{src: "!job['runs-on']", want: false},
+		{src: "${{ format('{0}-{1}', job.id, join(job.runs-on)) == 'deploy-prod-ubuntu-latest' }}", want: true},
+	}
+	for _, tc := range tests {
+		e, err := compilePolicyExpr(tc.src, []string{"workflow", "job"})
+		if err != nil {
+			t.Errorf("compilePolicyExpr(%q): %v", tc.src, err)
+			continue
+		}
+		if got := e.test(vars); got != tc.want {
+			t.Errorf("%q = %v, want %v", tc.src, got, tc.want)
+		}
+	}
+
+	for src, want := range map[string]string{
+		"step.uses == 'x'":       `unknown variable "step"`,
+		"hashFiles('**/go.sum')": `unknown function "hashFiles"`,
+		"contains(job.id)":       "wrong number of arguments",
+		"job.id ==":              "invalid expression",
+	} {
+		if _, err := compilePolicyExpr(src, []string{"workflow", "job"}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("compilePolicyExpr(%q) error = %v, want %q", src, err, want)
+		}
+	}
+}
+
+func TestParseConfig_CustomRules(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		cfg, want string
+	}{
+		{cfg: "custom-rules:\n  - name: Bad_Name\n    deny: 'true'\n    message: m\n", want: "lower case letters"},
+		{cfg: "custom-rules:\n  - name: r\n    message: m\n", want: `exactly one of "assert" or "deny"`},
+		{cfg: "custom-rules:\n  - name: r\n    deny: 'true'\n", want: `"message" must be set`},
+		{cfg: "custom-rules:\n  - name: r\n    target: matrix\n    deny: 'true'\n    message: m\n", want: `target "matrix"`},
+		{cfg: "custom-rules:\n  - name: r\n    target: job\n    deny: step.run != null\n    message: m\n", want: `custom-rules[0] (r): deny: invalid expression`},
+		{cfg: "custom-rules:\n  - name: r\n    deny: 'true'\n    message: ${{ job.id\n", want: "unclosed"},
+		{cfg: "custom-rules:\n  - name: r\n    deny: 'true'\n    message: m\n  - name: r\n    deny: 'true'\n    message: m\n", want: "declared more than once"},
+	} {
+		if _, err := parseConfig([]byte(tc.cfg), "sisakulint.yaml"); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("parseConfig(%q) error = %v, want %q", tc.cfg, err, tc.want)
+		}
+	}
+}
+
+const customRulesTestConfig = `custom-rules:
+  - name: no-ubuntu-latest
+    deny: contains(job.runs-on, 'ubuntu-latest')
+    message: job "${{ job.id }}" runs on ${{ join(job.runs-on, ', ') }}
+  - name: deploy-needs-prod-environment
+    description: Deploy jobs must run in the prod environment with concurrency
+    if: startsWith(job.id, 'deploy')
+    assert: job.environment.name == 'prod' && job.concurrency != null
+    message: deploy job "${{ job.id }}" must use environment "prod" and set concurrency
+    severity: high
+  - name: no-curl-pipe
+    target: step
+    deny: contains(step.run, 'curl') && contains(step.run, '| sh')
+    message: do not pipe curl into sh in ${{ workflow.name }}/${{ job.id }}
+  - name: require-push-trigger
+    target: workflow
+    assert: contains(workflow.on, 'push')
+    message: workflows must run on push
+    severity: low
+rules:
+  no-ubuntu-latest:
+    severity: info
+`
+
+const customRulesTestWorkflow = `name: CI
+on: pull_request
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: curl https://example.com/install.sh | sh
+      # sisakulint-disable-next-line no-curl-pipe
+      - run: curl https://example.com/install.sh | sh
+  deploy-web:
+    runs-on: [self-hosted, linux]
+    environment: staging
+    steps:
+      - run: ./deploy.sh
+  deploy-api:
+    runs-on: [self-hosted, linux]
+    environment: prod
+    concurrency: deploy
+    steps:
+      - run: ./deploy.sh
+`
+
+func TestLinter_CustomRules(t *testing.T) {
+	t.Parallel()
+
+	configPath := filepath.Join(t.TempDir(), "sisakulint.yaml")
+	writeTestFile(t, configPath, customRulesTestConfig)
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard, ConfigurationFilePath: configPath})
+	if err != nil {
+		t.Fatalf("NewLinter: %v", err)
+	}
+	result, err := linter.Lint("test.yaml", []byte(customRulesTestWorkflow), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type finding struct {
+		rule     string
+		line     int
+		severity Severity
+		message  string
+	}
+	var got []finding
+	for _, e := range result.Errors {
+		switch e.Type {
+		case "no-ubuntu-latest", "deploy-needs-prod-environment", "no-curl-pipe", "require-push-trigger":
+			got = append(got, finding{e.Type, e.LineNumber, e.Severity, e.Description})
+		}
+	}
+	want := []finding{
+		{"require-push-trigger", 1, SeverityLow, "workflows must run on push"},
+		{"no-ubuntu-latest", 4, SeverityInfo, `job "build" runs on ubuntu-latest`},
+		{"no-curl-pipe", 7, SeverityMedium, "do not pipe curl into sh in CI/build"},
+		{"deploy-needs-prod-environment", 10, SeverityHigh, `deploy job "deploy-web" must use environment "prod" and set concurrency`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d findings, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLinter_CustomRuleNameCollision(t *testing.T) {
+	t.Parallel()
+
+	configPath := filepath.Join(t.TempDir(), "sisakulint.yaml")
+	writeTestFile(t, configPath, "custom-rules:\n  - name: permissions\n    deny: 'true'\n    message: m\n")
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard, ConfigurationFilePath: configPath})
+	if err != nil {
+		t.Fatalf("NewLinter: %v", err)
+	}
+	if _, err := linter.Lint("test.yaml", []byte(customRulesTestWorkflow), nil); err == nil || !strings.Contains(err.Error(), "same name as a built-in rule") {
+		t.Errorf("want a name collision error, got %v", err)
+	}
+}
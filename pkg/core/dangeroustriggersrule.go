@@ -6,12 +6,6 @@ import (
 	"github.com/sisaku-security/sisakulint/pkg/ast"
 )
 
-// Severity constants for dangerous triggers rules
-const (
-	SeverityCritical = "critical"
-	SeverityMedium   = "medium"
-)
-
 // MitigationStatus represents the security mitigations applied to a workflow
 // with privileged triggers. These mitigations help reduce the risk of security
 // vulnerabilities when using dangerous workflow triggers like pull_request_target.
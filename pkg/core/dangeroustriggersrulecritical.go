@@ -26,6 +26,7 @@ func NewDangerousTriggersCriticalRule() *DangerousTriggersCriticalRule {
 		BaseRule: BaseRule{
 			RuleName: "dangerous-triggers-critical",
 			RuleDesc: "Detects workflows using privileged triggers without any security mitigations. These triggers grant elevated privileges (write access, secrets) that can be exploited by malicious actors. See https://sisaku-security.github.io/lint/docs/rules/dangeroustriggersrulecritical/",
+			severity: SeverityCritical,
 		},
 	}
 }
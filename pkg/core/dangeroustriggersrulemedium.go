@@ -25,6 +25,7 @@ func NewDangerousTriggersMediumRule() *DangerousTriggersMediumRule {
 		BaseRule: BaseRule{
 			RuleName: "dangerous-triggers-medium",
 			RuleDesc: "Detects workflows using privileged triggers with only partial security mitigations. Consider adding more mitigations for defense in depth. See https://sisaku-security.github.io/lint/docs/rules/dangeroustriggersrulemedium/",
+			severity: SeverityMedium,
 		},
 	}
 }
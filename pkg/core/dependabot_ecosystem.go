@@ -72,6 +72,7 @@ func NewDependabotEcosystemRule(workflowPath string, isRemote bool) *DependabotE
 		BaseRule: BaseRule{
 			RuleName: "dependabot-ecosystem",
 			RuleDesc: "Check if dependabot config covers package ecosystems detected from lockfiles and setup actions",
+			severity: SeverityLow,
 		},
 		workflowPath:          workflowPath,
 		isRemote:              isRemote,
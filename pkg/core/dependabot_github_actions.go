@@ -40,6 +40,7 @@ func NewDependabotGitHubActionsRule(workflowPath string, isRemote bool) *Dependa
 		BaseRule: BaseRule{
 			RuleName: "dependabot-github-actions",
 			RuleDesc: "Check if dependabot.yaml has github-actions ecosystem configured when unpinned actions are detected",
+			severity: SeverityMedium,
 		},
 		workflowPath:                  workflowPath,
 		alreadyChecked:                make(map[string]bool),
@@ -148,7 +149,7 @@ func (rule *DependabotGitHubActionsRule) VisitWorkflowPost(_ *ast.Workflow) erro
 				"Create .github/dependabot.yaml with github-actions ecosystem. See https://sisaku-security.github.io/lint/docs/rules/dependabotgithubactionsrule/",
 		)
 		if rule.allowRepositoryFileAutoFixers {
-			rule.AddAutoFixer(NewFuncFixer(rule.RuleName, func() error {
+			rule.AddAutoFixer(NewRepositoryFileFixer(rule.RuleName, func() error {
 				return createDependabotFile(rule.projectRoot)
 			}))
 		}
@@ -170,7 +171,7 @@ func (rule *DependabotGitHubActionsRule) VisitWorkflowPost(_ *ast.Workflow) erro
 				"See https://sisaku-security.github.io/lint/docs/rules/dependabotgithubactionsrule/",
 		)
 		if rule.allowRepositoryFileAutoFixers {
-			rule.AddAutoFixer(NewFuncFixer(rule.RuleName, func() error {
+			rule.AddAutoFixer(NewRepositoryFileFixer(rule.RuleName, func() error {
 				return updateDependabotFile(dependabotPath)
 			}))
 		}
@@ -25,6 +25,7 @@ func NewDependencyReviewSettingsRule() *DependencyReviewSettingsRule {
 		BaseRule: BaseRule{
 			RuleName: dependencyReviewSettingsRuleName,
 			RuleDesc: "Checks actions/dependency-review-action security gate settings and required permissions",
+			severity: SeverityMedium,
 		},
 	}
 }
@@ -59,6 +59,7 @@ func NewDeprecatedNodeRuntimeRule(resolver ActionMetadataResolver) *DeprecatedNo
 		BaseRule: BaseRule{
 			RuleName: "deprecated-node-runtime",
 			RuleDesc: "Detects actions running on the deprecated Node.js 20 runtime (EOL 2026-04-30, removed from the runner on 2026-09-16) and workflow settings that pin the insecure runtime",
+			severity: SeverityHigh,
 		},
 		metadataResolver: resolver,
 	}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DumpAST returns the JSON form of the workflow or composite action in src,
+// as written by MarshalWorkflowJSON. Syntax errors are listed under "errors"
+// and the returned tree then contains what could be parsed.
+func DumpAST(path string, src []byte) (map[string]interface{}, []*LintingError) {
+	parse := Parse
+	if isCompositeActionFile(src) {
+		parse = ParseCompositeAction
+	}
+	w, errs := parse(src)
+	doc := workflowJSONDocument(path, w)
+	if len(errs) > 0 {
+		list := make([]interface{}, 0, len(errs))
+		for _, e := range errs {
+			list = append(list, map[string]interface{}{
+				"message": e.Description,
+				"rule":    e.Type,
+				"pos":     map[string]interface{}{"line": e.LineNumber, "col": e.ColNumber},
+			})
+		}
+		doc["errors"] = list
+	}
+	return doc, errs
+}
+
+// runDumpAST implements "sisakulint dump-ast", which prints the syntax tree
+// of a workflow as JSON for other tools.
+func (cmd *Command) runDumpAST(args []string) int {
+	var compact bool
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(cmd.Stderr)
+	flags.BoolVar(&compact, "compact", false, "Print the JSON on one line instead of indenting it")
+	flags.Usage = func() {
+		fmt.Fprintf(cmd.Stderr, "Usage: sisakulint dump-ast [FLAGS] FILE\n\nPrint the syntax tree of the workflow or composite action in FILE (\"-\" for stdin) as JSON, including the parsed ${{ }} expressions.\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitStatusSuccessNoProblem
+		}
+		return ExitStatusInvalidCommandOption
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(cmd.Stderr, "sisakulint dump-ast takes exactly one file: %s\n", strings.Join(flags.Args(), " "))
+		return ExitStatusInvalidCommandOption
+	}
+
+	path := flags.Arg(0)
+	var src []byte
+	var err error
+	if path == "-" {
+		path = "<stdin>"
+		src, err = io.ReadAll(cmd.Stdin)
+	} else {
+		src, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "could not read %q: %v\n", path, err)
+		return ExitStatusFailure
+	}
+
+	doc, errs := DumpAST(path, src)
+	enc := json.NewEncoder(cmd.Stdout)
+	enc.SetEscapeHTML(false) // Keep && and < of expressions readable
+	if !compact {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(doc); err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+	if len(errs) > 0 {
+		return ExitStatusSuccessProblemFound
+	}
+	return ExitStatusSuccessNoProblem
+}
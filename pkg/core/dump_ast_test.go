@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDumpAST_Expressions(t *testing.T) {
+	t.Parallel()
+
+	src := `on: push
+jobs:
+  build:
+    if: github.event_name == 'push' && !cancelled()
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.pull_request.title }}" ${{ matrix.os[0] }}
+      - run: echo ${{ github.ref
+`
+	doc, errs := DumpAST("ci.yml", []byte(src))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type node map[string]interface{}
+	var out struct {
+		Version  int `json:"version"`
+		Workflow struct {
+			Jobs map[string]struct {
+				If struct {
+					Expressions []struct {
+						Source string `json:"source"`
+						Tree   node   `json:"tree"`
+					} `json:"expressions"`
+				} `json:"if"`
+				Steps []struct {
+					Exec struct {
+						Run struct {
+							Expressions []struct {
+								Source string `json:"source"`
+								Pos    struct{ Line, Col int }
+								Tree   node   `json:"tree"`
+								Error  string `json:"error"`
+							} `json:"expressions"`
+						} `json:"run"`
+					} `json:"exec"`
+				} `json:"steps"`
+			} `json:"jobs"`
+		} `json:"workflow"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Version != WorkflowJSONVersion {
+		t.Errorf("unexpected version %d", out.Version)
+	}
+	job := out.Workflow.Jobs["build"]
+
+	// A condition without ${{ }} is a whole expression.
+	if len(job.If.Expressions) != 1 {
+		t.Fatalf("want 1 condition expression, got %+v", job.If)
+	}
+	cond := job.If.Expressions[0].Tree
+	if cond["kind"] != "logical" || cond["operator"] != "&&" {
+		t.Errorf("unexpected condition tree: %v", cond)
+	}
+	left := cond["left"].(map[string]interface{})
+	right := left["right"].(map[string]interface{})
+	if left["kind"] != "compare" || left["operator"] != "==" || right["kind"] != "string" || right["value"] != "push" {
+		t.Errorf("unexpected comparison: %v", left)
+	}
+	if pos := right["pos"].(map[string]interface{}); pos["line"] != 4.0 || pos["col"] != 30.0 {
+		t.Errorf("unexpected position of 'push': %v", pos)
+	}
+	if not := cond["right"].(map[string]interface{}); not["kind"] != "not" || not["operand"].(map[string]interface{})["callee"] != "cancelled" {
+		t.Errorf("unexpected negation: %v", not)
+	}
+
+	exprs := job.Steps[0].Exec.Run.Expressions
+	if len(exprs) != 2 || exprs[0].Source != "github.event.pull_request.title" || exprs[1].Source != "matrix.os[0]" {
+		t.Fatalf("unexpected expressions: %+v", exprs)
+	}
+	if exprs[0].Pos.Line != 7 || exprs[0].Pos.Col != 20 || exprs[1].Pos.Col != 60 {
+		t.Errorf("unexpected positions: %+v", exprs)
+	}
+	if deref := exprs[0].Tree; deref["kind"] != "object_deref" || deref["property"] != "title" {
+		t.Errorf("unexpected tree: %v", deref)
+	}
+	if idx := exprs[1].Tree; idx["kind"] != "index_access" || idx["index"].(map[string]interface{})["value"] != 0.0 {
+		t.Errorf("unexpected tree: %v", idx)
+	}
+
+	// An unterminated expression is recorded with its error.
+	if exprs := job.Steps[1].Exec.Run.Expressions; len(exprs) != 1 || exprs[0].Error == "" || exprs[0].Tree != nil {
+		t.Errorf("want a parse error, got %+v", exprs)
+	}
+}
+
+func TestCommand_DumpAST(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "action.yml")
+	writeTestFile(t, path, `name: Hello
+description: Say hello
+runs:
+  using: composite
+  steps:
+    - run: echo hello
+      shell: bash
+`)
+	var stdout, stderr bytes.Buffer
+	cmd := &Command{Stdout: &stdout, Stderr: &stderr}
+	if status := cmd.Main([]string{"sisakulint", "dump-ast", "-compact", path}); status != ExitStatusSuccessNoProblem {
+		t.Fatalf("dump-ast failed with %d: %s", status, stderr.String())
+	}
+	if strings.Count(stdout.String(), "\n") != 1 || !strings.Contains(stdout.String(), `"kind":"exec_run"`) {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	cmd = &Command{Stdin: strings.NewReader("on: push\njobs:\n  a:\n    runs-on: [\n"), Stdout: &stdout, Stderr: &stderr}
+	if status := cmd.Main([]string{"sisakulint", "dump-ast", "-"}); status != ExitStatusSuccessProblemFound {
+		t.Errorf("want exit status %d for a syntax error, got %d", ExitStatusSuccessProblemFound, status)
+	}
+	var doc struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &doc); err != nil || len(doc.Errors) == 0 {
+		t.Errorf("want syntax errors in the output, got %v: %s", err, stdout.String())
+	}
+
+	if status := cmd.Main([]string{"sisakulint", "dump-ast"}); status != ExitStatusInvalidCommandOption {
+		t.Errorf("want an invalid option status without a file, got %d", status)
+	}
+}
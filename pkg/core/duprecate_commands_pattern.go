@@ -25,6 +25,7 @@ func DeprecatedCommandsRule() *RuleDeprecatedCommands {
 		BaseRule: BaseRule{
 			RuleName: "deprecated-commands",
 			RuleDesc: "Checks for deprecated \"set-output\", \"save-state\", \"set-env\", and \"add-path\" commands at \"run:\"",
+			severity: SeverityHigh,
 		},
 	}
 }
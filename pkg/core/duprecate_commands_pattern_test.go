@@ -18,6 +18,7 @@ func TestDeprecatedCommandsRule(t *testing.T) {
 				BaseRule: BaseRule{
 					RuleName: "deprecated-commands",
 					RuleDesc: "Checks for deprecated \"set-output\", \"save-state\", \"set-env\", and \"add-path\" commands at \"run:\"",
+					severity: SeverityHigh,
 				},
 			},
 		},
@@ -18,6 +18,7 @@ func EnvironmentVariableRule() *EnvironmentVariableChecker {
 		BaseRule: BaseRule{
 			RuleName: "env-var",
 			RuleDesc: "Checks for environment variables configuration at \"env:\"",
+			severity: SeverityLow,
 		},
 	}
 }
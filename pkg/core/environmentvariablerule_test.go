@@ -18,6 +18,7 @@ func TestEnvironmentVariableRule(t *testing.T) {
 				BaseRule: BaseRule{
 					RuleName: "env-var",
 					RuleDesc: "Checks for environment variables configuration at \"env:\"",
+					severity: SeverityLow,
 				},
 			},
 		},
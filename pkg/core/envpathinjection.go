@@ -68,6 +68,7 @@ func newEnvPathInjectionRule(severityLevel string, checkPrivileged bool) *EnvPat
 		BaseRule: BaseRule{
 			RuleName: "envpath-injection-" + severityLevel,
 			RuleDesc: desc,
+			severity: Severity(severityLevel),
 		},
 		severityLevel:      severityLevel,
 		checkPrivileged:    checkPrivileged,
@@ -2175,7 +2176,7 @@ func (rule *EnvPathInjectionRule) parseExpression(exprStr string) (expressions.E
 
 // checkUntrustedInput checks if the expression contains untrusted input
 func (rule *EnvPathInjectionRule) checkUntrustedInput(expr parsedExpression) []string {
-	checker := expressions.NewExprSemanticsChecker(true, nil)
+	checker := newUntrustedInputChecker(rule.workflow)
 	_, errs := checker.Check(expr.node)
 
 	var paths []string
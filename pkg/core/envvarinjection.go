@@ -68,6 +68,7 @@ func newEnvVarInjectionRule(severityLevel string, checkPrivileged bool, wfTaintM
 		BaseRule: BaseRule{
 			RuleName: "envvar-injection-" + severityLevel,
 			RuleDesc: desc,
+			severity: Severity(severityLevel),
 		},
 		severityLevel:      severityLevel,
 		checkPrivileged:    checkPrivileged,
@@ -466,7 +467,7 @@ func (rule *EnvVarInjectionRule) parseExpression(exprStr string) (expressions.Ex
 
 // checkUntrustedInput checks if the expression contains untrusted input
 func (rule *EnvVarInjectionRule) checkUntrustedInput(expr parsedExpression) []string {
-	checker := expressions.NewExprSemanticsChecker(true, nil)
+	checker := newUntrustedInputChecker(rule.workflow)
 	_, errs := checker.Check(expr.node)
 
 	var paths []string
@@ -554,16 +555,19 @@ func (rule *EnvVarInjectionRule) VisitWorkflowPost(node *ast.Workflow) error {
 		}
 
 		taintPath := fmt.Sprintf("%s (tainted via %s)", pending.expr.raw, strings.Join(sources, ", "))
+		flow := taintFlowWithSink(rule.workflowTaintMap.FlowFromExprNode(pending.expr.node), newTaintFlowStep(pending.expr.pos, "written to $GITHUB_ENV"))
 
 		if rule.checkPrivileged {
-			rule.Errorf(
+			rule.ErrorfWithFlow(
 				pending.expr.pos,
+				flow,
 				"environment variable injection (critical): \"%s\" is potentially untrusted and written to $GITHUB_ENV in a workflow with privileged triggers. This can allow attackers to inject additional environment variables. Use heredoc syntax with unique delimiters or sanitize the input with 'tr -d '\\n''. See https://sisaku-security.github.io/lint/docs/rules/envvarinjectioncritical/",
 				taintPath,
 			)
 		} else {
-			rule.Errorf(
+			rule.ErrorfWithFlow(
 				pending.expr.pos,
+				flow,
 				"environment variable injection (medium): \"%s\" is potentially untrusted and written to $GITHUB_ENV. This can allow attackers to inject additional environment variables. Use heredoc syntax with unique delimiters or sanitize the input with 'tr -d '\\n''. See https://sisaku-security.github.io/lint/docs/rules/envvarinjectionmedium/",
 				taintPath,
 			)
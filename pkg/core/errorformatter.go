@@ -6,10 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/fatih/color"
 	"github.com/sisaku-security/sisakulint/pkg/ast"
@@ -37,6 +39,10 @@ type LintingError struct {
 	ColNumber int
 	//LintingErrorが発生した行の内容
 	Type string
+	//LintingErrorの深刻度
+	Severity Severity
+	// TaintFlowは汚染された値がソースからシンクに至るまでの経路 (インジェクション系のルールのみ)
+	TaintFlow []*TaintFlowStep
 }
 
 func (e *LintingError) Error() string {
@@ -75,18 +81,25 @@ func (e *LintingError) ExtractTemplateFields(sourceContent []byte) *TemplateFiel
 		}
 	}
 	return &TemplateFields{
-		Message:  e.Description,
-		Filepath: e.FilePath,
-		Line:     e.LineNumber,
-		Column:   e.ColNumber,
-		Type:     e.Type,
-		Snippet:  codeSnippet,
+		Message:     e.Description,
+		Filepath:    e.FilePath,
+		Line:        e.LineNumber,
+		Column:      e.ColNumber,
+		Type:        e.Type,
+		Severity:    string(e.Severity),
+		Snippet:     codeSnippet,
+		TaintFlow:   resolveTaintFlowPaths(e.TaintFlow, e.FilePath),
+		Fingerprint: e.Fingerprint(sourceContent),
 	}
 }
 
 type RuleTemplateField struct {
 	Name        string
 	Description string
+	// Severity はルールの既定の深刻度。空の場合は未設定
+	Severity string
+	// HelpURI はルールのドキュメントのURL。空の場合はドキュメントなし
+	HelpURI string
 }
 
 type ByRuleTemplateField []*RuleTemplateField
@@ -113,7 +126,12 @@ func (e *LintingError) DisplayError(output io.Writer, sourceContent []byte) {
 	fmt.Fprint(output, e.ColNumber)
 	printColored(output, GrayStyle, ": ")
 	printColored(output, OrangeStyle, e.Description)
-	printColored(output, RedStyle, fmt.Sprintf(" [%s]\n", e.Type))
+	printColored(output, RedStyle, fmt.Sprintf(" [%s]", e.Type))
+	if e.Severity != "" {
+		printColored(output, severityStyle(e.Severity), fmt.Sprintf(" (%s)", e.Severity))
+	}
+	fmt.Fprintln(output)
+	defer e.displayTaintFlow(output)
 
 	if len(sourceContent) == 0 || e.LineNumber == 0 {
 		return
@@ -135,6 +153,30 @@ func (e *LintingError) DisplayError(output io.Writer, sourceContent []byte) {
 	printColored(output, GrayStyle, fmt.Sprintf("%s %s\n", padding, strings.Repeat(" ", colNumber)))
 }
 
+// displayTaintFlowは汚染された値の経路をソースからシンクまで順に出力する
+func (e *LintingError) displayTaintFlow(output io.Writer) {
+	if len(e.TaintFlow) == 0 {
+		return
+	}
+	printColored(output, GrayStyle, "    taint flow:\n")
+	for i, s := range resolveTaintFlowPaths(e.TaintFlow, e.FilePath) {
+		printColored(output, GrayStyle, fmt.Sprintf("      %d. %s:%d:%d: ", i+1, s.FilePath, s.Line, s.Column))
+		fmt.Fprintln(output, s.Message)
+	}
+}
+
+// severityStyleは深刻度に対応する色を返す
+func severityStyle(severity Severity) *color.Color {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return RedStyle
+	case SeverityMedium:
+		return YellowStyle
+	default:
+		return GrayStyle
+	}
+}
+
 // helper function to print with color
 func printColored(output io.Writer, colorizer *color.Color, content string) {
 	colorizer.Fprint(output, content)
@@ -186,9 +228,17 @@ type TemplateFields struct {
 	Column int `json:"column"`
 	// Type はエラーが属しているルールの名前
 	Type string `json:"type"`
+	// Severity はエラーの深刻度 (critical, high, medium, low, info)
+	Severity string `json:"severity,omitempty"`
 	// Snippet はエラーが発生した位置を示すコードスニペットおよびインジケーター
 	// JSONにエンコードする際、スニペットが空の場合、(このフィールドは省略される可能性あり)
 	Snippet string `json:"snippet,omitempty"`
+	// TaintFlow は汚染された値がソースからシンクに至るまでの経路。経路を持たないエラーでは省略される
+	TaintFlow []*TaintFlowStep `json:"taint_flow,omitempty"`
+	// Fingerprint は行番号に依存しないエラーの識別子。ベースラインと同じ値
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Fixes はエラーに対応するauto-fixerによる変更。フォーマットがsarifまたはFixesを参照する場合のみ設定される
+	Fixes []*SuggestedFix `json:"fixes,omitempty"`
 }
 
 // backslashのunescape
@@ -220,6 +270,8 @@ type ErrorFormatter struct {
 	templateInstance *template.Template
 	ruleTemplates    map[string]*RuleTemplateField
 	m                sync.Mutex
+	// needsFixesはフォーマットがauto-fixerによる変更を出力するかどうか
+	needsFixes bool
 }
 
 // NewErrorformatterは新しいErrorFormatterインスタンスを作成する。
@@ -230,8 +282,10 @@ func NewErrorFormatter(format string) (*ErrorFormatter, error) {
 		return nil, fmt.Errorf("the specified format should contain at least one {{ }} placeholder : %s", format)
 	}
 
-	ruleTemplates := map[string]*RuleTemplateField{
-		"syntax-check": {"syntax-check", "Check the Github Actions workflow syntax"},
+	formatter := &ErrorFormatter{
+		ruleTemplates: map[string]*RuleTemplateField{
+			"syntax-check": {Name: "syntax-check", Description: "Check the Github Actions workflow syntax"},
+		},
 	}
 
 	funcMap := template.FuncMap(map[string]interface{}{
@@ -250,22 +304,90 @@ func NewErrorFormatter(format string) (*ErrorFormatter, error) {
 
 		"toPascalCase": toPascalCase,
 		//"getVersion": getCommandVersion,
-		"allKinds": func() []*RuleTemplateField {
-			ret := make([]*RuleTemplateField, 0, len(ruleTemplates))
-			for _, rule := range ruleTemplates {
-				ret = append(ret, rule)
-			}
-			sort.Sort(ByRuleTemplateField(ret))
-			return ret
-		},
+		"allKinds": formatter.allRules,
 
-		"sarif": toSARIF,
+		"sarif": func(fields []*TemplateFields) (string, error) {
+			return toSARIF(fields, formatter.allRules())
+		},
 	})
 	t, err := template.New("error formatter").Funcs(funcMap).Parse(unescapeBackslash(format))
 	if err != nil {
 		return nil, fmt.Errorf("failed to ast %q the specified format: %w", format, err)
 	}
-	return &ErrorFormatter{t, ruleTemplates, sync.Mutex{}}, nil
+	formatter.templateInstance = t
+	formatter.needsFixes = templateUsesFixes(t)
+	return formatter, nil
+}
+
+// templateUsesFixesはテンプレートがsarif関数か.Fixesフィールドを使うかどうかを返す
+// フォーマット文字列ではなく構文木を見るので、テキストやコメント中の"sarif"は無視される
+func templateUsesFixes(t *template.Template) bool {
+	var uses func(n parse.Node) bool
+	uses = func(n parse.Node) bool {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return false
+			}
+			for _, c := range n.Nodes {
+				if uses(c) {
+					return true
+				}
+			}
+		case *parse.ActionNode:
+			return uses(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return false
+			}
+			for _, c := range n.Cmds {
+				if uses(c) {
+					return true
+				}
+			}
+		case *parse.CommandNode:
+			for _, a := range n.Args {
+				if uses(a) {
+					return true
+				}
+			}
+		case *parse.IfNode:
+			return uses(n.Pipe) || uses(n.List) || uses(n.ElseList)
+		case *parse.RangeNode:
+			return uses(n.Pipe) || uses(n.List) || uses(n.ElseList)
+		case *parse.WithNode:
+			return uses(n.Pipe) || uses(n.List) || uses(n.ElseList)
+		case *parse.TemplateNode:
+			return uses(n.Pipe)
+		case *parse.IdentifierNode:
+			return n.Ident == "sarif"
+		case *parse.FieldNode:
+			return slices.Contains(n.Ident, "Fixes")
+		case *parse.VariableNode:
+			return slices.Contains(n.Ident, "Fixes")
+		case *parse.ChainNode:
+			return slices.Contains(n.Field, "Fixes") || uses(n.Node)
+		}
+		return false
+	}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil && uses(tmpl.Tree.Root) {
+			return true
+		}
+	}
+	return false
+}
+
+// allRulesは登録済みのルールを名前順に返す
+func (formatter *ErrorFormatter) allRules() []*RuleTemplateField {
+	formatter.m.Lock()
+	defer formatter.m.Unlock()
+	ret := make([]*RuleTemplateField, 0, len(formatter.ruleTemplates))
+	for _, rule := range formatter.ruleTemplates {
+		ret = append(ret, rule)
+	}
+	sort.Sort(ByRuleTemplateField(ret))
+	return ret
 }
 
 // PrintErrorsはテンプレートでフォーマットした後でエラーを出力する
@@ -292,9 +414,14 @@ func (formatter *ErrorFormatter) RegisterRule(rule Rule) {
 	formatter.m.Lock()
 	defer formatter.m.Unlock()
 	if _, exists := formatter.ruleTemplates[ruleName]; !exists {
-		formatter.ruleTemplates[ruleName] = &RuleTemplateField{
+		field := &RuleTemplateField{
 			Name:        ruleName,
 			Description: rule.RuleDescription(),
+			Severity:    string(rule.RuleSeverity()),
+		}
+		if _, custom := rule.(*CustomRule); !custom {
+			field.HelpURI = ruleDocURL(ruleName)
 		}
+		formatter.ruleTemplates[ruleName] = field
 	}
 }
@@ -0,0 +1,317 @@
+package core
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+)
+
+// minScheduleInterval is the shortest interval in minutes GitHub runs
+// scheduled workflows at.
+const minScheduleInterval = 5
+
+// webhookEventSpec is what one webhook event accepts in its configuration.
+type webhookEventSpec struct {
+	// types are the activity types of the event. nil means the event has no
+	// "types" filter.
+	types []string
+	// filters are the filters other than "types" the event accepts.
+	filters []string
+}
+
+// Filters accepted by the events which support filtering by ref and path.
+var (
+	branchFilters    = []string{"branches", "branches-ignore"}
+	refPathFilters   = []string{"branches", "branches-ignore", "paths", "paths-ignore"}
+	pullRequestTypes = []string{
+		"assigned", "unassigned", "labeled", "unlabeled", "opened", "edited", "closed", "reopened",
+		"synchronize", "converted_to_draft", "ready_for_review", "locked", "unlocked",
+		"review_requested", "review_request_removed", "auto_merge_enabled", "auto_merge_disabled",
+		"milestoned", "demilestoned", "enqueued", "dequeued",
+	}
+)
+
+// allWebhookEvents is the table of webhook events which can trigger workflows.
+// schedule, workflow_dispatch, repository_dispatch and workflow_call are
+// parsed into their own nodes and are not listed here.
+// * https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows
+var allWebhookEvents = map[string]webhookEventSpec{
+	"branch_protection_rule":      {types: []string{"created", "edited", "deleted"}},
+	"check_run":                   {types: []string{"created", "rerequested", "completed", "requested_action"}},
+	"check_suite":                 {types: []string{"completed"}},
+	"create":                      {},
+	"delete":                      {},
+	"deployment":                  {},
+	"deployment_status":           {},
+	"discussion":                  {types: []string{"created", "edited", "deleted", "transferred", "pinned", "unpinned", "labeled", "unlabeled", "locked", "unlocked", "category_changed", "answered", "unanswered"}},
+	"discussion_comment":          {types: []string{"created", "edited", "deleted"}},
+	"fork":                        {},
+	"gollum":                      {},
+	"image_version":               {types: []string{"created", "ready"}},
+	"issue_comment":               {types: []string{"created", "edited", "deleted"}},
+	"issues":                      {types: []string{"opened", "edited", "deleted", "transferred", "pinned", "unpinned", "closed", "reopened", "assigned", "unassigned", "labeled", "unlabeled", "locked", "unlocked", "milestoned", "demilestoned", "typed", "untyped"}},
+	"label":                       {types: []string{"created", "edited", "deleted"}},
+	"merge_group":                 {types: []string{"checks_requested"}, filters: branchFilters},
+	"milestone":                   {types: []string{"created", "closed", "opened", "edited", "deleted"}},
+	"page_build":                  {},
+	"project":                     {types: []string{"created", "closed", "reopened", "edited", "deleted"}},
+	"project_card":                {types: []string{"created", "moved", "converted", "edited", "deleted"}},
+	"project_column":              {types: []string{"created", "updated", "moved", "deleted"}},
+	"public":                      {},
+	"pull_request":                {types: pullRequestTypes, filters: refPathFilters},
+	"pull_request_review":         {types: []string{"submitted", "edited", "dismissed"}},
+	"pull_request_review_comment": {types: []string{"created", "edited", "deleted"}},
+	"pull_request_target":         {types: pullRequestTypes, filters: refPathFilters},
+	"push":                        {filters: []string{"branches", "branches-ignore", "tags", "tags-ignore", "paths", "paths-ignore"}},
+	"registry_package":            {types: []string{"published", "updated"}},
+	"release":                     {types: []string{"published", "unpublished", "created", "edited", "deleted", "prereleased", "released"}},
+	"status":                      {},
+	"watch":                       {types: []string{"started"}},
+	"workflow_run":                {types: []string{"completed", "requested", "in_progress"}, filters: []string{"branches", "branches-ignore", "workflows"}},
+}
+
+// RuleEvents is a rule to check the events in the "on" section of workflows.
+type RuleEvents struct {
+	BaseRule
+}
+
+// EventsRule creates a new RuleEvents instance.
+func EventsRule() *RuleEvents {
+	return &RuleEvents{
+		BaseRule: BaseRule{
+			RuleName: "events",
+			RuleDesc: "Checks for unknown events, activity types, filters, cron syntax and workflow_dispatch inputs",
+			severity: SeverityMedium,
+		},
+	}
+}
+
+// VisitWorkflowPre is callback when visiting Workflow node before visiting its children.
+func (rule *RuleEvents) VisitWorkflowPre(n *ast.Workflow) error {
+	for _, e := range n.On {
+		switch e := e.(type) {
+		case *ast.WebhookEvent:
+			rule.checkWebhookEvent(e)
+		case *ast.ScheduledEvent:
+			rule.checkScheduledEvent(e)
+		case *ast.WorkflowDispatchEvent:
+			rule.checkWorkflowDispatchEvent(e)
+		case *ast.RepositoryDispatchEvent:
+			rule.checkDuplicateTypes(e.Types, "repository_dispatch")
+		}
+	}
+	return nil
+}
+
+func (rule *RuleEvents) checkWebhookEvent(event *ast.WebhookEvent) {
+	hook := event.Hook.Value
+	spec, ok := allWebhookEvents[hook]
+	if !ok {
+		names := make([]string, 0, len(allWebhookEvents))
+		for name := range allWebhookEvents {
+			names = append(names, name)
+		}
+		msg := "unknown Webhook event %q. "
+		if s := closestEventName(hook, names); s != "" {
+			msg += "did you mean \"" + s + "\"? "
+		}
+		rule.Errorf(event.Hook.Pos, msg+"all available Webhook events are listed in https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows", hook)
+		return
+	}
+
+	if len(event.Types) > 0 {
+		if spec.types == nil {
+			rule.Errorf(event.Types[0].Pos, "\"types\" cannot be specified for %q event since it has no activity types", hook)
+		} else {
+			rule.checkTypes(event.Types, hook, spec.types)
+		}
+	}
+
+	filters := []*ast.WebhookEventFilter{event.Branches, event.BranchesIgnore, event.Tags, event.TagsIgnore, event.Paths, event.PathsIgnore}
+	for _, f := range filters {
+		if f == nil || f.Name == nil {
+			continue
+		}
+		if !slices.Contains(spec.filters, f.Name.Value) {
+			rule.Errorf(f.Name.Pos, "%q filter is not available for %q event. %s", f.Name.Value, hook, availableFiltersNote(spec.filters))
+		}
+	}
+	if len(event.Workflows) > 0 && !slices.Contains(spec.filters, "workflows") {
+		rule.Errorf(event.Workflows[0].Pos, "\"workflows\" filter is not available for %q event. %s", hook, availableFiltersNote(spec.filters))
+	}
+
+	rule.checkExclusiveFilters(hook, event.Branches, event.BranchesIgnore)
+	rule.checkExclusiveFilters(hook, event.Tags, event.TagsIgnore)
+	rule.checkExclusiveFilters(hook, event.Paths, event.PathsIgnore)
+
+	if hook == "workflow_run" && len(event.Workflows) == 0 {
+		rule.Errorf(event.Pos, "no workflow is configured for \"workflow_run\" event. Set the names of the workflows in \"workflows\" filter")
+	}
+}
+
+// checkTypes reports activity types which the event does not have and which
+// are listed twice.
+func (rule *RuleEvents) checkTypes(types []*ast.String, hook string, available []string) {
+	for _, t := range types {
+		if strings.Contains(t.Value, "${{") || slices.Contains(available, t.Value) {
+			continue
+		}
+		msg := "invalid activity type %q for %q event. "
+		if s := closestEventName(t.Value, available); s != "" {
+			msg += "did you mean \"" + s + "\"? "
+		}
+		rule.Errorf(t.Pos, msg+"available types are %s", t.Value, hook, expressions.SortedQuotes(available))
+	}
+	rule.checkDuplicateTypes(types, hook)
+}
+
+func (rule *RuleEvents) checkDuplicateTypes(types []*ast.String, hook string) {
+	seen := make(map[string]*ast.String, len(types))
+	for _, t := range types {
+		if prev, ok := seen[t.Value]; ok {
+			rule.Errorf(t.Pos, "activity type %q of %q event is duplicated. The same type is at %s", t.Value, hook, prev.Pos)
+			continue
+		}
+		seen[t.Value] = t
+	}
+}
+
+// checkExclusiveFilters reports a filter used with its "-ignore" counterpart.
+// GitHub rejects a workflow which has both of them.
+func (rule *RuleEvents) checkExclusiveFilters(hook string, filter, ignore *ast.WebhookEventFilter) {
+	if filter == nil || ignore == nil || filter.Name == nil || ignore.Name == nil {
+		return
+	}
+	rule.Errorf(ignore.Name.Pos, "both %q and %q filters cannot be used for the same event %q. Use %q with \"!\" patterns to exclude values", filter.Name.Value, ignore.Name.Value, hook, filter.Name.Value)
+}
+
+// checkScheduledEvent checks the syntax of cron expressions and reports
+// schedules which run more often than GitHub allows.
+// * https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows#schedule
+func (rule *RuleEvents) checkScheduledEvent(event *ast.ScheduledEvent) {
+	for _, c := range event.Cron {
+		if c == nil || strings.Contains(c.Value, "${{") {
+			continue
+		}
+		sched, err := parseCron(c.Value)
+		if err != nil {
+			rule.Errorf(c.Pos, "invalid CRON format %q in schedule event: %s", c.Value, err)
+			continue
+		}
+		if d := sched.minInterval(); d < minScheduleInterval {
+			rule.Errorf(c.Pos, "scheduled job runs too frequently. it runs once per %d minutes with %q. The shortest interval GitHub allows is once every %d minutes", d, c.Value, minScheduleInterval)
+		}
+	}
+}
+
+// checkWorkflowDispatchEvent checks that options and default values of
+// workflow_dispatch inputs match their types.
+// * https://docs.github.com/en/actions/using-workflows/workflow-syntax-for-github-actions#onworkflow_dispatchinputs
+func (rule *RuleEvents) checkWorkflowDispatchEvent(event *ast.WorkflowDispatchEvent) {
+	ids := make([]string, 0, len(event.Inputs))
+	for id := range event.Inputs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		input := event.Inputs[id]
+		name := input.Name.Value
+
+		if input.Type == ast.WorkflowDispatchEventInputTypeChoice {
+			if len(input.Options) == 0 {
+				rule.Errorf(input.Name.Pos, "input %q of workflow_dispatch event has no options. \"choice\" type requires at least one option in \"options\"", name)
+			}
+			seen := make(map[string]*ast.String, len(input.Options))
+			for _, o := range input.Options {
+				if prev, ok := seen[o.Value]; ok {
+					rule.Errorf(o.Pos, "option %q of input %q is duplicated. The same option is at %s", o.Value, name, prev.Pos)
+					continue
+				}
+				seen[o.Value] = o
+			}
+		} else if len(input.Options) > 0 {
+			rule.Errorf(input.Options[0].Pos, "\"options\" is set for input %q of workflow_dispatch event but it can only be used with \"choice\" type", name)
+		}
+
+		def := input.Default
+		if def == nil || strings.Contains(def.Value, "${{") {
+			continue
+		}
+		switch input.Type {
+		case ast.WorkflowDispatchEventInputTypeChoice:
+			if len(input.Options) > 0 && !containsOption(input.Options, def.Value) {
+				opts := make([]string, 0, len(input.Options))
+				for _, o := range input.Options {
+					if !slices.Contains(opts, o.Value) {
+						opts = append(opts, o.Value)
+					}
+				}
+				rule.Errorf(def.Pos, "default value %q of input %q is not included in its options %s", def.Value, name, expressions.SortedQuotes(opts))
+			}
+		case ast.WorkflowDispatchEventInputTypeBoolean:
+			if def.Value != "true" && def.Value != "false" {
+				rule.Errorf(def.Pos, "default value %q of input %q must be \"true\" or \"false\" since its type is \"boolean\"", def.Value, name)
+			}
+		case ast.WorkflowDispatchEventInputTypeNumber:
+			if _, err := strconv.ParseFloat(def.Value, 64); err != nil {
+				rule.Errorf(def.Pos, "default value %q of input %q must be a number since its type is \"number\"", def.Value, name)
+			}
+		}
+	}
+}
+
+func containsOption(options []*ast.String, v string) bool {
+	for _, o := range options {
+		if o.Value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func availableFiltersNote(filters []string) string {
+	if len(filters) == 0 {
+		return "the event has no filters"
+	}
+	return "available filters are " + expressions.SortedQuotes(filters)
+}
+
+// closestEventName returns the candidate closest to s, or "" when no candidate
+// is within two edits of it. It is used to suggest the intended name of a typo.
+func closestEventName(s string, candidates []string) string {
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+	best, bestDist := "", 3
+	for _, c := range sorted {
+		if d := editDistance(strings.ToLower(s), c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func runEventsRule(t *testing.T, on string) []*LintingError {
+	t.Helper()
+
+	rule := EventsRule()
+	visitWorkflowSource(t, rule, on+`jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo hello
+`)
+	return rule.Errors()
+}
+
+func TestEventsRule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		on   string
+		want []string
+	}{
+		{
+			name: "valid",
+			on: `on:
+  push:
+    branches: [main]
+    tags: ['v*']
+    paths-ignore: ['docs/**']
+  pull_request:
+    types: [opened, synchronize]
+  workflow_run:
+    workflows: [CI]
+    types: [completed]
+  schedule:
+    - cron: '*/5 * * * *'
+    - cron: '30 2 * JAN-MAR mon-fri'
+  repository_dispatch:
+    types: [deploy]
+  workflow_dispatch:
+    inputs:
+      env:
+        type: choice
+        options: [dev, prod]
+        default: prod
+      dry-run:
+        type: boolean
+        default: false
+`,
+		},
+		{
+			name: "scalar and sequence",
+			on: `on: [push, issues]
+`,
+		},
+		{
+			name: "unknown event",
+			on: `on: pull_requests
+`,
+			want: []string{`1:5: unknown Webhook event "pull_requests". did you mean "pull_request"?`},
+		},
+		{
+			name: "invalid and duplicate types",
+			on: `on:
+  pull_request:
+    types: [opend, closed, closed]
+`,
+			want: []string{
+				`3:13: invalid activity type "opend" for "pull_request" event. did you mean "opened"? available types are "assigned"`,
+				`3:28: activity type "closed" of "pull_request" event is duplicated. The same type is at line:3,col:20`,
+			},
+		},
+		{
+			name: "types of event without activity types",
+			on: `on:
+  push:
+    types: [created]
+`,
+			want: []string{`"types" cannot be specified for "push" event since it has no activity types`},
+		},
+		{
+			name: "unavailable filters",
+			on: `on:
+  pull_request:
+    tags: [v1]
+  issues:
+    branches: [main]
+  push:
+    workflows: [CI]
+`,
+			want: []string{
+				`3:5: "tags" filter is not available for "pull_request" event. available filters are "branches", "branches-ignore", "paths", "paths-ignore"`,
+				`5:5: "branches" filter is not available for "issues" event. the event has no filters`,
+				`"workflows" filter is not available for "push" event`,
+			},
+		},
+		{
+			name: "exclusive filters",
+			on: `on:
+  push:
+    branches: [main]
+    branches-ignore: [dev]
+    paths: [src]
+    paths-ignore: [docs]
+`,
+			want: []string{
+				`4:5: both "branches" and "branches-ignore" filters cannot be used for the same event "push"`,
+				`6:5: both "paths" and "paths-ignore" filters cannot be used for the same event "push"`,
+			},
+		},
+		{
+			name: "workflow_run without workflows",
+			on: `on:
+  workflow_run:
+    types: [completed]
+`,
+			want: []string{`2:3: no workflow is configured for "workflow_run" event`},
+		},
+		{
+			name: "invalid cron",
+			on: `on:
+  schedule:
+    - cron: '0 25 * * *'
+    - cron: '* * *'
+    - cron: '0 0 * * FOO'
+    - cron: '10-5 * * * *'
+    - cron: '*/0 * * * *'
+    - cron: '${{ inputs.cron }}'
+`,
+			want: []string{
+				`3:13: invalid CRON format "0 25 * * *" in schedule event: value 25 of hour field is out of range 0-23`,
+				`expected 5 fields (minute, hour, day of month, month, day of week) but found 3`,
+				`"FOO" is not a valid value of day of week field`,
+				`range "10-5" of minute field is reversed`,
+				`step "0" of minute field must be a positive number`,
+			},
+		},
+		{
+			name: "too frequent schedules",
+			on: `on:
+  schedule:
+    - cron: '* * * * *'
+    - cron: '*/3 0 * * *'
+    - cron: '0,58 * * * *'
+    - cron: '0,58 1,3 * * *'
+    - cron: '0,58 0,23 * * *'
+`,
+			want: []string{
+				`scheduled job runs too frequently. it runs once per 1 minutes with "* * * * *"`,
+				`it runs once per 3 minutes`,
+				`it runs once per 2 minutes with "0,58 * * * *"`,
+				`it runs once per 2 minutes with "0,58 0,23 * * *"`,
+			},
+		},
+		{
+			name: "workflow_dispatch inputs",
+			on: `on:
+  workflow_dispatch:
+    inputs:
+      a:
+        type: choice
+        options: [dev, prod, dev]
+        default: stg
+      b:
+        type: boolean
+        default: yes
+      c:
+        type: number
+        default: abc
+      d:
+        default: x
+        options: [x]
+      e:
+        type: choice
+`,
+			want: []string{
+				`6:30: option "dev" of input "a" is duplicated. The same option is at line:6,col:19`,
+				`7:18: default value "stg" of input "a" is not included in its options "dev", "prod"`,
+				`default value "yes" of input "b" must be "true" or "false"`,
+				`default value "abc" of input "c" must be a number`,
+				`"options" is set for input "d" of workflow_dispatch event but it can only be used with "choice" type`,
+				`input "e" of workflow_dispatch event has no options`,
+			},
+		},
+		{
+			name: "duplicate repository_dispatch types",
+			on: `on:
+  repository_dispatch:
+    types: [deploy, deploy]
+`,
+			want: []string{`activity type "deploy" of "repository_dispatch" event is duplicated`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			errs := runEventsRule(t, tc.on)
+			if len(errs) != len(tc.want) {
+				t.Fatalf("want %d errors, got %v", len(tc.want), errs)
+			}
+			for i, want := range tc.want {
+				got := errs[i].Error()
+				if !strings.Contains(got, want) {
+					t.Errorf("error %d %q does not contain %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCronScheduleMinInterval(t *testing.T) {
+	t.Parallel()
+
+	for spec, want := range map[string]int{
+		"0 * * * *":        60,
+		"*/15 * * * *":     15,
+		"5,10 * * * *":     5,
+		"0,30 9 * * *":     30,
+		"10,50 9-10 * * *": 20,
+		"10,50 9,11 * * *": 40,
+		"5/20 * * * *":     20,
+		"0 0 1 jan sun":    60,
+	} {
+		c, err := parseCron(spec)
+		if err != nil {
+			t.Errorf("parseCron(%q) error = %v", spec, err)
+			continue
+		}
+		if got := c.minInterval(); got != want {
+			t.Errorf("minInterval of %q = %d, want %d", spec, got, want)
+		}
+	}
+}
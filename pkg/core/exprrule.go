@@ -36,6 +36,7 @@ func ExpressionRule(actionsCache *LocalActionsMetadataCache, workflowsCache *Loc
 		BaseRule: BaseRule{
 			RuleName: "expression",
 			RuleDesc: "Checks for syntax errors in expressions ${{ }} syntax",
+			severity: SeverityMedium,
 		},
 		MatrixType:          nil,
 		StepsType:           nil,
@@ -0,0 +1,368 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fixDiffContext is the number of unchanged lines around each hunk, the same
+// default as diff -u and git diff.
+const fixDiffContext = 3
+
+// maxDiffCells bounds the LCS table. Larger regions are emitted as a single
+// delete/insert block, which is still a valid patch.
+const maxDiffCells = 4_000_000
+
+type diffOpKind byte
+
+const (
+	diffEqual  diffOpKind = ' '
+	diffDelete diffOpKind = '-'
+	diffInsert diffOpKind = '+'
+)
+
+type diffOp struct {
+	kind diffOpKind
+	line string
+}
+
+// diffHunk is a single "@@ ... @@" section of a unified diff. oldFrom/oldTo
+// are the 0-based half-open range of original lines it covers.
+type diffHunk struct {
+	oldFrom, oldTo int
+	newFrom, newTo int
+	ops            []diffOp
+	rules          []string
+}
+
+// fixDiff is the change auto-fixers made to one file, rendered as a unified
+// diff which can be applied with git apply or patch -p1.
+type fixDiff struct {
+	path  string
+	hunks []*diffHunk
+}
+
+// splitDiffLines splits b into lines keeping their line terminators so that a
+// missing newline at end of file is preserved in the diff.
+func splitDiffLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script from a to b. Common prefix and
+// suffix are stripped first since fixes usually touch a few lines only.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		ops = append(ops, diffOp{diffEqual, l})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{diffEqual, l})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	if len(a)*len(b) > maxDiffCells || len(a) == 0 || len(b) == 0 {
+		for _, l := range a {
+			ops = append(ops, diffOp{diffDelete, l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{diffInsert, l})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	w := len(b) + 1
+	lcs := make([]int, (len(a)+1)*w)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+			} else {
+				lcs[i*w+j] = max(lcs[(i+1)*w+j], lcs[i*w+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{diffEqual, a[i]})
+			i++
+			j++
+		case lcs[(i+1)*w+j] >= lcs[i*w+j+1]:
+			ops = append(ops, diffOp{diffDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{diffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{diffDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{diffInsert, b[j]})
+	}
+	return ops
+}
+
+// diffHunks groups the edit script into hunks with the given context lines.
+// Changes closer than 2*context lines are merged into one hunk.
+func diffHunks(ops []diffOp, context int) []*diffHunk {
+	var hunks []*diffHunk
+	var cur *diffHunk
+	oldLine, newLine := 0, 0
+	lastChange := -1
+	start := 0 // index into ops where cur starts
+
+	flush := func(end int) {
+		if cur == nil {
+			return
+		}
+		cur.ops = ops[start:end]
+		for _, op := range cur.ops {
+			if op.kind != diffInsert {
+				cur.oldTo++
+			}
+			if op.kind != diffDelete {
+				cur.newTo++
+			}
+		}
+		cur.oldTo += cur.oldFrom
+		cur.newTo += cur.newFrom
+		hunks = append(hunks, cur)
+		cur = nil
+	}
+
+	// Line numbers at each op index so a hunk can start in the middle.
+	oldAt := make([]int, len(ops)+1)
+	newAt := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldAt[i], newAt[i] = oldLine, newLine
+		if op.kind != diffInsert {
+			oldLine++
+		}
+		if op.kind != diffDelete {
+			newLine++
+		}
+	}
+	oldAt[len(ops)], newAt[len(ops)] = oldLine, newLine
+
+	for i, op := range ops {
+		if op.kind == diffEqual {
+			continue
+		}
+		if cur != nil && i-lastChange > 2*context {
+			flush(lastChange + context + 1)
+		}
+		if cur == nil {
+			start = max(0, i-context)
+			cur = &diffHunk{oldFrom: oldAt[start], newFrom: newAt[start]}
+		}
+		lastChange = i
+	}
+	if cur != nil {
+		flush(min(len(ops), lastChange+context+1))
+	}
+	return hunks
+}
+
+// newFixDiff returns the diff between the original and fixed source, or nil
+// when they are identical.
+func newFixDiff(path string, before, after []byte) *fixDiff {
+	hunks := diffHunks(diffLines(splitDiffLines(before), splitDiffLines(after)), fixDiffContext)
+	if len(hunks) == 0 {
+		return nil
+	}
+	return &fixDiff{path: path, hunks: hunks}
+}
+
+// fixDiffPath returns the path used in the "--- a/" and "+++ b/" headers:
+// slash separated and relative to the current directory when possible. Paths
+// outside the current directory lose their root so that the headers are not
+// "a//abs/path"; such a patch applies with -p1 from the root directory.
+func fixDiffPath(path string) string {
+	if filepath.IsAbs(path) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				path = rel
+			}
+		}
+	}
+	path = filepath.Clean(path)
+	if filepath.IsAbs(path) {
+		path = strings.TrimLeft(filepath.ToSlash(strings.TrimPrefix(path, filepath.VolumeName(path))), "/")
+	}
+	return filepath.ToSlash(path)
+}
+
+func hunkRange(from, to int) string {
+	n := to - from
+	start := from + 1
+	if n == 0 {
+		start = from
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// WriteTo writes the diff in unified format. The rule names which produced a
+// hunk follow its "@@" header; git apply and patch ignore that text.
+func (d *fixDiff) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", d.path, d.path)
+	for _, h := range d.hunks {
+		fmt.Fprintf(&b, "@@ -%s +%s @@", hunkRange(h.oldFrom, h.oldTo), hunkRange(h.newFrom, h.newTo))
+		if len(h.rules) > 0 {
+			b.WriteString(" " + strings.Join(h.rules, ", "))
+		}
+		b.WriteByte('\n')
+		for _, op := range h.ops {
+			b.WriteByte(byte(op.kind))
+			b.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// changeBlock is a maximal run of deleted/inserted lines in an edit script,
+// as the 0-based half-open range of the lines it replaces.
+type changeBlock struct {
+	from, to int
+}
+
+func changeBlocks(ops []diffOp) []changeBlock {
+	var blocks []changeBlock
+	var cur *changeBlock
+	oldLine := 0
+	for _, op := range ops {
+		if op.kind == diffEqual {
+			if cur != nil {
+				blocks = append(blocks, *cur)
+				cur = nil
+			}
+			oldLine++
+			continue
+		}
+		if cur == nil {
+			cur = &changeBlock{from: oldLine, to: oldLine}
+		}
+		if op.kind == diffDelete {
+			oldLine++
+			cur.to = oldLine
+		}
+	}
+	if cur != nil {
+		blocks = append(blocks, *cur)
+	}
+	return blocks
+}
+
+// fixAttribution tracks which rule's fixer produced which changes. record is
+// called after each fixer with the file as written at that point. Only the
+// difference to the previous state is attributed to the fixer's rule, and it
+// is mapped back to the lines of the original source.
+type fixAttribution struct {
+	source []string
+	prev   []string
+	owners []attributedBlock
+}
+
+type attributedBlock struct {
+	changeBlock
+	rule string
+}
+
+func newFixAttribution(source []byte) *fixAttribution {
+	lines := splitDiffLines(source)
+	return &fixAttribution{source: lines, prev: lines}
+}
+
+func (a *fixAttribution) record(rule string, current []byte) {
+	cur := splitDiffLines(current)
+	blocks := changeBlocks(diffLines(a.prev, cur))
+	if len(blocks) == 0 {
+		return
+	}
+
+	// start[k] is the source line at or after line k of the previous state
+	// and end[k] is the end of the source range line k corresponds to. Lines
+	// added by earlier fixers have an empty range at their insertion point.
+	start := make([]int, len(a.prev)+1)
+	end := make([]int, len(a.prev)+1)
+	k, srcLine := 0, 0
+	for _, op := range diffLines(a.source, a.prev) {
+		switch op.kind {
+		case diffEqual:
+			start[k], end[k] = srcLine, srcLine+1
+			k++
+			srcLine++
+		case diffDelete:
+			srcLine++
+		case diffInsert:
+			start[k], end[k] = srcLine, srcLine
+			k++
+		}
+	}
+	start[k], end[k] = srcLine, srcLine
+
+	for _, b := range blocks {
+		mapped := changeBlock{from: start[b.from], to: start[b.from]}
+		if b.to > b.from {
+			mapped.to = end[b.to-1]
+		}
+		a.owners = append(a.owners, attributedBlock{mapped, rule})
+	}
+	a.prev = cur
+}
+
+// annotate sets the rule names of each hunk of d.
+func (a *fixAttribution) annotate(d *fixDiff) {
+	for _, h := range d.hunks {
+		added := map[string]struct{}{}
+		for _, o := range a.owners {
+			overlaps := o.from < h.oldTo && o.to > h.oldFrom
+			if o.from == o.to {
+				overlaps = h.oldFrom <= o.from && o.from <= h.oldTo
+			}
+			if !overlaps {
+				continue
+			}
+			if _, ok := added[o.rule]; ok {
+				continue
+			}
+			added[o.rule] = struct{}{}
+			h.rules = append(h.rules, o.rule)
+		}
+	}
+}
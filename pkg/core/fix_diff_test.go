@@ -0,0 +1,196 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixDiff_UnifiedFormat(t *testing.T) {
+	t.Parallel()
+
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	d := newFixDiff(".github/workflows/ci.yml", []byte(before), []byte(after))
+	if d == nil {
+		t.Fatal("diff should not be empty")
+	}
+	d.hunks[0].rules = []string{"rule-a"}
+	var b strings.Builder
+	if _, err := d.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `--- a/.github/workflows/ci.yml
++++ b/.github/workflows/ci.yml
+@@ -1,5 +1,5 @@ rule-a
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFixDiff_NoNewlineAtEOF(t *testing.T) {
+	t.Parallel()
+
+	d := newFixDiff("x.yml", []byte("a\nb"), []byte("a\nc\n"))
+	var b strings.Builder
+	if _, err := d.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "--- a/x.yml\n+++ b/x.yml\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestFixDiff_Identical(t *testing.T) {
+	t.Parallel()
+
+	if d := newFixDiff("x.yml", []byte("a\n"), []byte("a\n")); d != nil {
+		t.Errorf("identical sources should have no diff, got %+v", d)
+	}
+}
+
+func TestFixAttribution_PerHunk(t *testing.T) {
+	t.Parallel()
+
+	var src strings.Builder
+	for i := 0; i < 20; i++ {
+		src.WriteString("line\n")
+	}
+	source := []byte(src.String())
+	lines := splitDiffLines(source)
+
+	first := append([]string{}, lines...)
+	first[1] = "first\n"
+	second := append([]string{}, first...)
+	second[18] = "second\n"
+
+	a := newFixAttribution(source)
+	a.record("rule-a", []byte(strings.Join(first, "")))
+	a.record("rule-b", []byte(strings.Join(second, "")))
+	d := newFixDiff("x.yml", source, []byte(strings.Join(second, "")))
+	a.annotate(d)
+
+	if len(d.hunks) != 2 {
+		t.Fatalf("want 2 hunks, got %d", len(d.hunks))
+	}
+	if got := strings.Join(d.hunks[0].rules, ","); got != "rule-a" {
+		t.Errorf("first hunk rules = %q, want rule-a", got)
+	}
+	if got := strings.Join(d.hunks[1].rules, ","); got != "rule-b" {
+		t.Errorf("second hunk rules = %q, want rule-b", got)
+	}
+}
+
+func TestRunAutofix_PatchAppliesWithGit(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	root := makeTestProject(t)
+	path := filepath.Join(root, ".github", "workflows", "ci.yml")
+	src := `# CI
+on: push
+
+jobs:
+  build:
+    runs-on: ubuntu-latest   # runner
+    steps:
+      - run: echo "${{ github.event.head_commit.message }}"
+`
+	writeTestFile(t, path, src)
+
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := linter.LintFiles([]string{path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		r.FilePath = ".github/workflows/ci.yml"
+	}
+
+	var patch bytes.Buffer
+	cmd := &Command{Stdout: io.Discard, Stderr: io.Discard}
+	cmd.runAutofix(results, FileFixPatch, root, &patch, nil)
+	if !strings.HasPrefix(patch.String(), "--- a/.github/workflows/ci.yml\n") {
+		t.Fatalf("unexpected patch:\n%s", patch.String())
+	}
+	if !strings.Contains(patch.String(), "code-injection-medium") {
+		t.Errorf("hunk should name the fixing rule:\n%s", patch.String())
+	}
+	if got, _ := os.ReadFile(path); string(got) != src {
+		t.Fatal("patch mode must not modify the workflow")
+	}
+
+	patchFile := filepath.Join(t.TempDir(), "fixes.patch")
+	writeTestFile(t, patchFile, patch.String())
+	apply := exec.Command("git", "apply", patchFile)
+	apply.Dir = root
+	if out, err := apply.CombinedOutput(); err != nil {
+		t.Fatalf("git apply failed: %v\n%s\npatch:\n%s", err, out, patch.String())
+	}
+	got, _ := os.ReadFile(path)
+	if !strings.Contains(string(got), "# runner") || !strings.Contains(string(got), "env:") {
+		t.Errorf("patched workflow lost formatting or fix:\n%s", got)
+	}
+}
+
+func TestFixDiffPath_OutsideWorkingDirectory(t *testing.T) {
+	t.Parallel()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fixDiffPath(filepath.Join(wd, ".github", "workflows", "ci.yml")), ".github/workflows/ci.yml"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	outside := filepath.Join(filepath.Dir(wd), "other", "ci.yml")
+	got := fixDiffPath(outside)
+	if strings.HasPrefix(got, "/") || filepath.IsAbs(got) {
+		t.Errorf("path outside the working directory should not be absolute: %q", got)
+	}
+	if want := strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(outside, filepath.VolumeName(outside))), "/"); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestCommand_FixPatchPrintsFindingsToStderr(t *testing.T) {
+	t.Parallel()
+
+	root := makeTestProject(t)
+	path := filepath.Join(root, ".github", "workflows", "ci.yml")
+	writeTestFile(t, path, "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    timeout-minutes: 5\n    steps:\n      - run: echo \"${{ github.event.head_commit.message }}\"\n")
+
+	var stdout, stderr bytes.Buffer
+	cmd := &Command{Stdin: strings.NewReader(""), Stdout: &stdout, Stderr: &stderr}
+	if status := cmd.Main([]string{"sisakulint", "-fix", "patch", path}); status != ExitStatusSuccessProblemFound {
+		t.Fatalf("unexpected exit status %d: %s", status, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "--- a/") || strings.Contains(stdout.String(), "code-injection-medium]") {
+		t.Errorf("stdout should only hold the patch:\n%s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "code-injection-medium]") {
+		t.Errorf("findings should be printed to stderr:\n%s", stderr.String())
+	}
+}
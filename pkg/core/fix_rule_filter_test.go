@@ -0,0 +1,108 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFixRuleFilter_Allows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		rule    string
+		want    bool
+	}{
+		{name: "no filter", rule: "commit-sha", want: true},
+		{name: "included", include: []string{"commit-sha"}, rule: "commit-sha", want: true},
+		{name: "not included", include: []string{"commit-sha"}, rule: "credentials", want: false},
+		{name: "glob included", include: []string{"code-injection-*"}, rule: "code-injection-critical", want: true},
+		{name: "excluded", exclude: []string{"commit-sha"}, rule: "commit-sha", want: false},
+		{name: "exclude wins", include: []string{"code-injection-*"}, exclude: []string{"code-injection-medium"}, rule: "code-injection-medium", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			f, err := newFixRuleFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.allows(tt.rule); got != tt.want {
+				t.Errorf("allows(%q) = %v, want %v", tt.rule, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := newFixRuleFilter([]string{"["}, nil); err == nil {
+		t.Error("malformed pattern should be rejected")
+	}
+}
+
+func TestFixRuleFilter_RejectsPatternsMatchingNoRule(t *testing.T) {
+	t.Parallel()
+
+	names := builtinRuleNames()
+	for _, tc := range []struct {
+		include, exclude []string
+		want             string
+	}{
+		{include: []string{"comit-sha"}, want: `pattern "comit-sha" passed to -fix-rule matches no rule`},
+		{exclude: []string{"code-injecton-*"}, want: `pattern "code-injecton-*" passed to -fix-exclude-rule`},
+		{include: []string{"commit-sha", "code-injection-*"}, exclude: []string{"missing-timeout-minutes"}},
+	} {
+		f, err := newFixRuleFilter(tc.include, tc.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.checkKnownRules(names)
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("known patterns %v %v should be accepted: %v", tc.include, tc.exclude, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("want error containing %q, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestRunAutofix_ReportsSkippedFixesPerRule(t *testing.T) {
+	t.Parallel()
+
+	src := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.head_commit.message }}"
+      - run: echo "${{ github.event.head_commit.author.name }}"
+`
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := linter.Lint("test.yaml", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := newFixRuleFilter(nil, []string{"code-injection-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := &Command{Stdout: &stdout, Stderr: &stderr}
+	cmd.runAutofix([]*ValidateResult{result}, FileFixDryRun, "", &stdout, filter)
+
+	if strings.Contains(stdout.String(), "code-injection-medium") {
+		t.Errorf("excluded fixers should not run:\n%s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "  code-injection-medium: 2\n") {
+		t.Errorf("skipped fixes should be reported per rule, got:\n%s", stderr.String())
+	}
+}
@@ -3,10 +3,12 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 
 	"github.com/google/go-github/v68/github"
+	"github.com/sisaku-security/sisakulint/pkg/remote"
 	"golang.org/x/oauth2"
 )
 
@@ -43,6 +45,44 @@ func NewGitHubClient(ctx context.Context, token string) *github.Client {
 	return github.NewClient(oauth2.NewClient(ctx, src))
 }
 
+// ResolveGitHubAPIURL returns the REST API base URL of the GitHub instance
+// to talk to: override (-github-api-url) first, then GITHUB_API_URL which
+// Actions runners set on GitHub.com and GitHub Enterprise Server alike. The
+// result is normalized, and "" means GitHub.com.
+func ResolveGitHubAPIURL(override string, lookup func(string) (string, bool)) (string, error) {
+	if override != "" {
+		return remote.NormalizeAPIURL(override)
+	}
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	if v, ok := lookup("GITHUB_API_URL"); ok && v != "" {
+		apiURL, err := remote.NormalizeAPIURL(v)
+		if err != nil {
+			return "", fmt.Errorf("GITHUB_API_URL: %w", err)
+		}
+		return apiURL, nil
+	}
+	return "", nil
+}
+
+// NewGitHubClientWithAPIURL is NewGitHubClient for the GitHub Enterprise
+// Server whose API is at apiURL, as returned by ResolveGitHubAPIURL. An empty
+// apiURL means GitHub.com. An invalid apiURL is an error rather than a
+// fallback to GitHub.com, which would query the wrong instance and could send
+// an enterprise token to GitHub.com.
+func NewGitHubClientWithAPIURL(ctx context.Context, token, apiURL string) (*github.Client, error) {
+	client := NewGitHubClient(ctx, token)
+	if apiURL == "" {
+		return client, nil
+	}
+	enterprise, err := client.WithEnterpriseURLs(apiURL, apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: %w", apiURL, err)
+	}
+	return enterprise, nil
+}
+
 var ErrGitHubRateLimit = errors.New("github api rate limit exceeded")
 
 // IsGitHubRateLimitError covers both go-github's typed primary/secondary
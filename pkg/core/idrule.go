@@ -23,6 +23,7 @@ func IDRule() *RuleID {
 		BaseRule: BaseRule{
 			RuleName: "id",
 			RuleDesc: "Checks for duplication and naming convention of job/step IDs",
+			severity: SeverityLow,
 		},
 		seen: make(map[string]*ast.Position),
 	}
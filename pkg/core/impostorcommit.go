@@ -14,7 +14,6 @@ import (
 
 	"github.com/google/go-github/v68/github"
 	"github.com/sisaku-security/sisakulint/pkg/ast"
-	"golang.org/x/oauth2"
 )
 
 // Pagination limits for GitHub API requests
@@ -66,7 +65,12 @@ func isGitHubRateLimitErr(err error) bool {
 
 type ImpostorCommitRule struct {
 	BaseRule
+	// gitHubToken falls back to GITHUB_TOKEN when empty. gitHubAPIURL is the
+	// API of a GitHub Enterprise Server, or empty for GitHub.com.
+	gitHubToken          string
+	gitHubAPIURL         string
 	client               *github.Client
+	clientErr            error
 	clientOnce           sync.Once
 	commitCache          map[string]*commitVerificationResult
 	commitCacheMu        sync.Mutex
@@ -91,6 +95,7 @@ func ImpostorCommitRuleFactory() *ImpostorCommitRule {
 		BaseRule: BaseRule{
 			RuleName: "impostor-commit",
 			RuleDesc: "Detects impostor commits that exist in the fork network but not in the repository's branches or tags",
+			severity: SeverityCritical,
 		},
 		commitCache:        make(map[string]*commitVerificationResult),
 		tagCache:           make(map[string][]*github.RepositoryTag),
@@ -118,21 +123,17 @@ func parseImpostorActionRef(usesValue string) (owner, repo, ref string, skip boo
 	return owner, repo, ref, false
 }
 
-func (rule *ImpostorCommitRule) getGitHubClient() *github.Client {
+func (rule *ImpostorCommitRule) getGitHubClient() (*github.Client, error) {
 	rule.clientOnce.Do(func() {
 		// Check for GITHUB_TOKEN environment variable for authenticated requests
 		// Authenticated requests have higher rate limits (5000/hour vs 60/hour)
-		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-			ts := oauth2.StaticTokenSource(
-				&oauth2.Token{AccessToken: token},
-			)
-			tc := oauth2.NewClient(context.Background(), ts)
-			rule.client = github.NewClient(tc)
-		} else {
-			rule.client = github.NewClient(http.DefaultClient)
+		token := rule.gitHubToken
+		if token == "" {
+			token = os.Getenv("GITHUB_TOKEN")
 		}
+		rule.client, rule.clientErr = NewGitHubClientWithAPIURL(context.Background(), token, rule.gitHubAPIURL)
 	})
-	return rule.client
+	return rule.client, rule.clientErr
 }
 
 func (rule *ImpostorCommitRule) VisitStep(step *ast.Step) error {
@@ -221,7 +222,10 @@ func (rule *ImpostorCommitRule) doVerifyCommit(owner, repo, sha string) *commitV
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	client := rule.getGitHubClient()
+	client, err := rule.getGitHubClient()
+	if err != nil {
+		return &commitVerificationResult{isImpostor: false, err: err}
+	}
 	repoKey := fmt.Sprintf("%s/%s", owner, repo)
 
 	tags, tagsErr := rule.getTags(ctx, client, owner, repo)
@@ -476,7 +480,10 @@ func (f *impostorCommitFixer) FixStep(step *ast.Step) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client := f.rule.getGitHubClient()
+	client, err := f.rule.getGitHubClient()
+	if err != nil {
+		return err
+	}
 	sha, _, err := client.Repositories.GetCommitSHA1(ctx, f.owner, f.repo, f.latestTag, "")
 	if err != nil {
 		return fmt.Errorf("failed to get commit SHA for tag %s: %w", f.latestTag, err)
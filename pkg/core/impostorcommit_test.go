@@ -335,8 +335,11 @@ func TestImpostorCommitRule_GetGitHubClient(t *testing.T) {
 	t.Parallel()
 	rule := ImpostorCommitRuleFactory()
 
-	client1 := rule.getGitHubClient()
-	client2 := rule.getGitHubClient()
+	client1, err := rule.getGitHubClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client2, _ := rule.getGitHubClient()
 
 	if client1 == nil {
 		t.Error("Expected client to be initialized, got nil")
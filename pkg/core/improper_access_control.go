@@ -62,6 +62,7 @@ func NewImproperAccessControlRule() *ImproperAccessControlRule {
 		BaseRule: BaseRule{
 			RuleName: "improper-access-control",
 			RuleDesc: "Detects improper access control in workflows using label-based approval with synchronize events",
+			severity: SeverityHigh,
 		},
 	}
 }
@@ -50,10 +50,15 @@ type VulnerabilityInfo struct {
 type KnownVulnerableActionsRule struct {
 	BaseRule
 	client        *github.Client
+	clientErr     error
 	clientOnce    sync.Once
 	gitHubToken   string
+	gitHubAPIURL  string
 	advisoryCache map[string][]*VulnerabilityInfo
 	cacheMu       sync.RWMutex
+	// advisoryDB, when set, replaces every GitHub API call so the rule works
+	// offline.
+	advisoryDB *AdvisoryDB
 }
 
 // NewKnownVulnerableActionsRule creates a new instance of KnownVulnerableActionsRule
@@ -66,24 +71,34 @@ func NewKnownVulnerableActionsRule(gitHubToken ...string) *KnownVulnerableAction
 		BaseRule: BaseRule{
 			RuleName: "known-vulnerable-actions",
 			RuleDesc: "Detects GitHub Actions with known security vulnerabilities using GitHub Security Advisories database.",
+			severity: SeverityHigh,
 		},
 		gitHubToken:   token,
 		advisoryCache: make(map[string][]*VulnerabilityInfo),
 	}
 }
 
+// NewKnownVulnerableActionsRuleWithAdvisoryDB creates the rule matching
+// against a local advisory database instead of the GitHub API. A nil db
+// falls back to the API.
+func NewKnownVulnerableActionsRuleWithAdvisoryDB(db *AdvisoryDB, gitHubToken string) *KnownVulnerableActionsRule {
+	rule := NewKnownVulnerableActionsRule(gitHubToken)
+	rule.advisoryDB = db
+	return rule
+}
+
 func getKnownVulnerableActionsGitHubToken(override string) string {
 	token, _ := ResolveGitHubToken(override, nil)
 	return token
 }
 
 // getClient initializes the GitHub client with authentication
-func (rule *KnownVulnerableActionsRule) getClient() *github.Client {
+func (rule *KnownVulnerableActionsRule) getClient() (*github.Client, error) {
 	rule.clientOnce.Do(func() {
 		token := getKnownVulnerableActionsGitHubToken(rule.gitHubToken)
-		rule.client = NewGitHubClient(context.Background(), token)
+		rule.client, rule.clientErr = NewGitHubClientWithAPIURL(context.Background(), token, rule.gitHubAPIURL)
 	})
-	return rule.client
+	return rule.client, rule.clientErr
 }
 
 func (rule *KnownVulnerableActionsRule) debugGitHubAPIError(format string, args ...interface{}) {
@@ -134,7 +149,10 @@ func isFullLengthCommitSHA(ref string) bool {
 
 // resolveTagFromCommitSHA resolves a commit SHA to the best matching tag
 func (rule *KnownVulnerableActionsRule) resolveTagFromCommitSHA(ctx context.Context, owner, repo, sha string) (string, error) {
-	gh := rule.getClient()
+	gh, err := rule.getClient()
+	if err != nil {
+		return "", err
+	}
 	opts := &github.ListOptions{
 		PerPage: 100,
 	}
@@ -167,7 +185,10 @@ func (rule *KnownVulnerableActionsRule) resolveTagFromCommitSHA(ctx context.Cont
 
 // resolveSymbolicRef resolves a symbolic reference (tag/branch) to a commit SHA
 func (rule *KnownVulnerableActionsRule) resolveSymbolicRef(ctx context.Context, owner, repo, ref string) (sha string, err error) {
-	gh := rule.getClient()
+	gh, err := rule.getClient()
+	if err != nil {
+		return "", err
+	}
 	commit, _, err := gh.Repositories.GetCommitSHA1(ctx, owner, repo, ref, "")
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve ref %s: %w", ref, err)
@@ -209,6 +230,9 @@ func (rule *KnownVulnerableActionsRule) getVersionFromRef(ctx context.Context, o
 // fetchAdvisories fetches security advisories for a specific action
 func (rule *KnownVulnerableActionsRule) fetchAdvisories(ctx context.Context, owner, repo, version string) ([]*VulnerabilityInfo, error) {
 	packageName := fmt.Sprintf("%s/%s", owner, repo)
+	if rule.advisoryDB != nil {
+		return rule.filterVulnerableVersions(rule.advisoryDB.advisoriesFor(packageName), version), nil
+	}
 
 	// Check cache first
 	rule.cacheMu.RLock()
@@ -218,7 +242,10 @@ func (rule *KnownVulnerableActionsRule) fetchAdvisories(ctx context.Context, own
 		return rule.filterVulnerableVersions(cached, version), nil
 	}
 
-	gh := rule.getClient()
+	gh, err := rule.getClient()
+	if err != nil {
+		return nil, err
+	}
 	ecosystem := "actions"
 	opts := &github.ListGlobalSecurityAdvisoriesOptions{
 		Ecosystem: &ecosystem,
@@ -232,25 +259,8 @@ func (rule *KnownVulnerableActionsRule) fetchAdvisories(ctx context.Context, own
 
 	var vulns []*VulnerabilityInfo
 	for _, advisory := range advisories {
-		for _, vuln := range advisory.Vulnerabilities {
-			if vuln.Package == nil || vuln.Package.Name == nil {
-				continue
-			}
-			if *vuln.Package.Name != packageName {
-				continue
-			}
-
-			info := &VulnerabilityInfo{
-				GHSAID:          advisory.GetGHSAID(),
-				Severity:        advisory.GetSeverity(),
-				Summary:         advisory.GetSummary(),
-				VulnerableRange: vuln.GetVulnerableVersionRange(),
-				HTMLURL:         fmt.Sprintf("https://github.com/advisories/%s", advisory.GetGHSAID()),
-			}
-			if vuln.FirstPatchedVersion != nil {
-				info.FirstPatchedVersion = *vuln.FirstPatchedVersion
-			}
-			vulns = append(vulns, info)
+		for _, r := range advisoryVulnerabilities(advisory, packageName) {
+			vulns = append(vulns, r.vulnerabilityInfo())
 		}
 	}
 
@@ -437,6 +447,18 @@ func severityToLevel(severity string) int {
 	}
 }
 
+// advisorySeverity maps a GitHub advisory severity to a finding severity.
+// Advisories without a recognized severity are reported as high.
+func advisorySeverity(severity string) Severity {
+	if s, err := ParseSeverity(severity); err == nil {
+		return s
+	}
+	if strings.EqualFold(severity, "moderate") {
+		return SeverityMedium
+	}
+	return SeverityHigh
+}
+
 // VisitStep checks each step for actions with known vulnerabilities
 func (rule *KnownVulnerableActionsRule) VisitStep(step *ast.Step) error {
 	action, ok := step.Exec.(*ast.ExecAction)
@@ -459,7 +481,22 @@ func (rule *KnownVulnerableActionsRule) VisitStep(step *ast.Step) error {
 	ctx := context.Background()
 
 	// Resolve the version from the ref
-	version, err := rule.getVersionFromRef(ctx, owner, repo, ref)
+	var version string
+	var err error
+	if rule.advisoryDB != nil {
+		comment := ""
+		if action.Uses.BaseNode != nil {
+			comment = action.Uses.BaseNode.LineComment
+		}
+		v, ok := rule.advisoryDB.resolveVersion(owner+"/"+repo, ref, comment)
+		if !ok {
+			rule.Debug("could not resolve %s/%s@%s to a version offline; record its tags in the advisory database or add a version comment", owner, repo, ref)
+			return nil
+		}
+		version = v
+	} else {
+		version, err = rule.getVersionFromRef(ctx, owner, repo, ref)
+	}
 	if err != nil {
 		// Log as debug and skip (similar to commitsha.go pattern)
 		// This can happen for private repos, rate limiting, network errors, etc.
@@ -506,7 +543,8 @@ func (rule *KnownVulnerableActionsRule) VisitStep(step *ast.Step) error {
 			}
 		}
 
-		rule.Errorf(step.Pos,
+		rule.ErrorfWithSeverity(step.Pos,
+			advisorySeverity(vuln.Severity),
 			"Action '%s' has a known %s severity vulnerability (%s): %s.%s See: %s",
 			usesValue,
 			vuln.Severity,
@@ -585,7 +623,18 @@ func (f *KnownVulnerableActionsFixer) FixStep(step *ast.Step) error {
 		return nil
 	}
 
-	if isFullLengthCommitSHA(originalRef) {
+	if isFullLengthCommitSHA(originalRef) && f.rule.advisoryDB != nil {
+		pkg := owner + "/" + repo
+		newSHA, ok := f.rule.advisoryDB.tagSHA(pkg, "v"+patchedVersion)
+		if !ok {
+			newSHA, ok = f.rule.advisoryDB.tagSHA(pkg, patchedVersion)
+		}
+		if !ok {
+			return FormattedError(step.Pos, f.rule.RuleName, "failed to resolve patched version %s to commit SHA: tag not found in the advisory database at step '%s'", patchedVersion, step.String())
+		}
+		action.Uses.BaseNode.Value = fmt.Sprintf("%s/%s@%s", owner, repo, newSHA)
+		action.Uses.BaseNode.LineComment = "v" + patchedVersion
+	} else if isFullLengthCommitSHA(originalRef) {
 		ctx := context.Background()
 		// Try with "v" prefix first
 		newSHA, err := f.rule.resolveSymbolicRef(ctx, owner, repo, "v"+patchedVersion)
@@ -36,7 +36,10 @@ func TestKnownVulnerableActionsRuleUsesUnifiedGitHubTokenResolution(t *testing.T
 	defer srv.Close()
 
 	rule := NewKnownVulnerableActionsRule()
-	client := rule.getClient()
+	client, err := rule.getClient()
+	if err != nil {
+		t.Fatal(err)
+	}
 	baseURL, err := url.Parse(srv.URL + "/")
 	if err != nil {
 		t.Fatalf("url.Parse: %v", err)
@@ -70,7 +73,10 @@ func TestKnownVulnerableActionsRuleExplicitTokenOverridesEnvironment(t *testing.
 	defer srv.Close()
 
 	rule := NewKnownVulnerableActionsRule("flag-token")
-	client := rule.getClient()
+	client, err := rule.getClient()
+	if err != nil {
+		t.Fatal(err)
+	}
 	baseURL, err := url.Parse(srv.URL + "/")
 	if err != nil {
 		t.Fatalf("url.Parse: %v", err)
@@ -91,7 +97,7 @@ func TestKnownVulnerableActionsRuleExplicitTokenOverridesEnvironment(t *testing.
 }
 
 func TestMakeRulesPassesGitHubTokenToKnownVulnerableActionsRule(t *testing.T) {
-	rules := makeRules(".github/workflows/ci.yml", false, "flag-token", nil, nil, nil, nil, true, true)
+	rules := makeRules(".github/workflows/ci.yml", false, "flag-token", "", nil, nil, nil, nil, nil, true, true)
 
 	for _, rule := range rules {
 		known, ok := rule.(*KnownVulnerableActionsRule)
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -17,7 +18,9 @@ import (
 	"github.com/fatih/color"
 	"github.com/mattn/go-colorable"
 	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/remote"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sys/execabs"
 )
 
 // LogLevel は Linter インスタンスで使用されるログレベルを表す型
@@ -60,6 +63,7 @@ type LinterOptions struct {
 	// BoilerplateGenerationは、boilerplateを生成するためのディレクトリパス
 	BoilerplateGeneration string
 	// ShellcheckExecutableは、shellcheckを実行するための実行可能ファイル
+	// 空の場合はPATH上のshellcheckを使う。shellcheckルールは -enable-rule shellcheck で有効になる
 	ShellcheckExecutable string
 	// ErrorIgnorePatternsは、エラーをフィルタリングするための正規表現のリスト
 	ErrorIgnorePatterns []string
@@ -69,11 +73,15 @@ type LinterOptions struct {
 	BoilerplateFilePath string
 	// CustomErrorMessageFormatは、エラーメッセージをフォーマットするためのカスタムテンプレート
 	CustomErrorMessageFormat string
+	// FormatFixes computes the auto-fixes of findings when the format prints
+	// them, such as the SARIF fixes of {{sarif .}}. It runs every auto-fixer,
+	// some of which call the GitHub API, so it is off by default.
+	FormatFixes bool
 	// StdinInputFileNameは、標準入力から読み込む際のファイル名
 	StdinInputFileName string
 	// CurrentWorkingDirectoryPathは、現在の作業ディレクトリのパス
 	CurrentWorkingDirectoryPath string
-	//todo: OnCheckRulesModifiedは、チェックルールの追加や削除を行うフック
+	// OnCheckRulesModifiedは、チェックルールの追加や削除を行うフック。有効なルールが決まった後に呼ばれる
 	OnCheckRulesModified func([]Rule) []Rule
 	// IsRemoteは、リモートスキャンモードで実行されているかどうかを示すフラグ
 	// trueの場合、ローカルファイルシステムへのアクセスが不要なチェックをスキップする
@@ -84,6 +92,9 @@ type LinterOptions struct {
 	// GitHubToken は GitHub API を呼ぶルールに提示するトークン。
 	// 空文字なら未認証 60 req/h 制限が適用される。
 	GitHubToken string
+	// GitHubAPIURL is the REST API base URL of a GitHub Enterprise Server,
+	// such as https://github.example.com/api/v3. Empty means GitHub.com.
+	GitHubAPIURL string
 	// ReportFilePaths limits rendered findings to the listed repository-relative
 	// paths while still analyzing every file passed to LintFiles. This is used
 	// for pull-request scans: unchanged workflows provide cross-file context but
@@ -94,6 +105,21 @@ type LinterOptions struct {
 	// enable this because their fix response contract returns workflow targets
 	// only; silently modifying and then discarding a project file is unsafe.
 	DisableRepositoryFileAutoFixers bool
+	// BaselineFilePath is a baseline file written by -baseline-write. Findings
+	// recorded in it are dropped so that only new findings are reported.
+	BaselineFilePath string
+	// MinSeverity drops findings less severe than this severity. Empty means
+	// every finding is reported.
+	MinSeverity Severity
+	// AdvisoryDBPath is the local advisory database known-vulnerable-actions
+	// matches against instead of the GitHub API. See ReadAdvisoryDB.
+	AdvisoryDBPath string
+	// PolicyEngines evaluate the policies in .github/sisakulint/policies.
+	// They take precedence over the built-in engine for the same extension.
+	PolicyEngines []PolicyEngine
+	// OPAExecutable is the opa command which evaluates Rego policies. Empty
+	// means "opa" in PATH.
+	OPAExecutable string
 }
 
 // Linterは、workflowをlintするための構造体
@@ -116,9 +142,11 @@ type Linter struct {
 	boilerplateGeneration *Boiler
 	// errorFormatterは、エラーメッセージをカスタムフォーマットで出力するためのformatter
 	errorFormatter *ErrorFormatter
+	// formatFixes is LinterOptions.FormatFixes.
+	formatFixes bool
 	// currentWorkingDirectoryは、現在の作業ディレクトリのパス
 	currentWorkingDirectory string
-	//todo: modifyCheckRulesは、チェックルールを追加または削除するためのフック関数
+	// modifyCheckRulesは、チェックルールを追加または削除するためのフック関数
 	modifyCheckRules func([]Rule) []Rule
 	// isRemoteは、リモートスキャンモードで実行されているかどうかを示すフラグ
 	isRemote bool
@@ -127,6 +155,8 @@ type Linter struct {
 	// gitHubToken は GitHub API を呼ぶルールに提示するトークン。
 	// 空文字なら未認証 60 req/h 制限が適用される。
 	gitHubToken string
+	// gitHubAPIURL is the normalized LinterOptions.GitHubAPIURL.
+	gitHubAPIURL string
 	// reportFilePaths is the normalized set corresponding to
 	// LinterOptions.ReportFilePaths. nil means report every result.
 	reportFilePaths map[string]struct{}
@@ -138,6 +168,16 @@ type Linter struct {
 	// remoteActionsCache は lint 実行全体で共有する remote action.yml の
 	// キャッシュ。
 	remoteActionsCache *RemoteActionsMetadataCache
+	// baseline holds the known findings loaded from BaselineFilePath.
+	// nil means every finding is reported.
+	baseline *Baseline
+	// minSeverity mirrors LinterOptions.MinSeverity.
+	minSeverity Severity
+	// advisoryDB is loaded from LinterOptions.AdvisoryDBPath. nil means the
+	// GitHub API is queried.
+	advisoryDB *AdvisoryDB
+	// policyEngines evaluate the policies of projects, in order of precedence.
+	policyEngines []PolicyEngine
 }
 
 // NewLinterは新しいLinterインスタンスを作成する
@@ -190,6 +230,56 @@ func NewLinter(errorOutput io.Writer, options *LinterOptions) (*Linter, error) {
 		boiler = d
 	}
 
+	var minSeverity Severity
+	if options.MinSeverity != "" {
+		s, err := ParseSeverity(string(options.MinSeverity))
+		if err != nil {
+			return nil, err
+		}
+		minSeverity = s
+	}
+
+	var baseline *Baseline
+	if options.BaselineFilePath != "" {
+		b, err := ReadBaselineFile(options.BaselineFilePath)
+		if err != nil {
+			return nil, err
+		}
+		baseline = b
+	}
+
+	gitHubAPIURL, err := remote.NormalizeAPIURL(options.GitHubAPIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var advisoryDB *AdvisoryDB
+	if options.AdvisoryDBPath != "" {
+		db, err := ReadAdvisoryDB(options.AdvisoryDBPath)
+		if err != nil {
+			return nil, err
+		}
+		advisoryDB = db
+	}
+
+	policyEngines := append([]PolicyEngine{}, options.PolicyEngines...)
+	opaExecutable := options.OPAExecutable
+	if opaExecutable == "" {
+		opaExecutable = "opa"
+	}
+	if opa, err := NewOPAPolicyEngine(opaExecutable); err == nil {
+		policyEngines = append(policyEngines, opa)
+	} else if options.OPAExecutable != "" {
+		return nil, fmt.Errorf("invalid opa executable %q: %w", options.OPAExecutable, err)
+	}
+
+	shellcheckExecutable := options.ShellcheckExecutable
+	if shellcheckExecutable == "" {
+		shellcheckExecutable = "shellcheck"
+	} else if _, err := execabs.LookPath(shellcheckExecutable); err != nil {
+		return nil, fmt.Errorf("invalid shellcheck executable %q: %w", shellcheckExecutable, err)
+	}
+
 	ignorePatterns := make([]*regexp.Regexp, len(options.ErrorIgnorePatterns))
 	for i, pattern := range options.ErrorIgnorePatterns {
 		re, err := regexp.Compile(pattern)
@@ -221,6 +311,8 @@ func NewLinter(errorOutput io.Writer, options *LinterOptions) (*Linter, error) {
 	if logLevel >= LogLevelAllOutputIncludingDebug {
 		metadataDebug = logOutput
 	}
+	remoteActionsCache := NewRemoteActionsMetadataCache(metadataDebug)
+	remoteActionsCache.apiURL = gitHubAPIURL
 
 	var reportFilePaths map[string]struct{}
 	if len(options.ReportFilePaths) > 0 {
@@ -236,19 +328,25 @@ func NewLinter(errorOutput io.Writer, options *LinterOptions) (*Linter, error) {
 		errorOutput:                     errorOutput,
 		logOutput:                       logOutput,
 		loggingLevel:                    logLevel,
-		remoteActionsCache:              NewRemoteActionsMetadataCache(metadataDebug),
-		shellcheckExecutablePath:        options.ShellcheckExecutable,
+		remoteActionsCache:              remoteActionsCache,
+		shellcheckExecutablePath:        shellcheckExecutable,
 		errorIgnorePatterns:             ignorePatterns,
 		defaultConfiguration:            config,
 		boilerplateGeneration:           boiler,
 		errorFormatter:                  errorFormatter,
+		formatFixes:                     options.FormatFixes,
 		currentWorkingDirectory:         workDir,
 		modifyCheckRules:                options.OnCheckRulesModified,
 		isRemote:                        options.IsRemote,
 		enabledOptInRules:               options.EnabledOptInRules,
 		gitHubToken:                     options.GitHubToken,
+		gitHubAPIURL:                    gitHubAPIURL,
 		reportFilePaths:                 reportFilePaths,
 		disableRepositoryFileAutoFixers: options.DisableRepositoryFileAutoFixers,
+		baseline:                        baseline,
+		minSeverity:                     minSeverity,
+		advisoryDB:                      advisoryDB,
+		policyEngines:                   policyEngines,
 	}, nil
 }
 
@@ -268,6 +366,33 @@ func (l *Linter) shouldReportProjectFindings(path string) bool {
 	return l.shouldReport(path)
 }
 
+// applyBaseline drops the findings of result that are recorded in the
+// baseline. It must run after cross-file chain resolution so that chain
+// findings can be baselined too.
+func (l *Linter) applyBaseline(result *ValidateResult) {
+	if l.baseline == nil {
+		return
+	}
+	l.baseline.filter(result)
+}
+
+// resetBaselineRunState makes the baseline match against all of its entries
+// again, so that linting twice with the same Linter filters both runs.
+func (l *Linter) resetBaselineRunState() {
+	if l.baseline != nil {
+		l.baseline.reset()
+	}
+}
+
+// StaleBaselineEntries returns the baseline entries of the linted files that
+// no longer match any finding. It returns nil when no baseline is loaded.
+func (l *Linter) StaleBaselineEntries() []*BaselineEntry {
+	if l.baseline == nil {
+		return nil
+	}
+	return l.baseline.StaleEntries()
+}
+
 // logはlog levelがDetailedOutput以上の場合にログを出力する
 // pluralize returns singular when n == 1, otherwise plural. Used to render
 // human-readable log messages such as "1 yaml file" / "4 yaml files".
@@ -367,11 +492,48 @@ func (l *Linter) LintRepository(dir string) ([]*ValidateResult, error) {
 	}
 	l.log("Detected project:", project.RootDirectory())
 	workflowsDir := project.WorkflowDirectory()
-	return l.LintDir(workflowsDir, project)
+	actionFiles, err := findCompositeActionFiles(filepath.Join(project.RootDirectory(), ".github", "actions"))
+	if err != nil {
+		return nil, err
+	}
+	if len(actionFiles) == 0 {
+		return l.LintDir(workflowsDir, project)
+	}
+
+	files, err := collectYAMLFiles(workflowsDir)
+	if err != nil {
+		return nil, err
+	}
+	l.log("collected", len(actionFiles), pluralize(len(actionFiles), "action metadata file", "action metadata files"))
+	files = append(files, actionFiles...)
+	sort.Strings(files)
+	return l.LintFiles(files, project)
 }
 
-// LintDirは、指定されたディレクトリをLint
-func (l *Linter) LintDir(dir string, project *Project) ([]*ValidateResult, error) {
+// findCompositeActionFiles returns the action metadata files (action.yml or
+// action.yaml) of the local actions under dir. A missing dir is not an error.
+func findCompositeActionFiles(dir string) ([]string, error) {
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && (info.Name() == "action.yml" || info.Name() == "action.yaml") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("it could not read %q , failed to walk directory: %w", dir, err)
+	}
+	return files, nil
+}
+
+// collectYAMLFiles returns the YAML files under dir.
+func collectYAMLFiles(dir string) ([]string, error) {
 	// Preallocate files slice with a reasonable capacity for workflow files
 	files := make([]string, 0, 10)
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -386,6 +548,15 @@ func (l *Linter) LintDir(dir string, project *Project) ([]*ValidateResult, error
 	if err != nil {
 		return nil, fmt.Errorf("it could not read %q , failed to walk directory: %w", dir, err)
 	}
+	return files, nil
+}
+
+// LintDirは、指定されたディレクトリをLint
+func (l *Linter) LintDir(dir string, project *Project) ([]*ValidateResult, error) {
+	files, err := collectYAMLFiles(dir)
+	if err != nil {
+		return nil, err
+	}
 
 	if len(files) == 0 {
 		return nil, fmt.Errorf("no yaml files found in %q", dir)
@@ -414,7 +585,9 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 	}
 
 	resetKnownVulnerableActionsRunState()
+	resetCommitShaRunState()
 	resetDependabotEcosystemRunState()
+	l.resetBaselineRunState()
 
 	l.log("getting started linting", fileCount, pluralize(fileCount, "workflow file...", "workflow files..."))
 
@@ -425,9 +598,10 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 	reusableWorkflowCacheFactory := NewLocalReusableWorkflowCacheFactory(currentDir, debugLog)
 
 	type workspace struct {
-		path   string
-		result *ValidateResult
-		source []byte
+		path    string
+		result  *ValidateResult
+		source  []byte
+		project *Project
 	}
 
 	workspaces := make([]workspace, len(filepaths))
@@ -467,6 +641,7 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 			}
 			ws.source = source
 			ws.result = result
+			ws.project = localProject
 			return nil
 		})
 	}
@@ -489,6 +664,7 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 	for i := range workspaces {
 		ws := &workspaces[i]
 		l.postProcessResolvedChains(ws.path, ws.result)
+		l.applyBaseline(ws.result)
 	}
 
 	totalErrors := 0
@@ -508,9 +684,7 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 			if !l.shouldReport(ws.result.FilePath) {
 				continue
 			}
-			for _, err := range ws.result.Errors {
-				templateFields = append(templateFields, err.ExtractTemplateFields(ws.source))
-			}
+			templateFields = append(templateFields, l.templateFields(ws.result)...)
 			//allErrors = append(allErrors, ws.result.Errors...)
 			//allAutoFixers = append(allAutoFixers, ws.result.AutoFixers...)
 		}
@@ -545,7 +719,9 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 // projectパラメタはnilにできる。その場合、ファイルパスからプロジェクトが検出される
 func (l *Linter) LintFile(file string, project *Project) (*ValidateResult, error) {
 	resetKnownVulnerableActionsRunState()
+	resetCommitShaRunState()
 	resetDependabotEcosystemRunState()
+	l.resetBaselineRunState()
 
 	if project == nil {
 		pa, err := l.projectInformation.GetProjectForPath(file)
@@ -583,6 +759,7 @@ func (l *Linter) LintFile(file string, project *Project) (*ValidateResult, error
 		adapter := &workspaceAdapter{path: file, result: result}
 		localReusableWorkflow.ResolvePendingChains([]workspaceLike{adapter})
 		l.postProcessResolvedChains(file, result)
+		l.applyBaseline(result)
 	}
 
 	if err != nil {
@@ -590,7 +767,7 @@ func (l *Linter) LintFile(file string, project *Project) (*ValidateResult, error
 	}
 	if l.shouldReport(result.FilePath) {
 		if l.errorFormatter != nil {
-			if err := l.errorFormatter.PrintErrors(l.errorOutput, result.Errors, source); err != nil {
+			if err := l.errorFormatter.Print(l.errorOutput, l.templateFields(result)); err != nil {
 				return nil, fmt.Errorf("error formatting output: %w", err)
 			}
 		} else {
@@ -606,7 +783,9 @@ func (l *Linter) LintFile(file string, project *Project) (*ValidateResult, error
 // projectパラメタはnilにできる。その場合、ファイルパスからプロジェクトが検出される
 func (l *Linter) Lint(filepath string, content []byte, project *Project) (*ValidateResult, error) {
 	resetKnownVulnerableActionsRunState()
+	resetCommitShaRunState()
 	resetDependabotEcosystemRunState()
+	l.resetBaselineRunState()
 
 	if project == nil && filepath != "<stdin>" {
 		if _, err := os.Stat(filepath); !errors.Is(err, fs.ErrNotExist) {
@@ -618,25 +797,16 @@ func (l *Linter) Lint(filepath string, content []byte, project *Project) (*Valid
 		}
 	}
 
-	proc := NewConcurrentExecutor(runtime.NumCPU())
 	localActions := NewLocalActionsMetadataCache(project, l.debugWriter())
 	localReusableWorkflow := NewLocalReusableWorkflowCache(project, l.currentWorkingDirectory, l.debugWriter())
-	result, err := l.validate(filepath, content, project, proc, localActions, localReusableWorkflow)
-	proc.Wait()
-
-	if localReusableWorkflow != nil && result != nil {
-		adapter := &workspaceAdapter{path: filepath, result: result}
-		localReusableWorkflow.ResolvePendingChains([]workspaceLike{adapter})
-		l.postProcessResolvedChains(filepath, result)
-	}
-
+	result, err := l.lintWithCaches(filepath, content, project, localActions, localReusableWorkflow)
 	if err != nil {
 		return nil, err
 	}
 
 	if l.shouldReport(result.FilePath) {
 		if l.errorFormatter != nil {
-			if err := l.errorFormatter.PrintErrors(l.errorOutput, result.Errors, content); err != nil {
+			if err := l.errorFormatter.Print(l.errorOutput, l.templateFields(result)); err != nil {
 				return nil, fmt.Errorf("error formatting output: %w", err)
 			}
 		} else {
@@ -646,7 +816,24 @@ func (l *Linter) Lint(filepath string, content []byte, project *Project) (*Valid
 	return result, nil
 }
 
-func makeRules(filePath string, isRemote bool, gitHubToken string, localActions *LocalActionsMetadataCache, remoteActions *RemoteActionsMetadataCache, localReusableWorkflow *LocalReusableWorkflowCache, project *Project, reportProjectFindings bool, allowRepositoryFileAutoFixers bool) []Rule {
+// lintWithCaches validates content using the given caches and resolves the
+// cross-file chains recorded in localReusableWorkflow so far. Nothing is
+// printed. The LSP server calls this with caches kept across edits.
+func (l *Linter) lintWithCaches(path string, content []byte, project *Project, localActions *LocalActionsMetadataCache, localReusableWorkflow *LocalReusableWorkflowCache) (*ValidateResult, error) {
+	proc := NewConcurrentExecutor(runtime.NumCPU())
+	result, err := l.validate(path, content, project, proc, localActions, localReusableWorkflow)
+	proc.Wait()
+
+	if localReusableWorkflow != nil && result != nil {
+		adapter := &workspaceAdapter{path: path, result: result}
+		localReusableWorkflow.ResolvePendingChains([]workspaceLike{adapter})
+		l.postProcessResolvedChains(path, result)
+		l.applyBaseline(result)
+	}
+	return result, err
+}
+
+func makeRules(filePath string, isRemote bool, gitHubToken string, gitHubAPIURL string, advisoryDB *AdvisoryDB, localActions *LocalActionsMetadataCache, remoteActions *RemoteActionsMetadataCache, localReusableWorkflow *LocalReusableWorkflowCache, project *Project, reportProjectFindings bool, allowRepositoryFileAutoFixers bool) []Rule {
 	// WorkflowTaintMap is shared between Critical and Medium variants of
 	// CodeInjection, EnvVarInjection, ArgumentInjection, and RequestForgery rules
 	// to enable cross-job taint propagation tracking via needs.*.outputs.*
@@ -673,12 +860,31 @@ func makeRules(filePath string, isRemote bool, gitHubToken string, localActions
 		dependabotEcosystem.projectRoot = project.RootDirectory()
 	}
 
+	// Rules calling the GitHub API share the token and the API URL of a
+	// GitHub Enterprise Server.
+	commitSha := CommitShaRule(gitHubToken)
+	commitSha.gitHubAPIURL = gitHubAPIURL
+	commitSha.advisoryDB = advisoryDB
+	var lockfile *ActionLockfile
+	if project != nil && !isRemote {
+		lockfile = project.ActionLockfile()
+	}
+	commitSha.lockfile = lockfile
+	impostorCommit := ImpostorCommitRuleFactory()
+	impostorCommit.gitHubToken = gitHubToken
+	impostorCommit.gitHubAPIURL = gitHubAPIURL
+	refConfusion := NewRefConfusionRule()
+	refConfusion.gitHubToken = gitHubToken
+	refConfusion.gitHubAPIURL = gitHubAPIURL
+	knownVulnerableActions := NewKnownVulnerableActionsRuleWithAdvisoryDB(advisoryDB, gitHubToken)
+	knownVulnerableActions.gitHubAPIURL = gitHubAPIURL
+
 	return []Rule{
-		// MatrixRule(),
+		MatrixRule(),
 		CredentialsRule(),
-		// EventsRule(),
+		EventsRule(),
 		JobNeedsRule(),
-		// ActionRule(localActions),
+		ActionRule(actionMetadata),
 		EnvironmentVariableRule(),
 		IDRule(),
 		PermissionsRule(),
@@ -687,6 +893,7 @@ func makeRules(filePath string, isRemote bool, gitHubToken string, localActions
 		DeprecatedCommandsRule(),
 		NewConditionalRule(),
 		TimeoutMinuteRule(),
+		NewShellcheckRule(),                     // Checks run: scripts with shellcheck; its runner is set in validate()
 		CodeInjectionCriticalRule(wfTaintMap),   // Detects untrusted input in privileged workflow triggers
 		CodeInjectionMediumRule(wfTaintMap),     // Detects untrusted input in normal workflow triggers
 		EnvVarInjectionCriticalRule(wfTaintMap), // Detects envvar injection in privileged workflow triggers
@@ -695,7 +902,8 @@ func makeRules(filePath string, isRemote bool, gitHubToken string, localActions
 		EnvPathInjectionMediumRule(),            // Detects PATH injection in normal workflow triggers
 		OutputClobberingCriticalRule(),          // Detects output clobbering in privileged workflow triggers
 		OutputClobberingMediumRule(),            // Detects output clobbering in normal workflow triggers
-		CommitShaRule(gitHubToken),
+		commitSha,
+		NewActionLockfileRule(lockfile),   // Checks pinned SHAs against .github/sisakulint.lock
 		dependabotGitHubActions,           // Checks dependabot.yaml has github-actions ecosystem when unpinned actions found
 		dependabotEcosystem,               // Checks dependabot config covers ecosystems from lockfiles and setup actions
 		NewDependencyReviewSettingsRule(), // Checks dependency-review-action settings against required permissions
@@ -708,12 +916,12 @@ func makeRules(filePath string, isRemote bool, gitHubToken string, localActions
 		NewSecretExposureRule(),                                       // Detects toJSON(secrets) and secrets[dynamic-access]
 		NewUnmaskedSecretExposureRule(),                               // Detects fromJson(secrets.XXX).yyy unmasked exposure
 		NewImproperAccessControlRule(),                                // Detects improper access control with label-based approval and synchronize events
-		ImpostorCommitRuleFactory(),                                   // Detects impostor commits from fork network
+		impostorCommit,                                                // Detects impostor commits from fork network
 		NewUntrustedCheckoutTOCTOUCriticalRule(),                      // Detects TOCTOU with labeled event type and mutable refs
 		NewUntrustedCheckoutTOCTOUHighRule(),                          // Detects TOCTOU with deployment environment and mutable refs
-		NewRefConfusionRule(),                                         // Detects ref confusion attacks (same name branch and tag)
+		refConfusion,                                                  // Detects ref confusion attacks (same name branch and tag)
 		NewObfuscationRule(),                                          // Detects obfuscated workflow patterns
-		NewKnownVulnerableActionsRule(gitHubToken),                    // Detects actions with known security vulnerabilities
+		knownVulnerableActions,                                        // Detects actions with known security vulnerabilities
 		NewBotConditionsRule(),                                        // Detects spoofable bot detection conditions
 		NewArtipackedRule(),                                           // Detects credential leakage via artifact upload
 		NewUnsoundContainsRule(),                                      // Detects bypassable contains() function usage in conditions
@@ -755,6 +963,12 @@ type ValidateResult struct {
 	Errors         []*LintingError
 	AutoFixers     []AutoFixer
 	Repository     string
+	// overrides holds the "rules:" configuration applied to the file.
+	overrides *ruleOverrides
+	// suppressions holds the sisakulint-disable directives of the file so
+	// that findings added after validate() (cross-file chains) are filtered
+	// consistently.
+	suppressions *suppressionSet
 }
 
 // isDependabotConfigFile checks if the given filepath is a dependabot configuration file
@@ -764,10 +978,57 @@ func isDependabotConfigFile(filePath string) bool {
 		strings.HasSuffix(normalized, ".github/dependabot.yaml")
 }
 
-// isCompositeActionFile checks if the given content is a composite action file.
-// Composite actions (e.g. .github/workflows/my-action/action.yml) have a top-level
-// "runs:" key, which is absent from workflow files. Applying workflow-level validation
-// to these files produces false positives about missing "on" and "jobs" keys.
+// compositeActionRules is the set of rules run on the steps of composite actions.
+// Rules checking workflow-level or job-level configuration (triggers, permissions,
+// runners, timeouts, ...) are excluded since composite actions have none.
+var compositeActionRules = map[string]struct{}{
+	"deprecated-commands":         {},
+	"code-injection-critical":     {},
+	"code-injection-medium":       {},
+	"envvar-injection-critical":   {},
+	"envvar-injection-medium":     {},
+	"envpath-injection-critical":  {},
+	"envpath-injection-medium":    {},
+	"output-clobbering-critical":  {},
+	"output-clobbering-medium":    {},
+	"argument-injection-critical": {},
+	"argument-injection-medium":   {},
+	"request-forgery-critical":    {},
+	"request-forgery-medium":      {},
+	"commit-sha":                  {},
+	"action-list":                 {},
+	"impostor-commit":             {},
+	"known-vulnerable-actions":    {},
+	"archived-uses":               {},
+	"deprecated-node-runtime":     {},
+	"obfuscation":                 {},
+	"unsound-contains":            {},
+	"secret-exposure":             {},
+	"unmasked-secret-exposure":    {},
+	"secrets-in-artifacts":        {},
+	"secret-exfiltration":         {},
+	"secret-in-log":               {},
+	"artipacked":                  {},
+	"shellcheck":                  {},
+	"action":                      {},
+}
+
+// filterCompositeActionRules keeps the rules in compositeActionRules.
+func filterCompositeActionRules(rules []Rule) []Rule {
+	out := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		if _, ok := compositeActionRules[r.RuleNames()]; ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// isCompositeActionFile checks if the given content is an action metadata file.
+// Actions (e.g. .github/actions/my-action/action.yml) have a top-level "runs:" key,
+// which is absent from workflow files. Applying workflow-level validation to these
+// files produces false positives about missing "on" and "jobs" keys, so they are
+// parsed with ParseCompositeAction instead.
 func isCompositeActionFile(content []byte) bool {
 	for _, line := range strings.Split(string(content), "\n") {
 		trimmed := strings.TrimSpace(line)
@@ -787,7 +1048,7 @@ func (l *Linter) validate(
 	filePath string,
 	content []byte,
 	project *Project,
-	_ *ConcurrentExecutor, // proc parameter is unused
+	proc *ConcurrentExecutor,
 	localActions *LocalActionsMetadataCache,
 	localReusableWorkflow *LocalReusableWorkflowCache,
 ) (*ValidateResult, error) {
@@ -796,18 +1057,72 @@ func (l *Linter) validate(
 		validationStart = time.Now()
 	}
 
+	var cfg *Config
+	if l.defaultConfiguration != nil {
+		cfg = l.defaultConfiguration
+	} else if project != nil {
+		cfg = project.ProjectConfig()
+	}
+	if cfg != nil {
+		if _, dup := l.loggedConfigs.LoadOrStore(cfg, struct{}{}); !dup {
+			l.debug("setting configuration: %v", cfg)
+		}
+	} else {
+		if _, dup := l.loggedConfigs.LoadOrStore((*Config)(nil), struct{}{}); !dup {
+			l.debug("no configuration file")
+		}
+	}
+
 	// Validate -enable-rule names early so unknown names surface regardless
 	// of the file type or whether parsing later succeeds. Without this, a
 	// dependabot config / composite action / unparseable workflow would
 	// silently skip the rule-name check and the user's CLI typo would not
 	// be reported until a parseable workflow happened to reach validate().
-	rules := makeRules(filePath, l.isRemote, l.gitHubToken, localActions, l.remoteActionsCache, localReusableWorkflow, project, l.shouldReportProjectFindings(filePath), !l.disableRepositoryFileAutoFixers)
-	filteredRules, optErr := applyOptInRules(rules, l.enabledOptInRules)
+	rules := makeRules(filePath, l.isRemote, l.gitHubToken, l.gitHubAPIURL, l.advisoryDB, localActions, l.remoteActionsCache, localReusableWorkflow, project, l.shouldReportProjectFindings(filePath), !l.disableRepositoryFileAutoFixers)
+	if cfg != nil {
+		// Custom rules join the built-in ones before the "rules:" section and
+		// suppression comments are applied, so that both can refer to them.
+		custom, err := makeCustomRules(cfg.CustomRules, ruleNamesOf(rules))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, custom...)
+	}
+	knownRuleNames := ruleNamesOf(rules)
+	var policies []*Policy
+	if project != nil && !l.isRemote {
+		policies = l.selectPolicies(project.Policies())
+		if err := checkPolicyNames(policies, knownRuleNames); err != nil {
+			return nil, err
+		}
+		knownRuleNames = append(knownRuleNames, policyNames(policies)...)
+	}
+	enabledOptInRules := l.enabledOptInRules
+	if cfg != nil && cfg.TimeoutMinutes.isSet() {
+		// Configuring the rule is asking for it, so the config is not
+		// silently ignored without -enable-rule.
+		enabledOptInRules = append(slices.Clone(enabledOptInRules), "missing-timeout-minutes")
+	}
+	filteredRules, optErr := applyOptInRules(rules, enabledOptInRules)
 	if optErr != nil {
 		return nil, optErr
 	}
 	rules = filteredRules
 
+	// The "rules:" section is validated here for the same reason as
+	// -enable-rule: a typo in a rule name must not depend on the file type.
+	var overrides *ruleOverrides
+	if cfg != nil {
+		configured, o, err := applyRuleConfigs(rules, knownRuleNames, cfg.Rules, ruleConfigPath(filePath, project))
+		if err != nil {
+			return nil, err
+		}
+		rules, overrides = configured, o
+	}
+	if l.modifyCheckRules != nil {
+		rules = l.modifyCheckRules(rules)
+	}
+
 	// Check if this is a dependabot configuration file
 	if isDependabotConfigFile(filePath) {
 		l.log("validating dependabot config...", filePath)
@@ -822,46 +1137,32 @@ func (l *Linter) validate(
 		}, nil
 	}
 
-	// Check if this is a composite action file (e.g. .github/workflows/my-action/action.yml).
-	// Composite actions use "runs:" at the top level instead of "on:"/"jobs:", so applying
-	// workflow-level validation produces false positives.
-	if isCompositeActionFile(content) {
-		l.log("skipping composite action file (not a workflow)...", filePath)
-		return &ValidateResult{
-			FilePath:       filePath,
-			Source:         content,
-			ParsedWorkflow: nil,
-			Errors:         nil,
-			AutoFixers:     nil,
-		}, nil
+	// Check if this is an action metadata file (e.g. .github/actions/my-action/action.yml).
+	// Actions use "runs:" at the top level instead of "on:"/"jobs:", so only the steps of
+	// composite actions are linted, with the step-level rules.
+	compositeAction := isCompositeActionFile(content)
+	if compositeAction {
+		l.log("validating composite action...", filePath)
+	} else {
+		l.log("validating workflow...", filePath)
 	}
 
-	l.log("validating workflow...", filePath)
-
-	var cfg *Config
-	if l.defaultConfiguration != nil {
-		cfg = l.defaultConfiguration
-	} else if project != nil {
-		cfg = project.ProjectConfig()
-	}
-	if cfg != nil {
-		if _, dup := l.loggedConfigs.LoadOrStore(cfg, struct{}{}); !dup {
-			l.debug("setting configuration: %v", cfg)
-		}
+	var parsedWorkflow *ast.Workflow
+	var allErrors []*LintingError
+	if compositeAction {
+		parsedWorkflow, allErrors = ParseCompositeAction(content)
+		rules = filterCompositeActionRules(rules)
 	} else {
-		if _, dup := l.loggedConfigs.LoadOrStore((*Config)(nil), struct{}{}); !dup {
-			l.debug("no configuration file")
-		}
+		parsedWorkflow, allErrors = Parse(content)
 	}
 
-	parsedWorkflow, allErrors := Parse(content)
-
 	if l.loggingLevel >= LogLevelDetailedOutput {
 		elapsed := time.Since(validationStart)
 		l.log(fmt.Sprintf("parsed workflow in %dms", elapsed.Milliseconds()), filePath)
 	}
 
 	var allAutoFixers []AutoFixer
+	var activeRuleNames []string
 
 	if parsedWorkflow != nil {
 		dbg := l.debugWriter()
@@ -869,6 +1170,10 @@ func (l *Linter) validate(
 		// rules were built and filtered at the top of validate() so that
 		// rule-name validation runs even when this branch is skipped.
 
+		if err := l.setShellcheckRunner(rules, proc); err != nil {
+			return nil, err
+		}
+
 		v := NewSyntaxTreeVisitor()
 		for _, rule := range rules {
 			v.AddVisitor(rule)
@@ -889,6 +1194,17 @@ func (l *Linter) validate(
 			l.debug("error occurred while visiting syntax tree: %v", err)
 			return nil, err
 		}
+		activeRuleNames = ruleNamesOf(rules)
+
+		if !compositeAction && len(policies) > 0 {
+			errs, err := evaluatePolicies(policies, l.policyEngines, ruleConfigPath(filePath, project), parsedWorkflow)
+			if err != nil {
+				return nil, err
+			}
+			l.debug("policies found %d %s", len(errs), pluralize(len(errs), "error", "errors"))
+			allErrors = append(allErrors, errs...)
+			activeRuleNames = append(activeRuleNames, policyNames(policies)...)
+		}
 
 		silentRules := 0
 		for _, rule := range rules {
@@ -913,7 +1229,8 @@ func (l *Linter) validate(
 		}
 	}
 
-	l.filterAndLogErrors(filePath, &allErrors, &allAutoFixers, validationStart)
+	suppressions := newSuppressionSet(content, knownRuleNames, activeRuleNames)
+	l.filterAndLogErrors(filePath, &allErrors, &allAutoFixers, overrides, suppressions, validationStart)
 
 	return &ValidateResult{
 		FilePath:       filePath,
@@ -921,12 +1238,57 @@ func (l *Linter) validate(
 		ParsedWorkflow: parsedWorkflow,
 		Errors:         allErrors,
 		AutoFixers:     allAutoFixers,
+		overrides:      overrides,
+		suppressions:   suppressions,
 	}, nil
 }
 
-// filterAndSortErrors applies errorIgnorePatterns, sets FilePath, and stable-sorts.
+// setShellcheckRunner gives the shellcheck rule, when it is enabled, a runner
+// of the shellcheck executable on proc.
+func (l *Linter) setShellcheckRunner(rules []Rule, proc *ConcurrentExecutor) error {
+	for _, r := range rules {
+		rule, ok := r.(*ShellcheckRule)
+		if !ok {
+			continue
+		}
+		runner, err := proc.CommandRunner(l.shellcheckExecutablePath)
+		if err != nil {
+			return fmt.Errorf("shellcheck rule is enabled but shellcheck executable %q was not found: %w", l.shellcheckExecutablePath, err)
+		}
+		rule.setRunner(runner)
+	}
+	return nil
+}
+
+// filterAndSortErrors applies the "rules:" configuration, inline suppression
+// directives, -min-severity and errorIgnorePatterns, sets FilePath, and
+// stable-sorts.
 // Idempotent — safe to call repeatedly on the same result.
-func (l *Linter) filterAndSortErrors(filePath string, allErrors *[]*LintingError, allAutoFixers *[]AutoFixer) {
+func (l *Linter) filterAndSortErrors(filePath string, allErrors *[]*LintingError, allAutoFixers *[]AutoFixer, overrides *ruleOverrides, suppressions *suppressionSet) {
+	*allErrors, *allAutoFixers = overrides.apply(*allErrors, *allAutoFixers)
+	if suppressions != nil {
+		kept, suppressedRules := suppressions.apply(*allErrors)
+		*allErrors = kept
+		dropAutoFixersOfRules(allAutoFixers, suppressedRules)
+	}
+	if l.minSeverity != "" {
+		droppedRules := map[string]struct{}{}
+		keptRules := map[string]struct{}{}
+		filtered := make([]*LintingError, 0, len(*allErrors))
+		for _, err := range *allErrors {
+			if !err.Severity.AtLeast(l.minSeverity) {
+				droppedRules[err.Type] = struct{}{}
+				continue
+			}
+			keptRules[err.Type] = struct{}{}
+			filtered = append(filtered, err)
+		}
+		*allErrors = filtered
+		for r := range keptRules {
+			delete(droppedRules, r)
+		}
+		dropAutoFixersOfRules(allAutoFixers, droppedRules)
+	}
 	if len(l.errorIgnorePatterns) > 0 {
 		filtered := make([]*LintingError, 0, len(*allErrors))
 		for _, err := range *allErrors {
@@ -964,8 +1326,24 @@ func (l *Linter) filterAndSortErrors(filePath string, allErrors *[]*LintingError
 	sort.Stable(ByRuleErrorPosition(*allErrors))
 }
 
-func (l *Linter) filterAndLogErrors(filePath string, allErrors *[]*LintingError, allAutoFixers *[]AutoFixer, validationStart time.Time) {
-	l.filterAndSortErrors(filePath, allErrors, allAutoFixers)
+// dropAutoFixersOfRules removes the fixers of the given rules. Fixers are not
+// tied to individual findings, so callers only pass rules whose every finding
+// was filtered out.
+func dropAutoFixersOfRules(allAutoFixers *[]AutoFixer, rules map[string]struct{}) {
+	if len(rules) == 0 {
+		return
+	}
+	filtered := make([]AutoFixer, 0, len(*allAutoFixers))
+	for _, fixer := range *allAutoFixers {
+		if _, ok := rules[fixer.RuleName()]; !ok {
+			filtered = append(filtered, fixer)
+		}
+	}
+	*allAutoFixers = filtered
+}
+
+func (l *Linter) filterAndLogErrors(filePath string, allErrors *[]*LintingError, allAutoFixers *[]AutoFixer, overrides *ruleOverrides, suppressions *suppressionSet, validationStart time.Time) {
+	l.filterAndSortErrors(filePath, allErrors, allAutoFixers, overrides, suppressions)
 
 	if l.loggingLevel >= LogLevelDetailedOutput {
 		elapsed := time.Since(validationStart)
@@ -983,7 +1361,7 @@ func (l *Linter) postProcessResolvedChains(filePath string, result *ValidateResu
 	if result == nil {
 		return
 	}
-	l.filterAndSortErrors(filePath, &result.Errors, &result.AutoFixers)
+	l.filterAndSortErrors(filePath, &result.Errors, &result.AutoFixers, result.overrides, result.suppressions)
 }
 
 // displayErrorsは、指定されたエラーを出力する
@@ -0,0 +1,801 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// runLSP serves the language server protocol over stdin/stdout. Every opened
+// or changed document is linted with Linter and findings are published as
+// diagnostics. Rules calling the GitHub API only run when a document is opened
+// or saved. Each AutoFixer is offered as a quick-fix code action.
+func (cmd *Command) runLSP(args []string) int {
+	var linterOpts LinterOptions
+	var enabledRules enabledRuleFlags
+	var githubTokenFlag string
+	var githubAPIURLFlag string
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(cmd.Stderr)
+	flags.StringVar(&linterOpts.ConfigurationFilePath, "config-file", "", "File path to config file")
+	flags.Var(&enabledRules, "enable-rule", "Enable an opt-in rule by name. Repeatable")
+	flags.StringVar(&linterOpts.AdvisoryDBPath, "advisory-db", "", "Local advisory database for known-vulnerable-actions")
+	flags.StringVar(&githubTokenFlag, "github-token", "", "GitHub API token used by rules that call the GitHub API")
+	flags.StringVar(&githubAPIURLFlag, "github-api-url", "", "REST API URL of a GitHub Enterprise Server. Falls back to GITHUB_API_URL")
+	flags.BoolVar(&linterOpts.IsDebugOutputEnabled, "debug", false, "Enable debug output to stderr (for development)")
+	flags.Usage = func() {
+		fmt.Fprintf(cmd.Stderr, "Usage: sisakulint lsp [FLAGS]\n\nStart a language server speaking LSP over stdio.\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitStatusSuccessNoProblem
+		}
+		return ExitStatusInvalidCommandOption
+	}
+
+	linterOpts.EnabledOptInRules = enabledRules
+	linterOpts.LogOutputDestination = cmd.Stderr
+	linterOpts.GitHubToken, _ = ResolveGitHubToken(githubTokenFlag, nil)
+	apiURL, err := ResolveGitHubAPIURL(githubAPIURLFlag, nil)
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "Invalid value for -github-api-url: %v\n", err)
+		return ExitStatusInvalidCommandOption
+	}
+	linterOpts.GitHubAPIURL = apiURL
+	linter, err := NewLinter(io.Discard, &linterOpts)
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+
+	server := newLSPServer(linter, cmd.Stdout)
+	if err := server.serve(cmd.Stdin); err != nil {
+		fmt.Fprintf(cmd.Stderr, "sisakulint lsp: %v\n", err)
+		return ExitStatusFailure
+	}
+	if !server.shutdown {
+		// The client exited without the shutdown request.
+		return ExitStatusFailure
+	}
+	return ExitStatusSuccessNoProblem
+}
+
+// JSON-RPC / LSP error codes used by the server.
+const (
+	lspErrMethodNotFound = -32601
+	lspErrInvalidParams  = -32602
+)
+
+// LSP DiagnosticSeverity values.
+const (
+	lspSeverityError       = 1
+	lspSeverityWarning     = 2
+	lspSeverityInformation = 3
+	lspSeverityHint        = 4
+)
+
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspWorkspaceEdit struct {
+	Changes map[string][]lspTextEdit `json:"changes"`
+}
+
+type lspCodeAction struct {
+	Title       string           `json:"title"`
+	Kind        string           `json:"kind"`
+	Diagnostics []lspDiagnostic  `json:"diagnostics,omitempty"`
+	IsPreferred bool             `json:"isPreferred,omitempty"`
+	Edit        lspWorkspaceEdit `json:"edit"`
+}
+
+type lspTextDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type lspTextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type lspDidOpenParams struct {
+	TextDocument lspTextDocumentItem `json:"textDocument"`
+}
+
+type lspDidChangeParams struct {
+	TextDocument   lspTextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type lspDidSaveParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	// Text is only sent when the client is asked to include it on save.
+	Text *string `json:"text,omitempty"`
+}
+
+type lspDidCloseParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+}
+
+type lspCodeActionParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Range        lspRange                  `json:"range"`
+}
+
+// lspDocument is an open document and its latest lint result.
+type lspDocument struct {
+	uri         string
+	path        string
+	text        string
+	project     *Project
+	diagnostics []lspDiagnostic
+	errors      []*LintingError
+	// online is whether lspGitHubAPIRules ran in the latest lint. Fixes are
+	// computed with the same rules so that the fixers line up.
+	online bool
+	// fixes caches the edits of the fixers for the current lint result. It is
+	// dropped whenever the document is linted again.
+	fixes *lspDocumentFixes
+}
+
+// lspDocumentFixes holds the fixers of a document and the edits computed for
+// them so far. Editors request code actions on every cursor move, and each
+// edit needs a fresh lint of the document, so edits are only computed once
+// per fixer and lint result.
+type lspDocumentFixes struct {
+	// rules are the rule names of the fixers, in the order the linter
+	// returns them.
+	rules      []string
+	candidates map[int]*lspFixCandidate
+	fixAll     []lspTextEdit
+	hasFixAll  bool
+}
+
+// lspFixCandidate is the edits of a single fixer. A nil candidate means the
+// fixer changed nothing.
+type lspFixCandidate struct {
+	rule        string
+	edits       []lspTextEdit
+	first, last int
+}
+
+// lspProjectCaches are the caches shared by all documents of a project for
+// the lifetime of the server, so cross-file rules see the other workflows.
+type lspProjectCaches struct {
+	actions   *LocalActionsMetadataCache
+	workflows *LocalReusableWorkflowCache
+}
+
+// lspGitHubAPIRules are the rules which call the GitHub API. Running them on
+// every keystroke would spend the rate limit, so they only run when a document
+// is opened or saved.
+var lspGitHubAPIRules = map[string]struct{}{
+	"commit-sha":               {},
+	"impostor-commit":          {},
+	"known-vulnerable-actions": {},
+	"ref-confusion":            {},
+}
+
+type lspServer struct {
+	linter    *Linter
+	out       io.Writer
+	docs      map[string]*lspDocument
+	projects  map[string]*lspProjectCaches
+	actions   *LocalActionsMetadataCacheFactory
+	workflows *LocalReusableWorkflowCacheFactory
+	// online runs lspGitHubAPIRules in the current lint.
+	online   bool
+	shutdown bool
+}
+
+func newLSPServer(linter *Linter, out io.Writer) *lspServer {
+	dbg := linter.debugWriter()
+	s := &lspServer{
+		linter:    linter,
+		out:       out,
+		docs:      map[string]*lspDocument{},
+		projects:  map[string]*lspProjectCaches{},
+		actions:   NewLocalActionsMetadataCacheFactory(dbg),
+		workflows: NewLocalReusableWorkflowCacheFactory(linter.currentWorkingDirectory, dbg),
+	}
+	next := linter.modifyCheckRules
+	linter.modifyCheckRules = func(rules []Rule) []Rule {
+		if !s.online {
+			rules = slices.DeleteFunc(slices.Clone(rules), func(r Rule) bool {
+				_, ok := lspGitHubAPIRules[r.RuleNames()]
+				return ok
+			})
+		}
+		if next != nil {
+			rules = next(rules)
+		}
+		return rules
+	}
+	return s
+}
+
+// serve reads messages until the exit notification or the end of input.
+// Requests are handled one by one in the order they arrive.
+func (s *lspServer) serve(in io.Reader) error {
+	r := bufio.NewReader(in)
+	for {
+		body, err := readLSPMessage(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var msg lspMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return fmt.Errorf("malformed message: %w", err)
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if msg.Method == "" {
+			continue // response to a server request; none are sent
+		}
+		result, rerr := s.handle(&msg)
+		if msg.ID == nil {
+			continue // notification
+		}
+		resp := &lspMessage{JSONRPC: "2.0", ID: msg.ID, Result: result, Error: rerr}
+		if rerr == nil && result == nil {
+			// "result" must be present for a successful response.
+			resp.Result = json.RawMessage("null")
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("could not read message header: %w", err)
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("could not read message body: %w", err)
+	}
+	return body, nil
+}
+
+func (s *lspServer) write(msg *lspMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		return fmt.Errorf("could not write message: %w", err)
+	}
+	return nil
+}
+
+func (s *lspServer) notify(method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&lspMessage{JSONRPC: "2.0", Method: method, Params: raw})
+}
+
+func (s *lspServer) handle(msg *lspMessage) (interface{}, *lspError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": map[string]interface{}{
+					"openClose": true,
+					"change":    1, // full document sync
+					"save":      true,
+				},
+				"codeActionProvider": map[string]interface{}{
+					"codeActionKinds": []string{"quickfix", "source.fixAll"},
+				},
+			},
+			"serverInfo": map[string]string{"name": "sisakulint", "version": versionInfo},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p lspDidOpenParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspErrInvalidParams, err.Error()}
+		}
+		s.update(p.TextDocument.URI, p.TextDocument.Text, true)
+	case "textDocument/didChange":
+		var p lspDidChangeParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspErrInvalidParams, err.Error()}
+		}
+		if len(p.ContentChanges) > 0 {
+			s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text, false)
+		}
+	case "textDocument/didSave":
+		var p lspDidSaveParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspErrInvalidParams, err.Error()}
+		}
+		// Callers and callees of the saved workflow may have new cross-file
+		// findings.
+		if doc, ok := s.docs[p.TextDocument.URI]; ok {
+			if p.Text != nil {
+				doc.text = *p.Text
+			}
+			s.relintProject(doc)
+		}
+	case "textDocument/didClose":
+		var p lspDidCloseParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspErrInvalidParams, err.Error()}
+		}
+		s.close(p.TextDocument.URI)
+	case "textDocument/codeAction":
+		var p lspCodeActionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspErrInvalidParams, err.Error()}
+		}
+		return s.codeActions(p.TextDocument.URI, p.Range), nil
+	default:
+		if msg.ID != nil && !strings.HasPrefix(msg.Method, "$/") {
+			return nil, &lspError{lspErrMethodNotFound, "method not found: " + msg.Method}
+		}
+	}
+	return nil, nil
+}
+
+// uriToPath converts a file:// URI to a local file path. Other URIs (e.g.
+// unsaved buffers) are used as is.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	p := u.Path
+	if runtime.GOOS == "windows" && len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p)
+}
+
+// caches returns the caches of project. When a project is seen for the first
+// time, its workflows on disk are analyzed so that cross-file rules know the
+// other side of a reusable workflow call.
+func (s *lspServer) caches(project *Project) *lspProjectCaches {
+	if project == nil {
+		return &lspProjectCaches{
+			actions:   s.actions.GetCache(nil),
+			workflows: s.workflows.GetCache(nil),
+		}
+	}
+	if c, ok := s.projects[project.RootDirectory()]; ok {
+		return c
+	}
+	c := &lspProjectCaches{
+		actions:   s.actions.GetCache(project),
+		workflows: s.workflows.GetCache(project),
+	}
+	s.projects[project.RootDirectory()] = c
+	files, err := collectYAMLFiles(project.WorkflowDirectory())
+	if err != nil {
+		s.linter.log("could not read workflows of", project.RootDirectory(), ":", err)
+		return c
+	}
+	for _, f := range files {
+		src, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		if _, err := s.linter.lintWithCaches(f, src, project, c.actions, c.workflows); err != nil {
+			s.linter.log("could not analyze", f, ":", err)
+		}
+	}
+	return c
+}
+
+// lint analyzes doc's current text with the project caches. Everything the
+// previous content of the document recorded in the caches is dropped first.
+// lspGitHubAPIRules only run when doc.online is set.
+func (s *lspServer) lint(doc *lspDocument) (*ValidateResult, error) {
+	resetDependabotEcosystemRunState()
+	c := s.caches(doc.project)
+	c.workflows.ForgetWorkflow(doc.path)
+	s.online = doc.online
+	defer func() { s.online = false }()
+	return s.linter.lintWithCaches(doc.path, []byte(doc.text), doc.project, c.actions, c.workflows)
+}
+
+// resetGitHubAPIRunState drops what lspGitHubAPIRules remember from the GitHub
+// API, so that opening or saving a document queries it again.
+func resetGitHubAPIRunState() {
+	resetKnownVulnerableActionsRunState()
+	resetCommitShaRunState()
+}
+
+// update lints the new text of the document at uri. online runs
+// lspGitHubAPIRules too, which is done when the document is opened.
+func (s *lspServer) update(uri, text string, online bool) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		doc = &lspDocument{uri: uri, path: uriToPath(uri)}
+		if filepath.IsAbs(doc.path) {
+			project, err := s.linter.projectInformation.GetProjectForPath(doc.path)
+			if err != nil {
+				s.linter.log("could not detect project of", doc.path, ":", err)
+			}
+			doc.project = project
+		}
+		s.docs[uri] = doc
+	}
+	doc.text = text
+	doc.online = online
+	if online {
+		resetGitHubAPIRunState()
+	}
+	s.publish(doc)
+}
+
+func (s *lspServer) publish(doc *lspDocument) {
+	doc.errors = nil
+	doc.fixes = nil
+	doc.diagnostics = []lspDiagnostic{}
+	result, err := s.lint(doc)
+	if err != nil {
+		s.linter.log("could not lint", doc.path, ":", err)
+	} else if result != nil {
+		lines := strings.SplitAfter(doc.text, "\n")
+		doc.errors = result.Errors
+		for _, e := range result.Errors {
+			doc.diagnostics = append(doc.diagnostics, toLSPDiagnostic(e, lines))
+		}
+	}
+	if err := s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         doc.uri,
+		"diagnostics": doc.diagnostics,
+	}); err != nil {
+		s.linter.log("could not publish diagnostics:", err)
+	}
+}
+
+// relintProject lints every open document of doc's project again, with
+// lspGitHubAPIRules.
+func (s *lspServer) relintProject(doc *lspDocument) {
+	resetGitHubAPIRunState()
+	uris := make([]string, 0, len(s.docs))
+	for uri, d := range s.docs {
+		if d.project == doc.project {
+			uris = append(uris, uri)
+		}
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		s.docs[uri].online = true
+		s.publish(s.docs[uri])
+	}
+}
+
+func (s *lspServer) close(uri string) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return
+	}
+	delete(s.docs, uri)
+	c := s.caches(doc.project)
+	c.workflows.ForgetWorkflow(doc.path)
+	// Restore what the saved file on disk contributes to the project.
+	if src, err := os.ReadFile(doc.path); err == nil && doc.project != nil {
+		if _, err := s.linter.lintWithCaches(doc.path, src, doc.project, c.actions, c.workflows); err != nil {
+			s.linter.log("could not analyze", doc.path, ":", err)
+		}
+	}
+	if err := s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": []lspDiagnostic{},
+	}); err != nil {
+		s.linter.log("could not clear diagnostics:", err)
+	}
+}
+
+func lspSeverity(s Severity) int {
+	switch s {
+	case SeverityCritical, SeverityHigh:
+		return lspSeverityError
+	case SeverityMedium:
+		return lspSeverityWarning
+	case SeverityLow:
+		return lspSeverityInformation
+	default:
+		return lspSeverityHint
+	}
+}
+
+// utf16Column converts a 0-based character column of line to UTF-16 code
+// units, which LSP positions are counted in.
+func utf16Column(line string, col int) int {
+	n, i := 0, 0
+	for _, r := range strings.TrimRight(line, "\r\n") {
+		if i >= col {
+			break
+		}
+		n += utf16.RuneLen(r)
+		i++
+	}
+	return n
+}
+
+// toLSPDiagnostic converts a finding. The range spans from the reported
+// column to the end of the line since findings only carry a start position.
+func toLSPDiagnostic(e *LintingError, lines []string) lspDiagnostic {
+	line := max(e.LineNumber-1, 0)
+	col := max(e.ColNumber-1, 0)
+	start := lspPosition{Line: line, Character: col}
+	end := start
+	if line < len(lines) {
+		text := strings.TrimRight(lines[line], "\r\n")
+		start.Character = utf16Column(text, col)
+		end.Character = utf16Column(text, utf8.RuneCountInString(text))
+		if end.Character <= start.Character {
+			end.Character = start.Character + 1
+		}
+	}
+	return lspDiagnostic{
+		Range:    lspRange{Start: start, End: end},
+		Severity: lspSeverity(e.Severity),
+		Code:     e.Type,
+		Source:   "sisakulint",
+		Message:  e.Description,
+	}
+}
+
+// fixEdits lints doc again, runs only the fixers selected by run and returns
+// the result as text edits together with the first and last changed line.
+func (s *lspServer) fixEdits(doc *lspDocument, run func(i int, f AutoFixer) bool) ([]lspTextEdit, int, int) {
+	result, err := s.lint(doc)
+	if err != nil || result == nil || result.ParsedWorkflow == nil || result.ParsedWorkflow.BaseNode == nil {
+		return nil, 0, 0
+	}
+	snapshot := snapshotForFix(result.ParsedWorkflow.BaseNode)
+	for i, f := range result.AutoFixers {
+		if !run(i, f) {
+			continue
+		}
+		if err := f.Fix(); err != nil {
+			s.linter.log("fixer of", f.RuleName(), "failed:", err)
+		}
+	}
+	fixed, err := writeFixedSource(result.Source, snapshot, result.ParsedWorkflow.BaseNode)
+	if err != nil {
+		return nil, 0, 0
+	}
+	return textEdits(result.Source, fixed)
+}
+
+// textEdits converts the line diff of before and after into LSP text edits.
+func textEdits(before, after []byte) ([]lspTextEdit, int, int) {
+	old := splitDiffLines(before)
+	position := func(line int) lspPosition {
+		// The last line may lack a newline; end the range after its text.
+		if line == len(old) && line > 0 && !strings.HasSuffix(old[line-1], "\n") {
+			return lspPosition{Line: line - 1, Character: utf16Column(old[line-1], utf8.RuneCountInString(old[line-1]))}
+		}
+		return lspPosition{Line: line}
+	}
+
+	var edits []lspTextEdit
+	first, last := -1, -1
+	from, oldLine := -1, 0
+	var text strings.Builder
+	flush := func() {
+		if from < 0 {
+			return
+		}
+		edits = append(edits, lspTextEdit{
+			Range:   lspRange{Start: position(from), End: position(oldLine)},
+			NewText: text.String(),
+		})
+		if first < 0 {
+			first = from
+		}
+		last = oldLine
+		from = -1
+		text.Reset()
+	}
+	for _, op := range diffLines(old, splitDiffLines(after)) {
+		if op.kind == diffEqual {
+			flush()
+			oldLine++
+			continue
+		}
+		if from < 0 {
+			from = oldLine
+		}
+		if op.kind == diffDelete {
+			oldLine++
+		} else {
+			text.WriteString(op.line)
+		}
+	}
+	flush()
+	return edits, first, last
+}
+
+// documentFixes returns the fixer cache of doc, linting it once to list its
+// fixers when the cache is empty.
+func (s *lspServer) documentFixes(doc *lspDocument) *lspDocumentFixes {
+	if doc.fixes != nil {
+		return doc.fixes
+	}
+	doc.fixes = &lspDocumentFixes{candidates: map[int]*lspFixCandidate{}}
+	if result, err := s.lint(doc); err == nil && result != nil {
+		for _, f := range result.AutoFixers {
+			doc.fixes.rules = append(doc.fixes.rules, f.RuleName())
+		}
+	}
+	return doc.fixes
+}
+
+// fixCandidate returns the edits of the i-th fixer of doc. Each fixer runs
+// alone on a fresh tree so that its edits do not include other fixes.
+func (s *lspServer) fixCandidate(doc *lspDocument, fixes *lspDocumentFixes, i int) *lspFixCandidate {
+	if c, ok := fixes.candidates[i]; ok {
+		return c
+	}
+	var c *lspFixCandidate
+	if edits, first, last := s.fixEdits(doc, func(j int, _ AutoFixer) bool { return j == i }); len(edits) > 0 {
+		c = &lspFixCandidate{fixes.rules[i], edits, first, last}
+	}
+	fixes.candidates[i] = c
+	return c
+}
+
+// codeActions offers the fixers of the findings in rng as quick fixes, plus
+// a source action which applies every fixer of the document. Edits are only
+// computed for the fixers of rules in rng and are reused until the document
+// is linted again.
+func (s *lspServer) codeActions(uri string, rng lspRange) []lspCodeAction {
+	doc, ok := s.docs[uri]
+	actions := []lspCodeAction{}
+	if !ok {
+		return actions
+	}
+
+	var inRange []int
+	rules := map[string]struct{}{}
+	for i, d := range doc.diagnostics {
+		if d.Range.Start.Line <= rng.End.Line && d.Range.End.Line >= rng.Start.Line {
+			inRange = append(inRange, i)
+			rules[d.Code] = struct{}{}
+		}
+	}
+
+	var candidates []*lspFixCandidate
+	if len(rules) > 0 {
+		fixes := s.documentFixes(doc)
+		for i, rule := range fixes.rules {
+			if _, ok := rules[rule]; !ok {
+				continue
+			}
+			if c := s.fixCandidate(doc, fixes, i); c != nil {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	// Pick, per diagnostic, the fixer of its rule whose edits are closest.
+	offered := map[*lspFixCandidate][]lspDiagnostic{}
+	var order []*lspFixCandidate
+	for _, i := range inRange {
+		d := doc.diagnostics[i]
+		var best *lspFixCandidate
+		bestDist := 0
+		for _, c := range candidates {
+			if c.rule != d.Code {
+				continue
+			}
+			dist := 0
+			if line := d.Range.Start.Line; line < c.first {
+				dist = c.first - line
+			} else if line > c.last {
+				dist = line - c.last
+			}
+			if best == nil || dist < bestDist {
+				best, bestDist = c, dist
+			}
+		}
+		if best == nil {
+			continue
+		}
+		if _, ok := offered[best]; !ok {
+			order = append(order, best)
+		}
+		offered[best] = append(offered[best], d)
+	}
+	for _, c := range order {
+		actions = append(actions, lspCodeAction{
+			Title:       fmt.Sprintf("Apply sisakulint fix for %s", c.rule),
+			Kind:        "quickfix",
+			Diagnostics: offered[c],
+			IsPreferred: len(order) == 1,
+			Edit:        lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: c.edits}},
+		})
+	}
+
+	if len(doc.errors) > 0 {
+		fixes := s.documentFixes(doc)
+		if !fixes.hasFixAll && len(fixes.rules) > 0 {
+			fixes.fixAll, _, _ = s.fixEdits(doc, func(int, AutoFixer) bool { return true })
+			fixes.hasFixAll = true
+		}
+		if edits := fixes.fixAll; len(edits) > 0 {
+			actions = append(actions, lspCodeAction{
+				Title: "Apply all sisakulint fixes",
+				Kind:  "source.fixAll",
+				Edit:  lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: edits}},
+			})
+		}
+	}
+	return actions
+}
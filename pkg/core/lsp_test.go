@@ -0,0 +1,289 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// lspSession runs the server over the given client messages and returns the
+// messages it sent.
+func lspSession(t *testing.T, messages ...interface{}) []map[string]interface{} {
+	t.Helper()
+	var in bytes.Buffer
+	for _, m := range messages {
+		body, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	server := newLSPServer(linter, &out)
+	if err := server.serve(&in); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	var sent []map[string]interface{}
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readLSPMessage(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, m)
+	}
+	return sent
+}
+
+func lspRequest(id int, method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func lspNotification(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func lspDiagnosticsOf(sent []map[string]interface{}, uri string) []interface{} {
+	var diags []interface{}
+	for _, m := range sent {
+		if m["method"] != "textDocument/publishDiagnostics" {
+			continue
+		}
+		p := m["params"].(map[string]interface{})
+		if p["uri"] == uri {
+			diags = p["diagnostics"].([]interface{})
+		}
+	}
+	return diags
+}
+
+func lspResponse(sent []map[string]interface{}, id int) map[string]interface{} {
+	for _, m := range sent {
+		if v, ok := m["id"].(float64); ok && int(v) == id {
+			return m
+		}
+	}
+	return nil
+}
+
+func TestLSP_DiagnosticsAndCodeActions(t *testing.T) {
+	t.Parallel()
+
+	const uri = "untitled:ci.yml"
+	src := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    timeout-minutes: 5
+    steps:
+      - run: echo "${{ github.event.head_commit.message }}"
+`
+	sent := lspSession(t,
+		lspRequest(1, "initialize", map[string]interface{}{}),
+		lspNotification("initialized", map[string]interface{}{}),
+		lspNotification("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri, "languageId": "yaml", "version": 1, "text": src},
+		}),
+		lspRequest(2, "textDocument/codeAction", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri},
+			"range":        map[string]interface{}{"start": map[string]int{"line": 6, "character": 0}, "end": map[string]int{"line": 6, "character": 0}},
+			"context":      map[string]interface{}{"diagnostics": []interface{}{}},
+		}),
+		lspRequest(3, "shutdown", nil),
+		lspNotification("exit", nil),
+	)
+
+	if init := lspResponse(sent, 1); init == nil || init["result"] == nil {
+		t.Fatalf("initialize should be answered, got %v", sent)
+	}
+
+	var found map[string]interface{}
+	for _, d := range lspDiagnosticsOf(sent, uri) {
+		d := d.(map[string]interface{})
+		if d["code"] == "code-injection-medium" {
+			found = d
+		}
+	}
+	if found == nil {
+		t.Fatalf("code-injection-medium diagnostic not published: %v", lspDiagnosticsOf(sent, uri))
+	}
+	if found["severity"].(float64) != lspSeverityWarning || found["source"] != "sisakulint" {
+		t.Errorf("unexpected diagnostic: %v", found)
+	}
+	if line := found["range"].(map[string]interface{})["start"].(map[string]interface{})["line"].(float64); line != 6 {
+		t.Errorf("diagnostic line = %v, want 6", line)
+	}
+
+	resp := lspResponse(sent, 2)
+	if resp == nil {
+		t.Fatal("codeAction not answered")
+	}
+	var quickfix map[string]interface{}
+	for _, a := range resp["result"].([]interface{}) {
+		a := a.(map[string]interface{})
+		if a["kind"] == "quickfix" && strings.Contains(a["title"].(string), "code-injection-medium") {
+			quickfix = a
+		}
+	}
+	if quickfix == nil {
+		t.Fatalf("code-injection-medium quick fix not offered: %v", resp["result"])
+	}
+	edits := quickfix["edit"].(map[string]interface{})["changes"].(map[string]interface{})[uri].([]interface{})
+	var newText strings.Builder
+	for _, e := range edits {
+		newText.WriteString(e.(map[string]interface{})["newText"].(string))
+	}
+	if !strings.Contains(newText.String(), "env:") {
+		t.Errorf("quick fix should move the expression to env:, got %q", newText.String())
+	}
+
+	if resp := lspResponse(sent, 3); resp == nil || resp["error"] != nil {
+		t.Errorf("shutdown should succeed, got %v", resp)
+	}
+}
+
+func TestLSP_CrossFileTaintUsesProjectWorkflows(t *testing.T) {
+	t.Parallel()
+
+	root := makeTestProject(t)
+	writeTestFile(t, filepath.Join(root, ".github", "workflows", "callee.yml"), `on:
+  workflow_call:
+    inputs:
+      title:
+        type: string
+jobs:
+  greet:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ inputs.title }}"
+`)
+	callerPath := filepath.Join(root, ".github", "workflows", "caller.yml")
+	caller := `on: pull_request_target
+jobs:
+  call:
+    uses: ./.github/workflows/callee.yml
+    with:
+      title: ${{ github.event.pull_request.title }}
+`
+	writeTestFile(t, callerPath, caller)
+	uri := "file://" + filepath.ToSlash(callerPath)
+
+	open := lspNotification("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "yaml", "version": 1, "text": caller},
+	})
+	change := lspNotification("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []interface{}{map[string]interface{}{"text": caller}},
+	})
+	sent := lspSession(t, open, change, lspNotification("exit", nil))
+
+	chains := 0
+	for _, d := range lspDiagnosticsOf(sent, uri) {
+		d := d.(map[string]interface{})
+		if d["code"] == "reusable-workflow-taint" && strings.Contains(d["message"].(string), "taint-chain") {
+			chains++
+		}
+	}
+	// The second lint must neither lose nor duplicate the chain.
+	if chains != 1 {
+		t.Errorf("want 1 reusable-workflow-taint chain diagnostic after an edit, got %d: %v", chains, lspDiagnosticsOf(sent, uri))
+	}
+}
+
+func TestLSP_CodeActionsReuseFixesUntilDocumentChanges(t *testing.T) {
+	t.Parallel()
+
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newLSPServer(linter, io.Discard)
+	const uri = "untitled:ci.yml"
+	server.update(uri, `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    timeout-minutes: 5
+    steps:
+      - run: echo "${{ github.event.head_commit.message }}"
+`, true)
+	rng := lspRange{Start: lspPosition{Line: 6}, End: lspPosition{Line: 6}}
+
+	first := server.codeActions(uri, rng)
+	fixes := server.docs[uri].fixes
+	if fixes == nil || len(fixes.candidates) == 0 || !fixes.hasFixAll {
+		t.Fatalf("fixes should be cached after the first request, got %+v", fixes)
+	}
+	second := server.codeActions(uri, rng)
+	if server.docs[uri].fixes != fixes {
+		t.Error("a request for the same document version should reuse the cached fixes")
+	}
+	if len(first) != len(second) || len(first) == 0 || first[0].Title != second[0].Title {
+		t.Errorf("cached code actions differ: %v vs %v", first, second)
+	}
+
+	server.update(uri, "on: push\njobs: {}\n", false)
+	if server.docs[uri].fixes != nil {
+		t.Error("fixes should be dropped when the document changes")
+	}
+}
+
+func TestLSP_GitHubAPIRulesRunOnOpenAndSave(t *testing.T) {
+	t.Parallel()
+
+	var ran []bool
+	linter, err := NewLinter(io.Discard, &LinterOptions{
+		LogOutputDestination: io.Discard,
+		OnCheckRulesModified: func(rules []Rule) []Rule {
+			ran = append(ran, slices.ContainsFunc(rules, func(r Rule) bool { return r.RuleNames() == "commit-sha" }))
+			return rules
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newLSPServer(linter, io.Discard)
+	const uri = "untitled:ci.yml"
+	src := "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo hello\n"
+	notify := func(method string, params interface{}) {
+		t.Helper()
+		raw, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, rerr := server.handle(&lspMessage{Method: method, Params: raw}); rerr != nil {
+			t.Fatalf("%s: %s", method, rerr.Message)
+		}
+	}
+	doc := map[string]interface{}{"uri": uri}
+
+	notify("textDocument/didOpen", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri, "text": src}})
+	notify("textDocument/didChange", map[string]interface{}{"textDocument": doc, "contentChanges": []interface{}{map[string]interface{}{"text": src + "\n"}}})
+	notify("textDocument/didSave", map[string]interface{}{"textDocument": doc, "text": src})
+
+	if want := []bool{true, false, true}; !slices.Equal(ran, want) {
+		t.Errorf("commit-sha ran %v on open, change and save, want %v", ran, want)
+	}
+	if got := server.docs[uri].text; got != src {
+		t.Errorf("the text sent on save was not used: %q", got)
+	}
+}
@@ -0,0 +1,389 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+const (
+	// defaultMatrixMaxJobs is the number of jobs a matrix may expand to
+	// before it is reported, when matrix.max-jobs is not configured.
+	defaultMatrixMaxJobs = 100
+	// githubMatrixJobLimit is the number of jobs GitHub allows one matrix to
+	// generate.
+	githubMatrixJobLimit = 256
+	// matrixEnumerationLimit bounds the combinations enumerated to apply
+	// "exclude" and "include". Larger matrices are counted without them.
+	matrixEnumerationLimit = 1 << 16
+)
+
+// MatrixConfig holds the configuration of the matrix rule.
+type MatrixConfig struct {
+	// MaxJobs is the number of jobs a matrix may expand to. Zero means
+	// defaultMatrixMaxJobs.
+	MaxJobs int `yaml:"max-jobs"`
+}
+
+// RuleMatrix is a rule to check the matrix of jobs.
+type RuleMatrix struct {
+	BaseRule
+}
+
+// MatrixRule creates a new RuleMatrix instance.
+func MatrixRule() *RuleMatrix {
+	return &RuleMatrix{
+		BaseRule: BaseRule{
+			RuleName: "matrix",
+			RuleDesc: "Checks for duplicate values, unknown keys in exclude, and the number of jobs of matrices",
+			severity: SeverityMedium,
+		},
+	}
+}
+
+// VisitJobPre is callback when visiting Job node before visiting its children.
+func (rule *RuleMatrix) VisitJobPre(n *ast.Job) error {
+	if n.Strategy == nil || n.Strategy.Matrix == nil || n.Strategy.Matrix.Expression != nil {
+		return nil
+	}
+	m := n.Strategy.Matrix
+
+	names := matrixRowNames(m)
+	for _, name := range names {
+		rule.checkDuplicateValues(name, m.Rows[name])
+	}
+	if m.Include != nil && m.Include.Expression == nil {
+		rule.checkDuplicateCombinations("include", m.Include.Combinations)
+	}
+	if m.Exclude != nil && m.Exclude.Expression == nil {
+		rule.checkDuplicateCombinations("exclude", m.Exclude.Combinations)
+		rule.checkExclude(m, names)
+	}
+	rule.checkJobCount(n, m, names)
+	return nil
+}
+
+// checkDuplicateValues reports values which appear twice in one row. Each of
+// them runs the same job twice.
+func (rule *RuleMatrix) checkDuplicateValues(name string, row *ast.MatrixRow) {
+	if row.Expression != nil {
+		return
+	}
+	if row.Name != nil {
+		name = row.Name.Value
+	}
+	for i, v := range row.Values {
+		for _, prev := range row.Values[:i] {
+			if rawYAMLValuesEqual(v, prev) {
+				rule.Errorf(v.Pos(), "duplicate value %s in matrix %q. The same value is at %s, so the same job runs twice", v.String(), name, prev.Pos())
+				break
+			}
+		}
+	}
+}
+
+// checkDuplicateCombinations reports entries of "include" or "exclude" which
+// are the same as an earlier entry.
+func (rule *RuleMatrix) checkDuplicateCombinations(section string, combis []*ast.MatrixCombination) {
+	for i, c := range combis {
+		if c.Expression != nil || len(c.Assigns) == 0 {
+			continue
+		}
+		for _, prev := range combis[:i] {
+			if prev.Expression == nil && matrixCombinationsEqual(c, prev) {
+				rule.Errorf(matrixCombinationPos(c), "duplicate entry %s in %q section of matrix. The same entry is at %s", matrixCombinationString(c), section, matrixCombinationPos(prev))
+				break
+			}
+		}
+	}
+}
+
+// checkExclude reports keys of "exclude" which are neither a row of the
+// matrix nor added by "include", and values which match no value of their
+// row. GitHub rejects the former, and the latter exclude nothing.
+func (rule *RuleMatrix) checkExclude(m *ast.Matrix, names []string) {
+	included := map[string]struct{}{}
+	if m.Include != nil {
+		if m.Include.Expression != nil {
+			return
+		}
+		for _, c := range m.Include.Combinations {
+			if c.Expression != nil {
+				return
+			}
+			for k := range c.Assigns {
+				included[k] = struct{}{}
+			}
+		}
+	}
+
+	for _, c := range m.Exclude.Combinations {
+		if c.Expression != nil {
+			continue
+		}
+		for _, k := range sortedAssignKeys(c) {
+			a := c.Assigns[k]
+			row, ok := m.Rows[k]
+			if !ok {
+				if _, ok := included[k]; !ok {
+					rule.Errorf(a.Key.Pos, "%q in \"exclude\" section does not exist in matrix. Available keys are %s", a.Key.Value, quotedList(names))
+				}
+				continue
+			}
+			if row.Expression != nil {
+				continue
+			}
+			if !matrixRowHasValue(row, a.Value) {
+				values := make([]string, 0, len(row.Values))
+				for _, v := range row.Values {
+					values = append(values, v.String())
+				}
+				rule.Errorf(a.Value.Pos(), "value %s of %q in \"exclude\" section does not match any value of the matrix, so it excludes nothing. Available values are %s", a.Value.String(), a.Key.Value, strings.Join(values, ", "))
+			}
+		}
+	}
+}
+
+// checkJobCount reports a matrix whose "exclude" removes every combination
+// of its rows, and a matrix which expands to more jobs than configured.
+func (rule *RuleMatrix) checkJobCount(n *ast.Job, m *ast.Matrix, names []string) {
+	combis, ok := matrixRowCombinations(m, names)
+	if !ok {
+		return
+	}
+	if combis == nil {
+		// Too many combinations to enumerate; count them without
+		// "exclude" and "include".
+		count := 1
+		for _, name := range names {
+			count *= len(m.Rows[name].Values)
+		}
+		rule.reportJobCount(n, m, count, "before \"exclude\" is applied")
+		return
+	}
+
+	original := len(combis)
+	if m.Exclude != nil {
+		if m.Exclude.Expression != nil {
+			return
+		}
+		kept := combis[:0]
+		for _, combi := range combis {
+			if !matrixExcluded(combi, m.Exclude.Combinations) {
+				kept = append(kept, combi)
+			}
+		}
+		combis = kept
+		if original > 0 && len(combis) == 0 && len(m.Exclude.Combinations) > 0 {
+			rule.Errorf(matrixCombinationPos(m.Exclude.Combinations[0]), "\"exclude\" section removes all %d combinations of the matrix", original)
+		}
+	}
+
+	count := len(combis)
+	if m.Include != nil {
+		if m.Include.Expression != nil {
+			return
+		}
+		for _, c := range m.Include.Combinations {
+			if c.Expression != nil {
+				return
+			}
+			matched := false
+			for _, combi := range combis {
+				if matrixIncludeMatches(c, combi) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				// An entry which cannot be added to any combination adds a
+				// new one.
+				count++
+			} else if matrixOnlyHasRows(c, m) {
+				rule.Errorf(matrixCombinationPos(c), "entry %s in \"include\" section only has keys of the matrix and matches existing combinations, so it has no effect", matrixCombinationString(c))
+			}
+		}
+	}
+	rule.reportJobCount(n, m, count, "")
+}
+
+func (rule *RuleMatrix) reportJobCount(n *ast.Job, m *ast.Matrix, count int, note string) {
+	limit := defaultMatrixMaxJobs
+	if rule.userConfig != nil && rule.userConfig.Matrix.MaxJobs > 0 {
+		limit = rule.userConfig.Matrix.MaxJobs
+	}
+	if count <= limit {
+		return
+	}
+	if note != "" {
+		note = " " + note
+	}
+	msg := fmt.Sprintf("matrix of job %q expands to %d jobs%s, which is more than the limit of %d. The limit can be changed with \"matrix.max-jobs\" in the config file", n.ID.Value, count, note, limit)
+	if count > githubMatrixJobLimit {
+		rule.ErrorfWithSeverity(m.Pos, SeverityHigh, "%s. GitHub rejects a matrix which generates more than %d jobs", msg, githubMatrixJobLimit)
+		return
+	}
+	rule.Error(m.Pos, msg)
+}
+
+// matrixRowCombinations returns the combinations of the values of the rows.
+// ok is false when the rows use expressions, and the combinations are nil
+// when there are more than matrixEnumerationLimit of them.
+func matrixRowCombinations(m *ast.Matrix, names []string) (combis []map[string]ast.RawYAMLValue, ok bool) {
+	for _, name := range names {
+		if m.Rows[name].Expression != nil {
+			return nil, false
+		}
+	}
+	count := 1
+	for _, name := range names {
+		count *= len(m.Rows[name].Values)
+		if count > matrixEnumerationLimit {
+			return nil, true
+		}
+	}
+	if len(names) == 0 {
+		return []map[string]ast.RawYAMLValue{}, true
+	}
+	combis = []map[string]ast.RawYAMLValue{{}}
+	for _, name := range names {
+		next := make([]map[string]ast.RawYAMLValue, 0, len(combis)*len(m.Rows[name].Values))
+		for _, combi := range combis {
+			for _, v := range m.Rows[name].Values {
+				c := make(map[string]ast.RawYAMLValue, len(combi)+1)
+				for k, cv := range combi {
+					c[k] = cv
+				}
+				c[name] = v
+				next = append(next, c)
+			}
+		}
+		combis = next
+	}
+	return combis, true
+}
+
+// matrixExcluded returns whether an entry of "exclude" matches combi. Entries
+// only have to match partially, and an entry with a key which is not a row
+// matches nothing.
+func matrixExcluded(combi map[string]ast.RawYAMLValue, excludes []*ast.MatrixCombination) bool {
+	for _, c := range excludes {
+		if c.Expression != nil || len(c.Assigns) == 0 {
+			continue
+		}
+		matched := true
+		for k, a := range c.Assigns {
+			v, ok := combi[k]
+			if !ok || !a.Value.Equals(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matrixIncludeMatches returns whether an entry of "include" can be added to
+// combi without overwriting one of its values.
+func matrixIncludeMatches(c *ast.MatrixCombination, combi map[string]ast.RawYAMLValue) bool {
+	for k, a := range c.Assigns {
+		if v, ok := combi[k]; ok && !rawYAMLValuesEqual(a.Value, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func matrixOnlyHasRows(c *ast.MatrixCombination, m *ast.Matrix) bool {
+	for k := range c.Assigns {
+		if _, ok := m.Rows[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func matrixRowHasValue(row *ast.MatrixRow, v ast.RawYAMLValue) bool {
+	for _, rv := range row.Values {
+		if v.Equals(rv) {
+			return true
+		}
+	}
+	return false
+}
+
+// rawYAMLValuesEqual compares two values. RawYAMLValue.Equals only checks
+// that the properties of an object are in the other.
+func rawYAMLValuesEqual(a, b ast.RawYAMLValue) bool {
+	return a.Equals(b) && b.Equals(a)
+}
+
+func matrixCombinationsEqual(a, b *ast.MatrixCombination) bool {
+	if len(a.Assigns) != len(b.Assigns) {
+		return false
+	}
+	for k, av := range a.Assigns {
+		bv, ok := b.Assigns[k]
+		if !ok || !rawYAMLValuesEqual(av.Value, bv.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matrixCombinationPos returns the position of the first key of an entry.
+func matrixCombinationPos(c *ast.MatrixCombination) *ast.Position {
+	var pos *ast.Position
+	for _, a := range c.Assigns {
+		if a.Key == nil || a.Key.Pos == nil {
+			continue
+		}
+		if pos == nil || a.Key.Pos.IsBefore(pos) {
+			pos = a.Key.Pos
+		}
+	}
+	return pos
+}
+
+func matrixCombinationString(c *ast.MatrixCombination) string {
+	keys := sortedAssignKeys(c)
+	props := make([]string, 0, len(keys))
+	for _, k := range keys {
+		props = append(props, fmt.Sprintf("%s: %s", c.Assigns[k].Key.Value, c.Assigns[k].Value.String()))
+	}
+	return "{" + strings.Join(props, ", ") + "}"
+}
+
+func sortedAssignKeys(c *ast.MatrixCombination) []string {
+	keys := make([]string, 0, len(c.Assigns))
+	for k := range c.Assigns {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func matrixRowNames(m *ast.Matrix) []string {
+	names := make([]string, 0, len(m.Rows))
+	for name := range m.Rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func quotedList(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	quoted := make([]string, 0, len(names))
+	for _, n := range names {
+		quoted = append(quoted, fmt.Sprintf("%q", n))
+	}
+	return strings.Join(quoted, ", ")
+}
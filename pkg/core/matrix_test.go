@@ -0,0 +1,201 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func runMatrixRule(t *testing.T, cfg *Config, matrix string) []*LintingError {
+	t.Helper()
+
+	rule := MatrixRule()
+	rule.UpdateConfig(cfg)
+	visitWorkflowSource(t, rule, `on: push
+jobs:
+  test:
+    runs-on: ${{ matrix.os }}
+    strategy:
+      matrix:
+`+matrix+`    steps:
+      - run: echo ${{ matrix.node }}
+`)
+	return rule.Errors()
+}
+
+func TestMatrixRule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		matrix string
+		want   []string
+	}{
+		{
+			name: "valid",
+			matrix: `        os: [ubuntu-latest, macos-latest]
+        node: [18, 20]
+        include:
+          - os: ubuntu-latest
+            experimental: true
+          - os: windows-latest
+            node: 20
+        exclude:
+          - os: macos-latest
+            node: 18
+`,
+		},
+		{
+			name: "duplicate values",
+			matrix: `        os: [ubuntu-latest, macos-latest, ubuntu-latest]
+        node: [{version: 18}, {version: 18, lts: true}, {version: 18}]
+`,
+			want: []string{
+				`8:57: duplicate value {"version": "18"} in matrix "node". The same value is at line:8,col:16`,
+				`7:43: duplicate value "ubuntu-latest" in matrix "os". The same value is at line:7,col:14`,
+			},
+		},
+		{
+			name: "duplicate entries",
+			matrix: `        os: [ubuntu-latest]
+        include:
+          - os: ubuntu-latest
+            node: 20
+          - node: 20
+            os: ubuntu-latest
+`,
+			want: []string{`duplicate entry {node: "20", os: "ubuntu-latest"} in "include" section of matrix. The same entry is at line:9,col:13`},
+		},
+		{
+			name: "unknown exclude key and value",
+			matrix: `        os: [ubuntu-latest, macos-latest]
+        node: [18, 20]
+        include:
+          - os: ubuntu-latest
+            experimental: true
+        exclude:
+          - os: macos-latest
+            nod: 18
+          - os: windows-latest
+          - experimental: true
+`,
+			want: []string{
+				`"nod" in "exclude" section does not exist in matrix. Available keys are "node", "os"`,
+				`value "windows-latest" of "os" in "exclude" section does not match any value of the matrix`,
+			},
+		},
+		{
+			name: "exclude everything",
+			matrix: `        os: [ubuntu-latest, macos-latest]
+        node: [18, 20]
+        exclude:
+          - node: 18
+          - node: 20
+`,
+			want: []string{`10:13: "exclude" section removes all 4 combinations of the matrix`},
+		},
+		{
+			name: "include without effect",
+			matrix: `        os: [ubuntu-latest, macos-latest]
+        node: [18, 20]
+        include:
+          - os: macos-latest
+`,
+			want: []string{`entry {os: "macos-latest"} in "include" section only has keys of the matrix and matches existing combinations`},
+		},
+		{
+			name: "include of an excluded combination",
+			matrix: `        os: [ubuntu-latest, macos-latest]
+        node: [18, 20]
+        exclude:
+          - os: macos-latest
+        include:
+          - os: macos-latest
+            node: 20
+`,
+		},
+		{
+			name: "too many jobs",
+			matrix: `        os: [a, b, c, d, e]
+        node: [1, 2, 3, 4, 5]
+        arch: [x, y, z, w, v]
+`,
+			want: []string{`6:7: matrix of job "test" expands to 125 jobs, which is more than the limit of 100`},
+		},
+		{
+			name: "exclude and include are counted",
+			matrix: `        os: [a, b, c, d, e]
+        node: [1, 2, 3, 4, 5]
+        arch: [x, y, z, w, v]
+        exclude:
+          - arch: v
+        include:
+          - os: f
+`,
+			want: []string{`matrix of job "test" expands to 101 jobs`},
+		},
+		{
+			name: "more jobs than GitHub allows",
+			matrix: `        a: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        b: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        c: [1, 2, 3]
+`,
+			want: []string{`expands to 300 jobs, which is more than the limit of 100. The limit can be changed with "matrix.max-jobs" in the config file. GitHub rejects a matrix which generates more than 256 jobs`},
+		},
+		{
+			name: "expression",
+			matrix: `        os: ${{ fromJSON(inputs.os) }}
+        node: [18, 18]
+        exclude:
+          - os: ubuntu-latest
+`,
+			want: []string{`duplicate value "18" in matrix "node"`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			errs := runMatrixRule(t, nil, tc.matrix)
+			if len(errs) != len(tc.want) {
+				t.Fatalf("want %d errors, got %v", len(tc.want), errs)
+			}
+			for i, want := range tc.want {
+				got := errs[i].Error()
+				if !strings.Contains(got, want) {
+					t.Errorf("error %d %q does not contain %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatrixRule_MaxJobs(t *testing.T) {
+	t.Parallel()
+
+	matrix := `        os: [a, b, c]
+        node: [1, 2, 3]
+`
+	cfg := &Config{}
+	cfg.Matrix.MaxJobs = 8
+	errs := runMatrixRule(t, cfg, matrix)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "expands to 9 jobs, which is more than the limit of 8") {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if errs[0].Severity != SeverityMedium {
+		t.Errorf("unexpected severity: %s", errs[0].Severity)
+	}
+
+	cfg.Matrix.MaxJobs = 9
+	if errs := runMatrixRule(t, cfg, matrix); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestParseConfig_InvalidMatrixMaxJobs(t *testing.T) {
+	t.Parallel()
+
+	if _, err := parseConfig([]byte("matrix:\n  max-jobs: -1\n"), "sisakulint.yaml"); err == nil || !strings.Contains(err.Error(), "matrix.max-jobs") {
+		t.Fatalf("want an error for negative max-jobs, got %v", err)
+	}
+}
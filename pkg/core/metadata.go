@@ -23,8 +23,11 @@ import (
 // GitHub Actionsの入力メタデータ構造体 : inputs
 // *https://docs.github.com/en/actions/creating-actions/metadata-syntax-for-github-actions#inputs
 type ActionInputMetadata struct {
-	Name     string `json:"name"`
-	Required bool   `json:"required"`
+	Name string `json:"name"`
+	// Required は入力が必須で、かつデフォルト値を持たない場合にtrueになります。
+	Required bool `json:"required"`
+	// DeprecationMessage は 'deprecationMessage' に設定されたメッセージです。空の場合、入力は非推奨ではありません。
+	DeprecationMessage string `json:"deprecation_message,omitempty"`
 }
 
 // actionの入力メタデータのマップ
@@ -37,8 +40,9 @@ func (inputs *ActionInputsMetadata) UnmarshalYAML(n *yaml.Node) error {
 	}
 
 	type TempInputMetadata struct {
-		Required bool    `yaml:"required"`
-		Default  *string `yaml:"default"`
+		Required           bool    `yaml:"required"`
+		Default            *string `yaml:"default"`
+		DeprecationMessage string  `yaml:"deprecationMessage"`
 	}
 
 	md := make(ActionInputsMetadata, len(n.Content)/2)
@@ -54,7 +58,7 @@ func (inputs *ActionInputsMetadata) UnmarshalYAML(n *yaml.Node) error {
 		if _, ok := md[id]; ok {
 			return fmt.Errorf("duplicate input %q", name)
 		}
-		md[id] = &ActionInputMetadata{name, m.Required || m.Default != nil}
+		md[id] = &ActionInputMetadata{name, m.Required && m.Default == nil, m.DeprecationMessage}
 	}
 	*inputs = md
 	return nil
@@ -300,6 +304,9 @@ type RemoteActionsMetadataCache struct {
 	breakerOpen                  bool
 	flight                       singleflight.Group
 	dbg                          io.Writer
+	// apiURL is the API of a GitHub Enterprise Server, or empty for
+	// GitHub.com.
+	apiURL string
 	// fetchFile is a test seam; when nil, the lazily-constructed
 	// remote.Fetcher is used.
 	fetchFile func(ctx context.Context, repo *remote.RepositoryInfo, filePath, ref string) ([]byte, error)
@@ -342,7 +349,7 @@ func NewRemoteActionsMetadataCache(dbg io.Writer) *RemoteActionsMetadataCache {
 // that never reach a remote composite-action lookup.
 func (c *RemoteActionsMetadataCache) ensureFetcher() *remote.Fetcher {
 	c.fetcherOnce.Do(func() {
-		fetcher, err := remote.NewFetcher(1)
+		fetcher, err := remote.NewFetcherWithAPIURL(1, c.apiURL)
 		if err != nil {
 			c.fetcherErr = err
 			if c.dbg != nil {
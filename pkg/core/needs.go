@@ -44,6 +44,7 @@ func JobNeedsRule() *JobNeeds {
 		BaseRule: BaseRule{
 			RuleName: "needs",
 			RuleDesc: "Job needs is not defined correctly.",
+			severity: SeverityLow,
 		},
 		nodes: map[string]*jobNode{},
 	}
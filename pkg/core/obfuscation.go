@@ -15,12 +15,14 @@ type ObfuscationRule struct {
 }
 
 func NewObfuscationRule() *ObfuscationRule {
-	return &ObfuscationRule{
+	rule := &ObfuscationRule{
 		BaseRule: CreateBaseRule(
 			"obfuscation",
 			"Detects obfuscated workflow patterns that may be used to evade security scanners",
 		),
 	}
+	rule.severity = SeverityHigh
+	return rule
 }
 
 func checkUsesPathObfuscation(usesValue string) []string {
@@ -72,6 +72,7 @@ func newOutputClobberingRule(severityLevel string, checkPrivileged bool) *Output
 		BaseRule: BaseRule{
 			RuleName: "output-clobbering-" + severityLevel,
 			RuleDesc: desc,
+			severity: Severity(severityLevel),
 		},
 		severityLevel:      severityLevel,
 		checkPrivileged:    checkPrivileged,
@@ -588,7 +589,7 @@ func (rule *OutputClobberingRule) parseExpression(exprStr string) (expressions.E
 
 // checkUntrustedInput checks if the expression contains untrusted input
 func (rule *OutputClobberingRule) checkUntrustedInput(expr parsedExpression) []string {
-	checker := expressions.NewExprSemanticsChecker(true, nil)
+	checker := newUntrustedInputChecker(rule.workflow)
 	_, errs := checker.Check(expr.node)
 
 	var paths []string
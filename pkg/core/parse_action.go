@@ -0,0 +1,95 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// CompositeActionJobID is the ID of the synthetic job holding the steps of a
+// composite action.
+const CompositeActionJobID = "composite"
+
+// parseCompositeAction builds a syntax tree from composite action metadata.
+// The steps of "runs.steps" are put in a single job and the action is
+// treated as called with "workflow_call", since the triggers of the calling
+// workflows are unknown. It returns nil when the action is not a composite
+// action (JavaScript and Docker actions have no steps to lint).
+//
+// Only the keys needed to lint the steps are checked. Other metadata keys
+// are accepted as is.
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions
+func (project *parser) parseCompositeAction(node *yaml.Node) *ast.Workflow {
+	if len(node.Content) == 0 {
+		project.error(node, "empty action metadata")
+		return nil
+	}
+
+	workflow := &ast.Workflow{BaseNode: node}
+	action := &ast.CompositeAction{Inputs: map[string]*ast.String{}}
+	var runs *workflowKeyValue
+
+	for _, mapping := range project.parseMapping("action metadata", node.Content[0], false, true) {
+		switch mapping.id {
+		case MainName:
+			workflow.Name = project.parseString(mapping.val, true)
+		case SBOMDescription:
+			workflow.Description = project.parseString(mapping.val, true)
+		case "inputs":
+			for _, input := range project.parseSectionMapping("inputs", mapping.val, true, false) {
+				action.Inputs[input.id] = input.key
+			}
+		case "runs":
+			m := mapping
+			runs = &m
+		}
+	}
+	if runs == nil {
+		project.error(node, "section is missing required key \"runs\"")
+		return nil
+	}
+
+	var using *ast.String
+	var steps []*ast.Step
+	for _, kv := range project.parseMapping("runs", runs.val, false, true) {
+		switch kv.id {
+		case "using":
+			using = project.parseString(kv.val, false)
+		case "steps":
+			steps = project.parseSteps(kv.val)
+		}
+	}
+	if using == nil || !strings.EqualFold(using.Value, "composite") {
+		return nil
+	}
+
+	action.Pos = runs.key.Pos
+	workflow.CompositeAction = action
+	workflow.On = []ast.Event{&ast.WorkflowCallEvent{Pos: runs.key.Pos}}
+	workflow.Jobs = map[string]*ast.Job{
+		CompositeActionJobID: {
+			ID:       &ast.String{Value: CompositeActionJobID, Pos: runs.key.Pos},
+			Steps:    steps,
+			Pos:      runs.key.Pos,
+			BaseNode: runs.val,
+		},
+	}
+	return workflow
+}
+
+// ParseCompositeAction parses composite action metadata (action.yml) into a
+// syntax tree which step-level rules can visit. See parseCompositeAction for
+// the shape of the tree. The returned tree is nil for JavaScript and Docker
+// actions.
+func ParseCompositeAction(sourceContent []byte) (*ast.Workflow, []*LintingError) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(sourceContent, &node); err != nil {
+		return nil, handleYamlError(err)
+	}
+
+	parserInstance := &parser{}
+	workflow := parserInstance.parseCompositeAction(&node)
+
+	return workflow, parserInstance.errors
+}
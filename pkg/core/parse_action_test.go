@@ -0,0 +1,120 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const compositeActionFixture = `name: Greet
+description: Greets the PR author
+inputs:
+  title:
+    description: PR title
+runs:
+  using: composite
+  steps:
+    - name: greet
+      run: echo "${{ inputs.title }}"
+      shell: bash
+    - run: echo "TITLE=${{ inputs.title }}" >> "$GITHUB_ENV"
+      shell: bash
+    - run: echo "${{ github.event.issue.title }}"
+      shell: bash
+`
+
+func TestParseCompositeAction(t *testing.T) {
+	t.Parallel()
+
+	wf, errs := ParseCompositeAction([]byte(compositeActionFixture))
+	if len(errs) != 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	if wf == nil || wf.CompositeAction == nil {
+		t.Fatal("composite action should be parsed into a workflow tree")
+	}
+	if _, ok := wf.CompositeAction.Inputs["title"]; !ok {
+		t.Errorf("input \"title\" not collected: %v", wf.CompositeAction.Inputs)
+	}
+	job, ok := wf.Jobs[CompositeActionJobID]
+	if !ok {
+		t.Fatalf("synthetic job %q not found", CompositeActionJobID)
+	}
+	if len(job.Steps) != 3 {
+		t.Errorf("want 3 steps, got %d", len(job.Steps))
+	}
+	if job.Steps[0].Pos.Line != 9 {
+		t.Errorf("first step line = %d, want 9", job.Steps[0].Pos.Line)
+	}
+}
+
+func TestParseCompositeAction_NonComposite(t *testing.T) {
+	t.Parallel()
+
+	wf, errs := ParseCompositeAction([]byte("name: js\nruns:\n  using: node20\n  main: index.js\n"))
+	if wf != nil || len(errs) != 0 {
+		t.Errorf("JavaScript action should yield no tree and no errors, got %v, %v", wf, errs)
+	}
+}
+
+func TestLinter_LintsCompositeActionSteps(t *testing.T) {
+	t.Parallel()
+
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatalf("NewLinter: %v", err)
+	}
+	result, err := linter.Lint("action.yml", []byte(compositeActionFixture), nil)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+
+	got := map[string]int{}
+	for _, e := range result.Errors {
+		got[e.Type]++
+		if _, ok := compositeActionRules[e.Type]; !ok && e.Type != "syntax" {
+			t.Errorf("rule %q should not run on composite actions: %v", e.Type, e)
+		}
+	}
+	// inputs.title twice and github.event.issue.title once.
+	if got["code-injection-medium"] != 3 {
+		t.Errorf("want 3 code-injection-medium findings, got %d: %v", got["code-injection-medium"], result.Errors)
+	}
+	if got["envvar-injection-medium"] != 1 {
+		t.Errorf("want 1 envvar-injection-medium finding, got %d: %v", got["envvar-injection-medium"], result.Errors)
+	}
+}
+
+func TestLintRepository_IncludesLocalActions(t *testing.T) {
+	t.Parallel()
+
+	root := makeTestProject(t)
+	actionDir := filepath.Join(root, ".github", "actions", "greet")
+	if err := os.MkdirAll(actionDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(actionDir, "action.yml"), compositeActionFixture)
+	writeTestFile(t, filepath.Join(actionDir, "README.yml"), "not: an action\n")
+
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatalf("NewLinter: %v", err)
+	}
+	results, err := linter.LintRepository(root)
+	if err != nil {
+		t.Fatalf("LintRepository: %v", err)
+	}
+	found := false
+	for _, r := range results {
+		if filepath.Base(r.FilePath) == "README.yml" {
+			t.Errorf("non-action YAML under .github/actions should not be linted")
+		}
+		if filepath.Base(r.FilePath) == "action.yml" && len(r.Errors) > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("findings of the local composite action should be reported")
+	}
+}
@@ -95,7 +95,7 @@ func handleYamlError(err error) []*LintingError {
 			lineNumber, _ = strconv.Atoi(matches[1])
 		}
 		msg = fmt.Sprintf("it could not parse as YAML: %s", msg)
-		return &LintingError{msg, "", lineNumber, 0, "syntax"}
+		return &LintingError{Description: msg, LineNumber: lineNumber, Type: "syntax", Severity: SeverityHigh}
 	}
 
 	var typeError *yaml.TypeError
@@ -27,11 +27,11 @@ func nodeKindName(k yaml.Kind) string {
 }
 
 func (project *parser) error(node *yaml.Node, msg string) {
-	project.errors = append(project.errors, &LintingError{msg, "", node.Line, node.Column, "syntax"})
+	project.errors = append(project.errors, &LintingError{Description: msg, LineNumber: node.Line, ColNumber: node.Column, Type: "syntax", Severity: SeverityHigh})
 }
 
 func (project *parser) errorAt(position *ast.Position, msg string) {
-	project.errors = append(project.errors, &LintingError{msg, "", position.Line, position.Col, "syntax"})
+	project.errors = append(project.errors, &LintingError{Description: msg, LineNumber: position.Line, ColNumber: position.Col, Type: "syntax", Severity: SeverityHigh})
 }
 
 func (project *parser) errorf(node *yaml.Node, format string, args ...interface{}) {
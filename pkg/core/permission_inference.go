@@ -0,0 +1,362 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// jobPermissions maps a permission scope to "read" or "write". Scopes which
+// are not needed are absent.
+type jobPermissions map[string]string
+
+// permissionRank orders permission values so that grants can be compared.
+func permissionRank(v string) int {
+	switch v {
+	case "write":
+		return 2
+	case "read":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// require raises the scope to at least the given value.
+func (p jobPermissions) require(scope, value string) {
+	if permissionRank(value) > permissionRank(p[scope]) {
+		p[scope] = value
+	}
+}
+
+func (p jobPermissions) merge(other jobPermissions) {
+	for scope, value := range other {
+		p.require(scope, value)
+	}
+}
+
+func (p jobPermissions) scopes() []string {
+	scopes := make([]string, 0, len(p))
+	for s := range p {
+		scopes = append(scopes, s)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// String renders the permissions like a YAML flow mapping.
+func (p jobPermissions) String() string {
+	parts := make([]string, 0, len(p))
+	for _, s := range p.scopes() {
+		parts = append(parts, s+": "+p[s])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// knownActionPermissions lists the GITHUB_TOKEN permissions which popular
+// actions need with their default inputs. Keys are lower-cased "owner/repo"
+// or "owner/repo/path" without the ref. Actions with an empty map need no
+// permission at all.
+var knownActionPermissions = map[string]jobPermissions{
+	"actions/checkout":                      {"contents": "read"},
+	"actions/setup-node":                    {},
+	"actions/setup-python":                  {},
+	"actions/setup-go":                      {},
+	"actions/setup-java":                    {},
+	"actions/setup-dotnet":                  {},
+	"actions/cache":                         {},
+	"actions/cache/restore":                 {},
+	"actions/cache/save":                    {},
+	"actions/upload-artifact":               {},
+	"actions/download-artifact":             {},
+	"actions/upload-pages-artifact":         {},
+	"actions/configure-pages":               {"pages": "read"},
+	"actions/deploy-pages":                  {"pages": "write", "id-token": "write"},
+	"actions/attest-build-provenance":       {"id-token": "write", "attestations": "write"},
+	"actions/attest":                        {"id-token": "write", "attestations": "write"},
+	"actions/dependency-review-action":      {"contents": "read"},
+	"actions/labeler":                       {"contents": "read", "pull-requests": "write"},
+	"actions/stale":                         {"issues": "write", "pull-requests": "write"},
+	"actions/first-interaction":             {"issues": "write", "pull-requests": "write"},
+	"github/codeql-action/init":             {"actions": "read", "contents": "read"},
+	"github/codeql-action/autobuild":        {},
+	"github/codeql-action/analyze":          {"actions": "read", "security-events": "write"},
+	"github/codeql-action/upload-sarif":     {"actions": "read", "security-events": "write"},
+	"docker/setup-buildx-action":            {},
+	"docker/setup-qemu-action":              {},
+	"docker/metadata-action":                {},
+	"peter-evans/create-pull-request":       {"contents": "write", "pull-requests": "write"},
+	"softprops/action-gh-release":           {"contents": "write"},
+	"aws-actions/configure-aws-credentials": {},
+	"google-github-actions/auth":            {},
+	"azure/login":                           {},
+	"pypa/gh-action-pypi-publish":           {},
+	"hashicorp/vault-action":                {},
+}
+
+// oidcActions tells whether an action authenticates with a GitHub OIDC token
+// (and so needs "id-token: write") for the given inputs. Most of them fall
+// back to static credentials when configured with those.
+var oidcActions = map[string]func(inputs map[string]*ast.Input) bool{
+	"aws-actions/configure-aws-credentials": func(in map[string]*ast.Input) bool {
+		return in["role-to-assume"] != nil && in["aws-access-key-id"] == nil && in["web-identity-token-file"] == nil
+	},
+	"google-github-actions/auth": func(in map[string]*ast.Input) bool {
+		return in["workload_identity_provider"] != nil
+	},
+	"azure/login": func(in map[string]*ast.Input) bool {
+		return in["creds"] == nil
+	},
+	"pypa/gh-action-pypi-publish": func(in map[string]*ast.Input) bool {
+		return in["password"] == nil
+	},
+	"hashicorp/vault-action": func(in map[string]*ast.Input) bool {
+		return in["method"] != nil && in["method"].Value != nil && in["method"].Value.Value == "jwt"
+	},
+}
+
+// ghCommandPermissions lists the permissions needed by "gh <group> <command>"
+// when gh authenticates with GITHUB_TOKEN. Commands missing here are unknown.
+var ghCommandPermissions = map[string]map[string]jobPermissions{
+	"pr": {
+		"create":   {"pull-requests": "write"},
+		"edit":     {"pull-requests": "write"},
+		"comment":  {"pull-requests": "write"},
+		"review":   {"pull-requests": "write"},
+		"close":    {"pull-requests": "write"},
+		"reopen":   {"pull-requests": "write"},
+		"ready":    {"pull-requests": "write"},
+		"lock":     {"pull-requests": "write"},
+		"unlock":   {"pull-requests": "write"},
+		"merge":    {"contents": "write", "pull-requests": "write"},
+		"view":     {"pull-requests": "read"},
+		"list":     {"pull-requests": "read"},
+		"diff":     {"pull-requests": "read"},
+		"checks":   {"pull-requests": "read", "checks": "read"},
+		"status":   {"pull-requests": "read"},
+		"checkout": {"contents": "read", "pull-requests": "read"},
+	},
+	"issue": {
+		"create":   {"issues": "write"},
+		"edit":     {"issues": "write"},
+		"comment":  {"issues": "write"},
+		"close":    {"issues": "write"},
+		"reopen":   {"issues": "write"},
+		"delete":   {"issues": "write"},
+		"lock":     {"issues": "write"},
+		"unlock":   {"issues": "write"},
+		"pin":      {"issues": "write"},
+		"unpin":    {"issues": "write"},
+		"transfer": {"issues": "write"},
+		"view":     {"issues": "read"},
+		"list":     {"issues": "read"},
+		"status":   {"issues": "read"},
+	},
+	"label": {
+		"create": {"issues": "write"},
+		"edit":   {"issues": "write"},
+		"delete": {"issues": "write"},
+		"clone":  {"issues": "write"},
+		"list":   {"issues": "read"},
+	},
+	"release": {
+		"create":       {"contents": "write"},
+		"edit":         {"contents": "write"},
+		"delete":       {"contents": "write"},
+		"upload":       {"contents": "write"},
+		"delete-asset": {"contents": "write"},
+		"view":         {"contents": "read"},
+		"list":         {"contents": "read"},
+		"download":     {"contents": "read"},
+	},
+	"run": {
+		"rerun":    {"actions": "write"},
+		"cancel":   {"actions": "write"},
+		"delete":   {"actions": "write"},
+		"view":     {"actions": "read"},
+		"list":     {"actions": "read"},
+		"watch":    {"actions": "read"},
+		"download": {"actions": "read"},
+	},
+	"workflow": {
+		"run":     {"actions": "write"},
+		"enable":  {"actions": "write"},
+		"disable": {"actions": "write"},
+		"view":    {"actions": "read"},
+		"list":    {"actions": "read"},
+	},
+	"cache": {
+		"delete": {"actions": "write"},
+		"list":   {"actions": "read"},
+	},
+	"repo": {
+		"clone": {"contents": "read"},
+		"view":  {"contents": "read"},
+	},
+	"auth": {
+		"status":    {},
+		"token":     {},
+		"setup-git": {},
+	},
+}
+
+var (
+	ghCommandPattern = regexp.MustCompile(`(?m)(?:^|[;&|(\s])gh\s+(\S+)(?:[ \t]+(\S+))?`)
+	gitPushPattern   = regexp.MustCompile(`(?m)(?:^|[;&|(\s])git\s+push\b`)
+	// runTokenPattern finds scripts which use the token or the REST API
+	// directly, so that their permissions cannot be inferred.
+	runTokenPattern = regexp.MustCompile(`(?i)github\.token|secrets\.github_token|api\.github\.com|\$\{?GITHUB_API_URL|\$\{?GITHUB_GRAPHQL_URL`)
+	// runOIDCPattern finds scripts which may request an OIDC token, such as
+	// "npm publish --provenance" or keyless "cosign sign", so that whether
+	// they need "id-token: write" cannot be inferred.
+	runOIDCPattern = regexp.MustCompile(`ACTIONS_ID_TOKEN_REQUEST_|--provenance\b|\bcosign\b`)
+)
+
+// inferJobPermissions computes the minimal permissions the steps of the job
+// need. ok is false when some step is not understood, such as an unknown
+// action, a local action, or a script calling the API directly.
+func inferJobPermissions(job *ast.Job) (perms jobPermissions, ok bool) {
+	if job.WorkflowCall != nil {
+		return nil, false
+	}
+	perms = jobPermissions{}
+	for _, step := range job.Steps {
+		switch exec := step.Exec.(type) {
+		case *ast.ExecAction:
+			p, ok := inferActionPermissions(exec)
+			if !ok {
+				return nil, false
+			}
+			perms.merge(p)
+		case *ast.ExecRun:
+			p, ok := inferRunPermissions(exec)
+			if !ok {
+				return nil, false
+			}
+			perms.merge(p)
+		default:
+			return nil, false
+		}
+	}
+	return perms, true
+}
+
+func inferActionPermissions(exec *ast.ExecAction) (jobPermissions, bool) {
+	if exec.Uses == nil {
+		return nil, false
+	}
+	uses := exec.Uses.Value
+	if isLocalAction(uses) || isDockerAction(uses) || strings.Contains(uses, "${{") {
+		return nil, false
+	}
+	name := strings.ToLower(uses)
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	known, ok := knownActionPermissions[name]
+	if !ok {
+		return nil, false
+	}
+	perms := jobPermissions{}
+	perms.merge(known)
+	if oidc, ok := oidcActions[name]; ok && oidc(exec.Inputs) {
+		perms.require("id-token", "write")
+	}
+	return perms, true
+}
+
+func inferRunPermissions(exec *ast.ExecRun) (jobPermissions, bool) {
+	if exec.Run == nil {
+		return jobPermissions{}, true
+	}
+	script := exec.Run.Value
+	if runTokenPattern.MatchString(script) || runOIDCPattern.MatchString(script) {
+		return nil, false
+	}
+	perms := jobPermissions{}
+	for _, m := range ghCommandPattern.FindAllStringSubmatch(script, -1) {
+		commands, ok := ghCommandPermissions[m[1]]
+		if !ok {
+			return nil, false
+		}
+		p, ok := commands[m[2]]
+		if !ok {
+			return nil, false
+		}
+		perms.merge(p)
+	}
+	if gitPushPattern.MatchString(script) {
+		perms.require("contents", "write")
+	}
+	return perms, true
+}
+
+// excessPermissions returns the scopes granted by p above what is needed,
+// formatted for messages. "read-all" is accepted as a safe baseline, and
+// permissions which are invalid are reported elsewhere.
+func excessPermissions(p *ast.Permissions, needed jobPermissions) []string {
+	var excess []string
+	if p.All != nil {
+		if p.All.Value != "write-all" {
+			return nil
+		}
+		for scope := range allPermissionScopes {
+			if permissionRank(needed[scope]) < permissionRank("write") {
+				excess = append(excess, fmt.Sprintf("%s: write", scope))
+			}
+		}
+		sort.Strings(excess)
+		return excess
+	}
+	for name, scope := range p.Scopes {
+		if scope.Value == nil {
+			continue
+		}
+		if _, ok := allPermissionScopes[name]; !ok {
+			continue
+		}
+		if permissionRank(scope.Value.Value) > permissionRank(needed[name]) {
+			excess = append(excess, fmt.Sprintf("%s: %s", name, scope.Value.Value))
+		}
+	}
+	sort.Strings(excess)
+	return excess
+}
+
+// setJobPermissions replaces or inserts "permissions:" of the job mapping
+// node with the given permissions.
+func setJobPermissions(node *yaml.Node, perms jobPermissions) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(perms) == 0 {
+		value.Style = yaml.FlowStyle
+	}
+	for _, scope := range perms.scopes() {
+		value.Content = append(value.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scope},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: perms[scope]},
+		)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "permissions" {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "permissions"}
+	// Put it before steps, next to the other job settings.
+	insert := len(node.Content)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "steps" {
+			insert = i
+			break
+		}
+	}
+	node.Content = append(node.Content[:insert], append([]*yaml.Node{key, value}, node.Content[insert:]...)...)
+}
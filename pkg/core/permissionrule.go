@@ -1,6 +1,9 @@
 package core
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/sisaku-security/sisakulint/pkg/ast"
 	"github.com/sisaku-security/sisakulint/pkg/expressions"
 	"gopkg.in/yaml.v3"
@@ -33,6 +36,18 @@ type PermissionRule struct {
 	// isReusableWorkflow indicates if the workflow is a reusable workflow (has workflow_call event)
 	// Reusable workflows inherit permissions from the caller, so missing permissions is not an error
 	isReusableWorkflow bool
+	// workflowPermissions is the workflow-level permissions which jobs
+	// without their own "permissions:" inherit
+	workflowPermissions *ast.Permissions
+	// inheritingJobs are the jobs inheriting workflowPermissions with the
+	// permissions inferred from their steps
+	inheritingJobs map[*ast.Job]jobPermissions
+	// inheritedExcess is true when some job inherits more permissions than
+	// it needs from workflowPermissions
+	inheritedExcess bool
+	// inheritedUnknown is true when the needs of some job inheriting
+	// workflowPermissions cannot be inferred
+	inheritedUnknown bool
 }
 
 // PermissionsRule creates a new PermissionRule instance.
@@ -40,14 +55,119 @@ func PermissionsRule() *PermissionRule {
 	return &PermissionRule{
 		BaseRule: BaseRule{
 			RuleName: "permissions",
-			RuleDesc: "Checks for permissions configuration in \"permissions:\". Permission names and permission scopes are checked",
+			RuleDesc: "Checks for permissions configuration in \"permissions:\". Permission names and permission scopes are checked, and grants beyond what the steps of a job need are reported",
+			severity: SeverityHigh,
 		},
+		inheritingJobs: map[*ast.Job]jobPermissions{},
 	}
 }
 
 // VisitJobPre is callback when visiting Job node before visiting its children.
 func (rule *PermissionRule) VisitJobPre(n *ast.Job) error {
 	rule.checkPermissions(n.Permissions)
+	rule.checkLeastPrivilege(n)
+	return nil
+}
+
+// checkLeastPrivilege compares the permissions the job is granted, by itself
+// or by the workflow, with the minimal permissions inferred from its steps.
+// Jobs whose steps cannot all be understood are not checked.
+func (rule *PermissionRule) checkLeastPrivilege(n *ast.Job) {
+	if n.ID == nil {
+		return
+	}
+	granted := n.Permissions
+	pos := n.ID.Pos
+	if granted == nil {
+		granted = rule.workflowPermissions
+	} else if granted.Pos != nil {
+		pos = granted.Pos
+	}
+	if granted == nil || pos == nil {
+		return
+	}
+	inherited := n.Permissions == nil
+	needed, ok := inferJobPermissions(n)
+	if !ok {
+		if inherited {
+			rule.inheritedUnknown = true
+		}
+		return
+	}
+	if inherited {
+		rule.inheritingJobs[n] = needed
+	}
+	excess := excessPermissions(granted, needed)
+	if len(excess) == 0 {
+		return
+	}
+	where := "job"
+	if inherited {
+		where = "workflow"
+	}
+	// Read access beyond what is needed is far less dangerous than write
+	// access, so it is reported with a lower severity.
+	severity := rule.severity
+	if !slices.ContainsFunc(excess, func(e string) bool { return !strings.HasSuffix(e, ": read") }) {
+		severity = SeverityLow
+	}
+	rule.ErrorfWithSeverity(pos, severity,
+		"job %q is granted more permissions than its steps need by the %s-level \"permissions:\": %s. "+
+			"The minimal permissions inferred from its steps are %s. "+
+			"See https://sisaku-security.github.io/lint/docs/rules/permissions/",
+		n.ID.Value, where, strings.Join(excess, ", "), needed)
+	if inherited {
+		// Adding a job-level block would leave the broad workflow-level
+		// grant for the other jobs. It is fixed once for the whole workflow
+		// in VisitWorkflowPost.
+		rule.inheritedExcess = true
+		return
+	}
+	rule.AddAutoFixer(NewJobFixer(n, rule))
+}
+
+// FixJob writes the minimal permissions inferred from the steps into the
+// job-level "permissions:".
+func (rule *PermissionRule) FixJob(n *ast.Job) error {
+	needed, ok := inferJobPermissions(n)
+	if !ok {
+		return nil
+	}
+	setJobPermissions(n.BaseNode, needed)
+	return nil
+}
+
+// VisitWorkflowPost is callback when visiting Workflow node after visiting its children.
+// When jobs inherit more permissions than they need from the workflow-level
+// "permissions:", and the needs of every job inheriting it are known, a fix
+// moving the minimal permissions into each job and emptying the
+// workflow-level block is offered.
+func (rule *PermissionRule) VisitWorkflowPost(n *ast.Workflow) error {
+	if !rule.inheritedExcess || rule.inheritedUnknown {
+		return nil
+	}
+	jobs := rule.inheritingJobs
+	rule.AddAutoFixer(NewFuncFixer(rule.RuleNames(), func() error {
+		return rule.fixWorkflowPermissions(n, jobs)
+	}))
+	return nil
+}
+
+// fixWorkflowPermissions writes the minimal permissions of each job which
+// inherits the workflow-level "permissions:" into the job, and replaces the
+// workflow-level block with "permissions: {}".
+func (rule *PermissionRule) fixWorkflowPermissions(n *ast.Workflow, jobs map[*ast.Job]jobPermissions) error {
+	if n.BaseNode == nil {
+		return nil
+	}
+	workflow := n.BaseNode
+	if workflow.Kind == yaml.DocumentNode && len(workflow.Content) > 0 {
+		workflow = workflow.Content[0]
+	}
+	for job, needed := range jobs {
+		setJobPermissions(job.BaseNode, needed)
+	}
+	setJobPermissions(workflow, jobPermissions{})
 	return nil
 }
 
@@ -56,6 +176,10 @@ func (rule *PermissionRule) VisitJobPre(n *ast.Job) error {
 func (rule *PermissionRule) VisitWorkflowPre(n *ast.Workflow) error {
 	// Reset state for each workflow
 	rule.isReusableWorkflow = false
+	rule.workflowPermissions = n.Permissions
+	rule.inheritingJobs = map[*ast.Job]jobPermissions{}
+	rule.inheritedExcess = false
+	rule.inheritedUnknown = false
 
 	// Check if this is a reusable workflow (has workflow_call event)
 	// Reusable workflows inherit permissions from the caller
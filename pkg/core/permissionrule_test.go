@@ -1,6 +1,8 @@
 package core
 
 import (
+	"bytes"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -479,3 +481,340 @@ jobs:
 		})
 	}
 }
+
+func TestInferJobPermissions(t *testing.T) {
+	t.Parallel()
+
+	uses := func(v string, inputs ...string) *ast.Step {
+		in := map[string]*ast.Input{}
+		for _, name := range inputs {
+			in[name] = &ast.Input{Name: &ast.String{Value: name}, Value: &ast.String{Value: "x"}}
+		}
+		return &ast.Step{Exec: &ast.ExecAction{Uses: &ast.String{Value: v}, Inputs: in}}
+	}
+	run := func(script string) *ast.Step {
+		return &ast.Step{Exec: &ast.ExecRun{Run: &ast.String{Value: script}}}
+	}
+
+	tests := []struct {
+		name  string
+		steps []*ast.Step
+		want  string
+		ok    bool
+	}{
+		{
+			name:  "checkout and build",
+			steps: []*ast.Step{uses("actions/checkout@v4"), uses("actions/setup-go@v5"), run("go test ./...")},
+			want:  "{contents: read}",
+			ok:    true,
+		},
+		{
+			name:  "pages deployment",
+			steps: []*ast.Step{uses("actions/upload-pages-artifact@v3"), uses("actions/deploy-pages@v4")},
+			want:  "{id-token: write, pages: write}",
+			ok:    true,
+		},
+		{
+			name:  "sarif upload",
+			steps: []*ast.Step{uses("github/codeql-action/upload-sarif@v3")},
+			want:  "{actions: read, security-events: write}",
+			ok:    true,
+		},
+		{
+			name:  "gh CLI subcommands",
+			steps: []*ast.Step{run("gh pr comment 1 --body hi\ngh issue list | head && gh release create v1")},
+			want:  "{contents: write, issues: read, pull-requests: write}",
+			ok:    true,
+		},
+		{
+			name:  "git push",
+			steps: []*ast.Step{uses("actions/checkout@v4"), run("git commit -am bump\ngit push origin HEAD")},
+			want:  "{contents: write}",
+			ok:    true,
+		},
+		{
+			name:  "OIDC credentials",
+			steps: []*ast.Step{uses("aws-actions/configure-aws-credentials@v4", "role-to-assume", "aws-region")},
+			want:  "{id-token: write}",
+			ok:    true,
+		},
+		{
+			name:  "static credentials",
+			steps: []*ast.Step{uses("aws-actions/configure-aws-credentials@v4", "aws-access-key-id", "aws-secret-access-key")},
+			want:  "{}",
+			ok:    true,
+		},
+		{
+			name:  "unknown action",
+			steps: []*ast.Step{uses("actions/checkout@v4"), uses("example/unknown@v1")},
+		},
+		{
+			name:  "local action",
+			steps: []*ast.Step{uses("./.github/actions/setup")},
+		},
+		{
+			name:  "gh api",
+			steps: []*ast.Step{run("gh api repos/{owner}/{repo}/issues")},
+		},
+		{
+			name:  "direct REST API call",
+			steps: []*ast.Step{run(`curl -H "Authorization: Bearer ${{ github.token }}" https://api.github.com/repos/o/r`)},
+		},
+		{
+			name:  "npm provenance",
+			steps: []*ast.Step{uses("actions/checkout@v4"), uses("actions/setup-node@v4"), run("npm ci\nnpm publish --provenance --access public")},
+		},
+		{
+			name:  "keyless cosign",
+			steps: []*ast.Step{run(`cosign sign --yes "$IMAGE@$DIGEST"`)},
+		},
+		{
+			name:  "OIDC token request",
+			steps: []*ast.Step{run(`curl -H "Authorization: bearer $ACTIONS_ID_TOKEN_REQUEST_TOKEN" "$ACTIONS_ID_TOKEN_REQUEST_URL&audience=sts"`)},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := inferJobPermissions(&ast.Job{Steps: tc.steps})
+			if ok != tc.ok {
+				t.Fatalf("ok = %v, want %v (got %v)", ok, tc.ok, got)
+			}
+			if ok && got.String() != tc.want {
+				t.Errorf("inferred %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPermissionRule_LeastPrivilege(t *testing.T) {
+	t.Parallel()
+
+	src := `on: push
+permissions: write-all
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - run: go test ./...
+  comment:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+      pull-requests: write
+    steps:
+      - run: gh pr comment 1 --body done
+  exact:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: read
+    steps:
+      - uses: actions/checkout@v4
+  custom:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+    steps:
+      - uses: example/unknown@v1
+`
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := linter.Lint("test.yaml", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []int
+	for _, e := range result.Errors {
+		if e.Type == "permissions" && strings.Contains(e.Description, "more permissions than its steps need") {
+			lines = append(lines, e.LineNumber)
+		}
+	}
+	// "test" inherits write-all from the workflow and is reported at its key;
+	// "comment" is reported at its own permissions block.
+	if !reflect.DeepEqual(lines, []int{4, 11}) {
+		t.Fatalf("want least-privilege findings at lines 4 and 11, got %v in %v", lines, result.Errors)
+	}
+
+	for _, f := range result.AutoFixers {
+		if f.RuleName() != "permissions" {
+			continue
+		}
+		if err := f.Fix(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(result.ParsedWorkflow.BaseNode); err != nil {
+		t.Fatal(err)
+	}
+	fixed := buf.String()
+	for _, want := range []string{
+		"  test:\n    runs-on: ubuntu-latest\n    permissions:\n      contents: read\n    steps:\n",
+		"    permissions:\n      pull-requests: write\n    steps:\n      - run: gh pr comment 1 --body done\n",
+		"  custom:\n    runs-on: ubuntu-latest\n    permissions:\n      contents: write\n",
+		"on: push\npermissions: {}\njobs:\n",
+	} {
+		if !strings.Contains(fixed, want) {
+			t.Errorf("fixed workflow should contain %q:\n%s", want, fixed)
+		}
+	}
+}
+
+func TestPermissionRule_LeastPrivilegeEmptyBlock(t *testing.T) {
+	t.Parallel()
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("runs-on: ubuntu-latest\nsteps:\n  - run: make\n"), &node); err != nil {
+		t.Fatal(err)
+	}
+	job := &ast.Job{
+		ID:       &ast.String{Value: "build", Pos: &ast.Position{Line: 1, Col: 1}},
+		Steps:    []*ast.Step{{Exec: &ast.ExecRun{Run: &ast.String{Value: "make"}}}},
+		BaseNode: node.Content[0],
+	}
+	rule := PermissionsRule()
+	rule.workflowPermissions = &ast.Permissions{
+		Scopes: map[string]*ast.PermissionScope{
+			"contents": {Name: &ast.String{Value: "contents"}, Value: &ast.String{Value: "read"}},
+		},
+	}
+	if err := rule.VisitJobPre(job); err != nil {
+		t.Fatal(err)
+	}
+	if len(rule.Errors()) != 1 || !strings.Contains(rule.Errors()[0].Description, "contents: read") {
+		t.Fatalf("unexpected errors: %v", rule.Errors())
+	}
+	if err := rule.FixJob(job); err != nil {
+		t.Fatal(err)
+	}
+	out, err := yaml.Marshal(job.BaseNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "permissions: {}\nsteps:") {
+		t.Errorf("want an empty permissions block before steps, got:\n%s", out)
+	}
+}
+
+func lintLeastPrivilege(t *testing.T, src string) *ValidateResult {
+	t.Helper()
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := linter.Lint("test.yaml", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestPermissionRule_LeastPrivilegeReadOnlyExcessIsLowSeverity(t *testing.T) {
+	t.Parallel()
+
+	result := lintLeastPrivilege(t, `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: read
+      issues: read
+    steps:
+      - uses: actions/checkout@v4
+  release:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+      issues: read
+    steps:
+      - uses: actions/checkout@v4
+`)
+	got := map[int]Severity{}
+	for _, e := range result.Errors {
+		if e.Type == "permissions" && strings.Contains(e.Description, "more permissions than its steps need") {
+			got[e.LineNumber] = e.Severity
+		}
+	}
+	want := map[int]Severity{5: SeverityLow, 12: SeverityHigh}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want severities %v, got %v", want, got)
+	}
+}
+
+func TestPermissionRule_LeastPrivilegeWorkflowLevelFix(t *testing.T) {
+	t.Parallel()
+
+	const src = `on: push
+permissions:
+  contents: write
+  issues: write
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - run: gh release create v1
+`
+	t.Run("every inheriting job is known", func(t *testing.T) {
+		t.Parallel()
+
+		result := lintLeastPrivilege(t, src)
+		var fixers []AutoFixer
+		for _, f := range result.AutoFixers {
+			if f.RuleName() == "permissions" {
+				fixers = append(fixers, f)
+			}
+		}
+		if len(fixers) != 1 {
+			t.Fatalf("want one workflow-level fixer, got %d", len(fixers))
+		}
+		if err := fixers[0].Fix(); err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		enc := yaml.NewEncoder(&out)
+		enc.SetIndent(2)
+		if err := enc.Encode(result.ParsedWorkflow.BaseNode); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			"on: push\npermissions: {}\n",
+			"  test:\n    runs-on: ubuntu-latest\n    permissions:\n      contents: read\n",
+			"  release:\n    runs-on: ubuntu-latest\n    permissions:\n      contents: write\n",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("fixed workflow should contain %q:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("some inheriting job is unknown", func(t *testing.T) {
+		t.Parallel()
+
+		result := lintLeastPrivilege(t, src+`  custom:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: example/unknown@v1
+`)
+		reported := false
+		for _, e := range result.Errors {
+			reported = reported || strings.Contains(e.Description, "more permissions than its steps need")
+		}
+		if !reported {
+			t.Fatal("excess of the known jobs should still be reported")
+		}
+		for _, f := range result.AutoFixers {
+			if f.RuleName() == "permissions" {
+				t.Fatal("the workflow-level block should not be fixed when the needs of a job inheriting it are unknown")
+			}
+		}
+	})
+}
@@ -0,0 +1,290 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"golang.org/x/sys/execabs"
+)
+
+// PolicyDirectory is the directory of policy files relative to the
+// repository root.
+const PolicyDirectory = ".github/sisakulint/policies"
+
+// Policy is a policy file in PolicyDirectory.
+type Policy struct {
+	// Name is the file name without its extension. Findings of the policy
+	// have it as their rule name.
+	Name string
+	// Path is the path of the file.
+	Path string
+	// Source is the content of the file.
+	Source []byte
+}
+
+// PolicyDenial is a denial returned by a policy.
+type PolicyDenial struct {
+	// Message is the message of the finding.
+	Message string
+	// Pos is where the finding is reported. Nil means the top of the file.
+	Pos *ast.Position
+	// Severity overrides the default severity (medium) when not empty.
+	Severity Severity
+}
+
+// PolicyEngine evaluates policies written in one policy language. input is
+// the JSON form of a workflow written by MarshalWorkflowJSON. Engines for
+// other languages, e.g. an in-process Rego or CEL evaluator, can be plugged
+// in with LinterOptions.PolicyEngines.
+type PolicyEngine interface {
+	// Extensions returns the file extensions the engine evaluates, such as
+	// ".rego".
+	Extensions() []string
+	// Evaluate evaluates policy against input and returns its denials.
+	Evaluate(policy *Policy, input []byte) ([]*PolicyDenial, error)
+}
+
+// loadPolicies reads the policy files in PolicyDirectory of the repository
+// at root. Files ending with "_test" (Rego unit tests) are skipped.
+func loadPolicies(root string) ([]*Policy, error) {
+	dir := filepath.Join(root, filepath.FromSlash(PolicyDirectory))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read policy directory %q: %w", dir, err)
+	}
+	var policies []*Policy
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		if strings.HasSuffix(name, "_test") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read policy %q: %w", path, err)
+		}
+		policies = append(policies, &Policy{Name: name, Path: path, Source: src})
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Path < policies[j].Path })
+	return policies, nil
+}
+
+// policyLanguageExtensions are the extensions of the policy languages known to
+// sisakulint. A file with one of them is a policy even when no engine can
+// evaluate it, so that a missing engine is reported rather than ignored.
+var policyLanguageExtensions = []string{".rego", ".cel"}
+
+// selectPolicies returns the policies which are written in a known policy
+// language or which an engine can evaluate. Other files in PolicyDirectory,
+// such as a README.md or a data.json read by Rego policies, are skipped.
+func (l *Linter) selectPolicies(policies []*Policy) []*Policy {
+	selected := make([]*Policy, 0, len(policies))
+	for _, p := range policies {
+		ext := strings.ToLower(filepath.Ext(p.Path))
+		if !slices.Contains(policyLanguageExtensions, ext) && policyEngineFor(l.policyEngines, p) == nil {
+			l.debug("Skipped %q in the policy directory since no policy engine handles its extension", p.Path)
+			continue
+		}
+		selected = append(selected, p)
+	}
+	return selected
+}
+
+// policyNames returns the names of policies.
+func policyNames(policies []*Policy) []string {
+	names := make([]string, 0, len(policies))
+	for _, p := range policies {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// checkPolicyNames fails when a policy has the name of a rule, since their
+// findings could not be told apart.
+func checkPolicyNames(policies []*Policy, ruleNames []string) error {
+	names := make(map[string]struct{}, len(ruleNames))
+	for _, n := range ruleNames {
+		names[n] = struct{}{}
+	}
+	for _, p := range policies {
+		if _, ok := names[p.Name]; ok {
+			return fmt.Errorf("policy %q has the same name as a rule. rename the file", p.Path)
+		}
+	}
+	return nil
+}
+
+// evaluatePolicies evaluates every policy with the engine for its file
+// extension and converts the denials into errors whose Type is the policy
+// name. A policy no engine can evaluate is an error, so that a missing
+// engine does not silently turn a policy off.
+func evaluatePolicies(policies []*Policy, engines []PolicyEngine, path string, w *ast.Workflow) ([]*LintingError, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	input, err := MarshalWorkflowJSON(path, w)
+	if err != nil {
+		return nil, fmt.Errorf("could not serialize workflow %q for policies: %w", path, err)
+	}
+	var errs []*LintingError
+	for _, p := range policies {
+		engine := policyEngineFor(engines, p)
+		if engine == nil {
+			return nil, fmt.Errorf("no policy engine can evaluate %q. Rego policies need the opa command in PATH", p.Path)
+		}
+		denials, err := engine.Evaluate(p, input)
+		if err != nil {
+			return nil, fmt.Errorf("could not evaluate policy %q: %w", p.Path, err)
+		}
+		for _, d := range denials {
+			pos := d.Pos
+			if pos == nil {
+				pos = &ast.Position{Line: 1, Col: 1}
+			}
+			e := NewError(pos, p.Name, d.Message)
+			e.Severity = SeverityMedium
+			if d.Severity != "" {
+				e.Severity = d.Severity
+			}
+			errs = append(errs, e)
+		}
+	}
+	return errs, nil
+}
+
+func policyEngineFor(engines []PolicyEngine, p *Policy) PolicyEngine {
+	ext := strings.ToLower(filepath.Ext(p.Path))
+	for _, e := range engines {
+		for _, x := range e.Extensions() {
+			if strings.EqualFold(x, ext) {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// OPAPolicyEngine evaluates Rego policies with the opa command. Each policy
+// must define a "deny" set in its package, whose elements are either message
+// strings or objects like {"msg": "...", "pos": {"line": 3, "col": 5},
+// "severity": "high"}. "pos" can be taken from the input, e.g. job.pos.
+type OPAPolicyEngine struct {
+	exe string
+}
+
+// NewOPAPolicyEngine looks up the opa executable exe (a path or a command
+// name in PATH).
+func NewOPAPolicyEngine(exe string) (*OPAPolicyEngine, error) {
+	p, err := execabs.LookPath(exe)
+	if err != nil {
+		return nil, err
+	}
+	return &OPAPolicyEngine{exe: p}, nil
+}
+
+// Extensions implements PolicyEngine.
+func (e *OPAPolicyEngine) Extensions() []string {
+	return []string{".rego"}
+}
+
+var regoPackagePattern = regexp.MustCompile(`(?m)^\s*package\s+([A-Za-z_][\w.]*)`)
+
+// Evaluate implements PolicyEngine by querying data.<package>.deny. The whole
+// directory of the policy is loaded, so that policies can share Rego modules
+// and read data files placed next to them.
+func (e *OPAPolicyEngine) Evaluate(policy *Policy, input []byte) ([]*PolicyDenial, error) {
+	m := regoPackagePattern.FindSubmatch(policy.Source)
+	if m == nil {
+		return nil, fmt.Errorf("no package declaration")
+	}
+	query := "data." + string(m[1]) + ".deny"
+	out, err := executeWithStdin(e.exe, []string{"eval", "--format", "json", "--stdin-input", "--data", filepath.Dir(policy.Path), query}, string(input))
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if len(out) == 0 {
+				out = exitErr.Stderr
+			}
+			return nil, fmt.Errorf("opa failed: %s", strings.TrimSpace(string(out)))
+		}
+		return nil, fmt.Errorf("opa failed: %w", err)
+	}
+	return parseOPAEvalOutput(out)
+}
+
+// parseOPAEvalOutput parses the output of "opa eval --format json".
+func parseOPAEvalOutput(out []byte) ([]*PolicyDenial, error) {
+	var result struct {
+		Result []struct {
+			Expressions []struct {
+				Value interface{} `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("could not parse opa output: %w", err)
+	}
+	var denials []*PolicyDenial
+	for _, r := range result.Result {
+		for _, expr := range r.Expressions {
+			values, ok := expr.Value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("deny must be a set, but it is %T", expr.Value)
+			}
+			for _, v := range values {
+				d, err := policyDenialOf(v)
+				if err != nil {
+					return nil, err
+				}
+				denials = append(denials, d)
+			}
+		}
+	}
+	return denials, nil
+}
+
+// policyDenialOf converts an element of a deny set into a denial.
+func policyDenialOf(v interface{}) (*PolicyDenial, error) {
+	switch v := v.(type) {
+	case string:
+		return &PolicyDenial{Message: v}, nil
+	case map[string]interface{}:
+		msg, ok := v["msg"].(string)
+		if !ok {
+			return nil, fmt.Errorf("deny object must have a \"msg\" string: %v", v)
+		}
+		d := &PolicyDenial{Message: msg}
+		if pos, ok := v["pos"].(map[string]interface{}); ok {
+			line, _ := pos["line"].(float64)
+			col, _ := pos["col"].(float64)
+			if line > 0 {
+				d.Pos = &ast.Position{Line: int(line), Col: max(int(col), 1)}
+			}
+		}
+		if s, ok := v["severity"].(string); ok && s != "" {
+			sev, err := ParseSeverity(s)
+			if err != nil {
+				return nil, err
+			}
+			d.Severity = sev
+		}
+		return d, nil
+	default:
+		return nil, fmt.Errorf("deny element must be a string or an object: %v", v)
+	}
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+const policyTestWorkflow = `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    environment: staging
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          persist-credentials: false
+      - run: make
+`
+
+func TestMarshalWorkflowJSON(t *testing.T) {
+	t.Parallel()
+
+	w, errs := Parse([]byte(policyTestWorkflow))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	b, err := MarshalWorkflowJSON(".github/workflows/ci.yml", w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Version  int    `json:"version"`
+		Path     string `json:"path"`
+		Workflow struct {
+			On []struct {
+				Kind  string `json:"kind"`
+				Event string `json:"event"`
+			} `json:"on"`
+			Jobs map[string]struct {
+				Pos         ast.Position `json:"pos"`
+				Environment struct {
+					Name struct {
+						Value string       `json:"value"`
+						Pos   ast.Position `json:"pos"`
+					} `json:"name"`
+				} `json:"environment"`
+				RunsOn struct {
+					LabelsExpr struct {
+						Value string `json:"value"`
+					} `json:"labels_expr"`
+				} `json:"runs_on"`
+				Steps []struct {
+					Exec map[string]interface{} `json:"exec"`
+				} `json:"steps"`
+				TimeoutMinutes interface{} `json:"timeout_minutes"`
+			} `json:"jobs"`
+		} `json:"workflow"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, b)
+	}
+	if doc.Version != WorkflowJSONVersion || doc.Path != ".github/workflows/ci.yml" {
+		t.Errorf("unexpected header: %d %q", doc.Version, doc.Path)
+	}
+	if len(doc.Workflow.On) != 1 || doc.Workflow.On[0].Kind != "webhook_event" || doc.Workflow.On[0].Event != "push" {
+		t.Errorf("unexpected events: %+v", doc.Workflow.On)
+	}
+	job := doc.Workflow.Jobs["build"]
+	if job.Pos.Line != 3 || job.Environment.Name.Value != "staging" || job.Environment.Name.Pos.Line != 5 {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	if job.RunsOn.LabelsExpr.Value != "ubuntu-latest" || job.TimeoutMinutes != nil {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	if len(job.Steps) != 2 || job.Steps[0].Exec["kind"] != "exec_action" || job.Steps[1].Exec["kind"] != "exec_run" {
+		t.Errorf("unexpected steps: %+v", job.Steps)
+	}
+	if strings.Contains(string(b), "base_node") {
+		t.Error("YAML nodes should not be serialized")
+	}
+}
+
+func TestParseOPAEvalOutput(t *testing.T) {
+	t.Parallel()
+
+	out := `{"result":[{"expressions":[{"value":["plain message",{"msg":"at job","pos":{"line":3,"col":3},"severity":"high"}],"text":"data.x.deny"}]}]}`
+	denials, err := parseOPAEvalOutput([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(denials) != 2 || denials[0].Message != "plain message" || denials[0].Pos != nil {
+		t.Fatalf("unexpected denials: %+v", denials)
+	}
+	if d := denials[1]; d.Message != "at job" || d.Pos == nil || d.Pos.Line != 3 || d.Severity != SeverityHigh {
+		t.Errorf("unexpected denial: %+v", d)
+	}
+	if denials, err := parseOPAEvalOutput([]byte(`{}`)); err != nil || len(denials) != 0 {
+		t.Errorf("undefined deny should mean no denials, got %v %v", denials, err)
+	}
+	if _, err := parseOPAEvalOutput([]byte(`{"result":[{"expressions":[{"value":[{"message":"x"}]}]}]}`)); err == nil {
+		t.Error("a deny object without msg should be rejected")
+	}
+}
+
+// stubPolicyEngine denies every job which is not in the prod environment.
+type stubPolicyEngine struct{}
+
+func (stubPolicyEngine) Extensions() []string { return []string{".stub"} }
+
+func (stubPolicyEngine) Evaluate(_ *Policy, input []byte) ([]*PolicyDenial, error) {
+	var doc struct {
+		Workflow struct {
+			Jobs map[string]struct {
+				ID          struct{ Value string } `json:"id"`
+				Pos         *ast.Position          `json:"pos"`
+				Environment *struct {
+					Name struct{ Value string } `json:"name"`
+				} `json:"environment"`
+			} `json:"jobs"`
+		} `json:"workflow"`
+	}
+	if err := json.Unmarshal(input, &doc); err != nil {
+		return nil, err
+	}
+	var denials []*PolicyDenial
+	for _, j := range doc.Workflow.Jobs {
+		if j.Environment == nil || j.Environment.Name.Value != "prod" {
+			denials = append(denials, &PolicyDenial{Message: "job " + j.ID.Value + " must deploy to prod", Pos: j.Pos})
+		}
+	}
+	return denials, nil
+}
+
+func TestLinter_Policies(t *testing.T) {
+	t.Parallel()
+
+	root := makeTestProject(t)
+	dir := filepath.Join(root, filepath.FromSlash(PolicyDirectory))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "prod_only.stub"), "")
+	writeTestFile(t, filepath.Join(dir, "prod_only_test.stub"), "")
+	project, err := NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(project.Policies()); n != 1 {
+		t.Fatalf("want 1 policy, got %d", n)
+	}
+
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard, PolicyEngines: []PolicyEngine{stubPolicyEngine{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, ".github", "workflows", "ci.yml")
+	result, err := linter.Lint(path, []byte(policyTestWorkflow), project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []*LintingError
+	for _, e := range result.Errors {
+		if e.Type == "prod_only" {
+			found = append(found, e)
+		}
+	}
+	if len(found) != 1 || found[0].LineNumber != 3 || found[0].Severity != SeverityMedium || found[0].Description != "job build must deploy to prod" {
+		t.Fatalf("unexpected policy findings: %v", found)
+	}
+
+	// Policies are suppressed like rules.
+	suppressed := "# sisakulint-disable-file prod_only\n" + policyTestWorkflow
+	result, err = linter.Lint(path, []byte(suppressed), project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range result.Errors {
+		if e.Type == "prod_only" || e.Type == "suppression-unknown-rule" {
+			t.Errorf("unexpected finding: %v", e)
+		}
+	}
+
+	// Files which are not written in a policy language are skipped.
+	writeTestFile(t, filepath.Join(dir, "README.md"), "# Policies\n")
+	writeTestFile(t, filepath.Join(dir, "data.json"), "{}\n")
+	project, err = NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := linter.Lint(path, []byte(policyTestWorkflow), project); err != nil {
+		t.Fatalf("non-policy files should be skipped: %v", err)
+	}
+
+	// A policy without an engine is an error, not silently skipped.
+	writeTestFile(t, filepath.Join(dir, "other.cel"), "")
+	project, err = NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := linter.Lint(path, []byte(policyTestWorkflow), project); err == nil || !strings.Contains(err.Error(), "no policy engine") {
+		t.Errorf("want a missing engine error, got %v", err)
+	}
+}
+
+func TestOPAPolicyEngine(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake opa command is a shell script")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "opa")
+	// The fake opa echoes its data path and query so that the package and
+	// the loaded directory are checked too.
+	writeTestFile(t, fake, "#!/bin/sh\ncat > /dev/null\necho '{\"result\":[{\"expressions\":[{\"value\":[\"'\"$6 $7\"'\"]}]}]}'\n")
+	if err := os.Chmod(fake, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewOPAPolicyEngine(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &Policy{Name: "p", Path: filepath.Join(dir, "p.rego"), Source: []byte("# policy\npackage sisakulint.deploy\n\ndeny contains msg if { false }\n")}
+	denials, err := engine.Evaluate(policy, []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(denials) != 1 || denials[0].Message != dir+" data.sisakulint.deploy.deny" {
+		t.Errorf("unexpected denials: %+v", denials)
+	}
+	if _, err := engine.Evaluate(&Policy{Path: policy.Path, Source: []byte("deny[msg] { true }")}, nil); err == nil {
+		t.Error("a policy without a package should be rejected")
+	}
+}
@@ -11,9 +11,11 @@ import (
 
 // ProjectはGithubプロジェクト- 1つのリポジトリに対応
 type Project struct {
-	root   string
-	config *Config
-	boiler *Boiler
+	root     string
+	config   *Config
+	boiler   *Boiler
+	lockfile *ActionLockfile
+	policies []*Policy
 }
 
 func getAbsolutePath(path string) string {
@@ -87,7 +89,15 @@ func NewProject(root string) (*Project, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Project{root: root, config: c, boiler: d}, nil
+	l, err := loadActionLockfile(root)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := loadPolicies(root)
+	if err != nil {
+		return nil, err
+	}
+	return &Project{root: root, config: c, boiler: d, lockfile: l, policies: ps}, nil
 }
 
 // githubプロジェクトのルートディレクトリを返す
@@ -110,6 +120,17 @@ func (project *Project) ProjectConfig() *Config {
 	return project.config
 }
 
+// ActionLockfile returns the action lockfile of the project, or nil when the
+// project has no .github/sisakulint.lock.
+func (project *Project) ActionLockfile() *ActionLockfile {
+	return project.lockfile
+}
+
+// Policies returns the policy files in .github/sisakulint/policies.
+func (project *Project) Policies() []*Policy {
+	return project.policies
+}
+
 // Projectsはプロジェクトのset , 前に作られたprojectインスタンスをキャッシュして再利用
 type Projects struct {
 	known []*Project
@@ -19,7 +19,12 @@ type RefConfusion struct {
 	refCache     map[string]bool
 	refCacheMu   sync.Mutex
 	ghClient     *github.Client
+	ghClientErr  error
 	ghClientOnce sync.Once
+	// gitHubToken and gitHubAPIURL select the GitHub instance to query. Both
+	// are empty for unauthenticated GitHub.com requests.
+	gitHubToken  string
+	gitHubAPIURL string
 }
 
 // NewRefConfusionRule creates a new RefConfusion rule instance.
@@ -28,6 +33,7 @@ func NewRefConfusionRule() *RefConfusion {
 		BaseRule: BaseRule{
 			RuleName: "ref-confusion",
 			RuleDesc: "Detects actions using refs that exist as both a branch and tag, which can lead to supply chain attacks.",
+			severity: SeverityHigh,
 		},
 		refCache: make(map[string]bool),
 	}
@@ -69,15 +75,18 @@ func parseActionRefForRefConfusion(usesValue string) (owner, repo, ref string, o
 	return owner, repo, ref, true
 }
 
-func (rule *RefConfusion) getGitHubClient() *github.Client {
+func (rule *RefConfusion) getGitHubClient() (*github.Client, error) {
 	rule.ghClientOnce.Do(func() {
-		rule.ghClient = github.NewClient(http.DefaultClient)
+		rule.ghClient, rule.ghClientErr = NewGitHubClientWithAPIURL(context.Background(), rule.gitHubToken, rule.gitHubAPIURL)
 	})
-	return rule.ghClient
+	return rule.ghClient, rule.ghClientErr
 }
 
 func (rule *RefConfusion) hasBranch(ctx context.Context, owner, repo, branchName string) (bool, error) {
-	client := rule.getGitHubClient()
+	client, err := rule.getGitHubClient()
+	if err != nil {
+		return false, err
+	}
 	_, resp, err := client.Repositories.GetBranch(ctx, owner, repo, branchName, 0)
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
@@ -89,7 +98,10 @@ func (rule *RefConfusion) hasBranch(ctx context.Context, owner, repo, branchName
 }
 
 func (rule *RefConfusion) hasTag(ctx context.Context, owner, repo, tagName string) (bool, error) {
-	client := rule.getGitHubClient()
+	client, err := rule.getGitHubClient()
+	if err != nil {
+		return false, err
+	}
 	_, resp, err := client.Git.GetRef(ctx, owner, repo, "tags/"+tagName)
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
@@ -200,7 +212,10 @@ func (rule *RefConfusion) FixStep(step *ast.Step) error {
 		return FormattedError(step.Pos, rule.RuleName, "invalid action reference format: '%s'", usesValue)
 	}
 
-	client := rule.getGitHubClient()
+	client, err := rule.getGitHubClient()
+	if err != nil {
+		return FormattedError(step.Pos, rule.RuleName, "%s", err.Error())
+	}
 	sha, _, err := client.Repositories.GetCommitSHA1(context.Background(), owner, repo, ref, "")
 	if err != nil {
 		return FormattedError(step.Pos, rule.RuleName, "failed to get commit SHA for %s/%s@%s: %s", owner, repo, ref, err.Error())
@@ -404,12 +404,12 @@ func TestRefConfusion_GetGitHubClient(t *testing.T) {
 	t.Parallel()
 	rule := NewRefConfusionRule()
 
-	client := rule.getGitHubClient()
-	if client == nil {
-		t.Error("Expected GitHub client to be initialized")
+	client, err := rule.getGitHubClient()
+	if err != nil || client == nil {
+		t.Errorf("Expected GitHub client to be initialized, got error %v", err)
 	}
 
-	client2 := rule.getGitHubClient()
+	client2, _ := rule.getGitHubClient()
 	if client != client2 {
 		t.Error("Expected same client instance (singleton)")
 	}
@@ -0,0 +1,239 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/haya14busa/go-sarif/sarif"
+	"github.com/sisaku-security/sisakulint/pkg/remote"
+)
+
+// Formats of the aggregated report of -remote scans.
+const (
+	RemoteReportJSON     = "json"
+	RemoteReportSARIF    = "sarif"
+	RemoteReportMarkdown = "markdown"
+)
+
+// remoteReportVersion is the version of the JSON report format.
+const remoteReportVersion = 1
+
+// remoteReport is the consolidated result of scanning several repositories
+// with -remote, such as every repository of an organization.
+type remoteReport struct {
+	Version      int                     `json:"version"`
+	Repositories []*remoteRepositoryScan `json:"repositories"`
+	// serverURL is the web URL of the GitHub instance, used to link the
+	// repositories in SARIF.
+	serverURL string
+}
+
+// remoteRepositoryScan is the result of one repository. Findings in the
+// workflows of the repository have repository-relative paths. Findings in
+// reusable workflows of other repositories keep their "owner/repo/" prefix.
+type remoteRepositoryScan struct {
+	Repository string            `json:"repository"`
+	Error      string            `json:"error,omitempty"`
+	Findings   []*TemplateFields `json:"findings"`
+	// Counts maps rule name to severity to the number of findings.
+	Counts map[string]map[string]int `json:"counts"`
+}
+
+// remoteReportSources keeps the source of each scanned workflow by virtual
+// path so that findings get code snippets.
+type remoteReportSources struct {
+	m sync.Map
+}
+
+func (s *remoteReportSources) store(path string, content []byte) {
+	s.m.Store(path, content)
+}
+
+func (s *remoteReportSources) load(path string) []byte {
+	if v, ok := s.m.Load(path); ok {
+		return v.([]byte)
+	}
+	return nil
+}
+
+// newRemoteReport builds the report from scan results whose Findings are
+// *LintingError. Repositories are sorted by name.
+func newRemoteReport(results []*remote.ScanResult, sources *remoteReportSources, serverURL string) *remoteReport {
+	report := &remoteReport{Version: remoteReportVersion, Repositories: []*remoteRepositoryScan{}, serverURL: serverURL}
+	for _, result := range results {
+		name := result.Repository.FullName
+		scan := &remoteRepositoryScan{
+			Repository: name,
+			Findings:   []*TemplateFields{},
+			Counts:     map[string]map[string]int{},
+		}
+		if result.Error != nil {
+			scan.Error = result.Error.Error()
+		}
+		for _, f := range result.Findings {
+			lintErr, ok := f.(*LintingError)
+			if !ok {
+				continue
+			}
+			fields := lintErr.ExtractTemplateFields(sources.load(lintErr.FilePath))
+			fields.Filepath = strings.TrimPrefix(fields.Filepath, name+"/")
+			scan.Findings = append(scan.Findings, fields)
+
+			severity := fields.Severity
+			if severity == "" {
+				severity = SeverityInfo
+			}
+			if scan.Counts[fields.Type] == nil {
+				scan.Counts[fields.Type] = map[string]int{}
+			}
+			scan.Counts[fields.Type][severity]++
+		}
+		report.Repositories = append(report.Repositories, scan)
+	}
+	sort.Slice(report.Repositories, func(i, j int) bool {
+		return report.Repositories[i].Repository < report.Repositories[j].Repository
+	})
+	return report
+}
+
+// findings returns the total number of findings.
+func (r *remoteReport) findings() int {
+	n := 0
+	for _, repo := range r.Repositories {
+		n += len(repo.Findings)
+	}
+	return n
+}
+
+// write renders the report in the given format.
+func (r *remoteReport) write(w io.Writer, format string) error {
+	switch format {
+	case RemoteReportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case RemoteReportSARIF:
+		return r.writeSARIF(w)
+	case RemoteReportMarkdown:
+		return r.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// writeSARIF writes one SARIF run per repository. Each run has its own
+// automationDetails.id and versionControlProvenance so that code scanning
+// keeps the repositories apart.
+func (r *remoteReport) writeSARIF(w io.Writer) error {
+	log := &sarifLog{
+		Sarif: sarif.Sarif{
+			Version: sarif.The210,
+			Schema:  sarif.String("https://schemastore.azurewebsites.net/schemas/json/sarif-2.1.0.json"),
+		},
+		Runs: []sarifRun{},
+	}
+	for _, repo := range r.Repositories {
+		run := sarifRun{
+			Run: sarif.Run{
+				Invocations: []sarif.Invocation{{ExecutionSuccessful: true}},
+				AutomationDetails: &sarif.RunAutomationDetails{
+					ID: sarif.String("sisakulint/" + repo.Repository + "/"),
+				},
+				VersionControlProvenance: []sarif.VersionControlDetails{
+					{RepositoryURI: r.serverURL + "/" + repo.Repository},
+				},
+			},
+			Tool:    newSARIFTool(nil),
+			Results: []sarifResult{},
+		}
+		if repo.Error != "" {
+			run.Invocations = []sarif.Invocation{
+				{
+					ExecutionSuccessful: false,
+					ToolExecutionNotifications: []sarif.Notification{
+						{Message: sarif.Message{Text: sarif.String(repo.Error)}, Level: sarif.Error.Ptr()},
+					},
+				},
+			}
+		}
+		for _, f := range repo.Findings {
+			run.Results = append(run.Results, toResult(f))
+		}
+		log.Runs = append(log.Runs, run)
+	}
+	enc := json.NewEncoder(w)
+	return enc.Encode(log)
+}
+
+// writeMarkdown writes a summary table of all repositories followed by a
+// table of counts per rule and severity for each repository with findings.
+func (r *remoteReport) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	header := func(first string) {
+		b.WriteString("| " + first + " |")
+		for _, s := range allSeverities {
+			b.WriteString(" " + string(s) + " |")
+		}
+		b.WriteString(" total |\n|---|")
+		for range allSeverities {
+			b.WriteString("---:|")
+		}
+		b.WriteString("---:|\n")
+	}
+	row := func(first string, counts map[string]int) {
+		total := 0
+		b.WriteString("| " + first + " |")
+		for _, s := range allSeverities {
+			n := counts[string(s)]
+			total += n
+			fmt.Fprintf(&b, " %d |", n)
+		}
+		fmt.Fprintf(&b, " %d |\n", total)
+	}
+
+	fmt.Fprintf(&b, "# sisakulint report\n\n%d %s, %d %s\n\n",
+		len(r.Repositories), pluralize(len(r.Repositories), "repository", "repositories"),
+		r.findings(), pluralize(r.findings(), "finding", "findings"))
+	header("Repository")
+	for _, repo := range r.Repositories {
+		name := "`" + repo.Repository + "`"
+		if repo.Error != "" {
+			name += " (scan failed)"
+		}
+		bySeverity := map[string]int{}
+		for _, counts := range repo.Counts {
+			for s, n := range counts {
+				bySeverity[s] += n
+			}
+		}
+		row(name, bySeverity)
+	}
+
+	for _, repo := range r.Repositories {
+		if repo.Error == "" && len(repo.Findings) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", repo.Repository)
+		if repo.Error != "" {
+			fmt.Fprintf(&b, "Scan failed: %s\n\n", repo.Error)
+		}
+		if len(repo.Findings) == 0 {
+			continue
+		}
+		rules := make([]string, 0, len(repo.Counts))
+		for rule := range repo.Counts {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		header("Rule")
+		for _, rule := range rules {
+			row("`"+rule+"`", repo.Counts[rule])
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
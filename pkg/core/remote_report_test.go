@@ -0,0 +1,154 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/remote"
+)
+
+func testRemoteReport() *remoteReport {
+	sources := &remoteReportSources{}
+	sources.store("org/api/.github/workflows/ci.yml", []byte("on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n"))
+	results := []*remote.ScanResult{
+		{
+			Repository: &remote.RepositoryInfo{FullName: "org/web"},
+			Error:      errors.New("failed to fetch workflows: 404"),
+		},
+		{
+			Repository: &remote.RepositoryInfo{FullName: "org/api"},
+			HasErrors:  true,
+			Findings: []interface{}{
+				&LintingError{Description: "a", FilePath: "org/api/.github/workflows/ci.yml", LineNumber: 3, ColNumber: 3, Type: "permissions", Severity: SeverityHigh},
+				&LintingError{Description: "b", FilePath: "org/api/.github/workflows/ci.yml", LineNumber: 4, ColNumber: 5, Type: "permissions", Severity: SeverityHigh},
+				&LintingError{Description: "c", FilePath: "other/lib/.github/workflows/reuse.yml", LineNumber: 1, ColNumber: 1, Type: "timeout-minutes", Severity: SeverityLow},
+			},
+		},
+		{
+			Repository: &remote.RepositoryInfo{FullName: "org/docs"},
+		},
+	}
+	return newRemoteReport(results, sources, "https://github.com")
+}
+
+func TestRemoteReport_JSON(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := testRemoteReport().write(&buf, RemoteReportJSON); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Version      int `json:"version"`
+		Repositories []struct {
+			Repository string                    `json:"repository"`
+			Error      string                    `json:"error"`
+			Findings   []map[string]interface{}  `json:"findings"`
+			Counts     map[string]map[string]int `json:"counts"`
+		} `json:"repositories"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if got.Version != remoteReportVersion || len(got.Repositories) != 3 {
+		t.Fatalf("unexpected report: %s", buf.String())
+	}
+	api := got.Repositories[0]
+	if api.Repository != "org/api" || got.Repositories[1].Repository != "org/docs" || got.Repositories[2].Repository != "org/web" {
+		t.Errorf("repositories should be sorted by name: %s", buf.String())
+	}
+	if api.Counts["permissions"]["high"] != 2 || api.Counts["timeout-minutes"]["low"] != 1 {
+		t.Errorf("unexpected counts: %v", api.Counts)
+	}
+	if api.Findings[0]["filepath"] != ".github/workflows/ci.yml" {
+		t.Errorf("own workflows should be repository-relative, got %v", api.Findings[0]["filepath"])
+	}
+	if api.Findings[2]["filepath"] != "other/lib/.github/workflows/reuse.yml" {
+		t.Errorf("workflows of other repositories should keep their prefix, got %v", api.Findings[2]["filepath"])
+	}
+	if snippet, _ := api.Findings[0]["snippet"].(string); !strings.Contains(snippet, "build:") {
+		t.Errorf("findings should have snippets: %v", api.Findings[0])
+	}
+	if got.Repositories[2].Error == "" {
+		t.Error("scan errors should be reported")
+	}
+}
+
+func TestRemoteReport_SARIFRunPerRepository(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := testRemoteReport().write(&buf, RemoteReportSARIF); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Runs []struct {
+			AutomationDetails struct {
+				ID string `json:"id"`
+			} `json:"automationDetails"`
+			VersionControlProvenance []struct {
+				RepositoryURI string `json:"repositoryUri"`
+			} `json:"versionControlProvenance"`
+			Invocations []interface{} `json:"invocations"`
+			Results     []interface{} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+	if len(got.Runs) != 3 {
+		t.Fatalf("want one run per repository, got %d", len(got.Runs))
+	}
+	api := got.Runs[0]
+	if api.AutomationDetails.ID != "sisakulint/org/api/" || len(api.Results) != 3 {
+		t.Errorf("unexpected run: %+v", api)
+	}
+	if len(api.VersionControlProvenance) != 1 || api.VersionControlProvenance[0].RepositoryURI != "https://github.com/org/api" {
+		t.Errorf("unexpected provenance: %+v", api.VersionControlProvenance)
+	}
+	if len(got.Runs[2].Invocations) != 1 {
+		t.Errorf("failed scans should have an invocation notification: %+v", got.Runs[2])
+	}
+}
+
+func TestRemoteReport_Markdown(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := testRemoteReport().write(&buf, RemoteReportMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	md := buf.String()
+	for _, want := range []string{
+		"3 repositories, 3 findings",
+		"| Repository | critical | high | medium | low | info | total |",
+		"| `org/api` | 0 | 2 | 0 | 1 | 0 | 3 |",
+		"| `org/web` (scan failed) | 0 | 0 | 0 | 0 | 0 | 0 |",
+		"## org/api",
+		"| `permissions` | 0 | 2 | 0 | 0 | 0 | 2 |",
+		"Scan failed: failed to fetch workflows: 404",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown report should contain %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "## org/docs") {
+		t.Errorf("clean repositories should not have a section:\n%s", md)
+	}
+}
+
+func TestCommand_RemoteReportRequiresRemote(t *testing.T) {
+	t.Parallel()
+
+	var stderr bytes.Buffer
+	cmd := &Command{Stdout: &bytes.Buffer{}, Stderr: &stderr}
+	if status := cmd.Main([]string{"sisakulint", "-remote-report", "json"}); status != ExitStatusInvalidCommandOption {
+		t.Errorf("status = %d, want %d", status, ExitStatusInvalidCommandOption)
+	}
+	if status := cmd.Main([]string{"sisakulint", "-remote", "org/repo", "-remote-report", "xml"}); status != ExitStatusInvalidCommandOption {
+		t.Errorf("status = %d, want %d", status, ExitStatusInvalidCommandOption)
+	}
+}
@@ -90,6 +90,7 @@ func newRequestForgeryRule(severityLevel string, checkPrivileged bool, wfTaintMa
 		BaseRule: BaseRule{
 			RuleName: "request-forgery-" + severityLevel,
 			RuleDesc: desc,
+			severity: Severity(severityLevel),
 		},
 		severityLevel:      severityLevel,
 		checkPrivileged:    checkPrivileged,
@@ -710,7 +711,7 @@ func (rule *RequestForgeryRule) parseExpression(exprStr string) (expressions.Exp
 }
 
 func (rule *RequestForgeryRule) checkUntrustedInput(expr parsedExpression) []string {
-	checker := expressions.NewExprSemanticsChecker(true, nil)
+	checker := newUntrustedInputChecker(rule.workflow)
 	_, errs := checker.Check(expr.node)
 
 	var paths []string
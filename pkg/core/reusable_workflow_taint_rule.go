@@ -51,6 +51,7 @@ func NewReusableWorkflowTaintRule(workflowPath string, cache *LocalReusableWorkf
 		BaseRule: BaseRule{
 			RuleName: "reusable-workflow-taint",
 			RuleDesc: "Detects when untrusted inputs are passed to reusable workflows and used in dangerous contexts. See https://sisaku-security.github.io/lint/docs/rules/reusableworkflowtaint/",
+			severity: SeverityCritical,
 		},
 		workflowPath: workflowPath,
 		cache:        cache,
@@ -142,8 +143,9 @@ func (rule *ReusableWorkflowTaintRule) checkWorkflowCallInputs(job *ast.Job) {
 		if rule.hasPrivilegedTrigger {
 			severity = "critical"
 		}
-		rule.Errorf(
+		rule.ErrorfWithSeverity(
 			input.Value.Pos,
+			Severity(severity),
 			"reusable workflow input taint (%s): input %q receives untrusted value %q which may be used unsafely in the called workflow %q. Consider validating or sanitizing the input. See https://sisaku-security.github.io/lint/docs/rules/reusableworkflowtaint/",
 			severity, inputName, strings.Join(untrustedPaths, ", "), call.Uses.Value,
 		)
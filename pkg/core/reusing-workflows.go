@@ -516,6 +516,50 @@ func stepIDOf(step *ast.Step) string {
 	return step.ID.Value
 }
 
+// ForgetWorkflow drops everything recorded from the workflow at wpath: its
+// reusable workflow metadata and the caller taints and callee sinks it
+// contributed. Long-lived caches (e.g. in LSP mode) call this before the same
+// workflow is analyzed again with new content.
+func (c *LocalReusableWorkflowCache) ForgetWorkflow(wpath string) {
+	if c == nil {
+		return
+	}
+	spec, ok := c.pathToWorkflowSpecification(wpath)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok {
+		delete(c.cache, spec)
+		delete(c.calleeSinks, spec)
+		delete(c.calleeSeen, spec)
+	}
+	for callee, taints := range c.callerTaints {
+		kept := taints[:0]
+		for _, t := range taints {
+			if t.CallerWorkflowPath != wpath {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) == 0 {
+			delete(c.callerTaints, callee)
+		} else {
+			c.callerTaints[callee] = kept
+		}
+	}
+	for callee, sinks := range c.calleeSinks {
+		kept := sinks[:0]
+		for _, s := range sinks {
+			if s.CalleeWorkflowPath != wpath {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) == 0 {
+			delete(c.calleeSinks, callee)
+		} else {
+			c.calleeSinks[callee] = kept
+		}
+	}
+}
+
 // IsChainResolutionEnabled returns true when the cache can correlate
 // caller and callee taint info — i.e. it has a Project context.
 func (c *LocalReusableWorkflowCache) IsChainResolutionEnabled() bool {
@@ -14,7 +14,10 @@ type BaseRule struct {
 	RuleDesc string
 	// optIn が true のルールはデフォルト無効 (オプトイン)。
 	// CLI -enable-rule で名前を指定したときだけ動作する。
-	optIn      bool
+	optIn bool
+	// severity はこのルールが報告するfindingのデフォルトの深刻度。
+	// 空の場合は SeverityMedium として扱う。
+	severity   Severity
 	ruleErrors []*LintingError
 	autoFixers []AutoFixer
 	debugOut   io.Writer
@@ -29,12 +32,31 @@ func CreateBaseRule(name string, desc string) BaseRule {
 // Errorはソースの位置とエラーメッセージから新しいエラーを作成してrule instanceに追加する
 func (rule *BaseRule) Error(position *ast.Position, msg string) {
 	err := NewError(position, rule.RuleName, msg)
+	err.Severity = rule.RuleSeverity()
 	rule.ruleErrors = append(rule.ruleErrors, err)
 }
 
 // Errorf
 func (rule *BaseRule) Errorf(position *ast.Position, format string, args ...interface{}) {
 	err := FormattedError(position, rule.RuleName, format, args...)
+	err.Severity = rule.RuleSeverity()
+	rule.ruleErrors = append(rule.ruleErrors, err)
+}
+
+// ErrorfWithSeverity は Errorf と同じだが、ルールのデフォルトではなく
+// 指定された深刻度でエラーを追加する
+func (rule *BaseRule) ErrorfWithSeverity(position *ast.Position, severity Severity, format string, args ...interface{}) {
+	err := FormattedError(position, rule.RuleName, format, args...)
+	err.Severity = severity
+	rule.ruleErrors = append(rule.ruleErrors, err)
+}
+
+// ErrorfWithFlow は Errorf と同じだが、汚染された値がソースからシンクに至る経路を添付する。
+// flow が空の場合は Errorf と同じ
+func (rule *BaseRule) ErrorfWithFlow(position *ast.Position, flow []*TaintFlowStep, format string, args ...interface{}) {
+	err := FormattedError(position, rule.RuleName, format, args...)
+	err.Severity = rule.RuleSeverity()
+	err.TaintFlow = flow
 	rule.ruleErrors = append(rule.ruleErrors, err)
 }
 
@@ -61,6 +83,19 @@ func (rule *BaseRule) RuleDescription() string {
 	return rule.RuleDesc
 }
 
+// RuleSeverity は、このルールが報告するfindingのデフォルトの深刻度を返す。
+func (rule *BaseRule) RuleSeverity() Severity {
+	if rule.severity == "" {
+		return SeverityMedium
+	}
+	return rule.severity
+}
+
+// SetRuleSeverity は、設定ファイルの rules.<name>.severity でデフォルトの深刻度を上書きする。
+func (rule *BaseRule) SetRuleSeverity(severity Severity) {
+	rule.severity = severity
+}
+
 // IsOptIn は、このルールがデフォルト無効 (オプトイン) かどうかを返す。
 // true の場合、CLI -enable-rule <name> による明示的有効化が必要。
 func (rule *BaseRule) IsOptIn() bool {
@@ -92,6 +127,8 @@ type Rule interface {
 	Errors() []*LintingError
 	RuleNames() string
 	RuleDescription() string
+	RuleSeverity() Severity
+	SetRuleSeverity(severity Severity)
 	EnableDebugOutput(out io.Writer)
 	UpdateConfig(config *Config)
 	AddAutoFixer(fixer AutoFixer)
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"strings"
+)
+
+// ruleDocsBaseURL is where the pages in docs/ are published.
+const ruleDocsBaseURL = "https://sisaku-security.github.io/lint/docs/rules/"
+
+// ruleDocPages maps rules to their page in docs/ when the page is not named
+// after the rule without dashes and slashes, such as "idrule" for "id". An
+// empty page means the rule has no page yet.
+var ruleDocPages = map[string]string{
+	"credentials":                     "credentialrules",
+	"needs":                           "jobneeds",
+	"env-var":                         "environmentvariablerule",
+	"cond":                            "conditionalrule",
+	"missing-timeout-minutes":         "timeoutminutesrule",
+	"output-clobbering-critical":      "outputclobbering",
+	"output-clobbering-medium":        "outputclobbering",
+	"dependabot-github-actions":       "",
+	"self-hosted-runner":              "selfhostedrunners",
+	"argument-injection-critical":     "argumentinjection",
+	"argument-injection-medium":       "argumentinjection",
+	"request-forgery-critical":        "requestforgery",
+	"request-forgery-medium":          "requestforgery",
+	"id":                              "idrule",
+	"expression":                      "expressionrule",
+	"deprecated-commands":             "deprecatedcommandsrule",
+	"commit-sha":                      "commitsharule",
+	"dependabot-ecosystem":            "dependabotecosystemrule",
+	"cache-poisoning":                 "cachepoisoningrule",
+	"cache-poisoning-poisonable-step": "cachepoisoningpoisonablesteprule",
+	"cache-bloat":                     "cachebloatrule",
+	"secret-in-log":                   "secretinlogrule",
+	"dangerous-triggers-critical":     "dangeroustriggersrulecritical",
+	"dangerous-triggers-medium":       "dangeroustriggersrulemedium",
+	"action-lockfile":                 "actionlockfile",
+}
+
+// ruleDocPage returns the name of the page of a built-in rule in docs/,
+// without the ".md" extension, or "" when it has none.
+func ruleDocPage(ruleName string) string {
+	if page, ok := ruleDocPages[ruleName]; ok {
+		return page
+	}
+	return strings.NewReplacer("-", "", "/", "").Replace(ruleName)
+}
+
+// ruleDocURL returns the URL of the documentation of a rule, or "" when it
+// has none. Custom rules and policies are documented by their authors.
+func ruleDocURL(ruleName string) string {
+	page := ruleDocPage(ruleName)
+	if page == "" {
+		return ""
+	}
+	return ruleDocsBaseURL + page + "/"
+}
@@ -0,0 +1,215 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RuleConfig is the per-rule configuration in the "rules:" section of
+// sisakulint.yaml.
+//
+//	rules:
+//	  missing-timeout-minutes:
+//	    disable: true
+//	  commit-sha:
+//	    severity: low
+//	    ignore-paths:
+//	      - .github/workflows/experimental-*.yml
+type RuleConfig struct {
+	// Disable turns the rule off for every file.
+	Disable bool `yaml:"disable"`
+	// Severity overrides the severity of every finding of the rule.
+	Severity string `yaml:"severity"`
+	// Paths restricts the rule to files matching one of the globs. An empty
+	// list means all files.
+	Paths []string `yaml:"paths"`
+	// IgnorePaths excludes files matching one of the globs from the rule.
+	IgnorePaths []string `yaml:"ignore-paths"`
+
+	severity          Severity
+	pathRegexes       []*regexp.Regexp
+	ignorePathRegexes []*regexp.Regexp
+}
+
+// compile validates the severity and compiles the path globs.
+func (c *RuleConfig) compile(name string) error {
+	if c.Severity != "" {
+		sev, err := ParseSeverity(c.Severity)
+		if err != nil {
+			return fmt.Errorf("rules.%s.severity: %w", name, err)
+		}
+		c.severity = sev
+	}
+	for _, glob := range c.Paths {
+		re, err := compilePathGlob(glob)
+		if err != nil {
+			return fmt.Errorf("rules.%s.paths: invalid glob %q: %w", name, glob, err)
+		}
+		c.pathRegexes = append(c.pathRegexes, re)
+	}
+	for _, glob := range c.IgnorePaths {
+		re, err := compilePathGlob(glob)
+		if err != nil {
+			return fmt.Errorf("rules.%s.ignore-paths: invalid glob %q: %w", name, glob, err)
+		}
+		c.ignorePathRegexes = append(c.ignorePathRegexes, re)
+	}
+	return nil
+}
+
+// appliesTo reports whether the rule should run on the file at path, which
+// is relative to the repository root and slash-separated.
+func (c *RuleConfig) appliesTo(path string) bool {
+	if c.Disable {
+		return false
+	}
+	for _, re := range c.ignorePathRegexes {
+		if re.MatchString(path) {
+			return false
+		}
+	}
+	if len(c.pathRegexes) == 0 {
+		return true
+	}
+	for _, re := range c.pathRegexes {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePathGlob converts a path glob into a regular expression. "**"
+// matches any number of directories, "*" matches any string without "/" and
+// "?" matches one character other than "/".
+func compilePathGlob(glob string) (*regexp.Regexp, error) {
+	glob = strings.TrimPrefix(filepath.ToSlash(glob), "./")
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" also matches no directory at all.
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// ruleConfigPath returns the path that "paths"/"ignore-paths" globs are
+// matched against: relative to the project root when the file belongs to a
+// project, otherwise the path as given.
+func ruleConfigPath(filePath string, project *Project) string {
+	p := filePath
+	if project != nil {
+		if rel, err := filepath.Rel(project.RootDirectory(), getAbsolutePath(filePath)); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(p)), "./")
+}
+
+// ruleOverrides is the result of the "rules:" section for one file. It is
+// applied to findings in filterAndSortErrors so that findings added after
+// validate() (cross-file chains) honour the configuration as well.
+type ruleOverrides struct {
+	// excluded holds the rules that are disabled or out of scope for the file.
+	excluded map[string]struct{}
+	// severities holds the configured severity of each rule.
+	severities map[string]Severity
+}
+
+// applyRuleConfigs removes the rules that configs disables or scopes away
+// from the file at path, and returns the overrides to apply to findings.
+//
+// Names in configs that match no rule are returned as an error, in the same
+// way as applyOptInRules rejects unknown -enable-rule names. knownNames must
+// be computed before opt-in rules are filtered so that configuring an opt-in
+// rule which is not enabled is not an error.
+func applyRuleConfigs(rules []Rule, knownNames []string, configs map[string]*RuleConfig, path string) ([]Rule, *ruleOverrides, error) {
+	if len(configs) == 0 {
+		return rules, nil, nil
+	}
+
+	known := make(map[string]struct{}, len(knownNames))
+	for _, n := range knownNames {
+		known[n] = struct{}{}
+	}
+	var unknown []string
+	for name := range configs {
+		if _, ok := known[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, nil, fmt.Errorf("unknown rule name in \"rules\" section of config: %q", unknown[0])
+	}
+
+	overrides := &ruleOverrides{
+		excluded:   map[string]struct{}{},
+		severities: map[string]Severity{},
+	}
+	for name, c := range configs {
+		if c == nil {
+			continue
+		}
+		if !c.appliesTo(path) {
+			overrides.excluded[name] = struct{}{}
+		}
+		if c.severity != "" {
+			overrides.severities[name] = c.severity
+		}
+	}
+
+	out := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		if _, ok := overrides.excluded[r.RuleNames()]; ok {
+			continue
+		}
+		if sev, ok := overrides.severities[r.RuleNames()]; ok {
+			r.SetRuleSeverity(sev)
+		}
+		out = append(out, r)
+	}
+	return out, overrides, nil
+}
+
+// apply drops findings of excluded rules and overrides the severity of the
+// rest.
+func (o *ruleOverrides) apply(errs []*LintingError, fixers []AutoFixer) ([]*LintingError, []AutoFixer) {
+	if o == nil {
+		return errs, fixers
+	}
+	kept := make([]*LintingError, 0, len(errs))
+	for _, err := range errs {
+		if _, ok := o.excluded[err.Type]; ok {
+			continue
+		}
+		if sev, ok := o.severities[err.Type]; ok {
+			err.Severity = sev
+		}
+		kept = append(kept, err)
+	}
+	keptFixers := fixers
+	dropAutoFixersOfRules(&keptFixers, o.excluded)
+	return kept, keptFixers
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompilePathGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{".github/workflows/*.yml", ".github/workflows/ci.yml", true},
+		{".github/workflows/*.yml", ".github/workflows/sub/ci.yml", false},
+		{"./.github/workflows/ci.yml", ".github/workflows/ci.yml", true},
+		{"**/action.yml", "action.yml", true},
+		{"**/action.yml", "actions/setup/action.yml", true},
+		{".github/**", ".github/workflows/ci.yml", true},
+		{"release-?.yml", "release-1.yml", true},
+		{"release-?.yml", "release-10.yml", false},
+		{"a.b", "axb", false},
+	}
+	for _, tt := range tests {
+		re, err := compilePathGlob(tt.glob)
+		if err != nil {
+			t.Fatalf("compilePathGlob(%q): %v", tt.glob, err)
+		}
+		if got := re.MatchString(tt.path); got != tt.match {
+			t.Errorf("glob %q on %q = %v, want %v", tt.glob, tt.path, got, tt.match)
+		}
+	}
+}
+
+func TestParseConfig_Rules(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := parseConfig([]byte(`rules:
+  id:
+    disable: true
+  commit-sha:
+    severity: LOW
+    paths: [".github/workflows/*.yml"]
+`), "sisakulint.yaml")
+	if err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+	if !cfg.Rules["id"].Disable {
+		t.Error("rules.id.disable should be true")
+	}
+	if got := cfg.Rules["commit-sha"].severity; got != SeverityLow {
+		t.Errorf("rules.commit-sha severity = %q, want low", got)
+	}
+
+	if _, err := parseConfig([]byte("rules:\n  id:\n    severity: urgent\n"), "sisakulint.yaml"); err == nil || !strings.Contains(err.Error(), "rules.id.severity") {
+		t.Errorf("invalid severity should be rejected, got %v", err)
+	}
+}
+
+func TestApplyRuleConfigs(t *testing.T) {
+	t.Parallel()
+
+	rules := []Rule{newStubRule("rule-a", false), newStubRule("rule-b", false), newStubRule("rule-c", false)}
+	known := ruleNamesOf(rules)
+	configs := map[string]*RuleConfig{
+		"rule-a": {Disable: true},
+		"rule-b": {Severity: "critical", IgnorePaths: []string{".github/workflows/skip.yml"}},
+		"rule-c": {Paths: []string{".github/workflows/only-*.yml"}},
+	}
+	for name, c := range configs {
+		if err := c.compile(name); err != nil {
+			t.Fatalf("compile %s: %v", name, err)
+		}
+	}
+
+	got, overrides, err := applyRuleConfigs(rules, known, configs, ".github/workflows/only-this.yml")
+	if err != nil {
+		t.Fatalf("applyRuleConfigs: %v", err)
+	}
+	if names := ruleNamesOf(got); strings.Join(names, ",") != "rule-b,rule-c" {
+		t.Errorf("active rules = %v, want [rule-b rule-c]", names)
+	}
+	if got[0].RuleSeverity() != SeverityCritical {
+		t.Errorf("rule-b severity = %q, want critical", got[0].RuleSeverity())
+	}
+	errs, _ := overrides.apply([]*LintingError{{Type: "rule-a"}, {Type: "rule-b", Severity: SeverityLow}}, nil)
+	if len(errs) != 1 || errs[0].Severity != SeverityCritical {
+		t.Errorf("overrides.apply = %v, want only rule-b with critical severity", errs)
+	}
+
+	got, _, err = applyRuleConfigs(rules, known, configs, ".github/workflows/skip.yml")
+	if err != nil {
+		t.Fatalf("applyRuleConfigs: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("no rule should run on skip.yml, got %v", ruleNamesOf(got))
+	}
+
+	_, _, err = applyRuleConfigs(rules, known, map[string]*RuleConfig{"rule-typo": {}}, "x.yml")
+	if err == nil || !strings.Contains(err.Error(), `"rule-typo"`) {
+		t.Errorf("unknown rule name should be rejected, got %v", err)
+	}
+}
+
+func TestLinter_RulesConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "sisakulint.yaml")
+	writeTestFile(t, configPath, `rules:
+  code-injection-critical:
+    severity: low
+  missing-timeout-minutes:
+    disable: true
+`)
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard, ConfigurationFilePath: configPath})
+	if err != nil {
+		t.Fatalf("NewLinter: %v", err)
+	}
+	result, err := linter.Lint("test.yaml", []byte(baselineTestWorkflow), nil)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	found := false
+	for _, e := range result.Errors {
+		switch e.Type {
+		case "missing-timeout-minutes":
+			t.Errorf("disabled rule reported a finding: %v", e)
+		case "code-injection-critical":
+			found = true
+			if e.Severity != SeverityLow {
+				t.Errorf("overridden severity = %q, want low", e.Severity)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("fixture should produce a code-injection-critical finding, got %v", result.Errors)
+	}
+
+	writeTestFile(t, configPath, "rules:\n  no-such-rule:\n    disable: true\n")
+	linter, err = NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard, ConfigurationFilePath: configPath})
+	if err != nil {
+		t.Fatalf("NewLinter: %v", err)
+	}
+	if _, err := linter.Lint("test.yaml", []byte(baselineTestWorkflow), nil); err == nil || !strings.Contains(err.Error(), "no-such-rule") {
+		t.Errorf("unknown rule name should fail linting, got %v", err)
+	}
+}
@@ -1,57 +1,262 @@
 package core
 
 import (
+	"encoding/json"
+	"strings"
+
 	"github.com/haya14busa/go-sarif/sarif"
 )
 
-func toResult(fields *TemplateFields) sarif.Result {
-	return sarif.Result{
-		RuleID: sarif.String(fields.Type),
-		Level:  sarif.Warning.Ptr(),
-		Message: sarif.Message{
-			Text: &fields.Message,
+// sarifFingerprintKey is the key of the partial fingerprint of results. The
+// version is bumped when LintingError.Fingerprint changes.
+const sarifFingerprintKey = "sisakulint/v1"
+
+// sarifPropertyBag is a SARIF property bag with arbitrary keys.
+// sarif.PropertyBag only models "tags", but GitHub code scanning reads the
+// "security-severity" property.
+type sarifPropertyBag map[string]interface{}
+
+// sarifResult extends sarif.Result with a free-form property bag. The
+// Properties field shadows the embedded one when encoding to JSON.
+type sarifResult struct {
+	sarif.Result
+	Properties sarifPropertyBag `json:"properties,omitempty"`
+}
+
+// sarifRule extends sarif.ReportingDescriptor with a free-form property bag.
+type sarifRule struct {
+	sarif.ReportingDescriptor
+	Properties sarifPropertyBag `json:"properties,omitempty"`
+}
+
+// sarifDriver extends sarif.ToolComponent with rules carrying free-form
+// properties.
+type sarifDriver struct {
+	sarif.ToolComponent
+	Rules []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+// sarifRun extends sarif.Run with results and rules carrying free-form
+// properties.
+type sarifRun struct {
+	sarif.Run
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results,omitempty"`
+}
+
+// sarifLog extends sarif.Sarif with runs using sarifRun.
+type sarifLog struct {
+	sarif.Sarif
+	Runs []sarifRun `json:"runs"`
+}
+
+// newSARIFTool returns the tool of a run with the version of sisakulint and
+// the given rules.
+func newSARIFTool(rules []*RuleTemplateField) sarifTool {
+	driver := sarifDriver{
+		ToolComponent: sarif.ToolComponent{
+			Name:           "sisakulint",
+			InformationURI: sarif.String("https://sisaku-security.github.io/lint/"),
 		},
-		Locations: []sarif.Location{
-			{
-				PhysicalLocation: &sarif.PhysicalLocation{
-					ArtifactLocation: &sarif.ArtifactLocation{
-						URI: &fields.Filepath,
-					},
-					Region: &sarif.Region{
+	}
+	if versionInfo != "" {
+		driver.Version = sarif.String(versionInfo)
+		driver.SemanticVersion = sarif.String(strings.TrimPrefix(versionInfo, "v"))
+	}
+	for _, r := range rules {
+		driver.Rules = append(driver.Rules, toSARIFRule(r))
+	}
+	return sarifTool{Driver: driver}
+}
+
+// sarifRuleName returns the name of a rule in SARIF, which is its ID in
+// PascalCase such as "CodeInjectionCritical".
+func sarifRuleName(id string) string {
+	words := strings.FieldsFunc(id, func(r rune) bool { return r == '-' || r == '/' || r == '_' || r == '.' })
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, "")
+}
 
-						StartLine:   sarif.Int64(int64(fields.Line)),
-						StartColumn: sarif.Int64(int64(fields.Column)),
-						Snippet: &sarif.ArtifactContent{
-							Text: &fields.Snippet,
+// toSARIFRule converts a registered rule. The short description is the
+// first sentence of the description of the rule.
+func toSARIFRule(r *RuleTemplateField) sarifRule {
+	short := r.Description
+	if i := strings.Index(short, ". "); i >= 0 {
+		short = short[:i+1]
+	}
+	rule := sarifRule{
+		ReportingDescriptor: sarif.ReportingDescriptor{
+			ID:   r.Name,
+			Name: sarif.String(sarifRuleName(r.Name)),
+		},
+	}
+	if r.Description != "" {
+		rule.ShortDescription = &sarif.MultiformatMessageString{Text: short}
+		rule.FullDescription = &sarif.MultiformatMessageString{Text: r.Description}
+	}
+	if r.HelpURI != "" {
+		rule.HelpURI = sarif.String(r.HelpURI)
+	}
+	if r.Severity != "" {
+		rule.DefaultConfiguration = &sarif.ReportingConfiguration{Level: sarifLevel(r.Severity).Ptr()}
+		rule.Properties = sarifPropertyBag{
+			"security-severity": Severity(r.Severity).SecurityScore(),
+			"tags":              []string{"security"},
+		}
+	}
+	return rule
+}
+
+// sarifLevel maps a severity name to a SARIF level. Findings without a
+// severity keep the historical "warning" level.
+func sarifLevel(severity string) sarif.Level {
+	if severity == "" {
+		return sarif.Warning
+	}
+	return sarif.Level(Severity(severity).SARIFLevel())
+}
+
+func toResult(fields *TemplateFields) sarifResult {
+	result := sarifResult{
+		Result: sarif.Result{
+			RuleID: sarif.String(fields.Type),
+			Level:  sarifLevel(fields.Severity).Ptr(),
+			Message: sarif.Message{
+				Text: &fields.Message,
+			},
+			Locations: []sarif.Location{
+				{
+					PhysicalLocation: &sarif.PhysicalLocation{
+						ArtifactLocation: &sarif.ArtifactLocation{
+							URI: &fields.Filepath,
+						},
+						Region: &sarif.Region{
+
+							StartLine:   sarif.Int64(int64(fields.Line)),
+							StartColumn: sarif.Int64(int64(fields.Column)),
+							Snippet: &sarif.ArtifactContent{
+								Text: &fields.Snippet,
+							},
+							SourceLanguage: sarif.String("yaml"),
 						},
-						SourceLanguage: sarif.String("yaml"),
 					},
 				},
 			},
 		},
 	}
+	if len(fields.TaintFlow) > 0 {
+		result.CodeFlows = []sarif.CodeFlow{toCodeFlow(fields.TaintFlow)}
+	}
+	if fields.Fingerprint != "" {
+		result.PartialFingerprints = map[string]string{sarifFingerprintKey: fields.Fingerprint}
+	}
+	for _, fix := range fields.Fixes {
+		result.Fixes = append(result.Fixes, toSARIFFix(fields.Filepath, fix))
+	}
+	if fields.Severity != "" {
+		result.Properties = sarifPropertyBag{
+			"severity":          fields.Severity,
+			"security-severity": Severity(fields.Severity).SecurityScore(),
+		}
+	}
+	return result
 }
 
-func toSARIF(fields []*TemplateFields) (string, error) {
-	s := &sarif.Sarif{
-		Version: sarif.The210,
-		Schema:  sarif.String("https://schemastore.azurewebsites.net/schemas/json/sarif-2.1.0.json"),
-		Runs: []sarif.Run{
+// toSARIF converts findings into a SARIF log with one run. The rules of the
+// run are the given registered rules followed by the other rules of the
+// findings, such as policies.
+func toSARIF(fields []*TemplateFields, rules []*RuleTemplateField) (string, error) {
+	index := make(map[string]int, len(rules))
+	for i, r := range rules {
+		index[r.Name] = i
+	}
+	for _, f := range fields {
+		if _, ok := index[f.Type]; !ok {
+			index[f.Type] = len(rules)
+			rules = append(rules, &RuleTemplateField{Name: f.Type})
+		}
+	}
+	s := &sarifLog{
+		Sarif: sarif.Sarif{
+			Version: sarif.The210,
+			Schema:  sarif.String("https://schemastore.azurewebsites.net/schemas/json/sarif-2.1.0.json"),
+		},
+		Runs: []sarifRun{
 			{
-				Tool: sarif.Tool{
-					Driver: sarif.ToolComponent{
-						Name: "sisakulint",
-					},
+				Run: sarif.Run{
+					Invocations: []sarif.Invocation{{ExecutionSuccessful: true}},
 				},
+				Tool:    newSARIFTool(rules),
+				Results: make([]sarifResult, 0, len(fields)),
 			},
 		},
 	}
 	for _, f := range fields {
-		s.Runs[0].Results = append(s.Runs[0].Results, toResult(f))
+		r := toResult(f)
+		r.RuleIndex = sarif.Int64(int64(index[f.Type]))
+		s.Runs[0].Results = append(s.Runs[0].Results, r)
 	}
-	json, err := s.Marshal()
+	data, err := json.Marshal(s)
 	if err != nil {
 		return "", err
 	}
-	return string(json), nil
+	return string(data), nil
+}
+
+// toSARIFFix converts the fix of a finding into a SARIF fix changing the file
+// of the finding.
+func toSARIFFix(path string, fix *SuggestedFix) sarif.Fix {
+	uri := path
+	replacements := make([]sarif.Replacement, 0, len(fix.Replacements))
+	for _, r := range fix.Replacements {
+		rep := sarif.Replacement{
+			DeletedRegion: sarif.Region{
+				StartLine:   sarif.Int64(int64(r.StartLine)),
+				StartColumn: sarif.Int64(int64(r.StartColumn)),
+				EndLine:     sarif.Int64(int64(r.EndLine)),
+				EndColumn:   sarif.Int64(int64(r.EndColumn)),
+			},
+		}
+		if r.Text != "" {
+			text := r.Text
+			rep.InsertedContent = &sarif.ArtifactContent{Text: &text}
+		}
+		replacements = append(replacements, rep)
+	}
+	return sarif.Fix{
+		Description: &sarif.Message{Text: sarif.String("Auto-fix of " + fix.Rule)},
+		ArtifactChanges: []sarif.ArtifactChange{
+			{ArtifactLocation: sarif.ArtifactLocation{URI: &uri}, Replacements: replacements},
+		},
+	}
+}
+
+// toCodeFlow converts the path of a tainted value into a SARIF code flow with
+// one thread flow from the source to the sink.
+func toCodeFlow(flow []*TaintFlowStep) sarif.CodeFlow {
+	locs := make([]sarif.ThreadFlowLocation, 0, len(flow))
+	for _, s := range flow {
+		message := s.Message
+		uri := s.FilePath
+		locs = append(locs, sarif.ThreadFlowLocation{
+			Location: &sarif.Location{
+				Message: &sarif.Message{Text: &message},
+				PhysicalLocation: &sarif.PhysicalLocation{
+					ArtifactLocation: &sarif.ArtifactLocation{URI: &uri},
+					Region: &sarif.Region{
+						StartLine:   sarif.Int64(int64(s.Line)),
+						StartColumn: sarif.Int64(int64(max(s.Column, 1))),
+					},
+				},
+			},
+			Kinds: []string{"taint"},
+		})
+	}
+	return sarif.CodeFlow{ThreadFlows: []sarif.ThreadFlow{{Locations: locs}}}
 }
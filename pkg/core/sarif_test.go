@@ -0,0 +1,317 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type sarifTestRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifTestLog struct {
+	Runs []struct {
+		Tool struct {
+			Driver struct {
+				Name  string `json:"name"`
+				Rules []struct {
+					ID                   string `json:"id"`
+					Name                 string `json:"name"`
+					HelpURI              string `json:"helpUri"`
+					DefaultConfiguration struct {
+						Level string `json:"level"`
+					} `json:"defaultConfiguration"`
+					Properties map[string]interface{} `json:"properties"`
+				} `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Invocations []struct {
+			ExecutionSuccessful bool `json:"executionSuccessful"`
+		} `json:"invocations"`
+		Results []struct {
+			RuleID              string            `json:"ruleId"`
+			RuleIndex           int               `json:"ruleIndex"`
+			PartialFingerprints map[string]string `json:"partialFingerprints"`
+			Fixes               []struct {
+				ArtifactChanges []struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Replacements []struct {
+						DeletedRegion   sarifTestRegion `json:"deletedRegion"`
+						InsertedContent struct {
+							Text string `json:"text"`
+						} `json:"insertedContent"`
+					} `json:"replacements"`
+				} `json:"artifactChanges"`
+			} `json:"fixes"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+func TestToSARIF_RulesAndFingerprints(t *testing.T) {
+	t.Parallel()
+
+	rules := []*RuleTemplateField{
+		{Name: "code-injection-critical", Description: "Checks code injection. See the docs.", Severity: string(SeverityCritical), HelpURI: ruleDocURL("code-injection-critical")},
+		{Name: "id", Description: "Checks IDs", Severity: string(SeverityLow), HelpURI: ruleDocURL("id")},
+	}
+	out, err := toSARIF([]*TemplateFields{
+		{Message: "a", Filepath: "w.yml", Line: 1, Column: 1, Type: "id", Fingerprint: "fp-a"},
+		{Message: "b", Filepath: "w.yml", Line: 2, Column: 1, Type: "deploy_environment"},
+	}, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifTestLog
+	if err := json.Unmarshal([]byte(out), &log); err != nil {
+		t.Fatal(err)
+	}
+	run := log.Runs[0]
+	if len(run.Invocations) != 1 || !run.Invocations[0].ExecutionSuccessful {
+		t.Errorf("unexpected invocations: %+v", run.Invocations)
+	}
+	got := run.Tool.Driver.Rules
+	if len(got) != 3 || got[2].ID != "deploy_environment" {
+		t.Fatalf("want the registered rules followed by the policy, got %+v", got)
+	}
+	if got[0].Name != "CodeInjectionCritical" || got[0].HelpURI != "https://sisaku-security.github.io/lint/docs/rules/codeinjectioncritical/" || got[0].DefaultConfiguration.Level != "error" {
+		t.Errorf("unexpected rule: %+v", got[0])
+	}
+	if got[0].Properties["security-severity"] != Severity(SeverityCritical).SecurityScore() {
+		t.Errorf("unexpected properties: %v", got[0].Properties)
+	}
+	if got[1].HelpURI != "https://sisaku-security.github.io/lint/docs/rules/idrule/" {
+		t.Errorf("unexpected help URI: %s", got[1].HelpURI)
+	}
+	if r := run.Results[0]; r.RuleIndex != 1 || r.PartialFingerprints[sarifFingerprintKey] != "fp-a" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if r := run.Results[1]; r.RuleIndex != 2 || r.PartialFingerprints != nil {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
+
+func TestRuleDocURL_PagesExist(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range makeRules("ci.yml", false, "", "", nil, nil, nil, nil, nil, true, true) {
+		name := rule.RuleNames()
+		page := ruleDocPage(name)
+		if page == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join("..", "..", "docs", page+".md")); err != nil {
+			t.Errorf("rule %q links to missing page docs/%s.md", name, page)
+		}
+	}
+}
+
+func TestLinter_SARIFFixes(t *testing.T) {
+	t.Parallel()
+
+	dir := makeTestProject(t)
+	path := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	src := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    timeout-minutes: 5
+    steps:
+      - run: echo hello
+`
+	writeTestFile(t, path, src)
+	var out bytes.Buffer
+	l, err := NewLinter(&out, &LinterOptions{CurrentWorkingDirectoryPath: dir, CustomErrorMessageFormat: "{{sarif .}}", FormatFixes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := l.LintFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifTestLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+
+	fixed := false
+	for _, r := range log.Runs[0].Results {
+		if r.RuleID != "permissions" {
+			continue
+		}
+		if len(r.Fixes) != 1 || len(r.Fixes[0].ArtifactChanges) != 1 {
+			t.Fatalf("want one fix of the permissions finding, got %+v", r.Fixes)
+		}
+		change := r.Fixes[0].ArtifactChanges[0]
+		if change.ArtifactLocation.URI != filepath.ToSlash(filepath.Join(".github", "workflows", "ci.yml")) {
+			t.Errorf("unexpected artifact: %s", change.ArtifactLocation.URI)
+		}
+		rep := change.Replacements[0]
+		if rep.DeletedRegion != (sarifTestRegion{StartLine: 2, StartColumn: 1, EndLine: 2, EndColumn: 1}) || rep.InsertedContent.Text != "permissions: {}\n" {
+			t.Errorf("unexpected replacement: %+v", rep)
+		}
+		fixed = true
+	}
+	if !fixed {
+		t.Fatalf("no permissions finding: %s", out.String())
+	}
+
+	// The tree is restored after the fixes are computed, so -fix still
+	// applies the fixers to the original source.
+	snapshot := snapshotForFix(result.ParsedWorkflow.BaseNode)
+	for _, f := range result.AutoFixers {
+		if f.RuleName() == "permissions" {
+			if err := f.Fix(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	data, err := writeFixedSource(result.Source, snapshot, result.ParsedWorkflow.BaseNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(src, "jobs:", "permissions: {}\njobs:", 1); string(data) != want {
+		t.Errorf("unexpected fixed source:\n%s", data)
+	}
+}
+
+func TestLinter_SARIFFixesLintOnce(t *testing.T) {
+	t.Parallel()
+
+	dir := makeTestProject(t)
+	path := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	writeTestFile(t, path, "on: push\npermissions: {}\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo hello\n")
+	var out, logs bytes.Buffer
+	l, err := NewLinter(&out, &LinterOptions{
+		CurrentWorkingDirectoryPath: dir,
+		CustomErrorMessageFormat:    "{{sarif .}}",
+		FormatFixes:                 true,
+		EnabledOptInRules:           []string{"missing-timeout-minutes"},
+		IsVerboseOutputEnabled:      true,
+		LogOutputDestination:        &logs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := l.LintFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs := strings.Count(logs.String(), "validating workflow..."); runs != 1 {
+		t.Errorf("want the file linted once, linted %d times", runs)
+	}
+	var log sarifTestLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	fixes := 0
+	for _, r := range log.Runs[0].Results {
+		fixes += len(r.Fixes)
+	}
+	if fixes == 0 {
+		t.Fatalf("no fixes: %s", out.String())
+	}
+
+	job := result.ParsedWorkflow.Jobs["test"]
+	if job.TimeoutMinutes != nil {
+		t.Fatalf("timeout-minutes left in the tree: %+v", job.TimeoutMinutes)
+	}
+	snapshot := snapshotForFix(result.ParsedWorkflow.BaseNode)
+	for _, f := range result.AutoFixers {
+		if f.RuleName() == "missing-timeout-minutes" {
+			if err := f.Fix(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	data, err := writeFixedSource(result.Source, snapshot, result.ParsedWorkflow.BaseNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "timeout-minutes:"); got != 2 {
+		t.Errorf("want timeout-minutes added to the job and the step once, got:\n%s", data)
+	}
+}
+
+func TestLinter_SARIFFixesAreOptIn(t *testing.T) {
+	t.Parallel()
+
+	dir := makeTestProject(t)
+	path := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	writeTestFile(t, path, "on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    timeout-minutes: 5\n    steps:\n      - run: echo hello\n")
+	var out bytes.Buffer
+	l, err := NewLinter(&out, &LinterOptions{CurrentWorkingDirectoryPath: dir, CustomErrorMessageFormat: "{{sarif .}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.LintFile(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifTestLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	if len(log.Runs[0].Results) == 0 {
+		t.Fatalf("no results: %s", out.String())
+	}
+	for _, r := range log.Runs[0].Results {
+		if len(r.Fixes) != 0 {
+			t.Errorf("fixes without -format-fixes: %+v", r)
+		}
+	}
+}
+
+func TestTemplateUsesFixes(t *testing.T) {
+	t.Parallel()
+
+	for format, want := range map[string]bool{
+		"{{sarif .}}":                                        true,
+		"{{range .}}{{json .Fixes}}{{end}}":                  true,
+		"{{range $e := .}}{{$e.Fixes}}{{end}}":               true,
+		`{{define "x"}}{{sarif .}}{{end}}{{template "x" .}}`: true,
+		"{{range .}}{{.Message}} (not sarif){{end}}":         false,
+		"{{/* Fixes */}}{{range .}}{{.Type}}{{end}}":         false,
+		`{{range .}}{{if eq .Type "sarif"}}x{{end}}{{end}}`:  false,
+	} {
+		f, err := NewErrorFormatter(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.needsFixes != want {
+			t.Errorf("%q: needsFixes = %v, want %v", format, f.needsFixes, want)
+		}
+	}
+}
+
+func TestNewSuggestedFix(t *testing.T) {
+	t.Parallel()
+
+	original := []string{"a\n", "b\n", "c\n"}
+	// An earlier fixer inserted "x" after "a"; this one replaces "c".
+	prev := []string{"a\n", "x\n", "b\n", "c\n"}
+	cur := []string{"a\n", "x\n", "b\n", "C\n"}
+	fix := newSuggestedFix("r", original, prev, cur)
+	if fix == nil || len(fix.Replacements) != 1 {
+		t.Fatalf("unexpected fix: %+v", fix)
+	}
+	if r := fix.Replacements[0]; r.StartLine != 3 || r.EndLine != 4 || r.Text != "C\n" {
+		t.Errorf("unexpected replacement: %+v", r)
+	}
+	if fix.first != 3 || fix.last != 3 {
+		t.Errorf("unexpected lines: %d-%d", fix.first, fix.last)
+	}
+
+	// Rewriting the line added by the earlier fixer cannot be expressed as
+	// a change of the original source.
+	if fix := newSuggestedFix("r", original, prev, []string{"a\n", "y\n", "b\n", "c\n"}); fix != nil {
+		t.Errorf("want no fix, got %+v", fix)
+	}
+}
@@ -245,6 +245,7 @@ func NewSecretExfiltrationRule() *SecretExfiltrationRule {
 			RuleName: "secret-exfiltration",
 			RuleDesc: "Detects patterns where secrets may be exfiltrated to external services via network commands (curl, wget, nc, etc.). " +
 				"See https://sisaku-security.github.io/lint/docs/rules/secretexfiltration/",
+			severity: SeverityCritical,
 		},
 	}
 }
@@ -70,6 +70,7 @@ func NewSecretExposureRule() *SecretExposureRule {
 		BaseRule: BaseRule{
 			RuleName: "secret-exposure",
 			RuleDesc: "Detects excessive secret exposure patterns like toJSON(secrets) or secrets[dynamic-access]. See https://sisaku-security.github.io/lint/docs/rules/secretexposure/",
+			severity: SeverityHigh,
 		},
 	}
 }
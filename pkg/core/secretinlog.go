@@ -1,7 +1,9 @@
 package core
 
 import (
+	"maps"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -40,6 +42,14 @@ type SecretInLogRule struct {
 	// tainted な step output を、後続 step の `${{ steps.<id>.outputs.<name> }}`
 	// 参照に引き継ぐためのマップ。
 	crossStepOutputs map[string]map[string]string
+	// crossStepOutputFlows は crossStepOutputs の各 output について、secret が
+	// env 経由で $GITHUB_OUTPUT に書き込まれるまでの経路を保持する。
+	crossStepOutputFlows map[string]map[string][]*TaintFlowStep
+	// workflowEnv / jobEnv / stepEnv は secret を参照する env 変数の位置を
+	// 経路に含めるために保持する、処理中の workflow / job / step の env:。
+	workflowEnv *ast.Env
+	jobEnv      *ast.Env
+	stepEnv     *ast.Env
 	// pendingNeedsSteps は、consumer job が producer job より先に現れた場合に、
 	// needs.*.outputs.* の解決を VisitWorkflowPost まで遅延するための step 集合。
 	pendingNeedsSteps []*ast.Step
@@ -58,6 +68,7 @@ func NewSecretInLogRuleWithTaintMap(workflowSecretTaintMap *WorkflowSecretTaintM
 			RuleDesc: "Detects secret values being printed to build logs via echo/printf of " +
 				"shell variables derived from secret-sourced environment variables. " +
 				"See https://sisaku-security.github.io/lint/docs/rules/secretinlogrule/",
+			severity: SeverityHigh,
 		},
 	}
 }
@@ -620,6 +631,7 @@ func (rule *SecretInLogRule) VisitWorkflowPre(node *ast.Workflow) error {
 	}
 	rule.pendingNeedsSteps = nil
 	rule.workflowEnvSecrets = rule.collectSecretEnvVars(node.Env)
+	rule.workflowEnv = node.Env
 	return nil
 }
 
@@ -629,12 +641,15 @@ func (rule *SecretInLogRule) VisitWorkflowPre(node *ast.Workflow) error {
 func (rule *SecretInLogRule) VisitJobPre(node *ast.Job) error {
 	rule.jobEnvSecrets = rule.collectSecretEnvVars(node.Env)
 	rule.crossStepEnv = make(map[string]string)
+	rule.jobEnv = node.Env
 	rule.crossStepOutputs = make(map[string]map[string]string)
+	rule.crossStepOutputFlows = make(map[string]map[string][]*TaintFlowStep)
 	for _, step := range node.Steps {
 		rule.checkStep(step)
 	}
 	if rule.workflowSecretTaintMap != nil && node.ID != nil && node.ID.Value != "" {
 		rule.workflowSecretTaintMap.RegisterJobOutputs(node.ID.Value, rule.crossStepOutputs, node.Outputs)
+		rule.workflowSecretTaintMap.RecordJobOutputFlows(node.ID.Value, rule.crossStepOutputFlows, node.Outputs)
 	}
 	return nil
 }
@@ -647,10 +662,10 @@ func (rule *SecretInLogRule) VisitWorkflowPost(node *ast.Workflow) error {
 	if len(rule.pendingNeedsSteps) == 0 {
 		return nil
 	}
-	savedCrossStepOutputs := rule.crossStepOutputs
-	rule.crossStepOutputs = nil
+	savedCrossStepOutputs, savedCrossStepOutputFlows := rule.crossStepOutputs, rule.crossStepOutputFlows
+	rule.crossStepOutputs, rule.crossStepOutputFlows = nil, nil
 	defer func() {
-		rule.crossStepOutputs = savedCrossStepOutputs
+		rule.crossStepOutputs, rule.crossStepOutputFlows = savedCrossStepOutputs, savedCrossStepOutputFlows
 	}()
 
 	for _, step := range rule.pendingNeedsSteps {
@@ -673,6 +688,7 @@ func (rule *SecretInLogRule) checkStep(step *ast.Step) {
 	if script == "" {
 		return
 	}
+	rule.stepEnv = step.Env
 	if rule.stepHasUnregisteredNeedsOutputReference(step, script) {
 		rule.pendingNeedsSteps = append(rule.pendingNeedsSteps, step)
 	}
@@ -733,6 +749,7 @@ func (rule *SecretInLogRule) checkStep(step *ast.Step) {
 				rule.crossStepOutputs[step.ID.Value] = make(map[string]string)
 			}
 			rule.crossStepOutputs[step.ID.Value][name] = origin
+			rule.recordCrossStepOutputFlow(step, execRun.Run, name, origin)
 		}
 	}
 }
@@ -749,6 +766,7 @@ func (rule *SecretInLogRule) checkPendingNeedsStep(step *ast.Step) {
 	if script == "" {
 		return
 	}
+	rule.stepEnv = step.Env
 
 	for _, leak := range rule.findStepOutputExpressionLeaks(script, execRun.Run) {
 		rule.reportStepOutputLeak(leak)
@@ -794,8 +812,9 @@ func (rule *SecretInLogRule) reportLeak(leak echoLeakOccurrence) {
 		// $@ / $* or positional without shellvar upstream — single-var mask isn't possible.
 		suggestion = "Avoid printing this value, or restructure to mask the upstream variables before passing them to the function."
 	}
-	rule.Errorf(
+	rule.ErrorfWithFlow(
 		leak.Position,
+		taintFlowWithSink(rule.originFlow(leak.Origin), newTaintFlowStep(leak.Position, "printed via '%s'", leak.Command)),
 		"secret in log: variable $%s (origin: %s) is printed via '%s' without masking. "+
 			"GitHub Actions only masks direct secrets.* values; values derived via shell expansion or "+
 			"tools like jq are not masked and will appear in plaintext in build logs. %s "+
@@ -813,8 +832,9 @@ func (rule *SecretInLogRule) reportLeak(leak echoLeakOccurrence) {
 // step を編集することになり影響範囲が広い。Phase 2 で対応予定。
 // echoLeak 経路 (env 由来 shellvar) との非対称はこの理由による。
 func (rule *SecretInLogRule) reportStepOutputLeak(leak stepOutputLeakOccurrence) {
-	rule.Errorf(
+	rule.ErrorfWithFlow(
 		leak.Position,
+		taintFlowWithSink(rule.stepOutputPathFlow(leak.Expr), newTaintFlowStep(leak.Position, "printed via '%s'", leak.Command)),
 		"secret in log: step output %s (origin: %s) is printed via '%s' without masking. "+
 			"Values written to $GITHUB_OUTPUT are not automatically masked when later expanded "+
 			"through steps.*.outputs.* or needs.*.outputs.* and can appear in plaintext in build logs. "+
@@ -824,6 +844,70 @@ func (rule *SecretInLogRule) reportStepOutputLeak(leak stepOutputLeakOccurrence)
 	)
 }
 
+// originFlow は漏洩の origin から secret の経路を組み立てる。`secrets.X` は
+// それを参照する env 変数の位置、`<path> (origin: ...)` は step / job output
+// として記録済みの経路になる。shellvar: など位置の分からない origin は nil。
+func (rule *SecretInLogRule) originFlow(origin string) []*TaintFlowStep {
+	if path, _, ok := strings.Cut(origin, " (origin: "); ok {
+		return rule.stepOutputPathFlow(path)
+	}
+	secret, ok := strings.CutPrefix(strings.SplitN(origin, ",", 2)[0], "secrets.")
+	if !ok {
+		return nil
+	}
+	for _, env := range []*ast.Env{rule.stepEnv, rule.jobEnv, rule.workflowEnv} {
+		if env == nil {
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(env.Vars)) {
+			v := env.Vars[key]
+			if v == nil || v.Name == nil || v.Value == nil {
+				continue
+			}
+			for _, m := range secretEnvRefRe.FindAllStringSubmatchIndex(v.Value.Value, -1) {
+				if v.Value.Value[m[2]:m[3]] == secret {
+					return []*TaintFlowStep{newTaintFlowStep(stringOffsetPosition(v.Value, m[0]), "secrets.%s assigned to env %s", secret, v.Name.Value)}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// stepOutputPathFlow は secret 由来の steps.<id>.outputs.<name> /
+// needs.<job>.outputs.<name> の経路を返す。
+func (rule *SecretInLogRule) stepOutputPathFlow(path string) []*TaintFlowStep {
+	parts := strings.Split(path, ".")
+	if len(parts) < 4 || parts[2] != "outputs" {
+		return nil
+	}
+	switch parts[0] {
+	case "steps":
+		return rule.crossStepOutputFlows[parts[1]][strings.Join(parts[3:], ".")]
+	case "needs":
+		if rule.workflowSecretTaintMap != nil {
+			return rule.workflowSecretTaintMap.FlowOf(path)
+		}
+	}
+	return nil
+}
+
+// recordCrossStepOutputFlow は step が secret を output name に書き込むまでの
+// 経路を記録する。書き込みの位置は `name=` が最初に現れる行、見つからなければ step の位置。
+func (rule *SecretInLogRule) recordCrossStepOutputFlow(step *ast.Step, run *ast.String, name, origin string) {
+	pos := step.Pos
+	if loc := regexp.MustCompile(`(^|[^A-Za-z0-9_])` + regexp.QuoteMeta(name) + `=`).FindStringSubmatchIndex(run.Value); loc != nil {
+		pos = scriptLinePosition(run, strings.Count(run.Value[:loc[3]], "\n"))
+	}
+	if rule.crossStepOutputFlows[step.ID.Value] == nil {
+		rule.crossStepOutputFlows[step.ID.Value] = make(map[string][]*TaintFlowStep)
+	}
+	rule.crossStepOutputFlows[step.ID.Value][name] = extendTaintFlow(
+		rule.originFlow(origin),
+		newTaintFlowStep(pos, "written to steps.%s.outputs.%s", step.ID.Value, name),
+	)
+}
+
 // addAutoFixerForLeak は add-mask 行を run スクリプトに挿入する auto-fixer を登録する。
 func (rule *SecretInLogRule) addAutoFixerForLeak(step *ast.Step, leak echoLeakOccurrence) {
 	fixer := &secretInLogFixer{
@@ -30,6 +30,7 @@ func NewSecretsInArtifactsRule() *SecretsInArtifactsRule {
 		BaseRule: BaseRule{
 			RuleName: "secrets-in-artifacts",
 			RuleDesc: "Detects sensitive information that may be included in GitHub Actions artifacts. Uploading entire repositories or using older artifact upload versions can expose secrets like GITHUB_TOKEN or .env files. CWE-312.",
+			severity: SeverityHigh,
 		},
 	}
 }
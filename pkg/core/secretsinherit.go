@@ -25,6 +25,7 @@ func NewSecretsInheritRule() *SecretsInheritRule {
 		BaseRule: BaseRule{
 			RuleName: "secrets-inherit",
 			RuleDesc: "Detects excessive secret inheritance using 'secrets: inherit' in reusable workflow calls",
+			severity: SeverityHigh,
 		},
 	}
 }
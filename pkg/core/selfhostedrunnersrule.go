@@ -24,6 +24,7 @@ func NewSelfHostedRunnersRule() *SelfHostedRunnersRule {
 		BaseRule: BaseRule{
 			RuleName: "self-hosted-runner",
 			RuleDesc: "Detects use of self-hosted runners which may pose security risks in public repositories",
+			severity: SeverityHigh,
 		},
 	}
 }
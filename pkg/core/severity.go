@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is the severity of a finding. Rules set their default severity in
+// BaseRule; individual findings may override it (e.g. known-vulnerable-actions
+// inherits the severity of the advisory).
+type Severity string
+
+// The severity constants are untyped so that code comparing plain strings
+// (e.g. MitigationStatus.Severity) can use them as well.
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityInfo     = "info"
+)
+
+// allSeverities lists the valid severities from the most to the least severe.
+var allSeverities = []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo}
+
+// ParseSeverity parses a case-insensitive severity name.
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToLower(strings.TrimSpace(s)))
+	for _, v := range allSeverities {
+		if v == sev {
+			return sev, nil
+		}
+	}
+	return "", fmt.Errorf("invalid severity %q, expected one of critical, high, medium, low, info", s)
+}
+
+// Level returns a number that grows with the severity. Unknown severities
+// rank like info.
+func (s Severity) Level() int {
+	return severityToLevel(string(s))
+}
+
+// AtLeast reports whether s is as severe as min or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return s.Level() >= min.Level()
+}
+
+// SARIFLevel maps the severity to a SARIF result level.
+func (s Severity) SARIFLevel() string {
+	switch s {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// SecurityScore maps the severity to the CVSS-like score used by the
+// "security-severity" SARIF property. GitHub code scanning buckets scores as
+// critical (>= 9.0), high (7.0-8.9), medium (4.0-6.9) and low (0.1-3.9).
+func (s Severity) SecurityScore() string {
+	switch s {
+	case SeverityCritical:
+		return "9.5"
+	case SeverityHigh:
+		return "8.0"
+	case SeverityMedium:
+		return "5.5"
+	case SeverityLow:
+		return "2.0"
+	default:
+		return "0.0"
+	}
+}
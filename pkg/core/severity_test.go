@@ -0,0 +1,153 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestParseSeverity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    Severity
+		wantErr bool
+	}{
+		{in: "critical", want: SeverityCritical},
+		{in: "HIGH", want: SeverityHigh},
+		{in: " medium ", want: SeverityMedium},
+		{in: "low", want: SeverityLow},
+		{in: "info", want: SeverityInfo},
+		{in: "moderate", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSeverity(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSeverity(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSeverity(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSeverity_AtLeast(t *testing.T) {
+	t.Parallel()
+
+	if !Severity(SeverityCritical).AtLeast(SeverityHigh) {
+		t.Error("critical should be at least high")
+	}
+	if !Severity(SeverityHigh).AtLeast(SeverityHigh) {
+		t.Error("high should be at least high")
+	}
+	if Severity(SeverityMedium).AtLeast(SeverityHigh) {
+		t.Error("medium should not be at least high")
+	}
+	if Severity(SeverityInfo).AtLeast(SeverityLow) {
+		t.Error("info should not be at least low")
+	}
+}
+
+func TestBaseRule_ErrorCarriesRuleSeverity(t *testing.T) {
+	t.Parallel()
+
+	rule := CodeInjectionCriticalRule(nil)
+	if rule.RuleSeverity() != SeverityCritical {
+		t.Errorf("code-injection-critical severity = %q, want critical", rule.RuleSeverity())
+	}
+	if got := CodeInjectionMediumRule(nil).RuleSeverity(); got != SeverityMedium {
+		t.Errorf("code-injection-medium severity = %q, want medium", got)
+	}
+
+	base := &BaseRule{RuleName: "no-severity"}
+	if base.RuleSeverity() != SeverityMedium {
+		t.Errorf("default severity = %q, want medium", base.RuleSeverity())
+	}
+}
+
+func TestToSARIF_MapsSeverity(t *testing.T) {
+	t.Parallel()
+
+	out, err := toSARIF([]*TemplateFields{
+		{Message: "a", Filepath: "w.yml", Line: 1, Column: 1, Type: "code-injection-critical", Severity: SeverityCritical},
+		{Message: "b", Filepath: "w.yml", Line: 2, Column: 1, Type: "unsound-contains", Severity: SeverityMedium},
+		{Message: "c", Filepath: "w.yml", Line: 3, Column: 1, Type: "id", Severity: SeverityLow},
+		{Message: "d", Filepath: "w.yml", Line: 4, Column: 1, Type: "legacy"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("toSARIF: %v", err)
+	}
+	var doc struct {
+		Runs []struct {
+			Results []struct {
+				Level      string            `json:"level"`
+				Properties map[string]string `json:"properties"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	results := doc.Runs[0].Results
+	wantLevels := []string{"error", "warning", "note", "warning"}
+	for i, want := range wantLevels {
+		if results[i].Level != want {
+			t.Errorf("result %d level = %q, want %q", i, results[i].Level, want)
+		}
+	}
+	if got := results[0].Properties["security-severity"]; got != "9.5" {
+		t.Errorf("security-severity = %q, want 9.5", got)
+	}
+	if results[3].Properties != nil {
+		t.Errorf("result without severity should have no properties, got %v", results[3].Properties)
+	}
+}
+
+func TestLinter_MinSeverity(t *testing.T) {
+	t.Parallel()
+
+	src := []byte(`on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.head_commit.message }}"
+`)
+	lint := func(min Severity) []*LintingError {
+		linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard, MinSeverity: min})
+		if err != nil {
+			t.Fatalf("NewLinter: %v", err)
+		}
+		result, err := linter.Lint("test.yaml", src, nil)
+		if err != nil {
+			t.Fatalf("Lint: %v", err)
+		}
+		return result.Errors
+	}
+
+	all := lint("")
+	hasMedium := false
+	for _, e := range all {
+		if e.Severity == "" {
+			t.Errorf("finding without severity: %v", e)
+		}
+		if e.Severity == SeverityMedium {
+			hasMedium = true
+		}
+	}
+	if !hasMedium {
+		t.Fatalf("fixture should produce a medium finding, got %v", all)
+	}
+	for _, e := range lint(SeverityHigh) {
+		if !e.Severity.AtLeast(SeverityHigh) {
+			t.Errorf("finding below -min-severity high was reported: %v (%s)", e, e.Severity)
+		}
+	}
+
+	if _, err := NewLinter(io.Discard, &LinterOptions{MinSeverity: "urgent"}); err == nil {
+		t.Error("invalid MinSeverity should be rejected")
+	}
+}
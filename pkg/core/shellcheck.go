@@ -0,0 +1,254 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// shellcheckExcludedCodes are the shellcheck checks which misfire on run:
+// scripts once their ${{ }} expressions are replaced with placeholders, or
+// which depend on the environment the script runs in.
+var shellcheckExcludedCodes = []string{
+	"SC1091", // Not following sourced files; they are not available here
+	"SC2050", // Constant expression, caused by placeholders
+	"SC2154", // Variables set by env: look unassigned
+	"SC2157", // Argument to -z/-n is always false, caused by placeholders
+	"SC2194", // Constant case word, caused by placeholders
+}
+
+// shellcheckComment is one diagnostic of shellcheck's json1 output format.
+type shellcheckComment struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Level   string `json:"level"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// shellcheckScript is a run: script sent to shellcheck and what it reported.
+type shellcheckScript struct {
+	run      *ast.String
+	script   string
+	comments []shellcheckComment
+}
+
+// ShellcheckRule runs shellcheck on the run: scripts of steps and reports its
+// diagnostics at their position in the workflow.
+type ShellcheckRule struct {
+	BaseRule
+	// runner runs the shellcheck executable. The rule checks nothing while it
+	// is nil.
+	runner        *ExternalCommandRunner
+	workflowShell string
+	jobShell      string
+	windowsJob    bool
+	mu            sync.Mutex
+	scripts       []*shellcheckScript
+}
+
+// NewShellcheckRule creates the shellcheck rule. It is opt-in since it needs
+// the shellcheck command, whose runner is set with setRunner.
+func NewShellcheckRule() *ShellcheckRule {
+	return &ShellcheckRule{
+		BaseRule: BaseRule{
+			RuleName: "shellcheck",
+			RuleDesc: "Checks run: scripts with shellcheck",
+			optIn:    true,
+			severity: SeverityMedium,
+		},
+	}
+}
+
+// setRunner sets the runner of the shellcheck executable.
+func (rule *ShellcheckRule) setRunner(runner *ExternalCommandRunner) {
+	rule.runner = runner
+}
+
+// VisitWorkflowPre remembers the default shell of the workflow.
+func (rule *ShellcheckRule) VisitWorkflowPre(node *ast.Workflow) error {
+	rule.workflowShell = defaultsShell(node.Defaults)
+	return nil
+}
+
+// VisitJobPre remembers the default shell of the job and whether it runs on
+// Windows, where the default shell is pwsh.
+func (rule *ShellcheckRule) VisitJobPre(node *ast.Job) error {
+	rule.jobShell = defaultsShell(node.Defaults)
+	rule.windowsJob = detectRunnerOS(node.RunsOn) == "windows"
+	return nil
+}
+
+// VisitStep sends the script of a run: step to shellcheck.
+func (rule *ShellcheckRule) VisitStep(node *ast.Step) error {
+	if rule.runner == nil {
+		return nil
+	}
+	run, ok := node.Exec.(*ast.ExecRun)
+	if !ok || run.Run == nil || run.Run.Value == "" {
+		return nil
+	}
+
+	shell := rule.jobShell
+	if run.Shell != nil && run.Shell.Value != "" {
+		shell = run.Shell.Value
+	}
+	if shell == "" {
+		shell = rule.workflowShell
+	}
+	if shell == "" && !rule.windowsJob {
+		shell = "bash"
+	}
+	name := shellcheckShellName(shell)
+	if name == "" {
+		rule.Debug("shellcheck skips the script at %s since it does not support shell %q", run.Run.Pos, shell)
+		return nil
+	}
+
+	sanitized, _ := sanitizeForShellParse(run.Run.Value)
+	s := &shellcheckScript{run: run.Run, script: run.Run.Value}
+	rule.mu.Lock()
+	rule.scripts = append(rule.scripts, s)
+	rule.mu.Unlock()
+
+	args := []string{"--norc", "-f", "json1", "--shell=" + name, "-e", strings.Join(shellcheckExcludedCodes, ","), "-"}
+	rule.Debug("running shellcheck %s on the script at %s", strings.Join(args, " "), run.Run.Pos)
+	rule.runner.Execute(args, sanitized, func(stdout []byte, err error) error {
+		comments, err := parseShellcheckOutput(stdout, err)
+		if err != nil {
+			return err
+		}
+		rule.mu.Lock()
+		s.comments = comments
+		rule.mu.Unlock()
+		return nil
+	})
+	return nil
+}
+
+// VisitWorkflowPost waits for shellcheck and reports its diagnostics in the
+// order of the scripts.
+func (rule *ShellcheckRule) VisitWorkflowPost(node *ast.Workflow) error {
+	if rule.runner == nil {
+		return nil
+	}
+	if err := rule.runner.Wait(); err != nil {
+		return err
+	}
+	for _, s := range rule.scripts {
+		for _, c := range s.comments {
+			offset := shellcheckScriptOffset(s.script, c.Line, c.Column)
+			rule.ErrorfWithSeverity(offsetToPosition(s.run, s.script, offset), shellcheckSeverity(c.Level),
+				"shellcheck reported SC%d (%s): %s. See https://www.shellcheck.net/wiki/SC%d",
+				c.Code, c.Level, strings.TrimSuffix(c.Message, "."), c.Code)
+		}
+	}
+	rule.scripts = nil
+	return nil
+}
+
+// parseShellcheckOutput parses the output of one shellcheck run. shellcheck
+// exits with status 1 when it reports something, which is not a failure.
+func parseShellcheckOutput(stdout []byte, err error) ([]shellcheckComment, error) {
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			msg := err.Error()
+			if exitErr != nil && len(exitErr.Stderr) > 0 {
+				msg = strings.TrimSpace(string(exitErr.Stderr))
+			}
+			return nil, fmt.Errorf("shellcheck failed: %s", msg)
+		}
+	}
+	var out struct {
+		Comments []shellcheckComment `json:"comments"`
+	}
+	if err := json.Unmarshal(stdout, &out); err != nil {
+		return nil, fmt.Errorf("could not parse shellcheck output %q: %w", stdout, err)
+	}
+	return out.Comments, nil
+}
+
+// defaultsShell returns the shell of defaults.run, or "" when it is not set.
+func defaultsShell(d *ast.Defaults) string {
+	if d == nil || d.Run == nil || d.Run.Shell == nil {
+		return ""
+	}
+	return d.Run.Shell.Value
+}
+
+// shellcheckShellName returns the --shell argument of shellcheck for the
+// shell: value of a step, or "" when shellcheck cannot check the shell. A
+// custom shell such as "bash -e {0}" is checked as its command.
+func shellcheckShellName(shell string) string {
+	if strings.Contains(shell, "${{") {
+		return ""
+	}
+	fields := strings.Fields(shell)
+	if len(fields) == 0 {
+		return ""
+	}
+	switch name := filepath.Base(fields[0]); name {
+	case "bash", "sh", "dash", "ksh":
+		return name
+	default:
+		return ""
+	}
+}
+
+// shellcheckSeverity maps the level of a shellcheck diagnostic to a severity.
+func shellcheckSeverity(level string) Severity {
+	switch level {
+	case "error":
+		return SeverityHigh
+	case "warning":
+		return SeverityMedium
+	case "info":
+		return SeverityLow
+	default:
+		return SeverityInfo
+	}
+}
+
+// shellcheckScriptOffset converts the 1-based line and column shellcheck
+// reported for the sanitized form of script to a byte offset of script.
+// Columns count characters. A position inside a placeholder is mapped to the
+// start of its ${{ }} expression.
+func shellcheckScriptOffset(script string, line, col int) int {
+	sanitized, _ := sanitizeForShellParse(script)
+	offset := 0
+	for i := 1; i < line; i++ {
+		nl := strings.IndexByte(sanitized[offset:], '\n')
+		if nl < 0 {
+			break
+		}
+		offset += nl + 1
+	}
+	for i := 1; i < col && offset < len(sanitized) && sanitized[offset] != '\n'; i++ {
+		_, size := utf8.DecodeRuneInString(sanitized[offset:])
+		offset += size
+	}
+
+	// Placeholders are numbered in order, so the n-th match of script is
+	// replaced with the n-th placeholder.
+	delta := 0
+	for i, m := range taintGhExprPattern.FindAllStringIndex(script, -1) {
+		placeholder := len(fmt.Sprintf("%s%d_", taintPlaceholderPrefix, i))
+		start := m[0] + delta
+		if offset < start {
+			break
+		}
+		if offset < start+placeholder {
+			return m[0]
+		}
+		delta += placeholder - (m[1] - m[0])
+	}
+	return offset - delta
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeFakeShellcheck writes a shellcheck command which records its --shell
+// argument in dir/shells and runs script to produce its output.
+func writeFakeShellcheck(t *testing.T, script string) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake shellcheck command is a shell script")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "shellcheck")
+	shells := filepath.Join(dir, "shells")
+	writeTestFile(t, fake, "#!/bin/sh\nfor a; do case \"$a\" in --shell=*) echo \"$a\" >> '"+shells+"';; esac; done\ncat > /dev/null\n"+script)
+	if err := os.Chmod(fake, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return fake, shells
+}
+
+func lintWithShellcheck(t *testing.T, fake, src string) ([]*LintingError, error) {
+	t.Helper()
+	dir := makeTestProject(t)
+	path := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	writeTestFile(t, path, src)
+	l, err := NewLinter(&bytes.Buffer{}, &LinterOptions{
+		CurrentWorkingDirectoryPath: dir,
+		EnabledOptInRules:           []string{"shellcheck"},
+		ShellcheckExecutable:        fake,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := l.LintFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var errs []*LintingError
+	for _, e := range res.Errors {
+		if e.Type == "shellcheck" {
+			errs = append(errs, e)
+		}
+	}
+	return errs, nil
+}
+
+func TestShellcheckRule_MapsDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	fake, shells := writeFakeShellcheck(t, `echo '{"comments":[{"file":"-","line":2,"endLine":2,"column":23,"endColumn":27,"level":"info","code":2086,"message":"Double quote to prevent globbing and word splitting."}]}'
+exit 1
+`)
+	errs, err := lintWithShellcheck(t, fake, `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    defaults:
+      run:
+        shell: sh
+    steps:
+      - run: |
+          echo hello
+          echo ${{ github.sha }} $foo
+      - shell: bash -e {0}
+        run: |
+          true
+          echo ${{ github.sha }} $foo
+  win:
+    runs-on: windows-latest
+    steps:
+      - run: echo skipped
+      - shell: python
+        run: print("skipped")
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("want 2 errors, got %v", errs)
+	}
+	for i, line := range []int{11, 15} {
+		e := errs[i]
+		if e.LineNumber != line || e.ColNumber != 24 {
+			t.Errorf("error %d is at %d:%d, want %d:24", i, e.LineNumber, e.ColNumber, line)
+		}
+		if e.Severity != SeverityLow || !strings.Contains(e.Description, "SC2086 (info): Double quote to prevent globbing and word splitting.") {
+			t.Errorf("unexpected error: %+v", e)
+		}
+	}
+
+	data, err := os.ReadFile(shells)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Fields(string(data))
+	sort.Strings(got)
+	if strings.Join(got, " ") != "--shell=bash --shell=sh" {
+		t.Errorf("unexpected shells: %q", got)
+	}
+}
+
+func TestShellcheckRule_Failure(t *testing.T) {
+	t.Parallel()
+
+	fake, _ := writeFakeShellcheck(t, "echo 'unknown option' >&2\nexit 3\n")
+	_, err := lintWithShellcheck(t, fake, `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo hello
+`)
+	if err == nil || !strings.Contains(err.Error(), "shellcheck failed: unknown option") {
+		t.Fatalf("want a shellcheck failure, got %v", err)
+	}
+}
+
+func TestShellcheckRule_InvalidExecutable(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewLinter(&bytes.Buffer{}, &LinterOptions{ShellcheckExecutable: filepath.Join(t.TempDir(), "shellcheck")})
+	if err == nil || !strings.Contains(err.Error(), "invalid shellcheck executable") {
+		t.Fatalf("want an invalid executable error, got %v", err)
+	}
+}
+
+func TestShellcheckScriptOffset(t *testing.T) {
+	t.Parallel()
+
+	script := "echo ${{ a }} ${{ b }}\nécho $x"
+	tests := []struct {
+		line, col, want int
+	}{
+		{1, 1, 0},
+		{1, 8, 5}, // inside the first placeholder
+		{1, 22, 13},
+		{1, 23, 14}, // start of the second placeholder
+		{1, 39, 22}, // end of the line
+		{2, 6, 29},  // after a multi-byte character
+	}
+	for _, tc := range tests {
+		if got := shellcheckScriptOffset(script, tc.line, tc.col); got != tc.want {
+			t.Errorf("shellcheckScriptOffset(%d, %d) = %d, want %d", tc.line, tc.col, got, tc.want)
+		}
+	}
+}
+
+func TestShellcheckShellName(t *testing.T) {
+	t.Parallel()
+
+	for shell, want := range map[string]string{
+		"bash":               "bash",
+		"bash -e {0}":        "bash",
+		"/usr/bin/sh -e {0}": "sh",
+		"pwsh":               "",
+		"python":             "",
+		"${{ matrix.sh }}":   "",
+	} {
+		if got := shellcheckShellName(shell); got != want {
+			t.Errorf("shellcheckShellName(%q) = %q, want %q", shell, got, want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FixReplacement replaces the text between two positions of a file with
+// Text. Lines and columns start at 1 and columns count UTF-16 code units, as
+// in SARIF. The end is exclusive; an insertion has the same start and end.
+type FixReplacement struct {
+	StartLine   int    `json:"start_line"`
+	StartColumn int    `json:"start_column"`
+	EndLine     int    `json:"end_line"`
+	EndColumn   int    `json:"end_column"`
+	Text        string `json:"text"`
+}
+
+// SuggestedFix is the change one auto-fixer makes to the original source of
+// a file. Each fix applies on its own; applying several may conflict.
+type SuggestedFix struct {
+	Rule         string            `json:"rule"`
+	Replacements []*FixReplacement `json:"replacements"`
+	// first and last are the 1-based lines of the original source the fix
+	// touches, used to attach it to a finding.
+	first, last int
+}
+
+// suggestFixes returns the changes the auto-fixers of result make. The fixers
+// run on the syntax tree of result, which is restored afterwards so that -fix
+// can still apply them. Fixers writing other files are not run.
+func suggestFixes(result *ValidateResult) []*SuggestedFix {
+	if result.ParsedWorkflow == nil || result.ParsedWorkflow.BaseNode == nil || len(result.AutoFixers) == 0 {
+		return nil
+	}
+	restore := captureTreeState(reflect.ValueOf(result.ParsedWorkflow))
+	defer restore()
+	return suggestedFixes(result)
+}
+
+// suggestedFixes runs the fixers of result one after another and converts
+// what each of them changed into a fix of the original source. A fixer which
+// fails, or which rewrites lines added by an earlier fixer, gets no fix.
+func suggestedFixes(result *ValidateResult) []*SuggestedFix {
+	if result.ParsedWorkflow == nil || result.ParsedWorkflow.BaseNode == nil || len(result.AutoFixers) == 0 {
+		return nil
+	}
+	root := result.ParsedWorkflow.BaseNode
+	snapshot := snapshotForFix(root)
+	original := splitDiffLines(result.Source)
+	prev := original
+	var fixes []*SuggestedFix
+	for _, fixer := range result.AutoFixers {
+		if _, ok := fixer.(*repositoryFileFixer); ok {
+			continue
+		}
+		fixErr := fixer.Fix()
+		data, err := writeFixedSource(result.Source, snapshot, root)
+		if err != nil {
+			return fixes
+		}
+		cur := splitDiffLines(data)
+		if fixErr == nil {
+			if fix := newSuggestedFix(fixer.RuleName(), original, prev, cur); fix != nil {
+				fixes = append(fixes, fix)
+			}
+		}
+		prev = cur
+	}
+	return fixes
+}
+
+// captureTreeState records every struct, slice and map reachable from v,
+// which is a syntax tree with its YAML nodes, and returns a function putting
+// them back. Fixers change the tree in place, so the values are restored into
+// the same objects which the fixers and the rules refer to.
+func captureTreeState(v reflect.Value) (restore func()) {
+	var restores []func()
+	seen := map[uintptr]bool{}
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() || v.Elem().Kind() != reflect.Struct || seen[v.Pointer()] {
+				return
+			}
+			seen[v.Pointer()] = true
+			elem := v.Elem()
+			saved := reflect.New(elem.Type()).Elem()
+			saved.Set(elem)
+			restores = append(restores, func() { elem.Set(saved) })
+			for i := 0; i < elem.NumField(); i++ {
+				if elem.Type().Field(i).IsExported() {
+					walk(elem.Field(i))
+				}
+			}
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Slice:
+			if v.IsNil() {
+				return
+			}
+			saved := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			reflect.Copy(saved, v)
+			restores = append(restores, func() { reflect.Copy(v, saved) })
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Map:
+			if v.IsNil() {
+				return
+			}
+			saved := reflect.MakeMapWithSize(v.Type(), v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				saved.SetMapIndex(iter.Key(), iter.Value())
+				walk(iter.Value())
+			}
+			restores = append(restores, func() {
+				v.Clear()
+				iter := saved.MapRange()
+				for iter.Next() {
+					v.SetMapIndex(iter.Key(), iter.Value())
+				}
+			})
+		}
+	}
+	walk(v)
+	return func() {
+		for _, r := range restores {
+			r()
+		}
+	}
+}
+
+// newSuggestedFix maps the change from prev to cur, where prev is original
+// with the changes of earlier fixers, back onto original.
+func newSuggestedFix(rule string, original, prev, cur []string) *SuggestedFix {
+	// at[k] is the line of original which line k of prev is, or -1 for lines
+	// added by earlier fixers. before[k] is the line of original which line k
+	// of prev comes before.
+	at := make([]int, len(prev)+1)
+	before := make([]int, len(prev)+1)
+	k, src := 0, 0
+	for _, op := range diffLines(original, prev) {
+		switch op.kind {
+		case diffEqual:
+			at[k], before[k] = src, src
+			k++
+			src++
+		case diffDelete:
+			src++
+		case diffInsert:
+			at[k], before[k] = -1, src
+			k++
+		}
+	}
+	at[k], before[k] = -1, src
+
+	type block struct {
+		from, to int // range of original
+		lines    []string
+	}
+	var blocks []block
+	var cur0 *block
+	line := 0
+	flush := func() {
+		if cur0 != nil {
+			blocks = append(blocks, *cur0)
+			cur0 = nil
+		}
+	}
+	for _, op := range diffLines(prev, cur) {
+		if op.kind == diffEqual {
+			flush()
+			line++
+			continue
+		}
+		if cur0 == nil {
+			cur0 = &block{from: before[line], to: before[line]}
+		}
+		switch op.kind {
+		case diffDelete:
+			if at[line] < 0 || at[line] != cur0.to {
+				return nil
+			}
+			cur0.to++
+			line++
+		case diffInsert:
+			cur0.lines = append(cur0.lines, op.line)
+		}
+	}
+	flush()
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	var after []string
+	next := 0
+	for _, b := range blocks {
+		after = append(after, original[next:b.from]...)
+		after = append(after, b.lines...)
+		next = b.to
+	}
+	after = append(after, original[next:]...)
+
+	edits, _, _ := textEdits([]byte(strings.Join(original, "")), []byte(strings.Join(after, "")))
+	if len(edits) == 0 {
+		return nil
+	}
+	fix := &SuggestedFix{Rule: rule, first: blocks[0].from + 1, last: max(blocks[len(blocks)-1].to, blocks[len(blocks)-1].from+1)}
+	for _, e := range edits {
+		fix.Replacements = append(fix.Replacements, &FixReplacement{
+			StartLine:   e.Range.Start.Line + 1,
+			StartColumn: e.Range.Start.Character + 1,
+			EndLine:     e.Range.End.Line + 1,
+			EndColumn:   e.Range.End.Character + 1,
+			Text:        e.NewText,
+		})
+	}
+	return fix
+}
+
+// attachSuggestedFixes gives each fix to the finding of the same rule closest
+// to the lines it changes. Fixes of rules without findings are dropped.
+func attachSuggestedFixes(fields []*TemplateFields, fixes []*SuggestedFix) {
+	for _, fix := range fixes {
+		var best *TemplateFields
+		bestDist := 0
+		for _, f := range fields {
+			if f.Type != fix.Rule {
+				continue
+			}
+			dist := 0
+			if f.Line < fix.first {
+				dist = fix.first - f.Line
+			} else if f.Line > fix.last {
+				dist = f.Line - fix.last
+			}
+			if best == nil || dist < bestDist {
+				best, bestDist = f, dist
+			}
+		}
+		if best != nil {
+			best.Fixes = append(best.Fixes, fix)
+		}
+	}
+}
+
+// templateFields converts the findings of result for the error formatter,
+// with the fixes of the file when the format uses them and -format-fixes is
+// given.
+func (l *Linter) templateFields(result *ValidateResult) []*TemplateFields {
+	fields := make([]*TemplateFields, 0, len(result.Errors))
+	for _, err := range result.Errors {
+		fields = append(fields, err.ExtractTemplateFields(result.Source))
+	}
+	if l.formatFixes && l.errorFormatter.needsFixes && len(fields) > 0 {
+		attachSuggestedFixes(fields, suggestFixes(result))
+	}
+	return fields
+}
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// SuppressionUnusedRuleName is the Type of findings reporting a
+	// sisakulint-disable directive that did not suppress anything.
+	SuppressionUnusedRuleName = "suppression-unused"
+	// SuppressionUnknownRuleName is the Type of findings reporting a
+	// sisakulint-disable directive naming a rule that does not exist.
+	SuppressionUnknownRuleName = "suppression-unknown-rule"
+)
+
+// suppressionScope tells which findings a directive applies to.
+type suppressionScope int
+
+const (
+	// suppressNextLine applies to the next non-blank, non-comment line.
+	suppressNextLine suppressionScope = iota
+	// suppressFile applies to the whole file.
+	suppressFile
+)
+
+// suppressionDirectivePattern matches inline suppression comments:
+//
+//	# sisakulint-disable-next-line <rule>[,<rule>...] [-- reason]
+//	# sisakulint-disable-file <rule>[,<rule>...] [-- reason]
+//
+// The directive may follow other content on the same line, which also lets
+// it be written as a shell comment inside a run: block.
+var suppressionDirectivePattern = regexp.MustCompile(`(?:^|\s)#\s*sisakulint-disable-(next-line|file)\b(.*)$`)
+
+// suppressionDirective is one parsed sisakulint-disable comment.
+type suppressionDirective struct {
+	scope suppressionScope
+	// line and col locate the comment itself (1-based).
+	line int
+	col  int
+	// targetLine is the line the directive applies to for suppressNextLine.
+	// 0 means no following line exists.
+	targetLine int
+	rules      []string
+	reason     string
+	// used records which of rules actually suppressed a finding. It is kept
+	// across repeated filter passes so that suppression stays idempotent.
+	used map[string]bool
+}
+
+func (d *suppressionDirective) directiveName() string {
+	if d.scope == suppressFile {
+		return "sisakulint-disable-file"
+	}
+	return "sisakulint-disable-next-line"
+}
+
+// matches reports whether the directive covers the given finding.
+func (d *suppressionDirective) matches(err *LintingError) (string, bool) {
+	if d.scope == suppressNextLine && (d.targetLine == 0 || err.LineNumber != d.targetLine) {
+		return "", false
+	}
+	for _, r := range d.rules {
+		if r == err.Type {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// suppressionSet holds all directives of one file together with the rule
+// names needed to validate them.
+type suppressionSet struct {
+	directives []*suppressionDirective
+	// knownRules are every rule name that can appear in a directive.
+	knownRules map[string]struct{}
+	// activeRules are the rules that actually ran on the file. A directive for
+	// a known but inactive rule (e.g. a disabled opt-in rule) is not reported
+	// as unused because the rule never had the chance to fire.
+	activeRules map[string]struct{}
+}
+
+// parseSuppressionDirectives collects sisakulint-disable directives from the
+// comments in the given source.
+func parseSuppressionDirectives(content []byte) []*suppressionDirective {
+	var lines []string
+	s := bufio.NewScanner(bytes.NewReader(content))
+	s.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+
+	var directives []*suppressionDirective
+	for i, line := range lines {
+		m := suppressionDirectivePattern.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		d := &suppressionDirective{
+			line: i + 1,
+			col:  strings.Index(line[m[0]:], "#") + m[0] + 1,
+			used: map[string]bool{},
+		}
+		if line[m[2]:m[3]] == "file" {
+			d.scope = suppressFile
+		} else {
+			d.scope = suppressNextLine
+			d.targetLine = nextSignificantLine(lines, i+1)
+		}
+		body := line[m[4]:m[5]]
+		if idx := strings.Index(body, "--"); idx >= 0 {
+			d.reason = strings.TrimSpace(body[idx+2:])
+			body = body[:idx]
+		}
+		d.rules = strings.FieldsFunc(body, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+// nextSignificantLine returns the 1-based number of the first line at or
+// after index start that is neither blank nor a comment-only line. Stacked
+// directives therefore all apply to the same line.
+func nextSignificantLine(lines []string, start int) int {
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		return i + 1
+	}
+	return 0
+}
+
+// newSuppressionSet parses the directives in content. knownRules are every
+// rule name makeRules can produce; activeRules are the rules that ran on the
+// file. It returns nil when the file contains no directive.
+func newSuppressionSet(content []byte, knownRules []string, activeRules []string) *suppressionSet {
+	directives := parseSuppressionDirectives(content)
+	if len(directives) == 0 {
+		return nil
+	}
+	set := &suppressionSet{
+		directives:  directives,
+		knownRules:  make(map[string]struct{}, len(knownRules)+1),
+		activeRules: make(map[string]struct{}, len(activeRules)+1),
+	}
+	// Parse errors are reported with the "syntax" type and can be suppressed too.
+	set.knownRules["syntax"] = struct{}{}
+	set.activeRules["syntax"] = struct{}{}
+	for _, n := range knownRules {
+		set.knownRules[n] = struct{}{}
+	}
+	for _, n := range activeRules {
+		set.activeRules[n] = struct{}{}
+	}
+	return set
+}
+
+// isSuppressionFinding reports whether err was produced by the suppression
+// machinery itself.
+func isSuppressionFinding(err *LintingError) bool {
+	return err.Type == SuppressionUnusedRuleName || err.Type == SuppressionUnknownRuleName
+}
+
+// apply drops findings covered by a directive and appends findings for
+// directives that are malformed, name unknown rules, or suppressed nothing.
+// Findings produced by a previous call are replaced, so apply is idempotent.
+// The returned set contains the rule names of which at least one finding was
+// suppressed and none remain, so that their auto-fixers can be dropped too.
+func (s *suppressionSet) apply(errs []*LintingError) ([]*LintingError, map[string]struct{}) {
+	kept := make([]*LintingError, 0, len(errs))
+	suppressedRules := map[string]struct{}{}
+	remainingRules := map[string]struct{}{}
+	for _, err := range errs {
+		if isSuppressionFinding(err) {
+			continue
+		}
+		suppressed := false
+		for _, d := range s.directives {
+			if rule, ok := d.matches(err); ok {
+				d.used[rule] = true
+				suppressed = true
+			}
+		}
+		if suppressed {
+			suppressedRules[err.Type] = struct{}{}
+			continue
+		}
+		remainingRules[err.Type] = struct{}{}
+		kept = append(kept, err)
+	}
+
+	for _, d := range s.directives {
+		if len(d.rules) == 0 {
+			kept = append(kept, &LintingError{
+				Description: fmt.Sprintf("%s directive must name at least one rule, e.g. \"# %s code-injection-critical -- reason\"", d.directiveName(), d.directiveName()),
+				LineNumber:  d.line,
+				ColNumber:   d.col,
+				Type:        SuppressionUnknownRuleName,
+				Severity:    SeverityLow,
+			})
+			continue
+		}
+		for _, r := range d.rules {
+			if _, ok := s.knownRules[r]; !ok {
+				kept = append(kept, &LintingError{
+					Description: fmt.Sprintf("%s directive refers to unknown rule %q", d.directiveName(), r),
+					LineNumber:  d.line,
+					ColNumber:   d.col,
+					Type:        SuppressionUnknownRuleName,
+					Severity:    SeverityLow,
+				})
+				continue
+			}
+			if _, ok := s.activeRules[r]; !ok || d.used[r] {
+				continue
+			}
+			kept = append(kept, &LintingError{
+				Description: fmt.Sprintf("%s directive for rule %q did not suppress any finding. Remove it", d.directiveName(), r),
+				LineNumber:  d.line,
+				ColNumber:   d.col,
+				Type:        SuppressionUnusedRuleName,
+				Severity:    SeverityLow,
+			})
+		}
+	}
+
+	for r := range remainingRules {
+		delete(suppressedRules, r)
+	}
+	return kept, suppressedRules
+}
+
+// ruleNamesOf returns the names of the given rules.
+func ruleNamesOf(rules []Rule) []string {
+	names := make([]string, 0, len(rules))
+	for _, r := range rules {
+		names = append(names, r.RuleNames())
+	}
+	return names
+}
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func lintForSuppressionTest(t *testing.T, src string) *ValidateResult {
+	t.Helper()
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatalf("NewLinter: %v", err)
+	}
+	result, err := linter.Lint("test.yaml", []byte(src), nil)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	return result
+}
+
+func countErrorsOfType(errs []*LintingError, typ string) int {
+	n := 0
+	for _, e := range errs {
+		if e.Type == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSuppression_DisableNextLine(t *testing.T) {
+	t.Parallel()
+
+	src := `on: pull_request_target
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      # sisakulint-disable-next-line code-injection-critical -- title is validated upstream
+      - run: echo "${{ github.event.pull_request.title }}"
+`
+	result := lintForSuppressionTest(t, src)
+	if n := countErrorsOfType(result.Errors, "code-injection-critical"); n != 0 {
+		t.Errorf("code-injection-critical should be suppressed, got %d: %v", n, result.Errors)
+	}
+	if n := countErrorsOfType(result.Errors, SuppressionUnusedRuleName); n != 0 {
+		t.Errorf("directive was used, got unused findings: %v", result.Errors)
+	}
+	for _, f := range result.AutoFixers {
+		if f.RuleName() == "code-injection-critical" {
+			t.Errorf("auto-fixer of fully suppressed rule should be dropped")
+		}
+	}
+}
+
+func TestSuppression_DisableNextLineOnlyCoversNextLine(t *testing.T) {
+	t.Parallel()
+
+	src := `on: pull_request_target
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      # sisakulint-disable-next-line code-injection-critical
+      - run: echo "${{ github.event.pull_request.title }}"
+      - run: echo "${{ github.event.pull_request.body }}"
+`
+	result := lintForSuppressionTest(t, src)
+	if n := countErrorsOfType(result.Errors, "code-injection-critical"); n != 1 {
+		t.Errorf("expected exactly 1 unsuppressed code-injection-critical, got %d: %v", n, result.Errors)
+	}
+	hasFixer := false
+	for _, f := range result.AutoFixers {
+		if f.RuleName() == "code-injection-critical" {
+			hasFixer = true
+		}
+	}
+	if !hasFixer {
+		t.Errorf("auto-fixers must be kept while the rule still has findings")
+	}
+}
+
+func TestSuppression_DisableFile(t *testing.T) {
+	t.Parallel()
+
+	src := `# sisakulint-disable-file code-injection-critical -- trusted internal repository
+on: pull_request_target
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.pull_request.title }}"
+      - run: echo "${{ github.event.pull_request.body }}"
+`
+	result := lintForSuppressionTest(t, src)
+	if n := countErrorsOfType(result.Errors, "code-injection-critical"); n != 0 {
+		t.Errorf("code-injection-critical should be suppressed in the whole file, got %d", n)
+	}
+}
+
+func TestSuppression_ReportsUnusedAndUnknownDirectives(t *testing.T) {
+	t.Parallel()
+
+	src := `on: push
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      # sisakulint-disable-next-line code-injection-medium
+      - run: echo hello
+      # sisakulint-disable-next-line no-such-rule
+      - run: echo world
+      # sisakulint-disable-next-line
+      - run: echo again
+`
+	result := lintForSuppressionTest(t, src)
+	if n := countErrorsOfType(result.Errors, SuppressionUnusedRuleName); n != 1 {
+		t.Errorf("want 1 %s finding, got %d: %v", SuppressionUnusedRuleName, n, result.Errors)
+	}
+	if n := countErrorsOfType(result.Errors, SuppressionUnknownRuleName); n != 2 {
+		t.Errorf("want 2 %s findings, got %d: %v", SuppressionUnknownRuleName, n, result.Errors)
+	}
+	for _, e := range result.Errors {
+		if e.Type == SuppressionUnusedRuleName && e.LineNumber != 7 {
+			t.Errorf("unused directive reported at line %d, want 7", e.LineNumber)
+		}
+	}
+}
+
+func TestSuppression_OptInRuleDirectiveIsNotUnused(t *testing.T) {
+	t.Parallel()
+
+	src := `on: push
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    # sisakulint-disable-next-line missing-timeout-minutes
+    steps:
+      - run: echo hello
+`
+	result := lintForSuppressionTest(t, src)
+	if n := countErrorsOfType(result.Errors, SuppressionUnusedRuleName) + countErrorsOfType(result.Errors, SuppressionUnknownRuleName); n != 0 {
+		t.Errorf("directive for a disabled opt-in rule should be accepted silently, got %v", result.Errors)
+	}
+}
+
+func TestSuppression_FilterIsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	src := `on: pull_request_target
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      # sisakulint-disable-next-line code-injection-critical
+      - run: echo "${{ github.event.pull_request.title }}"
+      # sisakulint-disable-next-line code-injection-critical
+      - run: echo ok
+`
+	linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := linter.Lint("test.yaml", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := len(result.Errors)
+	linter.postProcessResolvedChains(result.FilePath, result)
+	if after := len(result.Errors); after != before {
+		t.Errorf("re-filtering changed error count from %d to %d: %v", before, after, result.Errors)
+	}
+	if n := countErrorsOfType(result.Errors, SuppressionUnusedRuleName); n != 1 {
+		t.Errorf("want 1 unused directive after re-filtering, got %d", n)
+	}
+}
+
+func TestParseSuppressionDirectives(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.Join([][]byte{
+		[]byte("# sisakulint-disable-file a, b -- reason here"),
+		[]byte("key: value # sisakulint-disable-next-line c"),
+		[]byte(""),
+		[]byte("  # another comment"),
+		[]byte("target: line"),
+		[]byte("url: http://example.com/#sisakulint-disable-file x"),
+	}, []byte("\n"))
+	ds := parseSuppressionDirectives(src)
+	if len(ds) != 2 {
+		t.Fatalf("want 2 directives, got %d", len(ds))
+	}
+	if ds[0].scope != suppressFile || len(ds[0].rules) != 2 || ds[0].rules[1] != "b" || ds[0].reason != "reason here" {
+		t.Errorf("unexpected file directive: %+v", ds[0])
+	}
+	if ds[1].scope != suppressNextLine || ds[1].targetLine != 5 || ds[1].col != 12 {
+		t.Errorf("unexpected next-line directive: %+v", ds[1])
+	}
+}
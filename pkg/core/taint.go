@@ -86,6 +86,11 @@ type TaintTracker struct {
 	// knownTaintedActions maps action patterns to their tainted outputs
 	// Phase 3: Used to infer taint from known action behaviors
 	knownTaintedActions map[string][]KnownTaintedOutput
+
+	// outputFlows maps stepID -> outputName -> path of the tainted value from
+	// its source to the write of the output. Attached to findings so that
+	// indirect taint can be verified.
+	outputFlows map[string]map[string][]*TaintFlowStep
 }
 
 // KnownTaintedOutput represents a known tainted output from an action.
@@ -100,6 +105,7 @@ func NewTaintTracker() *TaintTracker {
 		taintedOutputs:      make(map[string]map[string][]string),
 		taintedVars:         make(map[string]shell.Entry),
 		knownTaintedActions: make(map[string][]KnownTaintedOutput),
+		outputFlows:         make(map[string]map[string][]*TaintFlowStep),
 	}
 
 	// Phase 3: Initialize known tainted actions database
@@ -259,6 +265,11 @@ func (t *TaintTracker) AnalyzeStep(step *ast.Step) {
 		}
 		expandShellvarMarkers(expanded)
 		t.recordRedirWrite(stepID, w, exprMap, expanded)
+		line := 0
+		if w.Offset >= 0 && w.Offset <= len(sanitized) {
+			line = strings.Count(sanitized[:w.Offset], "\n")
+		}
+		t.recordOutputFlow(step, run.Run, w.Name, line, w.Value, exprMap)
 	}
 }
 
@@ -358,6 +369,9 @@ func (t *TaintTracker) analyzeActionStep(step *ast.Step) {
 
 	for _, output := range taintedOutputs {
 		t.taintedOutputs[stepID][output.OutputName] = []string{output.TaintSource}
+		t.setOutputFlow(stepID, output.OutputName, []*TaintFlowStep{
+			newTaintFlowStep(action.Uses.Pos, "%s sets steps.%s.outputs.%s from %s", uses, stepID, output.OutputName, output.TaintSource),
+		})
 	}
 }
 
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// TaintFlowStep is a location on the path of an untrusted value, from the
+// place it enters a workflow to the sink of a finding. For example:
+//
+//	github.event.pull_request.title at .github/workflows/ci.yml:9:30
+//	-> written to steps.meta.outputs.title at 9:15
+//	-> job output needs.extract.outputs.title at 5:14
+//	-> used in a run: script at 14:22
+type TaintFlowStep struct {
+	// FilePath is the file of the location. Empty means the file of the
+	// finding, which is filled in when the finding is printed.
+	FilePath string `json:"filepath,omitempty"`
+	// Line is the line number of the location.
+	Line int `json:"line"`
+	// Column is the column number of the location.
+	Column int `json:"column"`
+	// Message describes what happens to the value at the location.
+	Message string `json:"message"`
+}
+
+// newTaintFlowStep returns a step at pos in the file of the finding.
+func newTaintFlowStep(pos *ast.Position, format string, args ...interface{}) *TaintFlowStep {
+	s := &TaintFlowStep{Message: fmt.Sprintf(format, args...)}
+	if pos != nil {
+		s.Line, s.Column = pos.Line, pos.Col
+	}
+	return s
+}
+
+// extendTaintFlow returns a copy of flow followed by steps. Flows recorded for
+// step and job outputs are shared by every finding reaching them, so they are
+// never appended to in place.
+func extendTaintFlow(flow []*TaintFlowStep, steps ...*TaintFlowStep) []*TaintFlowStep {
+	out := make([]*TaintFlowStep, 0, len(flow)+len(steps))
+	out = append(out, flow...)
+	return append(out, steps...)
+}
+
+// taintFlowWithSink returns the path of a finding, which is the recorded path
+// to the value followed by the sink. It returns nil when nothing was recorded,
+// since a path made of the sink alone explains nothing the message does not.
+func taintFlowWithSink(flow []*TaintFlowStep, sink *TaintFlowStep) []*TaintFlowStep {
+	if len(flow) == 0 {
+		return nil
+	}
+	return extendTaintFlow(flow, sink)
+}
+
+// resolveTaintFlowPaths returns a copy of flow whose steps in the file of the
+// finding have filePath.
+func resolveTaintFlowPaths(flow []*TaintFlowStep, filePath string) []*TaintFlowStep {
+	if len(flow) == 0 {
+		return nil
+	}
+	out := make([]*TaintFlowStep, 0, len(flow))
+	for _, s := range flow {
+		c := *s
+		if c.FilePath == "" {
+			c.FilePath = filePath
+		}
+		out = append(out, &c)
+	}
+	return out
+}
+
+// expressionPosition returns the position of the first "${{ expr }}" in s
+// whose content is expr, or the position of s when it is not found.
+func expressionPosition(s *ast.String, expr string) *ast.Position {
+	if s == nil {
+		return nil
+	}
+	want := normalizeExpression(expr)
+	for _, m := range taintGhExprPattern.FindAllStringSubmatchIndex(s.Value, -1) {
+		if normalizeExpression(s.Value[m[2]:m[3]]) == want {
+			return stringOffsetPosition(s, m[0])
+		}
+	}
+	return s.Pos
+}
+
+// stringOffsetPosition converts a byte offset in the value of s into a
+// position in the file, the same way as the positions of expressions in run:
+// scripts reported by the injection rules.
+func stringOffsetPosition(s *ast.String, offset int) *ast.Position {
+	if s.Pos == nil {
+		return nil
+	}
+	prefix := s.Value[:offset]
+	line := strings.Count(prefix, "\n")
+	col := offset
+	if nl := strings.LastIndex(prefix, "\n"); nl >= 0 {
+		col = offset - nl - 1
+	}
+	pos := &ast.Position{Line: s.Pos.Line + line, Col: s.Pos.Col + col}
+	if s.Literal {
+		pos.Line++
+	}
+	if line > 0 || s.Literal {
+		// Lines after the first one of a block scalar are not shifted by the
+		// column of the value.
+		pos.Col = col + 1
+	}
+	return pos
+}
+
+func (t *TaintTracker) setOutputFlow(stepID, outputName string, flow []*TaintFlowStep) {
+	if t.outputFlows[stepID] == nil {
+		t.outputFlows[stepID] = make(map[string][]*TaintFlowStep)
+	}
+	t.outputFlows[stepID][outputName] = flow
+}
+
+// FlowOf returns the path of the tainted value of a steps.<id>.outputs.<name>
+// expression from its source to the write of the output. It returns nil when
+// the output is not tainted.
+func (t *TaintTracker) FlowOf(exprStr string) []*TaintFlowStep {
+	parts := strings.Split(exprStr, ".")
+	if len(parts) < 4 || parts[0] != "steps" || parts[2] != "outputs" {
+		return nil
+	}
+	return t.outputFlows[parts[1]][strings.Join(parts[3:], ".")]
+}
+
+// taintFlowCandidate is an expression which may have carried a tainted value
+// into a write to $GITHUB_OUTPUT.
+type taintFlowCandidate struct {
+	expr string
+	str  *ast.String // the string containing the expression
+	env  string      // the name of the env var when str is its value
+}
+
+// recordOutputFlow records how the tainted value of output name of step got
+// there. The write is at the 0-based line of the run: script. The value came
+// through the first expression, in the written value, the env vars of the
+// step or the script, that carries one of the taint sources of the output.
+func (t *TaintTracker) recordOutputFlow(step *ast.Step, run *ast.String, name string, line int, value string, exprMap map[string]string) {
+	stepID := step.ID.Value
+	sources := t.taintedOutputs[stepID][name]
+	if len(sources) == 0 {
+		return
+	}
+
+	var candidates []taintFlowCandidate
+	for _, ph := range taintPlaceholderPattern.FindAllString(value, -1) {
+		if expr, ok := exprMap[ph]; ok {
+			candidates = append(candidates, taintFlowCandidate{expr: expr, str: run})
+		}
+	}
+	if step.Env != nil {
+		for _, key := range slices.Sorted(maps.Keys(step.Env.Vars)) {
+			v := step.Env.Vars[key]
+			if v == nil || v.Name == nil || v.Value == nil {
+				continue
+			}
+			for _, expr := range extractExpressionsFromString(v.Value.Value) {
+				candidates = append(candidates, taintFlowCandidate{expr: expr, str: v.Value, env: v.Name.Value})
+			}
+		}
+	}
+	for _, expr := range extractExpressionsFromString(run.Value) {
+		candidates = append(candidates, taintFlowCandidate{expr: expr, str: run})
+	}
+
+	var flow []*TaintFlowStep
+	for _, c := range candidates {
+		if tainted, srcs := t.IsTaintedExpr(c.expr); tainted && containsAny(sources, srcs) {
+			if upstream := t.FlowOf(c.expr); len(upstream) > 0 {
+				flow = extendTaintFlow(upstream)
+				if c.env != "" {
+					flow = append(flow, newTaintFlowStep(expressionPosition(c.str, c.expr), "assigned to env %s", c.env))
+				}
+				break
+			}
+		}
+		if containsAny(sources, []string{c.expr}) && t.isUntrustedExpression(c.expr) {
+			flow = []*TaintFlowStep{newTaintFlowStep(expressionPosition(c.str, c.expr), "untrusted input %s", c.expr)}
+			if c.env != "" {
+				flow[0].Message += " assigned to env " + c.env
+			}
+			break
+		}
+	}
+
+	write := newTaintFlowStep(scriptLinePosition(run, line), "written to steps.%s.outputs.%s", stepID, name)
+	if flow == nil {
+		write.Message = fmt.Sprintf("untrusted input %s written to steps.%s.outputs.%s", sources[0], stepID, name)
+	}
+	t.setOutputFlow(stepID, name, append(flow, write))
+}
+
+// scriptLinePosition returns the position of the first non-blank character
+// of the 0-based line of a run: script.
+func scriptLinePosition(run *ast.String, line int) *ast.Position {
+	offset := 0
+	for i := 0; i < line; i++ {
+		nl := strings.IndexByte(run.Value[offset:], '\n')
+		if nl < 0 {
+			break
+		}
+		offset += nl + 1
+	}
+	for offset < len(run.Value) && (run.Value[offset] == ' ' || run.Value[offset] == '\t') {
+		offset++
+	}
+	return stringOffsetPosition(run, offset)
+}
+
+func containsAny(haystack, needles []string) bool {
+	for _, n := range needles {
+		if slices.Contains(haystack, n) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func lintForTaintFlow(t *testing.T, src string) (string, []*LintingError) {
+	t.Helper()
+	dir := makeTestProject(t)
+	path := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	writeTestFile(t, path, src)
+	l, err := NewLinter(&bytes.Buffer{}, &LinterOptions{CurrentWorkingDirectoryPath: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := l.LintFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, res.Errors
+}
+
+func findError(errs []*LintingError, ruleType, needle string) *LintingError {
+	for _, e := range errs {
+		if e.Type == ruleType && strings.Contains(e.Description, needle) {
+			return e
+		}
+	}
+	return nil
+}
+
+func taintFlowMessages(flow []*TaintFlowStep) []string {
+	msgs := make([]string, 0, len(flow))
+	for _, s := range flow {
+		msgs = append(msgs, s.Message)
+	}
+	return msgs
+}
+
+func TestTaintFlow_CodeInjectionThroughJobOutput(t *testing.T) {
+	t.Parallel()
+
+	src := `on: pull_request_target
+jobs:
+  extract:
+    runs-on: ubuntu-latest
+    outputs:
+      title: ${{ steps.meta.outputs.title }}
+    steps:
+      - id: meta
+        env:
+          TITLE: ${{ github.event.pull_request.title }}
+        run: |
+          echo "preparing"
+          echo "title=$TITLE" >> "$GITHUB_OUTPUT"
+  use:
+    needs: extract
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ needs.extract.outputs.title }}"
+`
+	path, errs := lintForTaintFlow(t, src)
+	e := findError(errs, "code-injection-critical", "needs.extract.outputs.title")
+	if e == nil {
+		t.Fatalf("code injection through the job output was not reported: %v", errs)
+	}
+	want := []string{
+		"untrusted input github.event.pull_request.title assigned to env TITLE",
+		"written to steps.meta.outputs.title",
+		"job output needs.extract.outputs.title",
+		"used in run: script",
+	}
+	if got := taintFlowMessages(e.TaintFlow); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected taint flow:\nwant %q\ngot  %q", want, got)
+	}
+	lines := []int{10, 13, 6, 18}
+	for i, s := range e.TaintFlow {
+		if s.Line != lines[i] {
+			t.Errorf("step %d (%s) is at line %d, want %d", i, s.Message, s.Line, lines[i])
+		}
+	}
+	if e.TaintFlow[0].Column != 18 || e.TaintFlow[1].Column != 1 {
+		t.Errorf("unexpected columns: %d, %d", e.TaintFlow[0].Column, e.TaintFlow[1].Column)
+	}
+
+	e.FilePath = path
+	var out bytes.Buffer
+	e.DisplayError(&out, []byte(src))
+	if !strings.Contains(out.String(), "taint flow:") || !strings.Contains(out.String(), "3. "+path+":6:") {
+		t.Errorf("taint flow is not printed:\n%s", out.String())
+	}
+
+	sarifText, err := toSARIF([]*TemplateFields{e.ExtractTemplateFields([]byte(src))}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				CodeFlows []struct {
+					ThreadFlows []struct {
+						Locations []struct {
+							Location struct {
+								Message struct {
+									Text string `json:"text"`
+								} `json:"message"`
+								PhysicalLocation struct {
+									ArtifactLocation struct {
+										URI string `json:"uri"`
+									} `json:"artifactLocation"`
+									Region struct {
+										StartLine int `json:"startLine"`
+									} `json:"region"`
+								} `json:"physicalLocation"`
+							} `json:"location"`
+						} `json:"locations"`
+					} `json:"threadFlows"`
+				} `json:"codeFlows"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(sarifText), &log); err != nil {
+		t.Fatal(err)
+	}
+	flows := log.Runs[0].Results[0].CodeFlows
+	if len(flows) != 1 || len(flows[0].ThreadFlows) != 1 || len(flows[0].ThreadFlows[0].Locations) != len(want) {
+		t.Fatalf("unexpected codeFlows: %s", sarifText)
+	}
+	last := flows[0].ThreadFlows[0].Locations[len(want)-1].Location
+	if last.Message.Text != "used in run: script" || last.PhysicalLocation.ArtifactLocation.URI != path || last.PhysicalLocation.Region.StartLine != 18 {
+		t.Errorf("unexpected sink location: %+v", last)
+	}
+}
+
+func TestTaintFlow_DirectUseHasNoFlow(t *testing.T) {
+	t.Parallel()
+
+	_, errs := lintForTaintFlow(t, `on: pull_request_target
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.pull_request.title }}"
+`)
+	e := findError(errs, "code-injection-critical", "github.event.pull_request.title")
+	if e == nil {
+		t.Fatalf("code injection was not reported: %v", errs)
+	}
+	if e.TaintFlow != nil {
+		t.Errorf("a finding at its source should have no flow, got %q", taintFlowMessages(e.TaintFlow))
+	}
+}
+
+func TestTaintFlow_SecretInLogThroughStepOutput(t *testing.T) {
+	t.Parallel()
+
+	_, errs := lintForTaintFlow(t, `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: token
+        env:
+          TOKEN: ${{ secrets.API_TOKEN }}
+        run: echo "value=$TOKEN" >> "$GITHUB_OUTPUT"
+      - run: echo "${{ steps.token.outputs.value }}"
+`)
+	e := findError(errs, "secret-in-log", "steps.token.outputs.value")
+	if e == nil {
+		t.Fatalf("secret in log was not reported: %v", errs)
+	}
+	want := []string{
+		"secrets.API_TOKEN assigned to env TOKEN",
+		"written to steps.token.outputs.value",
+		"printed via 'echo'",
+	}
+	if got := taintFlowMessages(e.TaintFlow); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected taint flow:\nwant %q\ngot  %q", want, got)
+	}
+}
+
+func TestTaintFlow_ReusableWorkflowChain(t *testing.T) {
+	t.Parallel()
+
+	results := runCrossFileLinter(t,
+		"cross-file-taint-caller-critical.yaml",
+		"cross-file-taint-callee-run.yaml",
+	)
+	chains := collectChainErrors(results, "")
+	if len(chains) == 0 {
+		t.Fatal("no chain finding")
+	}
+	flow := chains[0].TaintFlow
+	if len(flow) != 2 {
+		t.Fatalf("want a caller and a callee step, got %q", taintFlowMessages(flow))
+	}
+	if !strings.Contains(flow[0].Message, "passed to reusable workflow input") {
+		t.Errorf("unexpected caller step: %+v", flow[0])
+	}
+	if !strings.HasSuffix(flow[1].FilePath, "cross-file-taint-callee-run.yaml") || !strings.Contains(flow[1].Message, "run sink") {
+		t.Errorf("unexpected callee step: %+v", flow[1])
+	}
+}
@@ -1,45 +1,339 @@
 package core
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
 	"gopkg.in/yaml.v3"
 )
 
+// defaultTimeoutMinutes is the value the auto-fixer inserts when no default is
+// configured.
+const defaultTimeoutMinutes = 5
+
+// TimeoutMinutesConfig holds the configuration of the missing-timeout-minutes
+// rule.
+type TimeoutMinutesConfig struct {
+	// Job is the policy of timeout-minutes of jobs.
+	Job TimeoutMinutesPolicy `yaml:"job"`
+	// Step is the policy of timeout-minutes of steps.
+	Step TimeoutMinutesPolicy `yaml:"step"`
+	// Runners maps runner labels to the default timeout-minutes of jobs
+	// running on them. When several labels of a job match, the largest
+	// default is used.
+	Runners map[string]float64 `yaml:"runners"`
+	// JobOnly disables the check of steps without timeout-minutes.
+	JobOnly bool `yaml:"job-only"`
+}
+
+// TimeoutMinutesPolicy is the default and the maximum timeout-minutes of jobs
+// or steps. Zero means not configured.
+type TimeoutMinutesPolicy struct {
+	// Default is the value the auto-fixer inserts.
+	Default float64 `yaml:"default"`
+	// Max is the largest value allowed.
+	Max float64 `yaml:"max"`
+}
+
+// isSet reports whether any option is configured. A configured
+// timeout-minutes section enables the opt-in rule without -enable-rule.
+func (c *TimeoutMinutesConfig) isSet() bool {
+	return c.Job != (TimeoutMinutesPolicy{}) || c.Step != (TimeoutMinutesPolicy{}) || len(c.Runners) > 0 || c.JobOnly
+}
+
+// validate checks that the values are positive and the defaults are not larger
+// than the maximums.
+func (c *TimeoutMinutesConfig) validate() error {
+	for _, name := range []string{"job", "step"} {
+		p := c.Job
+		if name == "step" {
+			p = c.Step
+		}
+		if p.Default < 0 || p.Max < 0 {
+			return fmt.Errorf("timeout-minutes.%s.default and timeout-minutes.%s.max must be positive numbers", name, name)
+		}
+		if p.Max > 0 && p.Default > p.Max {
+			return fmt.Errorf("timeout-minutes.%s.default %g is larger than timeout-minutes.%s.max %g", name, p.Default, name, p.Max)
+		}
+	}
+	for _, label := range slices.Sorted(maps.Keys(c.Runners)) {
+		d := c.Runners[label]
+		if d <= 0 {
+			return fmt.Errorf("timeout-minutes.runners.%s must be a positive number but it is %g", label, d)
+		}
+		if c.Job.Max > 0 && d > c.Job.Max {
+			return fmt.Errorf("timeout-minutes.runners.%s %g is larger than timeout-minutes.job.max %g", label, d, c.Job.Max)
+		}
+	}
+	return nil
+}
+
 type TimeoutMinutesRule struct {
 	BaseRule
+	// job is the job being visited. Matrix values of the job are used to
+	// evaluate timeout-minutes written as expressions.
+	job *ast.Job
 }
 
 func TimeoutMinuteRule() *TimeoutMinutesRule {
 	return &TimeoutMinutesRule{
 		BaseRule: BaseRule{
 			RuleName: "missing-timeout-minutes",
-			RuleDesc: "This rule checks missing timeout-minutes in job level.",
+			RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 			optIn:    true,
+			severity: SeverityLow,
 		},
 	}
 }
 
+// config returns the configuration of the rule, or the zero value when no
+// config file is used.
+func (rule *TimeoutMinutesRule) config() *TimeoutMinutesConfig {
+	if rule.userConfig == nil {
+		return &TimeoutMinutesConfig{}
+	}
+	return &rule.userConfig.TimeoutMinutes
+}
+
 func (rule *TimeoutMinutesRule) VisitJobPre(node *ast.Job) error {
+	rule.job = node
 	if node.TimeoutMinutes == nil {
 		rule.Errorf(node.Pos,
 			"timeout-minutes is not set for job %s; see https://sisaku-security.github.io/lint/docs/rules/timeoutminutesrule/ for more details.",
 			node.ID.Value)
 		rule.AddAutoFixer(NewJobFixer(node, rule))
+		return nil
 	}
+	rule.checkMax(node.TimeoutMinutes, "job "+node.ID.Value, "job", rule.config().Job.Max)
+	return nil
+}
+
+func (rule *TimeoutMinutesRule) VisitJobPost(node *ast.Job) error {
+	rule.job = nil
 	return nil
 }
 
 func (rule *TimeoutMinutesRule) VisitStep(node *ast.Step) error {
+	cfg := rule.config()
 	if node.TimeoutMinutes == nil {
+		if cfg.JobOnly {
+			return nil
+		}
 		rule.Errorf(node.Pos,
 			"timeout-minutes is not set for step %s; see https://sisaku-security.github.io/lint/docs/rules/timeoutminutesrule/ for more details.",
 			node.String())
 		rule.AddAutoFixer(NewStepFixer(node, rule))
+		return nil
 	}
+	rule.checkMax(node.TimeoutMinutes, "step "+node.String(), "step", cfg.Step.Max)
 	return nil
 }
 
-func addTimeoutMinutes(node *yaml.Node, candidate1, candidate2 string) {
+// checkMax reports timeout-minutes larger than max. An expression is
+// evaluated statically when it only uses literals and matrix values, and is
+// reported when it cannot be evaluated since the maximum cannot be enforced.
+func (rule *TimeoutMinutesRule) checkMax(f *ast.Float, what, section string, limit float64) {
+	if limit <= 0 {
+		return
+	}
+	if f.Expression == nil {
+		if f.Value > limit {
+			rule.Errorf(f.Pos, "timeout-minutes of %s is %g, which is larger than the maximum of %g minutes set in \"timeout-minutes.%s.max\" of the config file", what, f.Value, limit, section)
+		}
+		return
+	}
+	v, err := evalTimeoutMinutes(f.Expression.Value, rule.job)
+	if errors.Is(err, errTimeoutMatrixTooLarge) {
+		rule.Errorf(f.Pos, "timeout-minutes of %s is %q, whose matrix values have more than %d combinations to check against the maximum of %g minutes set in \"timeout-minutes.%s.max\" of the config file. Use a number or a matrix row of timeouts", what, f.Expression.Value, matrixEnumerationLimit, limit, section)
+		return
+	}
+	if err != nil {
+		rule.Errorf(f.Pos, "timeout-minutes of %s is %q, which cannot be evaluated statically to check the maximum of %g minutes set in \"timeout-minutes.%s.max\" of the config file. Use a number or matrix values", what, f.Expression.Value, limit, section)
+		return
+	}
+	if v > limit {
+		rule.Errorf(f.Pos, "timeout-minutes of %s can be %g with %q, which is larger than the maximum of %g minutes set in \"timeout-minutes.%s.max\" of the config file", what, v, f.Expression.Value, limit, section)
+	}
+}
+
+var (
+	// errTimeoutNotStatic is returned by evalTimeoutMinutes for expressions
+	// using other values than literals and matrix values.
+	errTimeoutNotStatic = errors.New("timeout-minutes cannot be evaluated statically")
+	// errTimeoutMatrixTooLarge is returned by evalTimeoutMinutes when the
+	// matrix rows used by the expression have more than
+	// matrixEnumerationLimit combinations.
+	errTimeoutMatrixTooLarge = errors.New("matrix has too many combinations")
+)
+
+// evalTimeoutMinutes evaluates an expression of timeout-minutes and returns
+// the largest value it can take. Only literals, functions and the matrix
+// context of job, whose values are enumerated, are supported.
+func evalTimeoutMinutes(src string, job *ast.Job) (float64, error) {
+	s := strings.TrimSpace(src)
+	if !strings.HasPrefix(s, "${{") || !strings.HasSuffix(s, "}}") || strings.Count(s, "${{") != 1 {
+		return 0, errTimeoutNotStatic
+	}
+	node, err := expressions.NewMiniParser().Parse(expressions.NewTokenizer(strings.TrimSpace(s[3:len(s)-2]) + "}}"))
+	if err != nil {
+		return 0, errTimeoutNotStatic
+	}
+
+	// keys are the matrix rows the expression refers to. Only they are
+	// enumerated, unless the matrix is used as a whole (e.g. matrix[name]).
+	usesMatrix := false
+	keys := map[string]struct{}{}
+	supported := true
+	expressions.VisitExprNode(node, func(n, parent expressions.ExprNode, entering bool) {
+		if v, ok := n.(*expressions.VariableNode); ok && entering {
+			if !strings.EqualFold(v.Name, "matrix") {
+				supported = false
+				return
+			}
+			usesMatrix = true
+			if d, ok := parent.(*expressions.ObjectDerefNode); ok && d.Receiver == n && keys != nil {
+				keys[strings.ToLower(d.Property)] = struct{}{}
+			} else {
+				keys = nil
+			}
+		}
+	})
+	if !supported {
+		return 0, errTimeoutNotStatic
+	}
+
+	combis := []map[string]interface{}{nil}
+	if usesMatrix {
+		var err error
+		if combis, err = timeoutMatrixCombinations(job, keys); err != nil {
+			return 0, err
+		}
+	}
+	largest := 0.0
+	for _, c := range combis {
+		f, ok := timeoutMinutesNumber(evalPolicyNode(node, map[string]interface{}{"matrix": c}))
+		if !ok {
+			return 0, errTimeoutNotStatic
+		}
+		largest = math.Max(largest, f)
+	}
+	return largest, nil
+}
+
+// timeoutMatrixCombinations returns the combinations of the matrix of job
+// including the entries of "include". Only the rows in keys (lower case) are
+// combined, or every row when keys is nil. Combinations removed by "exclude"
+// are kept since the result is only used to find the largest value.
+func timeoutMatrixCombinations(job *ast.Job, keys map[string]struct{}) ([]map[string]interface{}, error) {
+	if job == nil || job.Strategy == nil || job.Strategy.Matrix == nil || job.Strategy.Matrix.Expression != nil {
+		return nil, errTimeoutNotStatic
+	}
+	m := job.Strategy.Matrix
+	names := matrixRowNames(m)
+	if keys != nil {
+		names = slices.DeleteFunc(names, func(name string) bool {
+			_, ok := keys[strings.ToLower(name)]
+			return !ok
+		})
+	}
+	rows, ok := matrixRowCombinations(m, names)
+	if !ok {
+		return nil, errTimeoutNotStatic
+	}
+	if rows == nil {
+		return nil, errTimeoutMatrixTooLarge
+	}
+	var out []map[string]interface{}
+	for _, combi := range rows {
+		if len(combi) == 0 {
+			continue
+		}
+		c := make(map[string]interface{}, len(combi))
+		for k, v := range combi {
+			c[k] = rawYAMLPolicyValue(v)
+		}
+		out = append(out, c)
+	}
+	if m.Include != nil {
+		if m.Include.Expression != nil {
+			return nil, errTimeoutNotStatic
+		}
+		for _, inc := range m.Include.Combinations {
+			if inc.Expression != nil {
+				return nil, errTimeoutNotStatic
+			}
+			c := make(map[string]interface{}, len(inc.Assigns))
+			for k, a := range inc.Assigns {
+				c[k] = rawYAMLPolicyValue(a.Value)
+			}
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errTimeoutNotStatic
+	}
+	return out, nil
+}
+
+// rawYAMLPolicyValue converts a scalar matrix value to a value of the
+// expression evaluator. Mappings and sequences are converted to nil.
+func rawYAMLPolicyValue(v ast.RawYAMLValue) interface{} {
+	if s, ok := v.(*ast.RawYAMLString); ok {
+		return s.Value
+	}
+	return nil
+}
+
+// timeoutMinutesNumber converts the result of an expression to a number of
+// minutes. Strings are converted when they are numbers, like GitHub does.
+func timeoutMinutesNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// jobDefaultTimeoutMinutes returns the timeout-minutes the auto-fixer inserts
+// into job: the largest default of its runner labels, the default of jobs, or
+// defaultTimeoutMinutes.
+func (rule *TimeoutMinutesRule) jobDefaultTimeoutMinutes(job *ast.Job) float64 {
+	cfg := rule.config()
+	d := 0.0
+	if job.RunsOn != nil && len(cfg.Runners) > 0 {
+		labels := slices.Clone(job.RunsOn.Labels)
+		if job.RunsOn.LabelsExpr != nil && !job.RunsOn.LabelsExpr.ContainsExpression() {
+			labels = append(labels, job.RunsOn.LabelsExpr)
+		}
+		for _, l := range labels {
+			for label, v := range cfg.Runners {
+				if strings.EqualFold(l.Value, label) && v > d {
+					d = v
+				}
+			}
+		}
+	}
+	if d == 0 {
+		d = cfg.Job.Default
+	}
+	if d == 0 {
+		d = defaultTimeoutMinutes
+	}
+	return d
+}
+
+func addTimeoutMinutes(node *yaml.Node, minutes float64, candidate1, candidate2 string) {
 	// best effort to add timeout-minutes before run or uses
 	appendKey := func(i int) {
 		node.Content = append(node.Content[:i], append([]*yaml.Node{
@@ -49,7 +343,7 @@ func addTimeoutMinutes(node *yaml.Node, candidate1, candidate2 string) {
 			},
 			{
 				Kind:  yaml.ScalarNode,
-				Value: "5",
+				Value: strconv.FormatFloat(minutes, 'f', -1, 64),
 			},
 		}, node.Content[i:]...)...)
 	}
@@ -64,11 +358,15 @@ func addTimeoutMinutes(node *yaml.Node, candidate1, candidate2 string) {
 }
 
 func (rule *TimeoutMinutesRule) FixStep(node *ast.Step) error {
-	addTimeoutMinutes(node.BaseNode, "run", "with")
+	minutes := rule.config().Step.Default
+	if minutes == 0 {
+		minutes = defaultTimeoutMinutes
+	}
+	addTimeoutMinutes(node.BaseNode, minutes, "run", "with")
 	return nil
 }
 
 func (rule *TimeoutMinutesRule) FixJob(node *ast.Job) error {
-	addTimeoutMinutes(node.BaseNode, "steps", "runs-on")
+	addTimeoutMinutes(node.BaseNode, rule.jobDefaultTimeoutMinutes(node), "steps", "runs-on")
 	return nil
 }
@@ -1,6 +1,10 @@
 package core
 
 import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/sisaku-security/sisakulint/pkg/ast"
@@ -17,7 +21,7 @@ func TestTimeoutMinuteRule(t *testing.T) {
 			want: &TimeoutMinutesRule{
 				BaseRule: BaseRule{
 					RuleName: "missing-timeout-minutes",
-					RuleDesc: "This rule checks missing timeout-minutes in job level.",
+					RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 				},
 			},
 		},
@@ -54,7 +58,7 @@ func TestTimeoutMinutesRule_VisitJobPre(t *testing.T) {
 			fields: fields{
 				BaseRule: BaseRule{
 					RuleName: "missing-timeout-minutes",
-					RuleDesc: "This rule checks missing timeout-minutes in job level.",
+					RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 				},
 			},
 			args: args{
@@ -79,7 +83,7 @@ func TestTimeoutMinutesRule_VisitJobPre(t *testing.T) {
 			fields: fields{
 				BaseRule: BaseRule{
 					RuleName: "missing-timeout-minutes",
-					RuleDesc: "This rule checks missing timeout-minutes in job level.",
+					RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 				},
 			},
 			args: args{
@@ -102,7 +106,7 @@ func TestTimeoutMinutesRule_VisitJobPre(t *testing.T) {
 			fields: fields{
 				BaseRule: BaseRule{
 					RuleName: "missing-timeout-minutes",
-					RuleDesc: "This rule checks missing timeout-minutes in job level.",
+					RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 				},
 			},
 			args: args{
@@ -127,7 +131,7 @@ func TestTimeoutMinutesRule_VisitJobPre(t *testing.T) {
 			fields: fields{
 				BaseRule: BaseRule{
 					RuleName: "missing-timeout-minutes",
-					RuleDesc: "This rule checks missing timeout-minutes in job level.",
+					RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 				},
 			},
 			args: args{
@@ -184,7 +188,7 @@ func TestTimeoutMinutesRule_VisitStep(t *testing.T) {
 			fields: fields{
 				BaseRule: BaseRule{
 					RuleName: "missing-timeout-minutes",
-					RuleDesc: "This rule checks missing timeout-minutes in job level.",
+					RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 				},
 			},
 			args: args{
@@ -209,7 +213,7 @@ func TestTimeoutMinutesRule_VisitStep(t *testing.T) {
 			fields: fields{
 				BaseRule: BaseRule{
 					RuleName: "missing-timeout-minutes",
-					RuleDesc: "This rule checks missing timeout-minutes in job level.",
+					RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 				},
 			},
 			args: args{
@@ -232,7 +236,7 @@ func TestTimeoutMinutesRule_VisitStep(t *testing.T) {
 			fields: fields{
 				BaseRule: BaseRule{
 					RuleName: "missing-timeout-minutes",
-					RuleDesc: "This rule checks missing timeout-minutes in job level.",
+					RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 				},
 			},
 			args: args{
@@ -257,7 +261,7 @@ func TestTimeoutMinutesRule_VisitStep(t *testing.T) {
 			fields: fields{
 				BaseRule: BaseRule{
 					RuleName: "missing-timeout-minutes",
-					RuleDesc: "This rule checks missing timeout-minutes in job level.",
+					RuleDesc: "This rule checks that jobs and steps set timeout-minutes, and that their values do not exceed the maximums configured in the timeout-minutes section.",
 				},
 			},
 			args: args{
@@ -497,7 +501,7 @@ name: Test
 			}
 			mappingNode := node.Content[0]
 
-			addTimeoutMinutes(mappingNode, tt.candidate1, tt.candidate2)
+			addTimeoutMinutes(mappingNode, defaultTimeoutMinutes, tt.candidate1, tt.candidate2)
 
 			// Check if timeout-minutes was added
 			found := false
@@ -548,3 +552,279 @@ name: Test
 		})
 	}
 }
+
+func runTimeoutMinutesRule(t *testing.T, cfg *Config, src string) (*TimeoutMinutesRule, *ast.Workflow) {
+	t.Helper()
+
+	rule := TimeoutMinuteRule()
+	rule.UpdateConfig(cfg)
+	return rule, visitWorkflowSource(t, rule, src)
+}
+
+func TestTimeoutMinutesRule_Max(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{TimeoutMinutes: TimeoutMinutesConfig{
+		Job:  TimeoutMinutesPolicy{Max: 60},
+		Step: TimeoutMinutesPolicy{Max: 10},
+	}}
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "within maximum",
+			src: `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    timeout-minutes: 60
+    steps:
+      - run: echo
+        timeout-minutes: 10
+`,
+		},
+		{
+			name: "literal larger than maximum",
+			src: `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    timeout-minutes: 90
+    steps:
+      - run: echo
+        timeout-minutes: 15
+`,
+			want: []string{
+				`5:22: timeout-minutes of job test is 90, which is larger than the maximum of 60 minutes set in "timeout-minutes.job.max"`,
+				`8:26: timeout-minutes of step`,
+			},
+		},
+		{
+			name: "matrix expression",
+			src: `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        timeout: [30, 45]
+        include:
+          - timeout: 120
+    timeout-minutes: ${{ matrix.timeout }}
+    steps:
+      - run: echo
+        timeout-minutes: ${{ matrix.timeout == 30 && 5 || 10 }}
+`,
+			want: []string{`10:22: timeout-minutes of job test can be 120 with "${{ matrix.timeout }}", which is larger than the maximum of 60 minutes`},
+		},
+		{
+			name: "expression which cannot be evaluated",
+			src: `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    timeout-minutes: ${{ vars.TIMEOUT }}
+    steps:
+      - run: echo
+        timeout-minutes: 5
+`,
+			want: []string{`5:22: timeout-minutes of job test is "${{ vars.TIMEOUT }}", which cannot be evaluated statically`},
+		},
+		{
+			name: "only the rows used by the expression are enumerated",
+			src:  largeTimeoutMatrixWorkflow("${{ matrix.timeout }}"),
+			want: []string{`13:22: timeout-minutes of job test can be 90 with "${{ matrix.timeout }}", which is larger than the maximum of 60 minutes`},
+		},
+		{
+			name: "matrix rows too large to enumerate",
+			src:  largeTimeoutMatrixWorkflow("${{ matrix.a == matrix.b && matrix.c == matrix.a && 90 || 30 }}"),
+			want: []string{`13:22: timeout-minutes of job test is "${{ matrix.a == matrix.b && matrix.c == matrix.a && 90 || 30 }}", whose matrix values have more than 65536 combinations`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, _ := runTimeoutMinutesRule(t, cfg, tc.src)
+			errs := rule.Errors()
+			if len(errs) != len(tc.want) {
+				t.Fatalf("want %d errors, got %v", len(tc.want), errs)
+			}
+			for i, want := range tc.want {
+				if got := errs[i].Error(); !strings.Contains(got, want) {
+					t.Errorf("error %d %q does not contain %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// largeTimeoutMatrixWorkflow returns a workflow whose matrix has more rows
+// a, b and c than can be enumerated, and timeout-minutes set to expr.
+func largeTimeoutMatrixWorkflow(expr string) string {
+	values := make([]string, 41)
+	for i := range values {
+		values[i] = fmt.Sprint(i)
+	}
+	row := "[" + strings.Join(values, ", ") + "]"
+	return `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        a: ` + row + `
+        b: ` + row + `
+        c: ` + row + `
+        timeout: [30, 90]
+        include:
+          - timeout: 45
+    timeout-minutes: ` + expr + `
+    steps:
+      - run: echo
+        timeout-minutes: 5
+`
+}
+
+func TestLinter_TimeoutMinutesConfigEnablesRule(t *testing.T) {
+	t.Parallel()
+
+	src := "on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo\n"
+	for cfg, want := range map[string]bool{
+		"timeout-minutes:\n  job:\n    max: 60\n": true,
+		"timeout-minutes:\n  job-only: false\n":   false,
+	} {
+		configPath := filepath.Join(t.TempDir(), "sisakulint.yaml")
+		writeTestFile(t, configPath, cfg)
+		linter, err := NewLinter(io.Discard, &LinterOptions{LogOutputDestination: io.Discard, ConfigurationFilePath: configPath})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := linter.Lint("test.yaml", []byte(src), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := false
+		for _, e := range result.Errors {
+			if e.Type == "missing-timeout-minutes" {
+				got = true
+			}
+		}
+		if got != want {
+			t.Errorf("config %q: missing-timeout-minutes reported = %v, want %v", cfg, got, want)
+		}
+	}
+}
+
+func TestTimeoutMinutesRule_ExpressionWithoutMax(t *testing.T) {
+	t.Parallel()
+
+	rule, _ := runTimeoutMinutesRule(t, &Config{}, `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    timeout-minutes: ${{ vars.TIMEOUT }}
+    steps:
+      - run: echo
+        timeout-minutes: ${{ vars.STEP_TIMEOUT }}
+`)
+	if errs := rule.Errors(); len(errs) != 0 {
+		t.Fatalf("expressions should not be evaluated without a maximum, got %v", errs)
+	}
+}
+
+func TestTimeoutMinutesRule_JobOnly(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{TimeoutMinutes: TimeoutMinutesConfig{JobOnly: true}}
+	rule, _ := runTimeoutMinutesRule(t, cfg, `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+	errs := rule.Errors()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "timeout-minutes is not set for job test") {
+		t.Fatalf("want only the error of the job, got %v", errs)
+	}
+}
+
+func TestTimeoutMinutesRule_ConfiguredDefaults(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{TimeoutMinutes: TimeoutMinutesConfig{
+		Job:     TimeoutMinutesPolicy{Default: 30},
+		Step:    TimeoutMinutesPolicy{Default: 15},
+		Runners: map[string]float64{"linux-large": 90, "gpu": 120},
+	}}
+	rule, workflow := runTimeoutMinutesRule(t, cfg, `on: push
+jobs:
+  integration:
+    runs-on: [self-hosted, Linux-Large]
+    steps:
+      - run: make integration
+  gpu:
+    runs-on: gpu
+    steps:
+      - run: make gpu
+        timeout-minutes: 60
+  unit:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make unit
+        timeout-minutes: 10
+`)
+	for _, f := range rule.AutoFixers() {
+		if err := f.Fix(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{"integration": "90", "gpu": "120", "unit": "30"}
+	for id, job := range workflow.Jobs {
+		if got := mappingValue(job.BaseNode, "timeout-minutes"); got != want[id] {
+			t.Errorf("timeout-minutes of job %s = %q, want %q", id, got, want[id])
+		}
+	}
+	if got := mappingValue(workflow.Jobs["integration"].Steps[0].BaseNode, "timeout-minutes"); got != "15" {
+		t.Errorf("timeout-minutes of step = %q, want \"15\"", got)
+	}
+}
+
+func mappingValue(node *yaml.Node, key string) string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+func TestTimeoutMinutesConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"negative max", "timeout-minutes:\n  step:\n    max: -1\n", "timeout-minutes.step.default and timeout-minutes.step.max must be positive numbers"},
+		{"default above max", "timeout-minutes:\n  job:\n    default: 90\n    max: 60\n", "timeout-minutes.job.default 90 is larger than timeout-minutes.job.max 60"},
+		{"runner default above max", "timeout-minutes:\n  job:\n    max: 60\n  runners:\n    gpu: 120\n", "timeout-minutes.runners.gpu 120 is larger than timeout-minutes.job.max 60"},
+		{"zero runner default", "timeout-minutes:\n  runners:\n    gpu: 0\n", "timeout-minutes.runners.gpu must be a positive number"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseConfig([]byte(tc.src), "test.yaml")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("want error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ func NewUnmaskedSecretExposureRule() *UnmaskedSecretExposureRule {
 			RuleDesc: "Detects unmasked secret exposure when secrets are derived using fromJson(). " +
 				"Derived secret values are not automatically masked and may be exposed in logs. " +
 				"See https://sisaku-security.github.io/lint/docs/rules/unmaskedsecretexposure/",
+			severity: SeverityHigh,
 		},
 	}
 }
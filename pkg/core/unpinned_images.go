@@ -135,6 +135,7 @@ func NewUnpinnedImagesRule() *UnpinnedImagesRule {
 		BaseRule: BaseRule{
 			RuleName: "unpinned-images",
 			RuleDesc: "Warn if container images are not pinned by SHA256 digest.",
+			severity: SeverityMedium,
 		},
 	}
 }
@@ -33,6 +33,7 @@ func NewUnsoundContainsRule() *UnsoundContainsRule {
 		BaseRule: BaseRule{
 			RuleName: "unsound-contains",
 			RuleDesc: "Detects bypassable contains() function usage in conditions",
+			severity: SeverityMedium,
 		},
 	}
 }
@@ -139,14 +140,17 @@ func (rule *UnsoundContainsRule) visitExprNode(node expressions.ExprNode, pos *a
 		isHighSeverity := rule.isUserControllableContext(userControlledContext)
 
 		var severity string
+		findingSeverity := Severity(SeverityInfo)
 		if isHighSeverity {
 			severity = "HIGH"
+			findingSeverity = SeverityHigh
 		} else {
 			severity = "INFORMATIONAL"
 		}
 
-		rule.Errorf(
+		rule.ErrorfWithSeverity(
 			pos,
+			findingSeverity,
 			"[%s] Unsound use of contains() in %s condition. The first argument '%s' is a string literal and the second argument '%s' is user-controllable. An attacker could create a branch named '%s' to bypass this condition. Use fromJSON() with an array instead: contains(fromJSON('%s'), %s)",
 			severity,
 			context,
@@ -55,6 +55,7 @@ func NewUntrustedCheckoutRule() *UntrustedCheckoutRule {
 		BaseRule: BaseRule{
 			RuleName: "untrusted-checkout",
 			RuleDesc: "Detects checkout of untrusted code in workflows with privileged triggers that have access to secrets",
+			severity: SeverityCritical,
 		},
 	}
 }
@@ -69,6 +69,7 @@ func NewUntrustedCheckoutTOCTOUCriticalRule() *UntrustedCheckoutTOCTOUCriticalRu
 		BaseRule: BaseRule{
 			RuleName: "untrusted-checkout-toctou/critical",
 			RuleDesc: "Detects TOCTOU vulnerabilities with label-based approval and mutable checkout references",
+			severity: SeverityCritical,
 		},
 	}
 }
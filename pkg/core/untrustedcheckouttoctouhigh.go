@@ -66,6 +66,7 @@ func NewUntrustedCheckoutTOCTOUHighRule() *UntrustedCheckoutTOCTOUHighRule {
 		BaseRule: BaseRule{
 			RuleName: "untrusted-checkout-toctou/high",
 			RuleDesc: "Detects TOCTOU vulnerabilities with deployment environment approval and mutable checkout references",
+			severity: SeverityHigh,
 		},
 	}
 }
@@ -0,0 +1,24 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// visitWorkflowSource parses src and visits its syntax tree with rule, failing
+// the test on syntax errors. The findings are left in rule.Errors().
+func visitWorkflowSource(t *testing.T, rule Rule, src string) *ast.Workflow {
+	t.Helper()
+
+	workflow, parseErrs := Parse([]byte(src))
+	if len(parseErrs) > 0 {
+		t.Fatalf("Parse() errors = %v", parseErrs)
+	}
+	visitor := NewSyntaxTreeVisitor()
+	visitor.AddVisitor(rule)
+	if err := visitor.VisitTree(workflow); err != nil {
+		t.Fatalf("VisitTree() error = %v", err)
+	}
+	return workflow
+}
@@ -13,6 +13,11 @@ type WorkflowSecretTaintMap struct {
 	// A registered job with no secret-derived outputs is represented by an empty inner map.
 	jobOutputSecrets map[string]map[string]string
 	pendingOutputs   []pendingSecretJobOutput
+	// outputValues: jobID (lowercase) -> outputName (lowercase) -> value, and
+	// stepOutputFlows: jobID (lowercase) -> stepID -> outputName -> path of the
+	// secret. Used to build the paths of secret-derived job outputs on demand.
+	outputValues    map[string]map[string]*ast.String
+	stepOutputFlows map[string]map[string]map[string][]*TaintFlowStep
 }
 
 type pendingSecretJobOutput struct {
@@ -24,12 +29,74 @@ type pendingSecretJobOutput struct {
 func NewWorkflowSecretTaintMap() *WorkflowSecretTaintMap {
 	return &WorkflowSecretTaintMap{
 		jobOutputSecrets: make(map[string]map[string]string),
+		outputValues:     make(map[string]map[string]*ast.String),
+		stepOutputFlows:  make(map[string]map[string]map[string][]*TaintFlowStep),
 	}
 }
 
 func (m *WorkflowSecretTaintMap) Reset() {
 	m.jobOutputSecrets = make(map[string]map[string]string)
 	m.pendingOutputs = nil
+	m.outputValues = make(map[string]map[string]*ast.String)
+	m.stepOutputFlows = make(map[string]map[string]map[string][]*TaintFlowStep)
+}
+
+// RecordJobOutputFlows records the outputs of a job and the paths of the
+// secret-derived outputs of its steps, from which FlowOf builds the paths of
+// its job outputs.
+func (m *WorkflowSecretTaintMap) RecordJobOutputFlows(jobID string, stepOutputFlows map[string]map[string][]*TaintFlowStep, outputs map[string]*ast.Output) {
+	jobID = strings.ToLower(jobID)
+	values := make(map[string]*ast.String, len(outputs))
+	for name, output := range outputs {
+		if output != nil && output.Value != nil {
+			values[strings.ToLower(name)] = output.Value
+		}
+	}
+	m.outputValues[jobID] = values
+	m.stepOutputFlows[jobID] = stepOutputFlows
+}
+
+// FlowOf returns the path of the secret in a secret-derived
+// needs.X.outputs.Y, or nil.
+func (m *WorkflowSecretTaintMap) FlowOf(path string) []*TaintFlowStep {
+	return m.flowOf(path, 0)
+}
+
+func (m *WorkflowSecretTaintMap) flowOf(path string, depth int) []*TaintFlowStep {
+	parts := strings.Split(path, ".")
+	if depth > 16 || len(parts) < 4 || parts[0] != "needs" || parts[2] != "outputs" {
+		return nil
+	}
+	jobID, outputName := strings.ToLower(parts[1]), strings.ToLower(strings.Join(parts[3:], "."))
+	if origin, _ := m.IsSecretNeedsOutput(jobID, outputName); origin == "" {
+		return nil
+	}
+	value := m.outputValues[jobID][outputName]
+	if value == nil {
+		return nil
+	}
+	var flow []*TaintFlowStep
+	pos := value.Pos
+	for _, expr := range extractExpressionsFromString(value.Value) {
+		p := normalizeStepOutputExprPath(expr)
+		ps := strings.Split(p, ".")
+		if len(ps) < 4 || ps[2] != "outputs" {
+			continue
+		}
+		var upstream []*TaintFlowStep
+		switch ps[0] {
+		case "steps":
+			upstream = m.stepOutputFlows[jobID][ps[1]][strings.Join(ps[3:], ".")]
+		case "needs":
+			upstream = m.flowOf(p, depth+1)
+		}
+		if len(upstream) > 0 {
+			flow = upstream
+			pos = expressionPosition(value, expr)
+			break
+		}
+	}
+	return extendTaintFlow(flow, newTaintFlowStep(pos, "job output needs.%s.outputs.%s", jobID, outputName))
 }
 
 func (m *WorkflowSecretTaintMap) markJobAsRegistered(jobID string) {
@@ -29,18 +29,23 @@ type WorkflowTaintMap struct {
 	// jobOutputTaints: jobID (lowercase) -> outputName (lowercase) -> []taintSource
 	// A job that is registered but has no tainted outputs is represented by an empty inner map.
 	jobOutputTaints map[string]map[string][]string
+	// jobOutputFlows: jobID (lowercase) -> outputName (lowercase) -> path of the
+	// tainted value from its source to the job output.
+	jobOutputFlows map[string]map[string][]*TaintFlowStep
 }
 
 // NewWorkflowTaintMap creates a new WorkflowTaintMap instance.
 func NewWorkflowTaintMap() *WorkflowTaintMap {
 	return &WorkflowTaintMap{
 		jobOutputTaints: make(map[string]map[string][]string),
+		jobOutputFlows:  make(map[string]map[string][]*TaintFlowStep),
 	}
 }
 
 // Reset clears all registered job outputs. Called in VisitWorkflowPre to reset per workflow.
 func (m *WorkflowTaintMap) Reset() {
 	m.jobOutputTaints = make(map[string]map[string][]string)
+	m.jobOutputFlows = make(map[string]map[string][]*TaintFlowStep)
 }
 
 // markJobAsRegistered marks a job as processed even if it has no tainted outputs.
@@ -101,10 +106,54 @@ func (m *WorkflowTaintMap) RegisterJobOutputs(jobID string, tracker *TaintTracke
 
 		if len(sources) > 0 {
 			m.setJobOutputTaint(jobID, outputName, sources)
+			m.recordJobOutputFlow(jobID, outputName, output.Value, tracker)
 		}
 	}
 }
 
+// recordJobOutputFlow records the path of the tainted value of a job output:
+// the path to the first tainted steps.X.outputs.Y or needs.X.outputs.Y in its
+// value, followed by the output itself.
+func (m *WorkflowTaintMap) recordJobOutputFlow(jobID, outputName string, value *ast.String, tracker *TaintTracker) {
+	var flow []*TaintFlowStep
+	pos := value.Pos
+	for _, expr := range extractExpressionsFromString(value.Value) {
+		lower := strings.ToLower(expr)
+		var upstream []*TaintFlowStep
+		switch {
+		case strings.HasPrefix(lower, "steps."):
+			upstream = tracker.FlowOf(expr)
+		case strings.HasPrefix(lower, "needs."):
+			upstream = m.flowOfExprStr(lower)
+		}
+		if len(upstream) > 0 {
+			flow = upstream
+			pos = expressionPosition(value, expr)
+			break
+		}
+	}
+	jobID = strings.ToLower(jobID)
+	outputName = strings.ToLower(outputName)
+	if m.jobOutputFlows[jobID] == nil {
+		m.jobOutputFlows[jobID] = make(map[string][]*TaintFlowStep)
+	}
+	m.jobOutputFlows[jobID][outputName] = extendTaintFlow(flow, newTaintFlowStep(pos, "job output needs.%s.outputs.%s", jobID, outputName))
+}
+
+// FlowFromExprNode returns the path of the tainted value of a
+// needs.X.outputs.Y expression from its source to the job output, or nil.
+func (m *WorkflowTaintMap) FlowFromExprNode(node expressions.ExprNode) []*TaintFlowStep {
+	return m.flowOfExprStr(strings.ToLower(exprNodeToString(node)))
+}
+
+func (m *WorkflowTaintMap) flowOfExprStr(lower string) []*TaintFlowStep {
+	parts := strings.Split(lower, ".")
+	if len(parts) < 4 || parts[0] != "needs" || parts[2] != "outputs" {
+		return nil
+	}
+	return m.jobOutputFlows[parts[1]][strings.Join(parts[3:], ".")]
+}
+
 // extractTaintSourcesFromValue extracts taint sources from an output value expression string.
 // Handles both steps.X.outputs.Y (via tracker) and needs.X.outputs.Y (via self).
 // Uses extractExpressionsFromString (which searches for "}}" as a delimiter) so that
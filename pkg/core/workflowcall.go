@@ -22,6 +22,7 @@ func WorkflowCall(workflowPath string, cache *LocalReusableWorkflowCache) *RuleW
 		BaseRule: BaseRule{
 			RuleName: "workflow-call",
 			RuleDesc: "Checks for reusable workflow calls. Inputs and outputs of called reusable workflow are checked",
+			severity: SeverityMedium,
 		},
 		workflowCallEventPos: nil,
 		workflowPath:         workflowPath,
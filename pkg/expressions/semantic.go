@@ -415,6 +415,16 @@ func (sema *ExprSemanticsChecker) UpdateSecrets(ty *ObjectType) {
 	sema.vars["secrets"] = copied
 }
 
+// UpdateUntrustedInputs replaces the search roots of untrusted inputs. This is used to treat
+// properties of contexts such as 'inputs' as untrusted in addition to BuiltinUntrustedInputs.
+// This method has no effect when the checker was created without untrusted input check.
+func (sema *ExprSemanticsChecker) UpdateUntrustedInputs(roots ContextPropertySearchRoots) {
+	if sema.untrusted == nil {
+		return
+	}
+	sema.untrusted = NewUntiChecker(roots)
+}
+
 // UpdateInputs updates 'inputs' context object to given object type.
 func (sema *ExprSemanticsChecker) UpdateInputs(ty *ObjectType) {
 	sema.ensureVarsCopied()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"strings"
@@ -33,57 +34,134 @@ type Fetcher struct {
 	archiveClient *http.Client
 	// allowInsecureArchive is test-only; production archive URLs must use TLS.
 	allowInsecureArchive bool
-	limit                int
+	// insecureArchiveHost is the host of a GitHub Enterprise Server reached
+	// over plain HTTP. Archives are downloaded over HTTP from that host only.
+	insecureArchiveHost string
+	limit               int
 }
 
 // NewFetcher creates a new Fetcher
 func NewFetcher(limit int) (*Fetcher, error) {
-	return NewFetcherWithToken(limit, getToken())
+	return NewFetcherWithAPIURL(limit, "")
+}
+
+// NewFetcherWithAPIURL creates a Fetcher for the GitHub instance whose REST
+// API is at apiURL, resolving the token with the same fallback chain as
+// NewFetcher. An empty apiURL means GitHub.com.
+func NewFetcherWithAPIURL(limit int, apiURL string) (*Fetcher, error) {
+	host := "github.com"
+	if apiURL != "" {
+		u, err := parseAPIURL(apiURL)
+		if err != nil {
+			return nil, err
+		}
+		host = u.Host
+	}
+	return NewFetcherWithTokenAndAPIURL(limit, getToken(host), apiURL)
 }
 
 // NewFetcherWithToken creates a Fetcher with an explicitly resolved token.
 // CLI callers should use this constructor so -github-token and the standard
 // environment fallback chain are shared by remote scans and lint rules.
-// The fetcher targets GitHub.com; custom API base URLs and GitHub Enterprise
-// Server are not currently supported.
 func NewFetcherWithToken(limit int, token string) (*Fetcher, error) {
+	return NewFetcherWithTokenAndAPIURL(limit, token, "")
+}
+
+// NewFetcherWithTokenAndAPIURL creates a Fetcher for the GitHub Enterprise
+// Server whose REST API is at apiURL (e.g. https://ghe.example.com/api/v3).
+// The token is sent to that host only. An empty apiURL means GitHub.com.
+func NewFetcherWithTokenAndAPIURL(limit int, token, apiURL string) (*Fetcher, error) {
 	apiClient := &http.Client{Timeout: 2 * time.Minute}
+	var apiHost string
+	var insecureArchiveHost string
+	if apiURL != "" {
+		u, err := parseAPIURL(apiURL)
+		if err != nil {
+			return nil, err
+		}
+		apiHost = u.Hostname()
+		if u.Scheme == "http" {
+			insecureArchiveHost = u.Host
+		}
+	}
 	if token != "" {
 		apiClient.Transport = &tokenTransport{
-			token: token,
-			base:  http.DefaultTransport,
+			token:   token,
+			apiHost: apiHost,
+			base:    http.DefaultTransport,
 		}
 	}
 
 	client := github.NewClient(apiClient)
+	if apiURL != "" {
+		var err error
+		client, err = client.WithEnterpriseURLs(apiURL, apiURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub API URL %q: %w", apiURL, err)
+		}
+	}
 
 	return &Fetcher{
-		client:        client,
-		archiveClient: newArchiveHTTPClient(),
-		limit:         limit,
+		client:              client,
+		archiveClient:       newArchiveHTTPClient(insecureArchiveHost),
+		insecureArchiveHost: insecureArchiveHost,
+		limit:               limit,
 	}, nil
 }
 
-// getToken retrieves authentication token using a fallback chain
+// NormalizeAPIURL validates the REST API base URL of a GitHub instance and
+// returns it in canonical form. The API of GitHub.com and an empty string are
+// both returned as "", meaning the default.
+func NormalizeAPIURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	u, err := parseAPIURL(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "api.github.com" {
+		return "", nil
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/"
+	return u.String(), nil
+}
+
+func parseAPIURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: %w", raw, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: must be an absolute http(s) URL such as https://github.example.com/api/v3", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: must not contain credentials, a query, or a fragment", raw)
+	}
+	return u, nil
+}
+
+// getToken retrieves authentication token for host using a fallback chain
 // Priority: environment variable → gh CLI → git credential
-func getToken() string {
+func getToken(host string) string {
 	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
 		return token
 	}
 	if token := os.Getenv("GH_TOKEN"); token != "" {
 		return token
 	}
-	if token, err := getTokenFromGhCLI(); err == nil && token != "" {
+	if token, err := getTokenFromGhCLI(host); err == nil && token != "" {
 		return token
 	}
-	if token, err := getTokenFromGitCredential(); err == nil && token != "" {
+	if token, err := getTokenFromGitCredential(host); err == nil && token != "" {
 		return token
 	}
 	return ""
 }
 
-func getTokenFromGhCLI() (string, error) {
-	cmd := exec.CommandContext(context.Background(), "gh", "auth", "token")
+func getTokenFromGhCLI(host string) (string, error) {
+	cmd := exec.CommandContext(context.Background(), "gh", "auth", "token", "--hostname", host)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -91,9 +169,9 @@ func getTokenFromGhCLI() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-func getTokenFromGitCredential() (string, error) {
+func getTokenFromGitCredential(host string) (string, error) {
 	cmd := exec.CommandContext(context.Background(), "git", "credential", "fill")
-	cmd.Stdin = strings.NewReader("protocol=https\nhost=github.com\n")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=" + host + "\n")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -109,7 +187,10 @@ func getTokenFromGitCredential() (string, error) {
 // tokenTransport is a Transport that adds token to GitHub API requests
 type tokenTransport struct {
 	token string
-	base  http.RoundTripper
+	// apiHost is the GitHub Enterprise Server host. When empty, the token is
+	// sent to the GitHub.com API hosts only.
+	apiHost string
+	base    http.RoundTripper
 }
 
 func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -121,7 +202,7 @@ func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	// Never forward a GitHub token to the signed archive host (or any other
 	// redirect target). The archive downloader intentionally uses a separate
 	// unauthenticated client as a second line of defense.
-	if !isGitHubAPIHost(req.URL.Hostname()) {
+	if !t.isAPIHost(req.URL.Hostname()) {
 		clonedReq.Header.Del("Authorization")
 		return base.RoundTrip(clonedReq)
 	}
@@ -129,6 +210,13 @@ func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return base.RoundTrip(clonedReq)
 }
 
+func (t *tokenTransport) isAPIHost(host string) bool {
+	if t.apiHost != "" {
+		return strings.EqualFold(host, t.apiHost)
+	}
+	return isGitHubAPIHost(host)
+}
+
 func isGitHubAPIHost(host string) bool {
 	return host == "api.github.com" || host == "uploads.github.com"
 }
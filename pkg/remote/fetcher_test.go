@@ -66,3 +66,58 @@ func TestTokenTransportRestrictsCredentialsToGitHubAPIHosts(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenTransportSendsCredentialsToEnterpriseHostOnly(t *testing.T) {
+	tests := []struct {
+		host     string
+		wantAuth string
+	}{
+		{host: "ghe.example.com", wantAuth: "Bearer test-token"},
+		{host: "api.github.com", wantAuth: ""},
+		{host: "codeload.ghe.example.com", wantAuth: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			var gotAuth string
+			transport := &tokenTransport{
+				token:   "test-token",
+				apiHost: "ghe.example.com",
+				base: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+					gotAuth = request.Header.Get("Authorization")
+					return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody, Request: request}, nil
+				}),
+			}
+			request, err := http.NewRequest(http.MethodGet, "https://"+tt.host+"/api/v3/resource", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := transport.RoundTrip(request); err != nil {
+				t.Fatal(err)
+			}
+			if gotAuth != tt.wantAuth {
+				t.Fatalf("Authorization = %q, want %q", gotAuth, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestNormalizeAPIURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "", want: ""},
+		{in: "https://api.github.com", want: ""},
+		{in: "https://api.github.com/", want: ""},
+		{in: "https://ghe.example.com/api/v3", want: "https://ghe.example.com/api/v3/"},
+		{in: " https://api.tenant.ghe.com ", want: "https://api.tenant.ghe.com/"},
+		{in: "ftp://ghe.example.com", wantErr: true},
+		{in: "https://ghe.example.com/api/v3?x=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeAPIURL(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeAPIURL(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
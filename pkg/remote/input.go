@@ -28,9 +28,15 @@ type ParsedInput struct {
 
 // ParseInput automatically detects and parses the input string
 func ParseInput(input string) (*ParsedInput, error) {
+	return ParseInputForAPIURL(input, "")
+}
+
+// ParseInputForAPIURL is ParseInput for the GitHub instance whose REST API is
+// at apiURL. URLs of its web host are accepted in addition to github.com.
+func ParseInputForAPIURL(input, apiURL string) (*ParsedInput, error) {
 	// 1. Check URL format: https://github.com/owner/repo
 	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
-		return parseURL(input)
+		return parseURL(input, webHostOfAPIURL(apiURL))
 	}
 
 	// 2. Check owner/repo format
@@ -42,13 +48,45 @@ func ParseInput(input string) (*ParsedInput, error) {
 	return parseSearchQuery(input), nil
 }
 
-func parseURL(input string) (*ParsedInput, error) {
+// webHostOfAPIURL returns the host serving the web UI of the GitHub instance
+// whose API is at apiURL: "ghe.example.com" for https://ghe.example.com/api/v3
+// and "tenant.ghe.com" for https://api.tenant.ghe.com. It is empty for
+// GitHub.com.
+func webHostOfAPIURL(apiURL string) string {
+	if apiURL == "" {
+		return ""
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Host, "api.")
+}
+
+// ServerURL returns the web URL of the GitHub instance whose REST API is at
+// apiURL, such as "https://github.com" for an empty apiURL.
+func ServerURL(apiURL string) string {
+	host := webHostOfAPIURL(apiURL)
+	if host == "" {
+		return "https://github.com"
+	}
+	scheme := "https"
+	if u, err := url.Parse(apiURL); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	return scheme + "://" + host
+}
+
+func parseURL(input, enterpriseHost string) (*ParsedInput, error) {
 	u, err := url.Parse(input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
-	if u.Host != "github.com" {
+	if u.Host != "github.com" && (enterpriseHost == "" || !strings.EqualFold(u.Host, enterpriseHost)) {
+		if enterpriseHost != "" {
+			return nil, fmt.Errorf("URLs other than github.com and %s are not supported: %s", enterpriseHost, u.Host)
+		}
 		return nil, fmt.Errorf("URLs other than github.com are not supported: %s", u.Host)
 	}
 
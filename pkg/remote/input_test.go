@@ -263,3 +263,19 @@ func TestIsOwnerRepoFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestParseInputForAPIURL_EnterpriseURL(t *testing.T) {
+	parsed, err := ParseInputForAPIURL("https://ghe.example.com/owner/repo/pull/12", "https://ghe.example.com/api/v3/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Owner != "owner" || parsed.Repo != "repo" || parsed.PullNumber != 12 {
+		t.Errorf("unexpected parse result: %+v", parsed)
+	}
+	if _, err := ParseInputForAPIURL("https://other.example.com/owner/repo", "https://ghe.example.com/api/v3/"); err == nil {
+		t.Error("URL of another host should be rejected")
+	}
+	if _, err := ParseInputForAPIURL("https://tenant.ghe.com/owner/repo", "https://api.tenant.ghe.com/"); err != nil {
+		t.Errorf("GHE.com URL should be accepted: %v", err)
+	}
+}
@@ -15,6 +15,11 @@ import (
 // LintFunc is the function type that scans workflows
 type LintFunc func(filepath string, content []byte) (hasErrors bool, err error)
 
+// ReportFunc scans a workflow like LintFunc and returns its findings. The
+// scanner does not look into them; it collects them per repository in
+// ScanResult.Findings.
+type ReportFunc func(filepath string, content []byte) (findings []interface{}, err error)
+
 // Scanner scans remote repositories
 type Scanner struct {
 	fetcher     *Fetcher
@@ -24,6 +29,8 @@ type Scanner struct {
 	verbose     bool
 	output      io.Writer
 	lintFunc    LintFunc
+	reportFunc  ReportFunc
+	apiURL      string
 }
 
 // ScanResult represents scan result
@@ -31,6 +38,9 @@ type ScanResult struct {
 	Repository *RepositoryInfo
 	HasErrors  bool  // whether there were errors
 	Error      error // errors for the entire repository
+	// Findings are the findings returned by ReportFunc for the workflows of
+	// the repository and the reusable workflows it calls, in scan order.
+	Findings []interface{}
 }
 
 // ReusableAction represents a reusable workflow
@@ -51,7 +61,13 @@ type ScannerOptions struct {
 	Verbose     bool
 	Output      io.Writer
 	LintFunc    LintFunc
+	// ReportFunc, when set, is used instead of LintFunc so that findings are
+	// collected in ScanResult.Findings.
+	ReportFunc  ReportFunc
 	GitHubToken string
+	// GitHubAPIURL is the REST API base URL of a GitHub Enterprise Server.
+	// Empty means GitHub.com.
+	GitHubAPIURL string
 }
 
 // NewScanner creates a new Scanner
@@ -65,7 +81,7 @@ func NewScanner(opts *ScannerOptions) (*Scanner, error) {
 	if opts.Limit <= 0 {
 		return nil, fmt.Errorf("limit must be greater than zero")
 	}
-	if opts.LintFunc == nil {
+	if opts.LintFunc == nil && opts.ReportFunc == nil {
 		return nil, fmt.Errorf("LintFunc is not specified")
 	}
 
@@ -74,9 +90,9 @@ func NewScanner(opts *ScannerOptions) (*Scanner, error) {
 	if opts.GitHubToken == "" {
 		// Preserve the library/CLI fallback chain (environment, gh CLI, then
 		// git credential) when no explicit token was resolved by the caller.
-		fetcher, err = NewFetcher(opts.Limit)
+		fetcher, err = NewFetcherWithAPIURL(opts.Limit, opts.GitHubAPIURL)
 	} else {
-		fetcher, err = NewFetcherWithToken(opts.Limit, opts.GitHubToken)
+		fetcher, err = NewFetcherWithTokenAndAPIURL(opts.Limit, opts.GitHubToken, opts.GitHubAPIURL)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize Fetcher: %w", err)
@@ -95,12 +111,14 @@ func NewScanner(opts *ScannerOptions) (*Scanner, error) {
 		verbose:     opts.Verbose,
 		output:      output,
 		lintFunc:    opts.LintFunc,
+		reportFunc:  opts.ReportFunc,
+		apiURL:      opts.GitHubAPIURL,
 	}, nil
 }
 
 // Scan parses input and scans repositories
 func (s *Scanner) Scan(ctx context.Context, input string) ([]*ScanResult, error) {
-	parsedInput, err := ParseInput(input)
+	parsedInput, err := ParseInputForAPIURL(input, s.apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse input: %w", err)
 	}
@@ -176,25 +194,36 @@ func (s *Scanner) scanRepository(ctx context.Context, repo *RepositoryInfo) *Sca
 		}
 	}
 
-	hasErrors := false
+	result := &ScanResult{Repository: repo}
 	var scanned sync.Map
 
 	for _, wf := range workflows {
 		if ctx.Err() != nil {
 			break
 		}
-		if s.scanWorkflowRecursive(ctx, wf, 0, &scanned) {
-			hasErrors = true
+		if s.scanWorkflowRecursive(ctx, wf, 0, &scanned, result) {
+			result.HasErrors = true
 		}
 	}
 
-	return &ScanResult{
-		Repository: repo,
-		HasErrors:  hasErrors,
+	return result
+}
+
+// lint runs ReportFunc or LintFunc on a workflow and appends its findings to
+// result.
+func (s *Scanner) lint(virtualPath string, content []byte, result *ScanResult) (bool, error) {
+	if s.reportFunc == nil {
+		return s.lintFunc(virtualPath, content)
+	}
+	findings, err := s.reportFunc(virtualPath, content)
+	if err != nil {
+		return false, err
 	}
+	result.Findings = append(result.Findings, findings...)
+	return len(findings) > 0, nil
 }
 
-func (s *Scanner) scanWorkflowRecursive(ctx context.Context, wf *WorkflowFile, currentDepth int, scanned *sync.Map) bool {
+func (s *Scanner) scanWorkflowRecursive(ctx context.Context, wf *WorkflowFile, currentDepth int, scanned *sync.Map, result *ScanResult) bool {
 	if ctx.Err() != nil {
 		return false
 	}
@@ -210,7 +239,7 @@ func (s *Scanner) scanWorkflowRecursive(ctx context.Context, wf *WorkflowFile, c
 		fmt.Fprintf(s.output, "%sScanning: %s (depth: %d)\n", indent, virtualPath, currentDepth)
 	}
 
-	hasErrors, err := s.lintFunc(virtualPath, wf.Content)
+	hasErrors, err := s.lint(virtualPath, wf.Content, result)
 	if err != nil {
 		if s.verbose {
 			fmt.Fprintf(s.output, "Error scanning %s: %v\n", virtualPath, err)
@@ -248,7 +277,7 @@ func (s *Scanner) scanWorkflowRecursive(ctx context.Context, wf *WorkflowFile, c
 				continue
 			}
 
-			if s.scanWorkflowRecursive(ctx, actionWorkflow, currentDepth+1, scanned) {
+			if s.scanWorkflowRecursive(ctx, actionWorkflow, currentDepth+1, scanned, result) {
 				hasErrors = true
 			}
 		}
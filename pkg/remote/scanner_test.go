@@ -400,7 +400,7 @@ func TestScanWorkflowRecursive_ContextCancellation(t *testing.T) {
 	}
 
 	var scanned sync.Map
-	result := scanner.scanWorkflowRecursive(ctx, wf, 0, &scanned)
+	result := scanner.scanWorkflowRecursive(ctx, wf, 0, &scanned, &ScanResult{})
 
 	if result {
 		t.Errorf("scanWorkflowRecursive with canceled context should return false")
@@ -431,12 +431,12 @@ func TestScanWorkflowRecursive_AlreadyScanned(t *testing.T) {
 
 	var scanned sync.Map
 
-	scanner.scanWorkflowRecursive(ctx, wf, 0, &scanned)
+	scanner.scanWorkflowRecursive(ctx, wf, 0, &scanned, &ScanResult{})
 	if callCount != 1 {
 		t.Errorf("First scan: lintFunc called %d times, want 1", callCount)
 	}
 
-	scanner.scanWorkflowRecursive(ctx, wf, 0, &scanned)
+	scanner.scanWorkflowRecursive(ctx, wf, 0, &scanned, &ScanResult{})
 	if callCount != 1 {
 		t.Errorf("Second scan: lintFunc called %d times, want 1 (should skip)", callCount)
 	}
@@ -492,3 +492,27 @@ func TestSyncMapConcurrency(t *testing.T) {
 		}
 	}
 }
+
+func TestScanWorkflowRecursive_ReportFuncCollectsFindings(t *testing.T) {
+	scanner := &Scanner{
+		output: io.Discard,
+		reportFunc: func(path string, _ []byte) ([]interface{}, error) {
+			return []interface{}{path + ":1", path + ":2"}, nil
+		},
+	}
+	wf := &WorkflowFile{
+		Path:     ".github/workflows/ci.yml",
+		Content:  []byte("on: push\njobs: {}\n"),
+		RepoInfo: &RepositoryInfo{Owner: "owner", Name: "repo", FullName: "owner/repo"},
+	}
+
+	var scanned sync.Map
+	result := &ScanResult{}
+	if !scanner.scanWorkflowRecursive(context.Background(), wf, 0, &scanned, result) {
+		t.Error("workflow with findings should report errors")
+	}
+	want := []interface{}{"owner/repo/.github/workflows/ci.yml:1", "owner/repo/.github/workflows/ci.yml:2"}
+	if len(result.Findings) != len(want) || result.Findings[0] != want[0] || result.Findings[1] != want[1] {
+		t.Errorf("Findings = %v, want %v", result.Findings, want)
+	}
+}
@@ -57,14 +57,17 @@ func (s *RepositorySnapshot) Close() error {
 	return os.RemoveAll(s.Root)
 }
 
-func newArchiveHTTPClient() *http.Client {
+// newArchiveHTTPClient returns the unauthenticated client for repository
+// archives. insecureHost is a GitHub Enterprise Server host which is served
+// over plain HTTP, or empty.
+func newArchiveHTTPClient(insecureHost string) *http.Client {
 	return &http.Client{
 		Timeout: 2 * time.Minute,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= maxArchiveRedirects {
 				return errors.New("too many archive redirects")
 			}
-			if req.URL.Scheme != "https" {
+			if !archiveSchemeAllowed(req.URL, insecureHost) {
 				return fmt.Errorf("repository archive redirect must use HTTPS, got %q", req.URL.Scheme)
 			}
 			// GitHub archive links are pre-signed. Never copy Authorization or
@@ -209,6 +212,16 @@ func (f *Fetcher) MaterializePullRequest(
 	}, nil
 }
 
+// archiveSchemeAllowed reports whether an archive may be downloaded from u.
+// Plain HTTP is accepted only from a GitHub Enterprise Server host which the
+// user configured with an http:// API URL.
+func archiveSchemeAllowed(u *url.URL, insecureHost string) bool {
+	if u.Scheme == "https" {
+		return true
+	}
+	return u.Scheme == "http" && insecureHost != "" && strings.EqualFold(u.Host, insecureHost)
+}
+
 func createSnapshotDestination(destination string) (root string, removeOnClose bool, err error) {
 	if destination == "" {
 		root, err = os.MkdirTemp("", "sisakulint-remote-")
@@ -280,12 +293,12 @@ func isWorkflowPath(name string) bool {
 }
 
 func (f *Fetcher) downloadAndExtractArchive(ctx context.Context, archiveURL *url.URL, root string) error {
-	if archiveURL.Scheme != "https" && !(f.allowInsecureArchive && archiveURL.Scheme == "http") {
+	if !archiveSchemeAllowed(archiveURL, f.insecureArchiveHost) && !(f.allowInsecureArchive && archiveURL.Scheme == "http") {
 		return fmt.Errorf("repository archive URL must use HTTPS, got %q", archiveURL.Scheme)
 	}
 	client := f.archiveClient
 	if client == nil {
-		client = newArchiveHTTPClient()
+		client = newArchiveHTTPClient(f.insecureArchiveHost)
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL.String(), nil)
 	if err != nil {
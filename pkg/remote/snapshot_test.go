@@ -202,7 +202,7 @@ func TestMaterializePullRequestRejectsRevisionChangeDuringFileListing(t *testing
 }
 
 func TestNewArchiveHTTPClientRedirectPolicy(t *testing.T) {
-	checkRedirect := newArchiveHTTPClient().CheckRedirect
+	checkRedirect := newArchiveHTTPClient("").CheckRedirect
 	if checkRedirect == nil {
 		t.Fatal("CheckRedirect is nil")
 	}
@@ -443,3 +443,52 @@ func makeTarGzip(t *testing.T, files map[string]string) []byte {
 	}
 	return buffer.Bytes()
 }
+
+func TestMaterializePullRequestFromEnterpriseServer(t *testing.T) {
+	archive := makeTarGzip(t, map[string]string{
+		"repository-root/.github/workflows/changed.yml": "name: Changed\non: push\njobs: {}\n",
+	})
+
+	var archiveAuth atomic.Value
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/v3/") && r.Header.Get("Authorization") != "Bearer ghes-token" {
+			t.Errorf("API request %s without the enterprise token", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo/pulls/7":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"number":7,"head":{"sha":%q},"base":{"sha":%q}}`, snapshotTestSHA, snapshotTestBaseSHA)
+		case "/api/v3/repos/owner/repo/pulls/7/files":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[{"filename":".github/workflows/changed.yml","status":"modified"}]`)
+		case "/api/v3/repos/owner/repo/tarball/" + snapshotTestSHA:
+			http.Redirect(w, r, server.URL+"/_codeload/owner/repo/legacy.tar.gz/"+snapshotTestSHA, http.StatusFound)
+		case "/_codeload/owner/repo/legacy.tar.gz/" + snapshotTestSHA:
+			archiveAuth.Store(r.Header.Get("Authorization"))
+			w.Header().Set("Content-Type", "application/gzip")
+			_, _ = w.Write(archive)
+		default:
+			t.Errorf("unexpected request path %q", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher, err := NewFetcherWithTokenAndAPIURL(1, "ghes-token", server.URL+"/api/v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := fetcher.MaterializePullRequest(context.Background(), "owner", "repo", 7, nil)
+	if err != nil {
+		t.Fatalf("MaterializePullRequest returned error: %v", err)
+	}
+	defer snapshot.Close()
+
+	if !slices.Equal(snapshot.WorkflowPaths, []string{".github/workflows/changed.yml"}) {
+		t.Fatalf("WorkflowPaths = %#v", snapshot.WorkflowPaths)
+	}
+	if got := archiveAuth.Load(); got != "" {
+		t.Errorf("archive download sent Authorization %q", got)
+	}
+}